@@ -0,0 +1,176 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// ACCESS RULES
+//--------------------
+
+// Access defines the permitted kinds of access to paths.
+type Access int
+
+// Kinds of access. They can be combined.
+const (
+	// ReadAccess permits reading the values.
+	ReadAccess Access = 1 << iota
+
+	// WriteAccess permits setting and deleting the values.
+	WriteAccess
+
+	// NoAccess denies reading and writing.
+	NoAccess Access = 0
+
+	// FullAccess permits reading and writing.
+	FullAccess = ReadAccess | WriteAccess
+)
+
+// AccessRule grants the access to the paths matching the pattern and
+// all paths below them. Patterns are the same as for queries.
+type AccessRule struct {
+	Pattern string
+	Access  Access
+}
+
+// AccessRules contain the rules for a restricted document. Paths not
+// matched by a rule have the default access. The rules are applied
+// from the root down to the path, so a rule matching a deeper path
+// overrides those of its ancestors. For the same path the last matching
+// rule wins. This way e.g. "/" can be granted fully, "/secrets" denied,
+// and "/secrets/public" granted for reading again.
+type AccessRules struct {
+	Default Access
+	Rules   []AccessRule
+}
+
+// access returns the access to the path.
+func (r AccessRules) access(path Path) Access {
+	access := r.Default
+	keys := splitPath(path)
+	for i := 0; i <= len(keys); i++ {
+		ancestor := pathify(keys[:i])
+		for _, rule := range r.Rules {
+			if MatchPattern(rule.Pattern, ancestor) {
+				access = rule.Access
+			}
+		}
+	}
+	return access
+}
+
+//--------------------
+// RESTRICTED DOCUMENT
+//--------------------
+
+// restriction contains the rules of a restricted document and the paths
+// of the elements hidden because reading them is denied.
+type restriction struct {
+	rules  AccessRules
+	hidden []Path
+}
+
+// Restricted returns a copy of the document for untrusted users like
+// plugins or tenants. Values which may not be read are removed from the
+// copy, objects and arrays only if none of their elements may be read.
+// Hidden array elements are kept as null to keep the indices. Reading
+// denied values returns undefined nodes. Writing at paths without write
+// access, or above hidden elements, returns an error wrapping
+// ErrForbidden. The permitted changes are recorded, so they can be
+// retrieved with Operations and applied to the original document with
+// ApplyOperations.
+func (d *Document) Restricted(rules AccessRules) *Document {
+	r := &restriction{
+		rules: rules,
+	}
+	root, _ := r.filter(d.root, Separator)
+	restricted := d.derive(root)
+	restricted.strict = d.strict
+	restricted.arrays = d.arrays
+	restricted.typesLocked = d.typesLocked
+	restricted.restriction = r
+	restricted.recording = true
+	return restricted
+}
+
+// filter copies the element without the values which may not be read
+// and collects their paths. Objects and arrays which may not be read are
+// kept if they contain readable elements. It returns false if the
+// element itself is hidden.
+func (r *restriction) filter(element Element, path Path) (Element, bool) {
+	if decoded, err := decodeRaw(element); err == nil {
+		element = decoded
+	}
+	readable := r.rules.access(path)&ReadAccess != 0
+	hidden := len(r.hidden)
+	visible := readable
+	var filtered Element
+	switch typed := element.(type) {
+	case Object:
+		obj := make(Object, len(typed))
+		for key, child := range typed {
+			if kept, ok := r.filter(child, appendKey(path, key)); ok {
+				obj[key] = kept
+				visible = true
+			}
+		}
+		filtered = obj
+	case Array:
+		arr := make(Array, len(typed))
+		for idx, child := range typed {
+			kept, ok := r.filter(child, appendKey(path, strconv.Itoa(idx)))
+			arr[idx] = kept
+			visible = visible || ok
+		}
+		filtered = arr
+	default:
+		filtered = copyElement(element)
+	}
+	if !visible {
+		// Hide the whole element instead of its children.
+		r.hidden = append(r.hidden[:hidden], path)
+		return nil, false
+	}
+	return filtered, true
+}
+
+// readable checks if the element at the path may be read. Objects and
+// arrays kept for their readable elements may be read too.
+func (d *Document) readable(path Path, element Element) bool {
+	if d.restriction == nil || d.restriction.rules.access(path)&ReadAccess != 0 {
+		return true
+	}
+	return isObjectOrArray(element)
+}
+
+// checkAccess checks if the path may be written. This is denied too if
+// hidden elements would be replaced or deleted.
+func (d *Document) checkAccess(keys Keys) error {
+	if d.restriction == nil {
+		return nil
+	}
+	path := pathify(keys)
+	if d.restriction.rules.access(path)&WriteAccess == 0 {
+		return fmt.Errorf("%w: cannot write at %q", ErrForbidden, path)
+	}
+	for _, hidden := range d.restriction.hidden {
+		if IsAncestor(path, hidden) {
+			return fmt.Errorf("%w: cannot write at %q: contains hidden elements", ErrForbidden, path)
+		}
+	}
+	return nil
+}
+
+// EOF
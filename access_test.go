@@ -0,0 +1,147 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestRestricted tests reading and writing restricted documents.
+func TestRestricted(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"name": "service",
+		"secrets": {"token": "abc", "public": "key"},
+		"plugins": {"a": {"enabled": true}},
+		"users": [{"name": "x", "password": "1"}, {"name": "y", "password": "2"}]
+	}`)
+	restricted := doc.Restricted(dynaj.AccessRules{
+		Default: dynaj.ReadAccess,
+		Rules: []dynaj.AccessRule{
+			{Pattern: "/secrets", Access: dynaj.NoAccess},
+			{Pattern: "/secrets/public", Access: dynaj.ReadAccess},
+			{Pattern: "/plugins/*", Access: dynaj.FullAccess},
+			{Pattern: "/users/*/password", Access: dynaj.NoAccess},
+		},
+	})
+
+	// Reading.
+	assert.Equal(restricted.NodeAt("/name").AsString(""), "service")
+	assert.True(restricted.NodeAt("/secrets/token").IsUndefined())
+	assert.Equal(restricted.NodeAt("/secrets/public").AsString(""), "key")
+	assert.True(restricted.NodeAt("/users/1/password").IsUndefined())
+	assert.Equal(restricted.NodeAt("/users/1/name").AsString(""), "y")
+	assert.Equal(restricted.Length("/secrets"), 1)
+	assert.Equal(restricted.Length("/secrets/token"), -1)
+	data, err := restricted.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"name":"service","plugins":{"a":{"enabled":true}},"secrets":{"public":"key"},"users":[{"name":"x"},{"name":"y"}]}`)
+
+	// Writing.
+	err = restricted.SetValueAt("/name", "other")
+	assert.True(errors.Is(err, dynaj.ErrForbidden))
+	assert.ErrorContains(err, `access forbidden: cannot write at "/name"`)
+	assert.True(errors.Is(restricted.DeleteElementAt("/users/0"), dynaj.ErrForbidden))
+	assert.True(errors.Is(restricted.SetRawAt("/secrets/token", []byte(`"x"`)), dynaj.ErrForbidden))
+	assert.True(errors.Is(restricted.AddToArray("/users", "z"), dynaj.ErrForbidden))
+	_, err = restricted.ApplyAll([]dynaj.Patch{{Merge: map[string]any{"name": "x"}}})
+	assert.True(errors.Is(err, dynaj.ErrForbidden))
+	restricted.Clear()
+	assert.Equal(restricted.NodeAt("/name").AsString(""), "service")
+	err = restricted.MkdirAll("/locked/deep/dir")
+	assert.True(errors.Is(err, dynaj.ErrForbidden))
+	assert.True(restricted.NodeAt("/locked").IsError())
+	changed := mustUnmarshal(assert, `{"name": "other"}`)
+	delta, err := dynaj.Delta(restricted, changed)
+	assert.NoError(err)
+	err = dynaj.ApplyDelta(restricted, delta)
+	assert.True(errors.Is(err, dynaj.ErrForbidden))
+	assert.Equal(restricted.NodeAt("/name").AsString(""), "service")
+	assert.Length(restricted.Operations(), 0)
+
+	assert.NoError(restricted.MkdirAll("/plugins/c/deep/dir"))
+	assert.True(restricted.NodeAt("/plugins/c/deep/dir").IsObject())
+	assert.NoError(restricted.SetValueAt("/plugins/a/enabled", false))
+	assert.NoError(restricted.SetValueAt("/plugins/b", map[string]any{"enabled": true}))
+	assert.NoError(restricted.DeleteElementAt("/plugins/a"))
+	assert.Equal(restricted.NodeAt("/plugins/b/enabled").AsBool(false), true)
+
+	// Taking over the changes.
+	assert.NoError(doc.ApplyOperations(restricted.Operations()))
+	assert.True(doc.NodeAt("/plugins/a").IsError())
+	assert.Equal(doc.NodeAt("/plugins/b/enabled").AsBool(false), true)
+	assert.Equal(doc.NodeAt("/secrets/token").AsString(""), "abc")
+	assert.Equal(doc.NodeAt("/users/0/password").AsString(""), "1")
+}
+
+// TestRestrictedHidden tests protecting hidden elements against writes.
+func TestRestrictedHidden(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"public": {"a": 1}, "config": {"key": "secret", "level": 1}}`)
+	restricted := doc.Restricted(dynaj.AccessRules{
+		Default: dynaj.NoAccess,
+		Rules: []dynaj.AccessRule{
+			{Pattern: "/public", Access: dynaj.FullAccess},
+			{Pattern: "/config", Access: dynaj.FullAccess},
+			{Pattern: "/config/key", Access: dynaj.WriteAccess},
+		},
+	})
+
+	assert.Equal(restricted.NodeAt("/public/a").AsInt(0), 1)
+	assert.True(restricted.NodeAt("/config/key").IsUndefined())
+	assert.False(restricted.NodeAt("/").IsUndefined())
+
+	// Writing above hidden elements is forbidden, writing them is allowed.
+	err := restricted.SetValueAt("/config", map[string]any{})
+	assert.ErrorContains(err, `cannot write at "/config": contains hidden elements`)
+	assert.NoError(restricted.SetValueAt("/config/key", "new"))
+	assert.True(restricted.NodeAt("/config/key").IsUndefined())
+	assert.NoError(restricted.SetValueAt("/public/a", "replaced"))
+	assert.True(errors.Is(restricted.SetValueAt("/other", 1), dynaj.ErrForbidden))
+
+	assert.NoError(doc.ApplyOperations(restricted.Operations()))
+	assert.Equal(doc.NodeAt("/config/key").AsString(""), "new")
+	assert.Equal(doc.NodeAt("/public/a").AsString(""), "replaced")
+
+	// Frozen documents cannot take over changes.
+	err = doc.Freeze().ApplyOperations(restricted.Operations())
+	assert.True(errors.Is(err, dynaj.ErrFrozen))
+}
+
+// TestRestrictedNegated tests negated patterns, which do not deny the
+// way to and the content of the excluded paths.
+func TestRestrictedNegated(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"public": {"a": 1}, "config": {"key": "secret"}}`)
+	restricted := doc.Restricted(dynaj.AccessRules{
+		Default: dynaj.FullAccess,
+		Rules: []dynaj.AccessRule{
+			{Pattern: "!/public", Access: dynaj.NoAccess},
+		},
+	})
+
+	assert.Equal(restricted.NodeAt("/public/a").AsInt(0), 1)
+	assert.True(restricted.NodeAt("/config/key").IsUndefined())
+	assert.NoError(restricted.SetValueAt("/public/b", 2))
+	assert.True(errors.Is(restricted.SetValueAt("/config/key", "new"), dynaj.ErrForbidden))
+}
+
+// EOF
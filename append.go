@@ -0,0 +1,178 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+)
+
+//--------------------
+// APPEND JSON
+//--------------------
+
+// AppendJSON appends the JSON encoding of the document to dst and
+// returns the extended buffer. The encoding is the same as the one of
+// MarshalJSON, but buffers can be reused.
+func (d *Document) AppendJSON(dst []byte) ([]byte, error) {
+	if d.incremental {
+		return d.appendIncremental(dst)
+	}
+	root, err := normalizeValue(d.root, Separator, d.nonFinite)
+	if err != nil {
+		return dst, fmt.Errorf("cannot marshal document: %v", err)
+	}
+	return appendElement(dst, root)
+}
+
+// AppendJSON appends the JSON encoding of the node including all
+// subnodes to dst and returns the extended buffer.
+func (node *Node) AppendJSON(dst []byte) ([]byte, error) {
+	if node.IsError() {
+		return dst, node.err
+	}
+	element, err := normalizeValue(node.element, node.path, RejectNonFinite)
+	if err != nil {
+		return dst, fmt.Errorf("cannot marshal node: %v", err)
+	}
+	return appendElement(dst, element)
+}
+
+// appendElement recursively appends a normalized element. Keys of
+// objects are sorted like by encoding/json.
+func appendElement(dst []byte, element Element) ([]byte, error) {
+	switch typed := element.(type) {
+	case nil:
+		return append(dst, "null"...), nil
+	case bool:
+		return strconv.AppendBool(dst, typed), nil
+	case string:
+		return appendString(dst, typed), nil
+	case int:
+		return strconv.AppendInt(dst, int64(typed), 10), nil
+	case float64:
+		return appendFloat(dst, typed), nil
+	case Object:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dst = append(dst, '{')
+		for i, key := range keys {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendString(dst, key)
+			dst = append(dst, ':')
+			var err error
+			if dst, err = appendElement(dst, typed[key]); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, '}'), nil
+	case Array:
+		dst = append(dst, '[')
+		for i, child := range typed {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			var err error
+			if dst, err = appendElement(dst, child); err != nil {
+				return dst, err
+			}
+		}
+		return append(dst, ']'), nil
+	}
+	// Not normalized values like from retained containers.
+	data, err := json.Marshal(element)
+	if err != nil {
+		return dst, err
+	}
+	return append(dst, data...), nil
+}
+
+// appendFloat appends a float like encoding/json does.
+func appendFloat(dst []byte, f float64) []byte {
+	format := byte('f')
+	if abs := math.Abs(f); abs != 0 && (abs < 1e-6 || abs >= 1e21) {
+		format = 'e'
+	}
+	dst = strconv.AppendFloat(dst, f, format, -1, 64)
+	if format == 'e' {
+		// Clean up e-09 to e-9.
+		n := len(dst)
+		if n >= 4 && dst[n-4] == 'e' && dst[n-3] == '-' && dst[n-2] == '0' {
+			dst[n-2] = dst[n-1]
+			dst = dst[:n-1]
+		}
+	}
+	return dst
+}
+
+// appendString appends a quoted string like encoding/json does,
+// including the escaping of HTML characters.
+func appendString(dst []byte, s string) []byte {
+	const hex = "0123456789abcdef"
+	dst = append(dst, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= ' ' && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			dst = append(dst, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				dst = append(dst, '\\', c)
+			case '\b':
+				dst = append(dst, '\\', 'b')
+			case '\f':
+				dst = append(dst, '\\', 'f')
+			case '\n':
+				dst = append(dst, '\\', 'n')
+			case '\r':
+				dst = append(dst, '\\', 'r')
+			case '\t':
+				dst = append(dst, '\\', 't')
+			default:
+				dst = append(dst, '\\', 'u', '0', '0', hex[c>>4], hex[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		switch {
+		case r == utf8.RuneError && size == 1:
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, "\ufffd"...)
+		case r == '\u2028' || r == '\u2029':
+			dst = append(dst, s[start:i]...)
+			dst = append(dst, '\\', 'u', '2', '0', '2', hex[r&0xF])
+		default:
+			i += size
+			continue
+		}
+		i += size
+		start = i
+	}
+	dst = append(dst, s[start:]...)
+	return append(dst, '"')
+}
+
+// EOF
@@ -0,0 +1,110 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestAppendJSON tests appending the JSON encoding to buffers.
+func TestAppendJSON(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+
+	buf, err := doc.AppendJSON(nil)
+	assert.NoError(err)
+	assert.Equal(string(buf), string(bs))
+
+	// Reuse the buffer.
+	buf, err = doc.AppendJSON(buf[:0])
+	assert.NoError(err)
+	assert.Equal(string(buf), string(bs))
+	buf, err = doc.NodeAt("/B/1").AppendJSON(append(buf[:0], "prefix "...))
+	assert.NoError(err)
+	expected, err := doc.NodeAt("/B/1").MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(buf), "prefix "+string(expected))
+
+	// Same encoding as encoding/json.
+	doc = dynaj.NewDocument()
+	values := []dynaj.Value{
+		"quote \" backslash \\ <html> & \b\f\n\r\t \x01 \u2028 \u2029 ä😀",
+		0.0, -0.5, 1e20, 1e21, 1e-6, 1e-7, 123456789.125, -1.5e-300,
+		42, -7, true, false, nil,
+		dynaj.Object{}, dynaj.Array{},
+	}
+	for i, value := range values {
+		assert.NoError(doc.SetValueAt("/values/"+string(rune('a'+i)), value))
+	}
+	buf, err = doc.AppendJSON(nil)
+	assert.NoError(err)
+	expected, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(buf), string(expected))
+	assert.True(json.Valid(buf))
+
+	// Invalid UTF-8 is replaced.
+	doc = dynaj.NewDocument()
+	assert.NoError(doc.SetValueAt("/a", "invalid \xff utf-8"))
+	buf, err = doc.AppendJSON(nil)
+	assert.NoError(err)
+	assert.True(json.Valid(buf))
+	doc, err = dynaj.Unmarshal(buf)
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/a").AsString(""), "invalid \ufffd utf-8")
+
+	// Errors.
+	assert.ErrorContains(doc.SetValueAt("/values/a", math.NaN()), "unsupported value NaN")
+	doc.SetNonFinitePolicy(dynaj.NullNonFinite)
+	assert.NoError(doc.SetValueAt("/nan", math.NaN()))
+	doc.SetNonFinitePolicy(dynaj.RejectNonFinite)
+	buf, err = doc.AppendJSON(nil)
+	assert.NoError(err)
+	assert.Substring(`"nan":null`, string(buf))
+	_, err = doc.NodeAt("/x/y").AppendJSON(nil)
+	assert.ErrorContains(err, "invalid path")
+}
+
+// BenchmarkAppendJSON compares appending with marshalling.
+func BenchmarkAppendJSON(b *testing.B) {
+	assert := asserts.NewTesting(b, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	doc, _ := dynaj.Unmarshal(bs)
+
+	b.Run("append", func(b *testing.B) {
+		b.ReportAllocs()
+		buf := make([]byte, 0, 1024)
+		for i := 0; i < b.N; i++ {
+			buf, _ = doc.AppendJSON(buf[:0])
+		}
+	})
+	b.Run("marshal", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = doc.MarshalJSON()
+		}
+	})
+}
+
+// EOF
@@ -0,0 +1,107 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// ARENA DECODER
+//--------------------
+
+// arenaDecoder is the scan decoder allocating from an arena.
+type arenaDecoder struct{}
+
+// NewArenaDecoder returns an experimental decoder allocating the bytes
+// of all strings and the elements of all arrays from a few large chunks
+// instead of one by one. This cuts the number of allocations and the
+// objects the garbage collector has to scan when holding many small
+// documents. The chunks are freed together when neither the document
+// nor any value read from it is in use anymore. Objects and the string
+// and number values are still allocated individually.
+func NewArenaDecoder() Decoder {
+	return arenaDecoder{}
+}
+
+// Decode implements Decoder.
+func (arenaDecoder) Decode(data []byte) (Element, error) {
+	s := &scanner{data: data, arena: newArena(len(data))}
+	return s.decode()
+}
+
+//--------------------
+// ARENA
+//--------------------
+
+// maxArenaChunk is the maximum number of values of a new chunk.
+const maxArenaChunk = 16384
+
+// arena allocates values from chunks. Chunks are only appended to
+// within their capacity, so allocated values never move. Full chunks
+// are replaced by new ones and stay alive as long as values in them
+// are referenced.
+type arena struct {
+	estimate int
+	bytes    []byte
+	elements []Element
+	scratch  []Element
+}
+
+// newArena creates an arena for data of the given size. The bytes of
+// all strings usually fit into one chunk of this size, the first chunk
+// of the array elements is sized for an estimated number of values.
+func newArena(size int) *arena {
+	return &arena{
+		estimate: size/8 + 1,
+		bytes:    make([]byte, 0, size),
+	}
+}
+
+// string copies the bytes into the arena and returns them as string.
+func (a *arena) string(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	if len(a.bytes)+len(b) > cap(a.bytes) {
+		a.bytes = make([]byte, 0, a.chunkSize(len(b), cap(a.bytes)))
+	}
+	start := len(a.bytes)
+	a.bytes = append(a.bytes, b...)
+	return viewString(a.bytes[start:])
+}
+
+// array copies the elements into the arena. The capacity of the array
+// is limited to its length, so appending to it copies the array.
+func (a *arena) array(elements []Element) Array {
+	n := len(elements)
+	if n == 0 {
+		return Array{}
+	}
+	if len(a.elements)+n > cap(a.elements) {
+		a.elements = make([]Element, 0, a.chunkSize(n, cap(a.elements)))
+	}
+	start := len(a.elements)
+	a.elements = append(a.elements, elements...)
+	return Array(a.elements[start:len(a.elements):len(a.elements)])
+}
+
+// chunkSize returns the size of a new chunk needed for n values. The
+// first chunk has the estimated size, following ones double up to the
+// maximum size.
+func (a *arena) chunkSize(n, previous int) int {
+	size := 2 * previous
+	if size == 0 {
+		size = a.estimate
+	}
+	if size > maxArenaChunk {
+		size = maxArenaChunk
+	}
+	if size < n {
+		size = n
+	}
+	return size
+}
+
+// EOF
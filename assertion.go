@@ -0,0 +1,59 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+)
+
+//--------------------
+// ASSERTIONS
+//--------------------
+
+// Assert checks if the document contains the expected value at the
+// given path like the test operation of JSON Patch. Numbers are
+// compared by value. A mismatch returns an error wrapping ErrAssertion,
+// so optimistic-concurrency checks can be done before changes.
+func (d *Document) Assert(path Path, expected Value) error {
+	keys := splitPath(path)
+	element, err := elementAt(d.root, keys)
+	if err != nil {
+		return fmt.Errorf("%w: invalid path %q: %v", ErrAssertion, path, err)
+	}
+	normalized, err := normalizeValue(expected, pathify(keys), RejectNonFinite)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrAssertion, err)
+	}
+	if !equalElements(element, normalized) {
+		return fmt.Errorf("%w: value at %q is %s, expected %s", ErrAssertion, path, preview(element), preview(normalized))
+	}
+	return nil
+}
+
+// AssertAll checks all expected values in the order of their paths
+// and returns the error of the first mismatch.
+func (d *Document) AssertAll(expectations map[Path]Value) error {
+	paths := make([]Path, 0, len(expectations))
+	for path := range expectations {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		if err := d.Assert(path, expectations[path]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,62 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestAssert tests the assertion of values.
+func TestAssert(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"version":3,"name":"foo","tags":["a","b"],"meta":{"x":null}}`)
+
+	assert.NoError(doc.Assert("/version", 3))
+	assert.NoError(doc.Assert("/version", 3.0))
+	assert.NoError(doc.Assert("name", "foo"))
+	assert.NoError(doc.Assert("/tags", []string{"a", "b"}))
+	assert.NoError(doc.Assert("/meta", map[string]any{"x": nil}))
+	assert.NoError(doc.Assert("/meta/x", nil))
+
+	err := doc.Assert("/version", 4)
+	assert.True(errors.Is(err, dynaj.ErrAssertion))
+	assert.ErrorContains(err, `value at "/version" is 3, expected 4`)
+	err = doc.Assert("/tags", []string{"b", "a"})
+	assert.ErrorContains(err, `value at "/tags" is ["a","b"], expected ["b","a"]`)
+	err = doc.Assert("/missing", nil)
+	assert.True(errors.Is(err, dynaj.ErrAssertion))
+	assert.ErrorContains(err, `invalid path "/missing"`)
+
+	// Batch assertions.
+	assert.NoError(doc.AssertAll(map[dynaj.Path]dynaj.Value{
+		"/version": 3,
+		"/tags/1":  "b",
+	}))
+	assert.NoError(doc.AssertAll(nil))
+	err = doc.AssertAll(map[dynaj.Path]dynaj.Value{
+		"/version": 3,
+		"/tags/0":  "x",
+		"/tags/1":  "y",
+	})
+	assert.ErrorContains(err, `value at "/tags/0" is "a", expected "x"`)
+}
+
+// EOF
@@ -0,0 +1,190 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// AttachmentKey is the key of the object an attachment is marshalled
+// into together with its metadata.
+const AttachmentKey = "$attachment"
+
+//--------------------
+// ATTACHMENT
+//--------------------
+
+// Attachment is a binary blob with its MIME type stored as a value in
+// a document. It is marshalled as
+//
+//	{"$attachment":{"type":"image/png","size":3,"sha256":"…","data":"AQID"}}
+//
+// with the data base64 encoded. Alternatively attachments can be
+// externalized before marshalling, e.g. as parts of multipart messages.
+type Attachment struct {
+	MIMEType string
+	Data     []byte
+}
+
+// NewAttachment creates an attachment with a copy of the data.
+func NewAttachment(mimeType string, data []byte) *Attachment {
+	cp := make([]byte, len(data))
+	copy(cp, data)
+	return &Attachment{
+		MIMEType: mimeType,
+		Data:     cp,
+	}
+}
+
+// Digest returns the hex encoded SHA-256 of the data. It can be used
+// to address the content, e.g. when externalizing attachments.
+func (a *Attachment) Digest() string {
+	sum := sha256.Sum256(a.Data)
+	return hex.EncodeToString(sum[:])
+}
+
+// MarshalJSON implements json.Marshaler.
+func (a *Attachment) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]attachmentJSON{
+		AttachmentKey: {
+			Type:   a.MIMEType,
+			Size:   len(a.Data),
+			SHA256: a.Digest(),
+			Data:   base64.StdEncoding.EncodeToString(a.Data),
+		},
+	})
+}
+
+// attachmentJSON contains the marshalled metadata and data.
+type attachmentJSON struct {
+	Type   string `json:"type"`
+	Size   int    `json:"size"`
+	SHA256 string `json:"sha256"`
+	Data   string `json:"data"`
+}
+
+// Externalizer replaces an attachment at a path by another value, e.g.
+// a reference to the stored data.
+type Externalizer func(path Path, a *Attachment) (Value, error)
+
+// SetAttachmentAt sets an attachment with a copy of the data at the
+// given path.
+func (d *Document) SetAttachmentAt(path Path, mimeType string, data []byte) error {
+	return d.SetValueAt(path, NewAttachment(mimeType, data))
+}
+
+// AttachmentAt returns the attachment at the given path. This may also
+// be an unmarshalled one, in this case its data is decoded and checked.
+func (d *Document) AttachmentAt(path Path) (*Attachment, error) {
+	element, err := elementAt(d.root, splitPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", path, err)
+	}
+	a, err := asAttachment(element)
+	if err != nil {
+		return nil, fmt.Errorf("no attachment at %q: %v", path, err)
+	}
+	return a, nil
+}
+
+// ExternalizeAttachments returns a copy of the document where all
+// attachments are replaced by the values returned by the externalizer.
+func (d *Document) ExternalizeAttachments(externalize Externalizer) (*Document, error) {
+	var walk func(element Element, path Path) (Element, error)
+	walk = func(element Element, path Path) (Element, error) {
+		switch typed := element.(type) {
+		case *Attachment:
+			value, err := externalize(path, typed)
+			if err != nil {
+				return nil, fmt.Errorf("cannot externalize attachment at %q: %v", path, err)
+			}
+			return normalizeValue(value, path, d.nonFinite)
+		case Object:
+			obj := make(Object, len(typed))
+			for key, child := range typed {
+				out, err := walk(child, appendKey(path, key))
+				if err != nil {
+					return nil, err
+				}
+				obj[key] = out
+			}
+			return obj, nil
+		case Array:
+			arr := make(Array, len(typed))
+			for idx, child := range typed {
+				out, err := walk(child, appendKey(path, strconv.Itoa(idx)))
+				if err != nil {
+					return nil, err
+				}
+				arr[idx] = out
+			}
+			return arr, nil
+		default:
+			return typed, nil
+		}
+	}
+	root, err := walk(d.root, Separator)
+	if err != nil {
+		return nil, err
+	}
+	return d.derive(root), nil
+}
+
+// IsAttachment returns true if the node is an attachment.
+func (node *Node) IsAttachment() bool {
+	_, ok := node.element.(*Attachment)
+	return ok
+}
+
+// asAttachment returns the attachment or decodes a marshalled one.
+func asAttachment(element Element) (*Attachment, error) {
+	if a, ok := element.(*Attachment); ok {
+		return a, nil
+	}
+	element, err := decodeRaw(element)
+	if err != nil {
+		return nil, err
+	}
+	obj, ok := element.(Object)
+	if !ok || len(obj) != 1 {
+		return nil, fmt.Errorf("element is no attachment")
+	}
+	meta, ok := obj[AttachmentKey].(Object)
+	if !ok {
+		return nil, fmt.Errorf("element is no attachment")
+	}
+	mimeType, _ := meta["type"].(string)
+	encoded, _ := meta["data"].(string)
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, fmt.Errorf("invalid data: %v", err)
+	}
+	a := &Attachment{
+		MIMEType: mimeType,
+		Data:     data,
+	}
+	if digest, ok := meta["sha256"].(string); ok && digest != a.Digest() {
+		return nil, fmt.Errorf("invalid data: checksum mismatch")
+	}
+	return a, nil
+}
+
+// EOF
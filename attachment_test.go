@@ -0,0 +1,93 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestAttachments tests storing and marshalling attachments.
+func TestAttachments(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"name":"upload","files":[]}`)
+	data := []byte{1, 2, 3}
+
+	assert.NoError(doc.SetAttachmentAt("/files/0", "application/octet-stream", data))
+	data[0] = 9
+	assert.True(doc.NodeAt("/files/0").IsAttachment())
+	assert.False(doc.NodeAt("/name").IsAttachment())
+	a, err := doc.AttachmentAt("/files/0")
+	assert.NoError(err)
+	assert.Equal(a.Data, []byte{1, 2, 3})
+	assert.NoError(dynaj.Verify(doc))
+
+	// Marshalled with metadata and back.
+	digest := "039058c6f2c0cb492c533b0a4d14ef77cc0f78abccced5287d84a1a2011cfb81"
+	expected := `{"files":[{"$attachment":{"type":"application/octet-stream","size":3,` +
+		`"sha256":"` + digest + `","data":"AQID"}}],"name":"upload"}`
+	assert.Equal(doc.String(), expected)
+	appended, err := doc.AppendJSON(nil)
+	assert.NoError(err)
+	assert.Equal(string(appended), expected)
+	loaded := mustUnmarshal(assert, expected)
+	assert.False(loaded.NodeAt("/files/0").IsAttachment())
+	a, err = loaded.AttachmentAt("/files/0")
+	assert.NoError(err)
+	assert.Equal(a.MIMEType, "application/octet-stream")
+	assert.Equal(a.Data, []byte{1, 2, 3})
+	assert.Equal(a.Digest(), digest)
+
+	_, err = doc.AttachmentAt("/name")
+	assert.ErrorContains(err, "no attachment at \"/name\"")
+	_, err = doc.AttachmentAt("/missing")
+	assert.ErrorContains(err, "invalid path")
+	corrupt := mustUnmarshal(assert, `{"a":{"$attachment":{"type":"text/plain","sha256":"00","data":"AQID"}}}`)
+	_, err = corrupt.AttachmentAt("/a")
+	assert.ErrorContains(err, "checksum mismatch")
+}
+
+// TestExternalizeAttachments tests replacing attachments by references.
+func TestExternalizeAttachments(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := dynaj.NewDocument()
+	assert.NoError(doc.SetAttachmentAt("/a/image", "image/png", []byte("png")))
+	assert.NoError(doc.SetAttachmentAt("/b/0", "text/plain", []byte("text")))
+	assert.NoError(doc.SetValueAt("/c", "plain"))
+
+	parts := map[string][]byte{}
+	external, err := doc.ExternalizeAttachments(func(path dynaj.Path, a *dynaj.Attachment) (dynaj.Value, error) {
+		parts[a.Digest()] = a.Data
+		return map[string]string{"$ref": "cid:" + a.Digest()[:8], "type": a.MIMEType}, nil
+	})
+	assert.NoError(err)
+	assert.Length(parts, 2)
+	assert.Equal(external.NodeAt("/a/image/type").AsString(""), "image/png")
+	assert.Substring("cid:", external.NodeAt("/b/0/$ref").AsString(""))
+	assert.Equal(external.NodeAt("/c").AsString(""), "plain")
+	assert.True(doc.NodeAt("/a/image").IsAttachment())
+
+	_, err = doc.ExternalizeAttachments(func(path dynaj.Path, a *dynaj.Attachment) (dynaj.Value, error) {
+		return nil, errors.New("ouch")
+	})
+	assert.ErrorContains(err, "cannot externalize attachment")
+}
+
+// EOF
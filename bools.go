@@ -0,0 +1,81 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// BOOL TABLE
+//--------------------
+
+// BoolTable maps strings to the bool values AsBool returns for them.
+// Keys are compared case-insensitive and without surrounding spaces.
+// Strings not contained in the table are parsed by strconv.
+type BoolTable map[string]bool
+
+// ExtendedBools contains the usual words of configuration files.
+var ExtendedBools = BoolTable{
+	"yes":      true,
+	"no":       false,
+	"y":        true,
+	"n":        false,
+	"on":       true,
+	"off":      false,
+	"enabled":  true,
+	"disabled": false,
+	"enable":   true,
+	"disable":  false,
+}
+
+// SetBoolTable sets the table used when reading strings with AsBool
+// from nodes of the document. A nil table only accepts the strings
+// understood by strconv. Frozen documents keep their table.
+func (d *Document) SetBoolTable(table BoolTable) {
+	if d.frozen {
+		return
+	}
+	if table == nil {
+		d.bools = nil
+		return
+	}
+	d.bools = BoolTable{}
+	for s, b := range table {
+		d.bools[normalizeBool(s)] = b
+	}
+}
+
+// parseBool parses the string using the table.
+func (t BoolTable) parseBool(s string) (bool, error) {
+	if b, ok := t[normalizeBool(s)]; ok {
+		return b, nil
+	}
+	return strconv.ParseBool(s)
+}
+
+// normalizeBool prepares a string for the lookup in the table.
+func normalizeBool(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// boolTable returns the bool table of the document the node has
+// been retrieved from.
+func (node *Node) boolTable() BoolTable {
+	if node.doc == nil {
+		return nil
+	}
+	return node.doc.bools
+}
+
+// EOF
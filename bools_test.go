@@ -0,0 +1,62 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestBoolTable tests parsing strings with bool tables.
+func TestBoolTable(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"yes": "Yes",
+		"off": " off ",
+		"enabled": "ENABLED",
+		"true": "true",
+		"ja": "ja",
+		"nein": "Nein"
+	}`)
+
+	// Default is strconv.
+	assert.Equal(doc.NodeAt("/yes").AsBool(false), false)
+	assert.Equal(doc.NodeAt("/true").AsBool(false), true)
+
+	doc.SetBoolTable(dynaj.ExtendedBools)
+	assert.Equal(doc.NodeAt("/yes").AsBool(false), true)
+	assert.Equal(doc.NodeAt("/off").AsBool(true), false)
+	assert.Equal(doc.NodeAt("/enabled").AsBool(false), true)
+	assert.Equal(doc.NodeAt("/true").AsBool(false), true)
+	assert.Equal(doc.NodeAt("/ja").AsBool(false), false)
+
+	// Own tables are normalized.
+	doc.SetBoolTable(dynaj.BoolTable{"JA": true, " nein": false})
+	assert.Equal(doc.NodeAt("/ja").AsBool(false), true)
+	assert.Equal(doc.NodeAt("/nein").AsBool(true), false)
+	assert.Equal(doc.NodeAt("/yes").AsBool(false), false)
+	frozen := doc.Freeze()
+	assert.Equal(frozen.NodeAt("/ja").AsBool(false), true)
+
+	doc.SetBoolTable(nil)
+	assert.Equal(doc.NodeAt("/ja").AsBool(false), false)
+	assert.Equal(frozen.NodeAt("/ja").AsBool(false), true)
+}
+
+// EOF
@@ -0,0 +1,119 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"sync"
+)
+
+//--------------------
+// CACHE
+//--------------------
+
+// cacheEntry is one cached document.
+type cacheEntry struct {
+	hash [sha256.Size]byte
+	doc  *Document
+}
+
+// Cache contains frozen documents keyed by the hash of their JSON
+// encoded data. So repeatedly received identical payloads, e.g. of
+// webhooks or configurations, are parsed only once. The least recently
+// used documents are removed if the cache is full. It can be used by
+// multiple goroutines.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	opts    []Option
+	metrics MetricsSink
+	entries map[[sha256.Size]byte]*list.Element
+	order   *list.List
+}
+
+// NewCache creates a cache for the given number of documents. The
+// options are used to parse the data. A metrics sink set by the options
+// also receives the lookups of the cache.
+func NewCache(size int, opts ...Option) *Cache {
+	if size < 1 {
+		size = 1
+	}
+	return &Cache{
+		size:    size,
+		opts:    opts,
+		metrics: newOptions(opts).metrics,
+		entries: map[[sha256.Size]byte]*list.Element{},
+		order:   list.New(),
+	}
+}
+
+// Parse returns the frozen document for the JSON encoded data and
+// true if it has been found in the cache. Otherwise the data is parsed
+// and the document is added to the cache. Invalid data returns nil and
+// false, use Unmarshal to get the error.
+func (c *Cache) Parse(data []byte) (*Document, bool) {
+	hash := sha256.Sum256(data)
+	c.mu.Lock()
+	if elem, ok := c.entries[hash]; ok {
+		c.order.MoveToFront(elem)
+		c.mu.Unlock()
+		c.count(true)
+		return elem.Value.(*cacheEntry).doc, true
+	}
+	c.mu.Unlock()
+	c.count(false)
+	// Parse outside of the lock.
+	doc, err := Unmarshal(data, c.opts...)
+	if err != nil {
+		return nil, false
+	}
+	doc = doc.Freeze()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if elem, ok := c.entries[hash]; ok {
+		// Parsed concurrently.
+		c.order.MoveToFront(elem)
+		return elem.Value.(*cacheEntry).doc, false
+	}
+	c.entries[hash] = c.order.PushFront(&cacheEntry{hash, doc})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*cacheEntry).hash)
+	}
+	return doc, false
+}
+
+// count reports a lookup to the metrics sink.
+func (c *Cache) count(hit bool) {
+	if c.metrics != nil {
+		c.metrics.CountCache(DocumentCache, hit)
+	}
+}
+
+// Len returns the number of cached documents.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// Clear removes all documents from the cache.
+func (c *Cache) Clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = map[[sha256.Size]byte]*list.Element{}
+	c.order.Init()
+}
+
+// EOF
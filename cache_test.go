@@ -0,0 +1,91 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCache tests the caching of parsed documents.
+func TestCache(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	cache := dynaj.NewCache(2, dynaj.WithDecoder(dynaj.NewScanDecoder()))
+
+	doc, ok := cache.Parse(bs)
+	assert.False(ok)
+	assert.True(doc.IsFrozen())
+	assert.Equal(doc.String(), string(bs))
+	cached, ok := cache.Parse(bs)
+	assert.True(ok)
+	assert.True(cached == doc)
+	assert.Equal(cache.Len(), 1)
+
+	// Invalid data.
+	doc, ok = cache.Parse([]byte(`{"a":`))
+	assert.Nil(doc)
+	assert.False(ok)
+	assert.Equal(cache.Len(), 1)
+
+	// Least recently used documents are removed.
+	_, ok = cache.Parse([]byte(`{"a":1}`))
+	assert.False(ok)
+	_, ok = cache.Parse(bs)
+	assert.True(ok)
+	_, ok = cache.Parse([]byte(`{"b":2}`))
+	assert.False(ok)
+	assert.Equal(cache.Len(), 2)
+	_, ok = cache.Parse(bs)
+	assert.True(ok)
+	_, ok = cache.Parse([]byte(`{"a":1}`))
+	assert.False(ok)
+
+	cache.Clear()
+	assert.Equal(cache.Len(), 0)
+	_, ok = cache.Parse(bs)
+	assert.False(ok)
+}
+
+// TestCacheConcurrent tests the concurrent usage of a cache.
+func TestCacheConcurrent(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	cache := dynaj.NewCache(5)
+	var wg sync.WaitGroup
+
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				data := fmt.Sprintf(`{"n":%d}`, (i+j)%10)
+				doc, _ := cache.Parse([]byte(data))
+				if doc.NodeAt("/n").AsInt(-1) != (i+j)%10 {
+					t.Errorf("invalid document for %s", data)
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	assert.Equal(cache.Len(), 5)
+}
+
+// EOF
@@ -0,0 +1,241 @@
+// Tideland Go Dynamic JSON - Command Line Tool
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Command dynaj queries and edits JSON documents using the paths
+// and patterns of the dynaj package.
+//
+//	dynaj get [file] <path>
+//	dynaj set [file] <path> <value>
+//	dynaj delete [file] <path>
+//	dynaj query [file] <pattern>
+//	dynaj diff <first> <second>
+//	dynaj repl [file]
+//
+// If no file is given the document is read from stdin. The REPL
+// starts with an empty document if no file is given. Values for
+// set are parsed as JSON, if this fails they are used as string.
+// Modified documents are written to stdout.
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// MAIN
+//--------------------
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "dynaj: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+//--------------------
+// COMMANDS
+//--------------------
+
+// usage describes the command line arguments.
+const usage = `usage:
+  dynaj get [file] <path>
+  dynaj set [file] <path> <value>
+  dynaj delete [file] <path>
+  dynaj query [file] <pattern>
+  dynaj diff <first> <second>
+  dynaj repl [file]`
+
+// command describes one subcommand with its number of arguments
+// not counting the optional file.
+type command struct {
+	args int
+	run  func(doc *dynaj.Document, args []string, out io.Writer) error
+}
+
+// commands contains all subcommands working on one document.
+var commands = map[string]command{
+	"get":    {1, get},
+	"set":    {2, set},
+	"delete": {1, remove},
+	"query":  {1, query},
+}
+
+// run executes the command line.
+func run(args []string, in io.Reader, out io.Writer) error {
+	if len(args) == 0 {
+		return fmt.Errorf("missing command\n%s", usage)
+	}
+	name, args := args[0], args[1:]
+	if name == "diff" {
+		if len(args) != 2 {
+			return fmt.Errorf("invalid arguments\n%s", usage)
+		}
+		return diff(args[0], args[1], out)
+	}
+	if name == "repl" {
+		if len(args) > 1 {
+			return fmt.Errorf("invalid arguments\n%s", usage)
+		}
+		doc := dynaj.NewDocument()
+		if len(args) == 1 {
+			var err error
+			if doc, err = loadDocument(args[0]); err != nil {
+				return err
+			}
+		}
+		return newREPL(doc, in, out).run()
+	}
+	cmd, ok := commands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q\n%s", name, usage)
+	}
+	var doc *dynaj.Document
+	var err error
+	switch len(args) {
+	case cmd.args:
+		doc, err = readDocument(in)
+	case cmd.args + 1:
+		doc, err = loadDocument(args[0])
+		args = args[1:]
+	default:
+		return fmt.Errorf("invalid arguments\n%s", usage)
+	}
+	if err != nil {
+		return err
+	}
+	return cmd.run(doc, args, out)
+}
+
+// get prints the element at the path.
+func get(doc *dynaj.Document, args []string, out io.Writer) error {
+	return writeJSON(out, doc.NodeAt(args[0]))
+}
+
+// set sets the value at the path and prints the document.
+func set(doc *dynaj.Document, args []string, out io.Writer) error {
+	if err := doc.SetValueAt(args[0], parseValue(args[1])); err != nil {
+		return err
+	}
+	return writeJSON(out, doc)
+}
+
+// remove deletes the element at the path and prints the document.
+func remove(doc *dynaj.Document, args []string, out io.Writer) error {
+	if err := doc.DeleteElementAt(args[0]); err != nil {
+		return err
+	}
+	return writeJSON(out, doc)
+}
+
+// query prints all paths and values matching the pattern.
+func query(doc *dynaj.Document, args []string, out io.Writer) error {
+	nodes, err := doc.Root().Query(args[0])
+	if err != nil {
+		return err
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Path() < nodes[j].Path()
+	})
+	for _, node := range nodes {
+		data, err := node.MarshalJSON()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(out, "%s = %s\n", node.Path(), data)
+	}
+	return nil
+}
+
+// diff prints the differences of two documents.
+func diff(first, second string, out io.Writer) error {
+	fd, err := loadDocument(first)
+	if err != nil {
+		return err
+	}
+	sd, err := loadDocument(second)
+	if err != nil {
+		return err
+	}
+	d, err := dynaj.CompareDocuments(fd, sd)
+	if err != nil {
+		return err
+	}
+	paths := d.Differences()
+	sort.Strings(paths)
+	for _, path := range paths {
+		fn, sn := d.DifferenceAt(path)
+		fmt.Fprintf(out, "%s: %s -> %s\n", path, describe(fn), describe(sn))
+	}
+	return nil
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// loadDocument reads the document from the named file.
+func loadDocument(filename string) (*dynaj.Document, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read file: %v", err)
+	}
+	return dynaj.Unmarshal(data)
+}
+
+// readDocument reads the document from the reader.
+func readDocument(in io.Reader) (*dynaj.Document, error) {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read input: %v", err)
+	}
+	return dynaj.Unmarshal(data)
+}
+
+// parseValue parses the argument as JSON value. If this fails the
+// argument is returned as string.
+func parseValue(arg string) dynaj.Value {
+	var value dynaj.Value
+	if err := json.Unmarshal([]byte(arg), &value); err != nil {
+		return arg
+	}
+	return value
+}
+
+// writeJSON writes the marshaled value followed by a newline.
+func writeJSON(out io.Writer, m json.Marshaler) error {
+	data, err := m.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
+// describe returns the JSON representation of a node or a marker
+// if the node does not exist.
+func describe(node *dynaj.Node) string {
+	if node.IsError() {
+		return "<missing>"
+	}
+	data, err := node.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return string(data)
+}
+
+// EOF
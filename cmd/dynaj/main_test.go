@@ -0,0 +1,107 @@
+// Tideland Go Dynamic JSON - Command Line Tool - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCommands tests the commands working on one document.
+func TestCommands(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := `{"a":{"name":"foo","n":1},"b":[{"name":"bar"}]}`
+	filename := writeFile(t, "doc.json", doc)
+
+	out, err := runCommand("", "get", filename, "/a/name")
+	assert.NoError(err)
+	assert.Equal(out, "\"foo\"\n")
+
+	out, err = runCommand(doc, "get", "/a")
+	assert.NoError(err)
+	assert.Equal(out, "{\"n\":1,\"name\":\"foo\"}\n")
+
+	out, err = runCommand(doc, "set", "/a/n", "2")
+	assert.NoError(err)
+	assert.Equal(out, "{\"a\":{\"n\":2,\"name\":\"foo\"},\"b\":[{\"name\":\"bar\"}]}\n")
+
+	out, err = runCommand(doc, "set", "/a/name", "baz")
+	assert.NoError(err)
+	assert.Substring("\"name\":\"baz\"", out)
+
+	out, err = runCommand(doc, "delete", "/b")
+	assert.NoError(err)
+	assert.Equal(out, "{\"a\":{\"n\":1,\"name\":\"foo\"}}\n")
+
+	out, err = runCommand(doc, "query", "*/name")
+	assert.NoError(err)
+	assert.Equal(out, "/a/name = \"foo\"\n/b/0/name = \"bar\"\n")
+
+	// Provoke errors.
+	_, err = runCommand(doc)
+	assert.ErrorContains(err, "missing command")
+	_, err = runCommand(doc, "fly", "/a")
+	assert.ErrorContains(err, "unknown command")
+	_, err = runCommand(doc, "get")
+	assert.ErrorContains(err, "invalid arguments")
+	_, err = runCommand("", "get", "does-not-exist.json", "/a")
+	assert.ErrorContains(err, "cannot read file")
+	_, err = runCommand("{", "get", "/a")
+	assert.ErrorContains(err, "cannot unmarshal document")
+	_, err = runCommand(doc, "get", "/x")
+	assert.ErrorContains(err, "invalid path")
+}
+
+// TestDiff tests the diff command.
+func TestDiff(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := writeFile(t, "first.json", `{"a":1,"b":2}`)
+	second := writeFile(t, "second.json", `{"a":1,"b":3,"c":4}`)
+
+	out, err := runCommand("", "diff", first, second)
+	assert.NoError(err)
+	assert.Equal(out, "/b: 2 -> 3\n/c: <missing> -> 4\n")
+
+	_, err = runCommand("", "diff", first)
+	assert.ErrorContains(err, "invalid arguments")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// runCommand runs the command line with the given input.
+func runCommand(in string, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := run(args, strings.NewReader(in), &out)
+	return out.String(), err
+}
+
+// writeFile writes a temporary file and returns its name.
+func writeFile(t *testing.T, name, content string) string {
+	filename := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(filename, []byte(content), 0o600); err != nil {
+		t.Fatalf("cannot write file: %v", err)
+	}
+	return filename
+}
+
+// EOF
@@ -0,0 +1,219 @@
+// Tideland Go Dynamic JSON - Command Line Tool
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// REPL
+//--------------------
+
+// replHelp describes the commands of the REPL.
+const replHelp = `commands:
+  cd [path]             change the current path, default is the root
+  pwd                   print the current path
+  ls [path]             list the keys or indices of an object or array
+  cat [path]            print the element as JSON
+  set <path> <value>    set a value, parsed as JSON or used as string
+  rm <path>             delete the element
+  find <pattern>        find all values below the current path
+  help                  print this help
+  exit                  leave the REPL`
+
+// repl interactively explores a loaded document.
+type repl struct {
+	doc     *dynaj.Document
+	cwd     dynaj.Path
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+// newREPL creates the REPL for the document.
+func newREPL(doc *dynaj.Document, in io.Reader, out io.Writer) *repl {
+	return &repl{
+		doc:     doc,
+		cwd:     dynaj.Separator,
+		scanner: bufio.NewScanner(in),
+		out:     out,
+	}
+}
+
+// run reads and executes commands until exit or end of input.
+func (r *repl) run() error {
+	for {
+		fmt.Fprintf(r.out, "dynaj:%s> ", r.cwd)
+		if !r.scanner.Scan() {
+			fmt.Fprintln(r.out)
+			return r.scanner.Err()
+		}
+		name, args := splitLine(r.scanner.Text())
+		var err error
+		switch name {
+		case "":
+			continue
+		case "exit", "quit":
+			return nil
+		case "help":
+			fmt.Fprintln(r.out, replHelp)
+		case "pwd":
+			fmt.Fprintln(r.out, r.cwd)
+		case "cd":
+			err = r.cd(args)
+		case "ls":
+			err = r.ls(args)
+		case "cat":
+			err = r.cat(args)
+		case "set":
+			err = r.set(args)
+		case "rm":
+			err = r.rm(args)
+		case "find":
+			err = r.find(args)
+		default:
+			err = fmt.Errorf("unknown command %q, try help", name)
+		}
+		if err != nil {
+			fmt.Fprintf(r.out, "error: %v\n", err)
+		}
+	}
+}
+
+// cd changes the current path to an object or array.
+func (r *repl) cd(args string) error {
+	path := r.resolve(args)
+	node := r.doc.NodeAt(path)
+	switch {
+	case node.IsError():
+		return node.Err()
+	case !node.IsObject() && !node.IsArray():
+		return fmt.Errorf("%q is no object or array", path)
+	}
+	r.cwd = path
+	return nil
+}
+
+// ls lists the keys or indices of an object or array.
+func (r *repl) ls(args string) error {
+	node := r.doc.NodeAt(r.resolve(args))
+	if node.IsError() {
+		return node.Err()
+	}
+	for _, key := range node.Keys() {
+		child := node.NodeAt(key)
+		if child.IsObject() || child.IsArray() {
+			fmt.Fprintf(r.out, "%s/\n", key)
+			continue
+		}
+		fmt.Fprintf(r.out, "%s = %s\n", key, describe(child))
+	}
+	return nil
+}
+
+// cat prints the element as JSON.
+func (r *repl) cat(args string) error {
+	return writeJSON(r.out, r.doc.NodeAt(r.resolve(args)))
+}
+
+// set sets a value at a path.
+func (r *repl) set(args string) error {
+	path, value := splitLine(args)
+	if path == "" || value == "" {
+		return fmt.Errorf("need path and value")
+	}
+	return r.doc.SetValueAt(r.resolve(path), parseValue(value))
+}
+
+// rm deletes the element at a path.
+func (r *repl) rm(args string) error {
+	if args == "" {
+		return fmt.Errorf("need path")
+	}
+	path := r.resolve(args)
+	if path == dynaj.Separator {
+		r.doc.Clear()
+		return nil
+	}
+	if err := r.doc.DeleteElementAt(path); err != nil {
+		return err
+	}
+	// Step back if the current path has been deleted.
+	for r.doc.NodeAt(r.cwd).IsError() {
+		r.cwd = r.resolve("..")
+	}
+	return nil
+}
+
+// find prints all values below the current path matching the pattern.
+func (r *repl) find(args string) error {
+	nodes, err := r.doc.NodeAt(r.cwd).Query(args)
+	if err != nil {
+		return err
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].Path() < nodes[j].Path()
+	})
+	for _, node := range nodes {
+		fmt.Fprintf(r.out, "%s = %s\n", node.Path(), describe(node))
+	}
+	return nil
+}
+
+// resolve creates an absolute path out of the current path and
+// the argument. It supports "." and "..".
+func (r *repl) resolve(arg string) dynaj.Path {
+	keys := strings.Split(r.cwd, dynaj.Separator)
+	if strings.HasPrefix(arg, dynaj.Separator) {
+		keys = nil
+	}
+	for _, key := range strings.Split(arg, dynaj.Separator) {
+		switch key {
+		case "", ".":
+		case "..":
+			if len(keys) > 0 {
+				keys = keys[:len(keys)-1]
+			}
+		default:
+			keys = append(keys, key)
+		}
+	}
+	path := dynaj.Separator
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if path != dynaj.Separator {
+			path += dynaj.Separator
+		}
+		path += key
+	}
+	return path
+}
+
+// splitLine splits a line into the first word and the trimmed rest.
+func splitLine(line string) (string, string) {
+	line = strings.TrimSpace(line)
+	idx := strings.IndexAny(line, " \t")
+	if idx < 0 {
+		return line, ""
+	}
+	return line[:idx], strings.TrimSpace(line[idx:])
+}
+
+// EOF
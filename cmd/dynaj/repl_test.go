@@ -0,0 +1,65 @@
+// Tideland Go Dynamic JSON - Command Line Tool - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestREPL tests the interactive exploration of a document.
+func TestREPL(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := writeFile(t, "doc.json", `{"a":{"b":[{"name":"foo"},{"name":"bar"}]},"c":1}`)
+	script := strings.Join([]string{
+		"ls",
+		"cd a/b",
+		"pwd",
+		"ls 1",
+		"cd ../..",
+		"cat c",
+		"set a/x \"hello world\"",
+		"cat /a/x",
+		"find */name",
+		"cd c",
+		"rm /a",
+		"ls",
+		"fly",
+		"exit",
+	}, "\n")
+
+	out, err := runCommand(script, "repl", filename)
+	assert.NoError(err)
+	lines := strings.Split(out, "dynaj:")
+	assert.Equal(lines[1], "/> a/\nc = 1\n")
+	assert.Equal(lines[3], "/a/b> /a/b\n")
+	assert.Equal(lines[4], "/a/b> name = \"bar\"\n")
+	assert.Equal(lines[6], "/> 1\n")
+	assert.Equal(lines[8], "/> \"hello world\"\n")
+	assert.Equal(lines[9], "/> /a/b/0/name = \"foo\"\n/a/b/1/name = \"bar\"\n")
+	assert.Equal(lines[10], "/> error: \"/c\" is no object or array\n")
+	assert.Equal(lines[12], "/> c = 1\n")
+	assert.Equal(lines[13], "/> error: unknown command \"fly\", try help\n")
+
+	// Empty document and end of input.
+	out, err = runCommand("set /a 1\ncat", "repl")
+	assert.NoError(err)
+	assert.Substring("{\"a\":1}", out)
+}
+
+// EOF
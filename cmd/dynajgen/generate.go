@@ -0,0 +1,195 @@
+// Tideland Go Dynamic JSON - Code Generator
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+//--------------------
+// GENERATION
+//--------------------
+
+// generator writes the code for the shapes.
+type generator struct {
+	buf         bytes.Buffer
+	types       map[string]bool
+	usesStrconv bool
+}
+
+// generate creates the formatted code for the root shape.
+func generate(pkg, typeName string, root *shape) ([]byte, error) {
+	if root.kind != objectKind && root.kind != arrayKind {
+		return nil, fmt.Errorf("cannot generate code: root is no object or array")
+	}
+	if !isIdentifier(typeName) {
+		return nil, fmt.Errorf("cannot generate code: invalid type name %q", typeName)
+	}
+	g := &generator{types: map[string]bool{typeName: true}}
+	g.printf("// New%s returns the typed access to the document.\n", typeName)
+	g.printf("func New%s(doc *dynaj.Document) %s {\n", typeName, typeName)
+	g.printf("return %s{doc.Root()}\n}\n\n", typeName)
+	g.wrapper(typeName, root)
+
+	var code bytes.Buffer
+	fmt.Fprintf(&code, "// Code generated by dynajgen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&code, "package %s\n\n", pkg)
+	if g.usesStrconv {
+		fmt.Fprintf(&code, "import (\n\"strconv\"\n\n\"tideland.dev/go/dynaj\"\n)\n\n")
+	} else {
+		fmt.Fprintf(&code, "import \"tideland.dev/go/dynaj\"\n\n")
+	}
+	code.Write(g.buf.Bytes())
+	formatted, err := format.Source(code.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("cannot generate code: %v", err)
+	}
+	return formatted, nil
+}
+
+// printf writes formatted code.
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// wrapper writes the type wrapping an object or array node including
+// its methods. The types of contained objects and arrays follow.
+func (g *generator) wrapper(typeName string, s *shape) {
+	description := "an object"
+	if s.kind == arrayKind {
+		description = "an array"
+	}
+	g.printf("// %s provides typed access to %s.\n", typeName, description)
+	g.printf("type %s struct {\nnode *dynaj.Node\n}\n\n", typeName)
+	g.printf("// Node returns the wrapped node.\n")
+	g.printf("func (x %s) Node() *dynaj.Node {\nreturn x.node\n}\n\n", typeName)
+	followers := []func(){}
+	if s.kind == arrayKind {
+		g.usesStrconv = true
+		g.printf("// Len returns the number of items.\n")
+		g.printf("func (x %s) Len() int {\nreturn len(x.node.Keys())\n}\n\n", typeName)
+		resultType, access, follow := g.accessor(typeName+"Item", s.items, "strconv.Itoa(i)")
+		g.printf("// At returns the item at the index.\n")
+		g.printf("func (x %s) At(i int) %s {\nreturn %s\n}\n\n", typeName, resultType, access)
+		followers = append(followers, follow)
+	} else {
+		keys := []string{}
+		for key := range s.fields {
+			if key != "" && !strings.Contains(key, "/") {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+		methods := map[string]bool{"Node": true}
+		for _, key := range keys {
+			method := uniqueName(identifier(key), methods)
+			methods[method] = true
+			resultType, access, follow := g.accessor(typeName+method, s.fields[key], strconv.Quote(key))
+			g.printf("// %s returns the value of %q.\n", method, key)
+			g.printf("func (x %s) %s() %s {\nreturn %s\n}\n\n", typeName, method, resultType, access)
+			followers = append(followers, follow)
+		}
+	}
+	for _, follow := range followers {
+		follow()
+	}
+}
+
+// accessor returns the result type and the access expression for the
+// shape at the given path expression. For objects and arrays the
+// function writing the wrapper type is returned too.
+func (g *generator) accessor(typeName string, s *shape, path string) (string, string, func()) {
+	node := "x.node.NodeAt(" + path + ")"
+	nothing := func() {}
+	if s == nil {
+		return "*dynaj.Node", node, nothing
+	}
+	switch s.kind {
+	case objectKind, arrayKind:
+		typeName = uniqueName(typeName, g.types)
+		g.types[typeName] = true
+		return typeName, typeName + "{" + node + "}", func() { g.wrapper(typeName, s) }
+	case stringKind:
+		return "string", node + `.AsString("")`, nothing
+	case intKind:
+		return "int", node + ".AsInt(0)", nothing
+	case numberKind:
+		return "float64", node + ".AsFloat64(0)", nothing
+	case boolKind:
+		return "bool", node + ".AsBool(false)", nothing
+	}
+	return "*dynaj.Node", node, nothing
+}
+
+//--------------------
+// NAMES
+//--------------------
+
+// identifier converts a key into an exported Go identifier.
+func identifier(key string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range key {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			if upper {
+				r = unicode.ToUpper(r)
+				upper = false
+			}
+			b.WriteRune(r)
+		default:
+			upper = true
+		}
+	}
+	name := b.String()
+	switch {
+	case name == "":
+		return "Field"
+	case unicode.IsDigit([]rune(name)[0]):
+		return "N" + name
+	}
+	return name
+}
+
+// uniqueName appends a number to the name if it is already used.
+func uniqueName(name string, used map[string]bool) string {
+	if !used[name] {
+		return name
+	}
+	for i := 2; ; i++ {
+		candidate := name + strconv.Itoa(i)
+		if !used[candidate] {
+			return candidate
+		}
+	}
+}
+
+// isIdentifier checks if the name is a valid exported Go identifier.
+func isIdentifier(name string) bool {
+	for i, r := range name {
+		switch {
+		case i == 0 && !unicode.IsUpper(r):
+			return false
+		case !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_':
+			return false
+		}
+	}
+	return name != ""
+}
+
+// EOF
@@ -0,0 +1,106 @@
+// Tideland Go Dynamic JSON - Code Generator
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Command dynajgen generates typed Go accessors for JSON documents
+// based on a sample document or a JSON Schema.
+//
+//	dynajgen [-package name] [-type name] [-schema] [-o file] [input]
+//
+// The generated types wrap dynaj nodes and provide one method per
+// field, so a document like {"server":{"port":8080}} can be read with
+// NewConfig(doc).Server().Port() when using -type Config. Objects
+// and arrays get own wrapper types, arrays provide Len() and At(i).
+// Numbers are accessed as int if all samples are integral, fields with
+// changing types as *dynaj.Node.
+//
+// If no input file is given it is read from stdin. The input is
+// handled as JSON Schema if -schema is set or it contains a top-level
+// "$schema" key. The code is written to stdout if no output file is
+// given.
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+)
+
+//--------------------
+// MAIN
+//--------------------
+
+func main() {
+	if err := run(os.Args[1:], os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintf(os.Stderr, "dynajgen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+//--------------------
+// COMMAND
+//--------------------
+
+// run executes the command line.
+func run(args []string, in io.Reader, out io.Writer) error {
+	flags := flag.NewFlagSet("dynajgen", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	pkg := flags.String("package", "data", "package name of the generated code")
+	typeName := flags.String("type", "Document", "name of the root type")
+	isSchema := flags.Bool("schema", false, "input is a JSON Schema")
+	output := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	var data []byte
+	var err error
+	switch flags.NArg() {
+	case 0:
+		data, err = io.ReadAll(in)
+	case 1:
+		data, err = os.ReadFile(flags.Arg(0))
+	default:
+		return fmt.Errorf("invalid arguments: more than one input")
+	}
+	if err != nil {
+		return fmt.Errorf("cannot read input: %v", err)
+	}
+	var input any
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&input); err != nil {
+		return fmt.Errorf("cannot parse input: %v", err)
+	}
+	var root *shape
+	if obj, ok := input.(map[string]any); ok && (*isSchema || obj["$schema"] != nil) {
+		root = schemaShape(obj)
+	} else if *isSchema {
+		return fmt.Errorf("cannot parse input: schema is no object")
+	} else {
+		root = sampleShape(input)
+	}
+	code, err := generate(*pkg, *typeName, root)
+	if err != nil {
+		return err
+	}
+	if *output == "" {
+		_, err = out.Write(code)
+		return err
+	}
+	if err := os.WriteFile(*output, code, 0o644); err != nil {
+		return fmt.Errorf("cannot write output: %v", err)
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,152 @@
+// Tideland Go Dynamic JSON - Code Generator - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"go/parser"
+	"go/token"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestGenerateSample tests the generation based on a sample document.
+func TestGenerateSample(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	sample := `{
+		"server": {"port": 8080, "host": "localhost", "ratio": 0.5, "tls": null},
+		"users": [{"name": "a", "roles": ["x"]}, {"name": "b", "age": 3}],
+		"matrix": [[1, 2], [3.5]],
+		"mixed": [1, "a"],
+		"node": true,
+		"1st": "x",
+		"a/b": 1
+	}`
+
+	code, err := runCommand(sample, "-package", "config", "-type", "Config")
+	assert.NoError(err)
+	assertValidCode(assert, code)
+	assert.Substring("// Code generated by dynajgen. DO NOT EDIT.", code)
+	assert.Substring("package config", code)
+	assert.Substring("func NewConfig(doc *dynaj.Document) Config {", code)
+	assert.Substring("func (x Config) Server() ConfigServer {", code)
+	assert.Substring(`func (x ConfigServer) Port() int {
+	return x.node.NodeAt("port").AsInt(0)
+}`, code)
+	assert.Substring(`func (x ConfigServer) Host() string {`, code)
+	assert.Substring(`func (x ConfigServer) Ratio() float64 {`, code)
+	assert.Substring(`func (x ConfigServer) Tls() *dynaj.Node {`, code)
+	assert.Substring(`func (x ConfigUsers) At(i int) ConfigUsersItem {
+	return ConfigUsersItem{x.node.NodeAt(strconv.Itoa(i))}
+}`, code)
+	assert.Substring(`func (x ConfigUsersItem) Age() int {`, code)
+	assert.Substring(`func (x ConfigUsersItemRoles) At(i int) string {`, code)
+	assert.Substring(`func (x ConfigMatrixItem) At(i int) float64 {`, code)
+	assert.Substring(`func (x ConfigMixed) At(i int) *dynaj.Node {`, code)
+	assert.Substring(`func (x Config) Node2() bool {`, code)
+	assert.Substring(`func (x Config) N1st() string {`, code)
+	assert.False(strings.Contains(code, `"a/b"`))
+
+	// Output file.
+	filename := filepath.Join(t.TempDir(), "config.go")
+	out, err := runCommand(sample, "-o", filename)
+	assert.NoError(err)
+	assert.Equal(out, "")
+	data, err := os.ReadFile(filename)
+	assert.NoError(err)
+	assert.Substring("package data", string(data))
+	assert.Substring("func NewDocument(doc *dynaj.Document) Document {", string(data))
+}
+
+// TestGenerateSchema tests the generation based on a JSON Schema.
+func TestGenerateSchema(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	schema := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"server": {
+				"properties": {
+					"port": {"type": "integer"},
+					"host": {"type": ["string", "null"]},
+					"load": {"type": "number"},
+					"debug": {"type": "boolean"},
+					"extra": {"$ref": "#/$defs/extra"}
+				}
+			},
+			"tags": {"type": "array", "items": {"type": "string"}}
+		}
+	}`
+
+	code, err := runCommand(schema)
+	assert.NoError(err)
+	assertValidCode(assert, code)
+	assert.Substring(`func (x DocumentServer) Port() int {`, code)
+	assert.Substring(`func (x DocumentServer) Host() string {`, code)
+	assert.Substring(`func (x DocumentServer) Load() float64 {`, code)
+	assert.Substring(`func (x DocumentServer) Debug() bool {`, code)
+	assert.Substring(`func (x DocumentServer) Extra() *dynaj.Node {`, code)
+	assert.Substring(`func (x DocumentTags) At(i int) string {`, code)
+
+	// Explicit schema without $schema key.
+	code, err = runCommand(`{"properties": {"a": {"type": "string"}}}`, "-schema")
+	assert.NoError(err)
+	assert.Substring(`func (x Document) A() string {`, code)
+	assert.False(strings.Contains(code, "strconv"))
+}
+
+// TestGenerateErrors tests the handling of invalid input.
+func TestGenerateErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	_, err := runCommand(`{"a":`)
+	assert.ErrorContains(err, "cannot parse input")
+	_, err = runCommand(`"just a string"`)
+	assert.ErrorContains(err, "root is no object or array")
+	_, err = runCommand(`[1]`, "-schema")
+	assert.ErrorContains(err, "schema is no object")
+	_, err = runCommand(`{}`, "-type", "lower")
+	assert.ErrorContains(err, `invalid type name "lower"`)
+	_, err = runCommand(`{}`, "-unknown")
+	assert.ErrorContains(err, "invalid arguments")
+	_, err = runCommand(`{}`, "a.json", "b.json")
+	assert.ErrorContains(err, "more than one input")
+	_, err = runCommand(``, "does-not-exist.json")
+	assert.ErrorContains(err, "cannot read input")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// runCommand runs the command line with the given input.
+func runCommand(in string, args ...string) (string, error) {
+	var out bytes.Buffer
+	err := run(args, strings.NewReader(in), &out)
+	return out.String(), err
+}
+
+// assertValidCode checks that the generated code can be parsed.
+func assertValidCode(assert *asserts.Asserts, code string) {
+	_, err := parser.ParseFile(token.NewFileSet(), "generated.go", code, parser.AllErrors)
+	assert.NoError(err)
+}
+
+// EOF
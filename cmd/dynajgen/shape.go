@@ -0,0 +1,173 @@
+// Tideland Go Dynamic JSON - Code Generator
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package main
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+//--------------------
+// SHAPES
+//--------------------
+
+// kind is the kind of a shape.
+type kind int
+
+// Kinds of shapes.
+const (
+	anyKind kind = iota
+	nullKind
+	objectKind
+	arrayKind
+	stringKind
+	intKind
+	numberKind
+	boolKind
+)
+
+// shape describes the structure of a document.
+type shape struct {
+	kind   kind
+	fields map[string]*shape
+	items  *shape
+}
+
+// sampleShape derives the shape of a sample element decoded with
+// numbers as json.Number.
+func sampleShape(element any) *shape {
+	switch typed := element.(type) {
+	case nil:
+		return &shape{kind: nullKind}
+	case map[string]any:
+		s := &shape{kind: objectKind, fields: map[string]*shape{}}
+		for key, child := range typed {
+			s.fields[key] = sampleShape(child)
+		}
+		return s
+	case []any:
+		s := &shape{kind: arrayKind}
+		for _, child := range typed {
+			s.items = mergeShapes(s.items, sampleShape(child))
+		}
+		return s
+	case string:
+		return &shape{kind: stringKind}
+	case bool:
+		return &shape{kind: boolKind}
+	case json.Number:
+		if strings.ContainsAny(typed.String(), ".eE") {
+			return &shape{kind: numberKind}
+		}
+		return &shape{kind: intKind}
+	}
+	return &shape{kind: anyKind}
+}
+
+// schemaShape derives the shape of a JSON Schema. References and
+// combinations are not followed and result in any.
+func schemaShape(schema map[string]any) *shape {
+	switch schemaType(schema) {
+	case "object":
+		s := &shape{kind: objectKind, fields: map[string]*shape{}}
+		properties, _ := schema["properties"].(map[string]any)
+		for key, property := range properties {
+			if sub, ok := property.(map[string]any); ok {
+				s.fields[key] = schemaShape(sub)
+			} else {
+				s.fields[key] = &shape{kind: anyKind}
+			}
+		}
+		return s
+	case "array":
+		s := &shape{kind: arrayKind}
+		switch items := schema["items"].(type) {
+		case map[string]any:
+			s.items = schemaShape(items)
+		case []any:
+			for _, item := range items {
+				if sub, ok := item.(map[string]any); ok {
+					s.items = mergeShapes(s.items, schemaShape(sub))
+				}
+			}
+		}
+		return s
+	case "string":
+		return &shape{kind: stringKind}
+	case "integer":
+		return &shape{kind: intKind}
+	case "number":
+		return &shape{kind: numberKind}
+	case "boolean":
+		return &shape{kind: boolKind}
+	}
+	return &shape{kind: anyKind}
+}
+
+// schemaType returns the type of the schema. Nullable types are
+// handled like the non-null type, objects without type are detected
+// by their properties.
+func schemaType(schema map[string]any) string {
+	switch typed := schema["type"].(type) {
+	case string:
+		return typed
+	case []any:
+		types := []string{}
+		for _, t := range typed {
+			if s, ok := t.(string); ok && s != "null" {
+				types = append(types, s)
+			}
+		}
+		if len(types) == 1 {
+			return types[0]
+		}
+		return ""
+	}
+	if _, ok := schema["properties"]; ok {
+		return "object"
+	}
+	return ""
+}
+
+// mergeShapes combines two shapes of the same element. Nulls take the
+// other shape, ints and numbers become numbers, and different kinds
+// become any.
+func mergeShapes(a, b *shape) *shape {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.kind == nullKind:
+		return b
+	case b.kind == nullKind:
+		return a
+	case a.kind == intKind && b.kind == numberKind, a.kind == numberKind && b.kind == intKind:
+		return &shape{kind: numberKind}
+	case a.kind != b.kind:
+		return &shape{kind: anyKind}
+	case a.kind == objectKind:
+		s := &shape{kind: objectKind, fields: map[string]*shape{}}
+		for key, field := range a.fields {
+			s.fields[key] = field
+		}
+		for key, field := range b.fields {
+			s.fields[key] = mergeShapes(s.fields[key], field)
+		}
+		return s
+	case a.kind == arrayKind:
+		return &shape{kind: arrayKind, items: mergeShapes(a.items, b.items)}
+	}
+	return a
+}
+
+// EOF
@@ -0,0 +1,214 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// NODE TYPES
+//--------------------
+
+// NodeType defines the expected type of a value when coercing a document.
+type NodeType int
+
+// Types values can be coerced into.
+const (
+	// StringType converts numbers and bools into strings.
+	StringType NodeType = iota
+
+	// IntType converts strings, integral floats, and bools into ints.
+	IntType
+
+	// FloatType converts strings and ints into floats.
+	FloatType
+
+	// BoolType converts strings and the numbers 0 and 1 into bools.
+	BoolType
+)
+
+// String implements fmt.Stringer.
+func (t NodeType) String() string {
+	switch t {
+	case StringType:
+		return "string"
+	case IntType:
+		return "int"
+	case FloatType:
+		return "float"
+	case BoolType:
+		return "bool"
+	}
+	return "type(" + strconv.Itoa(int(t)) + ")"
+}
+
+//--------------------
+// COERCION
+//--------------------
+
+// CoerceError is returned by Coerce and contains the errors of all
+// values that could not be coerced.
+type CoerceError struct {
+	Errors map[Path]error
+}
+
+// Error implements error.
+func (e *CoerceError) Error() string {
+	paths := make([]Path, 0, len(e.Errors))
+	for path := range e.Errors {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	msgs := make([]string, len(paths))
+	for i, path := range paths {
+		msgs[i] = e.Errors[path].Error()
+	}
+	return "cannot coerce document: " + strings.Join(msgs, "; ")
+}
+
+// Coerce converts the values at the paths of the specification into the
+// expected types, e.g. the string "42" into a number or "true" into a
+// bool, to clean up data from form encoders or CSV files. The paths may
+// be patterns and missing values are ignored. Strings are parsed with
+// the number format and bool table of the document. All values that
+// can be converted are changed, the others are returned in a CoerceError.
+func (d *Document) Coerce(spec map[Path]NodeType) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	patterns := make([]string, 0, len(spec))
+	for pattern := range spec {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	errs := map[Path]error{}
+	for _, pattern := range patterns {
+		paths, err := d.ExpandPattern(pattern)
+		if err != nil {
+			errs[pattern] = err
+			continue
+		}
+		for _, path := range paths {
+			element, err := elementAt(d.root, splitPath(path))
+			if err != nil {
+				continue
+			}
+			element, err = decodeRaw(element)
+			if err != nil {
+				errs[path] = fmt.Errorf("cannot coerce value at %q: %v", path, err)
+				continue
+			}
+			if element == nil || isObjectOrArray(element) {
+				continue
+			}
+			coerced, err := d.coerceElement(element, spec[pattern])
+			if err != nil {
+				errs[path] = fmt.Errorf("cannot coerce value at %q: %v", path, err)
+				continue
+			}
+			if coerced == element {
+				continue
+			}
+			if err := d.SetValueAt(path, coerced); err != nil {
+				errs[path] = err
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return &CoerceError{errs}
+	}
+	return nil
+}
+
+// coerceElement converts a simple value into the type.
+func (d *Document) coerceElement(element Element, t NodeType) (Element, error) {
+	switch t {
+	case StringType:
+		switch tv := element.(type) {
+		case string:
+			return tv, nil
+		case int:
+			return strconv.Itoa(tv), nil
+		case float64:
+			return strconv.FormatFloat(tv, 'f', -1, 64), nil
+		case bool:
+			return strconv.FormatBool(tv), nil
+		}
+	case IntType:
+		switch tv := element.(type) {
+		case string:
+			i, err := d.numbers.parseInt(strings.TrimSpace(tv))
+			if err != nil {
+				f, ferr := d.numbers.parseFloat(strings.TrimSpace(tv))
+				if ferr != nil || f != math.Trunc(f) || f < math.MinInt || f > math.MaxInt {
+					return nil, fmt.Errorf("%q is no int", tv)
+				}
+				return int(f), nil
+			}
+			return i, nil
+		case int:
+			return tv, nil
+		case float64:
+			if tv != math.Trunc(tv) || tv < math.MinInt || tv > math.MaxInt {
+				return nil, fmt.Errorf("%v is no int", tv)
+			}
+			return int(tv), nil
+		case bool:
+			if tv {
+				return 1, nil
+			}
+			return 0, nil
+		}
+	case FloatType:
+		switch tv := element.(type) {
+		case string:
+			f, err := d.numbers.parseFloat(strings.TrimSpace(tv))
+			if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+				return nil, fmt.Errorf("%q is no float", tv)
+			}
+			return f, nil
+		case int:
+			return float64(tv), nil
+		case float64:
+			return tv, nil
+		}
+	case BoolType:
+		switch tv := element.(type) {
+		case string:
+			b, err := d.bools.parseBool(strings.TrimSpace(tv))
+			if err != nil {
+				return nil, fmt.Errorf("%q is no bool", tv)
+			}
+			return b, nil
+		case int:
+			if tv == 0 || tv == 1 {
+				return tv == 1, nil
+			}
+		case float64:
+			if tv == 0 || tv == 1 {
+				return tv == 1, nil
+			}
+		case bool:
+			return tv, nil
+		}
+	default:
+		return nil, fmt.Errorf("invalid type %v", t)
+	}
+	return nil, fmt.Errorf("%v cannot be coerced into %v", element, t)
+}
+
+// EOF
@@ -0,0 +1,98 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCoerce tests converting values into the expected types.
+func TestCoerce(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"rows": [
+			{"age": "42", "score": "1.5", "active": "true", "id": 7},
+			{"age": " 17 ", "score": "2", "active": "false", "id": 8.0}
+		],
+		"missing": null
+	}`)
+
+	err := doc.Coerce(map[dynaj.Path]dynaj.NodeType{
+		"/rows/*/age":    dynaj.IntType,
+		"/rows/*/score":  dynaj.FloatType,
+		"/rows/*/active": dynaj.BoolType,
+		"/rows/*/id":     dynaj.StringType,
+		"/missing":       dynaj.IntType,
+		"/unknown":       dynaj.IntType,
+	})
+	assert.NoError(err)
+	data, err := doc.MarshalJSONAt("/rows")
+	assert.NoError(err)
+	assert.Equal(string(data), `[{"active":true,"age":42,"id":"7","score":1.5},{"active":false,"age":17,"id":"8","score":2}]`)
+	assert.True(doc.NodeAt("/missing").IsUndefined())
+}
+
+// TestCoerceErrors tests collecting the errors per path.
+func TestCoerceErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": "x", "b": "2", "c": 2.5, "d": "maybe", "e": 3}`)
+
+	err := doc.Coerce(map[dynaj.Path]dynaj.NodeType{
+		"/a": dynaj.IntType,
+		"/b": dynaj.IntType,
+		"/c": dynaj.IntType,
+		"/d": dynaj.BoolType,
+		"/e": dynaj.BoolType,
+	})
+	var cerr *dynaj.CoerceError
+	assert.True(errors.As(err, &cerr))
+	assert.Length(cerr.Errors, 4)
+	assert.ErrorContains(cerr.Errors["/a"], `"x" is no int`)
+	assert.ErrorContains(cerr.Errors["/e"], "3 cannot be coerced into bool")
+	assert.ErrorContains(err, `cannot coerce value at "/a"`)
+	assert.Equal(doc.NodeAt("/b").AsString(""), "2")
+	assert.Equal(doc.NodeAt("/c").AsFloat64(0), 2.5)
+
+	n, err := doc.NodeAt("/b").AsInt64Strict()
+	assert.NoError(err)
+	assert.Equal(n, int64(2))
+
+	assert.ErrorMatch(doc.Freeze().Coerce(nil), ".*frozen.*")
+}
+
+// TestCoerceFormats tests coercing with number formats and bool tables.
+func TestCoerceFormats(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"amount": "1.234,5", "flag": "yes"}`)
+	doc.SetNumberFormat(dynaj.CommaNumbers)
+	doc.SetBoolTable(dynaj.ExtendedBools)
+
+	err := doc.Coerce(map[dynaj.Path]dynaj.NodeType{
+		"/amount": dynaj.FloatType,
+		"/flag":   dynaj.BoolType,
+	})
+	assert.NoError(err)
+	data, err := doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"amount":1234.5,"flag":true}`)
+}
+
+// EOF
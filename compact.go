@@ -0,0 +1,90 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"reflect"
+	"unsafe"
+)
+
+//--------------------
+// COMPACTION
+//--------------------
+
+// elementSize is the size of one array element.
+const elementSize = int(unsafe.Sizeof(Element(nil)))
+
+// Compact rebuilds the objects and arrays of the document tightly. After
+// many deletions arrays retain their backing capacity and maps keep
+// their grown buckets. The returned number of reclaimed bytes counts the
+// unused array capacity, the memory of the rebuilt maps cannot be
+// measured. Shared subtrees of shared documents stay shared. The
+// values are not changed.
+func (d *Document) Compact() (int, error) {
+	if d.frozen {
+		return 0, ErrFrozen
+	}
+	c := &compactor{
+		rebuilt: map[compactKey]Element{},
+	}
+	d.root = c.element(d.root)
+	d.owned = nil
+	return c.reclaimed, nil
+}
+
+// compactKey identifies a container by its storage and its length.
+type compactKey struct {
+	ptr uintptr
+	len int
+}
+
+// compactor rebuilds the containers.
+type compactor struct {
+	rebuilt   map[compactKey]Element
+	reclaimed int
+}
+
+// element recursively rebuilds the element.
+func (c *compactor) element(element Element) Element {
+	switch typed := element.(type) {
+	case Object:
+		key := compactKey{reflect.ValueOf(typed).Pointer(), len(typed)}
+		if rebuilt, ok := c.rebuilt[key]; ok {
+			return rebuilt
+		}
+		obj := make(Object, len(typed))
+		for k, child := range typed {
+			obj[k] = c.element(child)
+		}
+		c.rebuilt[key] = obj
+		return obj
+	case Array:
+		if len(typed) == 0 {
+			c.reclaimed += cap(typed) * elementSize
+			return Array{}
+		}
+		key := compactKey{reflect.ValueOf(typed).Pointer(), len(typed)}
+		if rebuilt, ok := c.rebuilt[key]; ok {
+			return rebuilt
+		}
+		c.reclaimed += (cap(typed) - len(typed)) * elementSize
+		arr := make(Array, len(typed))
+		for idx, child := range typed {
+			arr[idx] = c.element(child)
+		}
+		c.rebuilt[key] = arr
+		return arr
+	}
+	return element
+}
+
+// EOF
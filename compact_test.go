@@ -0,0 +1,58 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCompact tests rebuilding the storage after deletions.
+func TestCompact(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a":[1,2,3,4,5,6,7,8],"b":{"c":[{"d":1},{"d":2}]}}`)
+
+	for i := 0; i < 6; i++ {
+		assert.NoError(doc.DeleteElementAt("/a/0"))
+	}
+	assert.NoError(doc.DeleteElementAt("/b/c/1"))
+	out := doc.String()
+
+	reclaimed, err := doc.Compact()
+	assert.NoError(err)
+	assert.True(reclaimed >= 7*16)
+	assert.Equal(doc.String(), out)
+
+	// A compact document reclaims nothing.
+	reclaimed, err = doc.Compact()
+	assert.NoError(err)
+	assert.Equal(reclaimed, 0)
+
+	// Shared subtrees stay shared and are copied on mutation.
+	doc = mustUnmarshal(assert, `[{"a":[1]},{"a":[1]}]`)
+	_, err = doc.DeduplicateSubtrees()
+	assert.NoError(err)
+	_, err = doc.Compact()
+	assert.NoError(err)
+	assert.NoError(doc.SetValueAt("/0/a/0", 2))
+	assert.Equal(doc.String(), `[{"a":[2]},{"a":[1]}]`)
+
+	_, err = doc.Freeze().Compact()
+	assert.ErrorMatch(err, ".*frozen.*")
+}
+
+// EOF
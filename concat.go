@@ -0,0 +1,206 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// CONCATENATION
+//--------------------
+
+// ConcatArrays creates a new document with a root array containing
+// the elements of the root arrays of all documents in their order.
+// Empty documents are skipped, any other root is an error. This way
+// for example paginated responses can be joined.
+func ConcatArrays(docs ...*Document) (*Document, error) {
+	arrs := make([]Array, len(docs))
+	size := 0
+	for i, doc := range docs {
+		arr, err := rootArray(doc)
+		if err != nil {
+			return nil, fmt.Errorf("cannot concatenate document %d: %v", i, err)
+		}
+		arrs[i] = arr
+		size += len(arr)
+	}
+	joined := make(Array, 0, size)
+	for _, arr := range arrs {
+		for _, element := range arr {
+			joined = append(joined, copyElement(element))
+		}
+	}
+	return &Document{
+		root: joined,
+	}, nil
+}
+
+// JoinAsArray creates a new document with a root array containing the
+// roots of all documents in their order. Roots of empty documents are
+// null. It is the counterpart of SplitArrayAt.
+func JoinAsArray(docs ...*Document) *Document {
+	joined := make(Array, len(docs))
+	for i, doc := range docs {
+		joined[i] = copyElement(doc.root)
+	}
+	return &Document{
+		root: joined,
+	}
+}
+
+// SplitArrayAt returns a new document for each element of the array at
+// the given path, e.g. for the fan-out processing of batch payloads. The
+// new documents keep the settings of the document for reading and
+// marshalling values.
+func (d *Document) SplitArrayAt(path Path) ([]*Document, error) {
+	element, err := elementAt(d.root, splitPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("cannot split array at %q: %v", path, err)
+	}
+	element, err = decodeRaw(element)
+	if err != nil {
+		return nil, fmt.Errorf("cannot split array at %q: %v", path, err)
+	}
+	arr, ok := element.(Array)
+	if !ok {
+		return nil, fmt.Errorf("cannot split array at %q: is no array", path)
+	}
+	docs := make([]*Document, len(arr))
+	for i, child := range arr {
+		child, err = decodeRaw(child)
+		if err != nil {
+			return nil, fmt.Errorf("cannot split array at %q: %v", path, err)
+		}
+		docs[i] = d.derive(copyElement(child))
+	}
+	return docs, nil
+}
+
+// AppendArrayAt appends the elements of the root array of the other
+// document to the array at the given path. If the path does not exist
+// the array is created like when setting a value.
+func (d *Document) AppendArrayAt(path Path, other *Document) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	appended, err := rootArray(other)
+	if err != nil {
+		return fmt.Errorf("cannot append to array at %q: %v", path, err)
+	}
+	keys := splitPath(path)
+	if err := d.checkAccess(keys); err != nil {
+		return err
+	}
+	element, err := elementAt(d.root, keys)
+	if err != nil {
+		// Create the array as new value.
+		arr := copyElement(appended)
+		if err := d.checkTypeLock(keys, arr); err != nil {
+			return err
+		}
+		if err := d.callHooks(BeforeSet, pathify(keys), nil, arr); err != nil {
+			return err
+		}
+		if err := d.insertAt(keys, arr); err != nil {
+			return err
+		}
+		d.record(SetOperation, path, arr)
+		return d.callHooks(AfterSet, pathify(keys), nil, arr)
+	}
+	element, err = decodeRaw(element)
+	if err != nil {
+		return fmt.Errorf("cannot append to array at %q: %v", path, err)
+	}
+	arr, ok := element.(Array)
+	if !ok {
+		return fmt.Errorf("cannot append to array at %q: is no array", path)
+	}
+	joined := make(Array, len(arr), len(arr)+len(appended))
+	copy(joined, arr)
+	for _, element := range appended {
+		joined = append(joined, copyElement(element))
+	}
+	// Check the array and the new elements like setValueAt does.
+	if err := d.checkStrict(keys); err != nil {
+		return err
+	}
+	if err := d.checkTypeLock(keys, joined); err != nil {
+		return err
+	}
+	for idx := len(arr); idx < len(joined); idx++ {
+		if err := d.checkTypeLock(append(keys[:len(keys):len(keys)], strconv.Itoa(idx)), joined[idx]); err != nil {
+			return err
+		}
+	}
+	for idx := len(arr); idx < len(joined); idx++ {
+		if err := d.callHooks(BeforeSet, appendKey(pathify(keys), strconv.Itoa(idx)), nil, joined[idx]); err != nil {
+			return err
+		}
+	}
+	d.unshare(keys)
+	root, err := replaceElement(d.root, keys, joined)
+	if err != nil {
+		return err
+	}
+	d.root = root
+	d.changed(keys)
+	for idx := len(arr); idx < len(joined); idx++ {
+		d.record(SetOperation, appendKey(pathify(keys), strconv.Itoa(idx)), joined[idx])
+	}
+	for idx := len(arr); idx < len(joined); idx++ {
+		if err := d.callHooks(AfterSet, appendKey(pathify(keys), strconv.Itoa(idx)), nil, joined[idx]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AddToArray appends the values to the array at the given path instead
+// of addressing the new elements by their indices. If the path does not
+// exist the array is created.
+func (d *Document) AddToArray(path Path, values ...Value) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	normalized, err := normalizeValue(Array(values), path, d.nonFinite)
+	if err != nil {
+		return fmt.Errorf("cannot append to array at %q: %v", path, err)
+	}
+	return d.AppendArrayAt(path, &Document{root: normalized})
+}
+
+// AddObjectToArray appends the object to the array at the given path,
+// see AddToArray.
+func (d *Document) AddObjectToArray(path Path, obj map[string]any) error {
+	return d.AddToArray(path, Object(obj))
+}
+
+// rootArray returns the root array of the document. Empty documents
+// return an empty array.
+func rootArray(doc *Document) (Array, error) {
+	root, err := decodeRaw(doc.root)
+	if err != nil {
+		return nil, err
+	}
+	switch typed := root.(type) {
+	case nil:
+		return nil, nil
+	case Array:
+		return typed, nil
+	default:
+		return nil, fmt.Errorf("root is no array")
+	}
+}
+
+// EOF
@@ -0,0 +1,159 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestConcatArrays tests joining the root arrays of documents.
+func TestConcatArrays(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := mustUnmarshal(assert, `[1,{"a":"b"}]`)
+	second := mustUnmarshal(assert, `[]`)
+	third := mustUnmarshal(assert, `[[true],null]`)
+
+	doc, err := dynaj.ConcatArrays(first, second, dynaj.NewDocument(), third)
+	assert.NoError(err)
+	assert.Equal(doc.String(), `[1,{"a":"b"},[true],null]`)
+
+	// Changes do not affect the sources.
+	assert.NoError(doc.SetValueAt("/1/a", "c"))
+	assert.Equal(first.String(), `[1,{"a":"b"}]`)
+
+	doc, err = dynaj.ConcatArrays()
+	assert.NoError(err)
+	assert.Equal(doc.String(), `[]`)
+
+	_, err = dynaj.ConcatArrays(first, mustUnmarshal(assert, `{"a":1}`))
+	assert.ErrorContains(err, "cannot concatenate document 1: root is no array")
+}
+
+// TestAppendArrayAt tests appending root arrays to nested arrays.
+func TestAppendArrayAt(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"data":{"items":[1,2]},"n":3}`)
+	page := mustUnmarshal(assert, `[3,{"x":4}]`)
+
+	doc.RecordOperations()
+	assert.NoError(doc.AppendArrayAt("/data/items", page))
+	assert.Equal(doc.String(), `{"data":{"items":[1,2,3,{"x":4}]},"n":3}`)
+	assert.NoError(page.SetValueAt("/1/x", 5))
+	assert.Equal(doc.NodeAt("/data/items/3/x").AsInt(0), 4)
+
+	// Operations can be replayed.
+	replayed, err := dynaj.Replay(doc.Operations())
+	assert.NoError(err)
+	assert.Equal(replayed.String(), doc.String())
+
+	// Missing arrays are created.
+	assert.NoError(doc.AppendArrayAt("/data/more", page))
+	bs, err := doc.MarshalJSONAt("/data/more")
+	assert.NoError(err)
+	assert.Equal(string(bs), `[3,{"x":5}]`)
+	assert.NoError(doc.AppendArrayAt("/data/none", dynaj.NewDocument()))
+	assert.True(doc.NodeAt("/data/none").IsArray())
+
+	// Raw arrays are decoded.
+	assert.NoError(doc.SetRawAt("/raw", []byte(`["a"]`)))
+	assert.NoError(doc.AppendArrayAt("/raw", page))
+	bs, err = doc.MarshalJSONAt("/raw")
+	assert.NoError(err)
+	assert.Equal(string(bs), `["a",3,{"x":5}]`)
+
+	// Strict mode and locked types are respected.
+	doc.SetStrict(true)
+	doc.LockTypes()
+	assert.NoError(doc.AppendArrayAt("/data/items", page))
+	assert.Equal(doc.Length("/data/items"), 6)
+	err = doc.AppendArrayAt("/missing/items", page)
+	assert.True(errors.Is(err, dynaj.ErrPathNotFound))
+	doc.SetStrict(false)
+	doc.UnlockTypes()
+
+	err = doc.AppendArrayAt("/n", page)
+	assert.ErrorContains(err, "is no array")
+	err = doc.AppendArrayAt("/data/items", mustUnmarshal(assert, `"x"`))
+	assert.ErrorContains(err, "root is no array")
+	err = doc.Freeze().AppendArrayAt("/data/items", page)
+	assert.Equal(err, dynaj.ErrFrozen)
+}
+
+// TestSplitAndJoin tests splitting arrays into documents and joining
+// them again.
+func TestSplitAndJoin(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"batch": [{"id": 1}, {"id": 2}, "three", null], "other": 1}`)
+	doc.SetNumberFormat(dynaj.CommaNumbers)
+
+	docs, err := doc.SplitArrayAt("/batch")
+	assert.NoError(err)
+	assert.Length(docs, 4)
+	assert.Equal(docs[0].String(), `{"id":1}`)
+	assert.Equal(docs[2].Root().AsString(""), "three")
+	assert.True(docs[3].Root().IsUndefined())
+
+	// The documents are independent.
+	assert.NoError(docs[1].SetValueAt("/id", "2,5"))
+	assert.Equal(docs[1].NodeAt("/id").AsFloat64(0), 2.5)
+	assert.Equal(doc.NodeAt("/batch/1/id").AsInt(0), 2)
+
+	joined := dynaj.JoinAsArray(docs...)
+	assert.Equal(joined.String(), `[{"id":1},{"id":"2,5"},"three",null]`)
+	assert.NoError(joined.SetValueAt("/0/id", 10))
+	assert.Equal(docs[0].NodeAt("/id").AsInt(0), 1)
+
+	assert.Equal(dynaj.JoinAsArray().String(), `[]`)
+	assert.Equal(dynaj.JoinAsArray(dynaj.NewDocument()).String(), `[null]`)
+
+	// Raw fragments and errors.
+	assert.NoError(doc.SetRawAt("/raw", []byte(`[[1], {"a": 2}]`)))
+	docs, err = doc.SplitArrayAt("/raw")
+	assert.NoError(err)
+	assert.Equal(docs[1].NodeAt("/a").AsInt(0), 2)
+	_, err = doc.SplitArrayAt("/other")
+	assert.ErrorContains(err, `cannot split array at "/other": is no array`)
+	_, err = doc.SplitArrayAt("/none")
+	assert.ErrorContains(err, `cannot split array at "/none"`)
+}
+
+// TestAddToArray tests appending values to arrays.
+func TestAddToArray(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"tags":["a"],"name":"x"}`)
+
+	assert.NoError(doc.AddToArray("/tags", "b", 1, []int{2, 3}))
+	assert.NoError(doc.AddToArray("/tags"))
+	assert.NoError(doc.AddObjectToArray("/users", map[string]any{"name": "jane", "age": uint8(42)}))
+	assert.NoError(doc.AddObjectToArray("/users", map[string]any{"name": "john"}))
+	assert.Equal(doc.String(), `{"name":"x","tags":["a","b",1,[2,3]],"users":[{"age":42,"name":"jane"},{"name":"john"}]}`)
+
+	// Appended values are copies.
+	obj := map[string]any{"n": 1}
+	assert.NoError(doc.AddObjectToArray("/objs", obj))
+	obj["n"] = 2
+	assert.Equal(doc.NodeAt("/objs/0/n").AsInt(0), 1)
+
+	assert.ErrorMatch(doc.AddToArray("/name", "y"), `cannot append to array at "/name": is no array`)
+	assert.ErrorMatch(doc.AddToArray("/tags", func() {}), `cannot append to array at "/tags": .*`)
+}
+
+// EOF
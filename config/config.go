@@ -0,0 +1,313 @@
+// Tideland Go Dynamic JSON - Configuration
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package config // import "tideland.dev/go/dynaj/config"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// OPTIONS
+//--------------------
+
+// Option configures a watcher.
+type Option func(w *Watcher)
+
+// Delay sets how long the watcher waits after the last file event before
+// it reloads the file. This way the events of one write are collected.
+// Default is 50 milliseconds.
+func Delay(delay time.Duration) Option {
+	return func(w *Watcher) {
+		if delay > 0 {
+			w.delay = delay
+		}
+	}
+}
+
+// DocumentOptions sets the options for unmarshalling the file.
+func DocumentOptions(opts ...dynaj.Option) Option {
+	return func(w *Watcher) {
+		w.opts = opts
+	}
+}
+
+// OnError sets the handler for errors when reloading the file. Default
+// is to ignore them, they can be retrieved with Err.
+func OnError(handler func(err error)) Option {
+	return func(w *Watcher) {
+		w.onError = handler
+	}
+}
+
+//--------------------
+// WATCHER
+//--------------------
+
+// Subscriber is called with the reloaded document and the sorted paths
+// of its changes.
+type Subscriber func(doc *dynaj.Document, paths []dynaj.Path)
+
+// subscription contains a subscriber and the patterns of the paths it
+// is interested in.
+type subscription struct {
+	subscriber Subscriber
+	patterns   []string
+}
+
+// Watcher watches a JSON configuration file and reloads it on changes.
+type Watcher struct {
+	mu            sync.Mutex
+	reloadMu      sync.Mutex
+	filename      string
+	delay         time.Duration
+	opts          []dynaj.Option
+	onError       func(err error)
+	fsw           *fsnotify.Watcher
+	doc           *dynaj.Document
+	data          []byte
+	err           error
+	subscriptions map[int]*subscription
+	nextID        int
+	closed        bool
+	done          chan struct{}
+}
+
+// Watch loads the file and starts watching it. An error is returned if
+// the file cannot be loaded or watched initially.
+func Watch(filename string, opts ...Option) (*Watcher, error) {
+	w := &Watcher{
+		filename:      filepath.Clean(filename),
+		delay:         50 * time.Millisecond,
+		subscriptions: map[int]*subscription{},
+		done:          make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	if _, err := w.reload(); err != nil {
+		return nil, err
+	}
+	fsw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("cannot watch configuration %q: %v", w.filename, err)
+	}
+	// Watch the directory, editors often replace the file by renaming
+	// a new one.
+	if err := fsw.Add(filepath.Dir(w.filename)); err != nil {
+		fsw.Close()
+		return nil, fmt.Errorf("cannot watch configuration %q: %v", w.filename, err)
+	}
+	w.fsw = fsw
+	go w.backend()
+	return w, nil
+}
+
+// Document returns the current frozen document.
+func (w *Watcher) Document() *dynaj.Document {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.doc
+}
+
+// Err returns the error of the last reload, nil if it succeeded.
+func (w *Watcher) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Subscribe registers the subscriber for changes. With patterns it is
+// only called if at least one changed path matches and only gets the
+// matching paths. The returned function cancels the subscription.
+func (w *Watcher) Subscribe(subscriber Subscriber, patterns ...string) (unsubscribe func()) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	id := w.nextID
+	w.nextID++
+	w.subscriptions[id] = &subscription{
+		subscriber: subscriber,
+		patterns:   patterns,
+	}
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscriptions, id)
+	}
+}
+
+// Reload checks the file immediately and notifies the subscribers if
+// it has been changed. Reloads and their notifications are serialised,
+// so subscribers see the changes in order. They must not call Reload
+// themselves.
+func (w *Watcher) Reload() error {
+	w.reloadMu.Lock()
+	defer w.reloadMu.Unlock()
+	changed, err := w.reload()
+	if err != nil {
+		return err
+	}
+	w.notify(changed)
+	return nil
+}
+
+// Close stops watching the file. It does not wait for a running
+// notification, so it can also be called by subscribers. Afterwards no
+// further subscribers are called.
+func (w *Watcher) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	close(w.done)
+	return w.fsw.Close()
+}
+
+// isClosed returns true if the watcher has been closed.
+func (w *Watcher) isClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
+}
+
+// backend handles the file events until the watcher is closed. Events
+// are delayed until no further ones arrive, then the file is reloaded.
+func (w *Watcher) backend() {
+	timer := time.NewTimer(w.delay)
+	if !timer.Stop() {
+		<-timer.C
+	}
+	defer timer.Stop()
+	for {
+		select {
+		case <-w.done:
+			return
+		case event, ok := <-w.fsw.Events:
+			if !ok {
+				return
+			}
+			if filepath.Clean(event.Name) != w.filename || event.Op == fsnotify.Chmod {
+				continue
+			}
+			timer.Reset(w.delay)
+		case err, ok := <-w.fsw.Errors:
+			if !ok {
+				return
+			}
+			if w.onError != nil && !w.isClosed() {
+				w.onError(fmt.Errorf("cannot watch configuration %q: %v", w.filename, err))
+			}
+		case <-timer.C:
+			if err := w.Reload(); err != nil && w.onError != nil && !w.isClosed() {
+				w.onError(err)
+			}
+		}
+	}
+}
+
+// reload loads and parses the file if its content has been changed. It
+// returns the sorted changed paths.
+func (w *Watcher) reload() ([]dynaj.Path, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	data, err := os.ReadFile(w.filename)
+	if err != nil {
+		return nil, w.fail(err)
+	}
+	if w.doc != nil && bytes.Equal(data, w.data) {
+		w.err = nil
+		return nil, nil
+	}
+	doc, err := dynaj.Unmarshal(data, w.opts...)
+	if err != nil {
+		return nil, w.fail(err)
+	}
+	doc = doc.Freeze()
+	var changed []dynaj.Path
+	if w.doc != nil {
+		diff, err := dynaj.CompareDocuments(w.doc, doc)
+		if err != nil {
+			return nil, w.fail(err)
+		}
+		changed = diff.Differences()
+		sort.Strings(changed)
+	}
+	w.doc = doc
+	w.data = data
+	w.err = nil
+	return changed, nil
+}
+
+// fail stores and returns the error of a reload.
+func (w *Watcher) fail(err error) error {
+	w.err = fmt.Errorf("cannot load configuration %q: %v", w.filename, err)
+	return w.err
+}
+
+// notify calls the subscribers interested in the changed paths until
+// the watcher is closed.
+func (w *Watcher) notify(changed []dynaj.Path) {
+	if len(changed) == 0 {
+		return
+	}
+	w.mu.Lock()
+	doc := w.doc
+	ids := make([]int, 0, len(w.subscriptions))
+	for id := range w.subscriptions {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+	subscriptions := make([]*subscription, len(ids))
+	for i, id := range ids {
+		subscriptions[i] = w.subscriptions[id]
+	}
+	w.mu.Unlock()
+	for _, s := range subscriptions {
+		if w.isClosed() {
+			return
+		}
+		if paths := s.matching(changed); len(paths) > 0 {
+			s.subscriber(doc, paths)
+		}
+	}
+}
+
+// matching returns the paths matching the patterns of the subscription.
+func (s *subscription) matching(paths []dynaj.Path) []dynaj.Path {
+	if len(s.patterns) == 0 {
+		return paths
+	}
+	matching := []dynaj.Path{}
+	for _, path := range paths {
+		for _, pattern := range s.patterns {
+			if dynaj.MatchPattern(pattern, path) {
+				matching = append(matching, path)
+				break
+			}
+		}
+	}
+	return matching
+}
+
+// EOF
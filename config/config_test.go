@@ -0,0 +1,244 @@
+// Tideland Go Dynamic JSON - Configuration - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package config_test // import "tideland.dev/go/dynaj/config"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/config"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestWatch tests loading and reloading a configuration file.
+func TestWatch(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := writeConfig(assert, filepath.Join(t.TempDir(), "config.json"), `{"server":{"port":8080,"host":"localhost"},"debug":false}`)
+
+	w, err := config.Watch(filename, config.Delay(10*time.Millisecond))
+	assert.NoError(err)
+	defer w.Close()
+	assert.Equal(w.Document().NodeAt("/server/port").AsInt(0), 8080)
+
+	all := make(chan []dynaj.Path, 1)
+	server := make(chan []dynaj.Path, 1)
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		all <- paths
+	})
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		server <- paths
+	}, "/server/*")
+
+	writeConfig(assert, filename, `{"server":{"port":9090,"host":"localhost"},"debug":true}`)
+	assert.Equal(receive(assert, all), []dynaj.Path{"/debug", "/server/port"})
+	assert.Equal(receive(assert, server), []dynaj.Path{"/server/port"})
+	assert.Equal(w.Document().NodeAt("/server/port").AsInt(0), 9090)
+	assert.True(w.Document().IsFrozen())
+
+	// Changes outside of the patterns are not notified.
+	writeConfig(assert, filename, `{"server":{"port":9090,"host":"localhost"},"debug":false,"extra":1}`)
+	assert.Equal(receive(assert, all), []dynaj.Path{"/debug", "/extra"})
+	select {
+	case <-server:
+		assert.Fail("unexpected notification")
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// TestWatchSameSize tests detecting rewrites keeping the size of the file.
+func TestWatchSameSize(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := writeConfig(assert, filepath.Join(t.TempDir(), "config.json"), `{"a":1}`)
+	w, err := config.Watch(filename, config.Delay(10*time.Millisecond))
+	assert.NoError(err)
+	defer w.Close()
+
+	all := make(chan []dynaj.Path, 1)
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		all <- paths
+	})
+	writeConfig(assert, filename, `{"a":2}`)
+	assert.Equal(receive(assert, all), []dynaj.Path{"/a"})
+	assert.Equal(w.Document().NodeAt("/a").AsInt(0), 2)
+}
+
+// TestWatchReplace tests detecting files replaced by renaming.
+func TestWatchReplace(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	dir := t.TempDir()
+	filename := writeConfig(assert, filepath.Join(dir, "config.json"), `{"a":1}`)
+	w, err := config.Watch(filename, config.Delay(10*time.Millisecond))
+	assert.NoError(err)
+	defer w.Close()
+
+	all := make(chan []dynaj.Path, 1)
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		all <- paths
+	})
+	tmpname := writeConfig(assert, filepath.Join(dir, "config.json.tmp"), `{"a":1,"b":2}`)
+	assert.NoError(os.Rename(tmpname, filename))
+	assert.Equal(receive(assert, all), []dynaj.Path{"/b"})
+	assert.Equal(w.Document().NodeAt("/b").AsInt(0), 2)
+}
+
+// TestReloadOrder tests that concurrent reloads notify in order.
+func TestReloadOrder(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := writeConfig(assert, filepath.Join(t.TempDir(), "config.json"), `{"n":0}`)
+	w, err := config.Watch(filename, config.Delay(time.Millisecond))
+	assert.NoError(err)
+	defer w.Close()
+
+	var mu sync.Mutex
+	last := 0
+	ordered := true
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		mu.Lock()
+		defer mu.Unlock()
+		n := doc.NodeAt("/n").AsInt(0)
+		if n <= last {
+			ordered = false
+		}
+		last = n
+	})
+	var wg sync.WaitGroup
+	for i := 1; i <= 50; i++ {
+		writeConfig(assert, filename, fmt.Sprintf(`{"n":%d}`, i))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w.Reload()
+		}()
+	}
+	wg.Wait()
+	assert.NoError(w.Reload())
+	mu.Lock()
+	defer mu.Unlock()
+	assert.True(ordered)
+	assert.Equal(last, 50)
+}
+
+// TestCloseBySubscriber tests closing the watcher in a subscriber,
+// further subscribers are not called anymore.
+func TestCloseBySubscriber(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := writeConfig(assert, filepath.Join(t.TempDir(), "config.json"), `{"a":1}`)
+	w, err := config.Watch(filename, config.Delay(10*time.Millisecond))
+	assert.NoError(err)
+
+	closed := make(chan error, 1)
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		closed <- w.Close()
+	})
+	w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		assert.Fail("subscriber called after closing")
+	})
+	writeConfig(assert, filename, `{"a":2}`)
+	select {
+	case err := <-closed:
+		assert.NoError(err)
+	case <-time.After(time.Second):
+		assert.Fail("watcher not closed")
+	}
+	assert.NoError(w.Close())
+}
+
+// TestWatchInvalid tests keeping the last valid document.
+func TestWatchInvalid(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	_, err := config.Watch(filepath.Join(t.TempDir(), "missing.json"))
+	assert.ErrorContains(err, "cannot load configuration")
+
+	filename := writeConfig(assert, filepath.Join(t.TempDir(), "config.json"), `{"a":1}`)
+	errs := make(chan error, 1)
+	w, err := config.Watch(filename,
+		config.Delay(10*time.Millisecond),
+		config.OnError(func(err error) {
+			select {
+			case errs <- err:
+			default:
+			}
+		}))
+	assert.NoError(err)
+	defer w.Close()
+
+	writeConfig(assert, filename, `{"a":`)
+	select {
+	case err := <-errs:
+		assert.ErrorContains(err, "cannot load configuration")
+	case <-time.After(time.Second):
+		assert.Fail("no error reported")
+	}
+	assert.NotNil(w.Err())
+	assert.Equal(w.Document().NodeAt("/a").AsInt(0), 1)
+
+	writeConfig(assert, filename, `{"a":2}`)
+	assert.NoError(w.Reload())
+	assert.Nil(w.Err())
+	assert.Equal(w.Document().NodeAt("/a").AsInt(0), 2)
+}
+
+// TestUnsubscribe tests cancelling a subscription.
+func TestUnsubscribe(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := writeConfig(assert, filepath.Join(t.TempDir(), "config.json"), `{"a":1}`)
+	w, err := config.Watch(filename, config.Delay(time.Hour))
+	assert.NoError(err)
+	defer w.Close()
+
+	calls := 0
+	unsubscribe := w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+		calls++
+	})
+	writeConfig(assert, filename, `{"a":2}`)
+	assert.NoError(w.Reload())
+	assert.Equal(calls, 1)
+
+	unsubscribe()
+	writeConfig(assert, filename, `{"a":3}`)
+	assert.NoError(w.Reload())
+	assert.Equal(calls, 1)
+	assert.Equal(w.Document().NodeAt("/a").AsInt(0), 3)
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// writeConfig writes the content into the file.
+func writeConfig(assert *asserts.Asserts, filename, content string) string {
+	assert.NoError(os.WriteFile(filename, []byte(content), 0o600))
+	return filename
+}
+
+// receive waits for the paths of a notification.
+func receive(assert *asserts.Asserts, ch chan []dynaj.Path) []dynaj.Path {
+	select {
+	case paths := <-ch:
+		return paths
+	case <-time.After(time.Second):
+		assert.Fail("no notification")
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,27 @@
+// Tideland Go Dynamic JSON - Configuration
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package config binds JSON configuration files to documents and
+// reloads them when the files change. Subscribers are notified about
+// the changed paths of each reload.
+//
+//	w, err := config.Watch("service.json")
+//	...
+//	port := w.Document().NodeAt("/server/port").AsInt(8080)
+//	unsubscribe := w.Subscribe(func(doc *dynaj.Document, paths []dynaj.Path) {
+//		// Reconfigure with the new document.
+//	}, "/server/*")
+//	...
+//	err = w.Close()
+//
+// The directories of the files are watched with fsnotify, so also files
+// replaced by renaming are detected. Each reload compares the content,
+// notifications are delivered in order. Invalid files are reported to
+// the error handler and the last valid document is kept.
+package config // import "tideland.dev/go/dynaj/config"
+
+// EOF
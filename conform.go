@@ -0,0 +1,448 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// RequiredTag is the struct tag marking fields as required, e.g.
+//
+//	Name string `json:"name" dynaj:"required"`
+const RequiredTag = "dynaj"
+
+//--------------------
+// CONFORMANCE
+//--------------------
+
+// ConformsTo checks if the document can be cleanly unmarshalled into
+// the prototype, typically a struct or a pointer to it. Struct fields
+// are found by their json tags like encoding/json does, fields tagged
+// with `dynaj:"required"` have to exist and must not be null. All
+// violations are returned with their paths, an empty result means the
+// document conforms.
+func (d *Document) ConformsTo(prototype any) []error {
+	c := &conformer{}
+	c.check(d.root, Separator, reflect.TypeOf(prototype))
+	return c.errs
+}
+
+// conformer collects the violations.
+type conformer struct {
+	errs []error
+}
+
+// violate adds a violation.
+func (c *conformer) violate(path Path, format string, args ...any) {
+	c.errs = append(c.errs, fmt.Errorf("invalid value at %q: %s", path, fmt.Sprintf(format, args...)))
+}
+
+// check recursively checks the element against the type.
+func (c *conformer) check(element Element, path Path, t reflect.Type) {
+	if t == nil {
+		return
+	}
+	element, err := decodeRaw(element)
+	if err != nil {
+		c.violate(path, "%v", err)
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Null leaves all values unchanged.
+	if element == nil {
+		return
+	}
+	// Types unmarshalling themselves are checked by trying it.
+	pt := reflect.PointerTo(t)
+	if pt.Implements(jsonUnmarshalerType) {
+		data, err := json.Marshal(element)
+		if err == nil {
+			err = reflect.New(t).Interface().(json.Unmarshaler).UnmarshalJSON(data)
+		}
+		if err != nil {
+			c.violate(path, "cannot unmarshal into %v: %v", t, err)
+		}
+		return
+	}
+	if pt.Implements(textUnmarshalerType) {
+		s, ok := element.(string)
+		if !ok {
+			c.violate(path, "%s instead of string", typeName(element))
+			return
+		}
+		if err := reflect.New(t).Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s)); err != nil {
+			c.violate(path, "cannot unmarshal into %v: %v", t, err)
+		}
+		return
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() > 0 {
+			c.violate(path, "cannot unmarshal into %v", t)
+		}
+	case reflect.Struct:
+		obj, ok := element.(Object)
+		if !ok {
+			c.violate(path, "%s instead of object", typeName(element))
+			return
+		}
+		for _, field := range jsonFields(t) {
+			key, child, ok := lookupField(obj, field.name)
+			if !ok || child == nil {
+				if field.required {
+					c.errs = append(c.errs, fmt.Errorf("%w: missing required value at %q", ErrPathNotFound, appendKey(path, field.name)))
+				}
+				continue
+			}
+			if field.quoted {
+				c.checkQuoted(child, appendKey(path, key), field.typ)
+				continue
+			}
+			c.check(child, appendKey(path, key), field.typ)
+		}
+	case reflect.Map:
+		obj, ok := element.(Object)
+		if !ok {
+			c.violate(path, "%s instead of object", typeName(element))
+			return
+		}
+		kt := t.Key()
+		for _, key := range childKeys(obj) {
+			if !isMapKey(key, kt) {
+				c.violate(appendKey(path, key), "invalid key for %v", kt)
+				continue
+			}
+			c.check(obj[key], appendKey(path, key), t.Elem())
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8 {
+			c.checkBytes(element, path)
+			return
+		}
+		arr, ok := element.(Array)
+		if !ok {
+			c.violate(path, "%s instead of array", typeName(element))
+			return
+		}
+		for idx, child := range arr {
+			c.check(child, appendKey(path, strconv.Itoa(idx)), t.Elem())
+		}
+	case reflect.String:
+		if _, ok := element.(string); !ok {
+			c.violate(path, "%s instead of string", typeName(element))
+		}
+	case reflect.Bool:
+		if _, ok := element.(bool); !ok {
+			c.violate(path, "%s instead of bool", typeName(element))
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		f, ok := asNumber(element)
+		if !ok {
+			c.violate(path, "%s instead of number", typeName(element))
+			return
+		}
+		if msg := numberFits(f, t); msg != "" {
+			c.violate(path, "%s", msg)
+		}
+	default:
+		c.violate(path, "cannot unmarshal into %v", t)
+	}
+}
+
+// checkQuoted checks values of fields with the string option.
+func (c *conformer) checkQuoted(element Element, path Path, t reflect.Type) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	s, ok := element.(string)
+	if !ok {
+		c.violate(path, "%s instead of quoted %v", typeName(element), t)
+		return
+	}
+	var value Element
+	if err := json.Unmarshal([]byte(s), &value); err != nil {
+		c.violate(path, "invalid quoted %v", t)
+		return
+	}
+	c.check(value, path, t)
+}
+
+// checkBytes checks base64 encoded strings for byte slices.
+func (c *conformer) checkBytes(element Element, path Path) {
+	s, ok := element.(string)
+	if !ok {
+		c.violate(path, "%s instead of base64 string", typeName(element))
+		return
+	}
+	var b []byte
+	if err := json.Unmarshal([]byte(strconv.Quote(s)), &b); err != nil {
+		c.violate(path, "invalid base64 string")
+	}
+}
+
+// numberFits checks if the number can be stored in the type. It returns
+// a message if not.
+func numberFits(f float64, t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Float32:
+		if math.Abs(f) > math.MaxFloat32 {
+			return fmt.Sprintf("number %v overflows %v", f, t)
+		}
+		return ""
+	case reflect.Float64:
+		return ""
+	}
+	if f != math.Trunc(f) {
+		return fmt.Sprintf("number %v is no integer for %v", f, t)
+	}
+	bits := t.Bits()
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if f < -math.Ldexp(1, bits-1) || f >= math.Ldexp(1, bits-1) {
+			return fmt.Sprintf("number %v overflows %v", f, t)
+		}
+	default:
+		if f < 0 || f >= math.Ldexp(1, bits) {
+			return fmt.Sprintf("number %v overflows %v", f, t)
+		}
+	}
+	return ""
+}
+
+// isMapKey checks if the key can be unmarshalled into the key type.
+func isMapKey(key Key, t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		_, err := strconv.ParseInt(key, 10, t.Bits())
+		return err == nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		_, err := strconv.ParseUint(key, 10, t.Bits())
+		return err == nil
+	}
+	return false
+}
+
+//--------------------
+// STRICT UNMARSHALLING
+//--------------------
+
+// UnmarshalAtStrict unmarshals the element at the path into the target
+// like encoding/json. Additionally it returns the sorted paths of all
+// object keys without corresponding struct field, e.g. to detect unknown
+// or undocumented fields in incoming payloads. They are no error.
+func (d *Document) UnmarshalAtStrict(path Path, target any) ([]Path, error) {
+	data, err := d.MarshalJSONAt(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, target); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal element at %q: %v", path, err)
+	}
+	element, err := elementAt(d.root, splitPath(path))
+	if err != nil {
+		return nil, err
+	}
+	leftovers := []Path{}
+	collectLeftovers(element, pathify(splitPath(path)), reflect.TypeOf(target), &leftovers)
+	sortPaths(leftovers)
+	return leftovers, nil
+}
+
+// collectLeftovers recursively collects the paths of the keys without
+// struct field.
+func collectLeftovers(element Element, path Path, t reflect.Type, leftovers *[]Path) {
+	element, err := decodeRaw(element)
+	if err != nil || t == nil {
+		return
+	}
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	pt := reflect.PointerTo(t)
+	if pt.Implements(jsonUnmarshalerType) || pt.Implements(textUnmarshalerType) {
+		return
+	}
+	switch typed := element.(type) {
+	case Object:
+		switch t.Kind() {
+		case reflect.Struct:
+			fields := jsonFields(t)
+			matched := map[Key]reflect.Type{}
+			for _, field := range fields {
+				if key, _, ok := lookupField(typed, field.name); ok {
+					if _, ok := matched[key]; !ok {
+						matched[key] = field.typ
+					}
+				}
+			}
+			for key, child := range typed {
+				ft, ok := matched[key]
+				if !ok {
+					*leftovers = append(*leftovers, appendKey(path, key))
+					continue
+				}
+				collectLeftovers(child, appendKey(path, key), ft, leftovers)
+			}
+		case reflect.Map:
+			for key, child := range typed {
+				collectLeftovers(child, appendKey(path, key), t.Elem(), leftovers)
+			}
+		}
+	case Array:
+		if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+			return
+		}
+		for idx, child := range typed {
+			if t.Kind() == reflect.Array && idx >= t.Len() {
+				*leftovers = append(*leftovers, appendKey(path, strconv.Itoa(idx)))
+				continue
+			}
+			collectLeftovers(child, appendKey(path, strconv.Itoa(idx)), t.Elem(), leftovers)
+		}
+	}
+}
+
+//--------------------
+// STRUCT FIELDS
+//--------------------
+
+// Types of the unmarshaller interfaces.
+var (
+	jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+// jsonField describes a struct field as seen by encoding/json.
+type jsonField struct {
+	name     string
+	typ      reflect.Type
+	quoted   bool
+	required bool
+}
+
+// jsonFields returns the fields of the struct type. Fields of embedded
+// structs without name are promoted, fields of shallower structs win.
+func jsonFields(t reflect.Type) []jsonField {
+	fields := []jsonField{}
+	seen := map[string]struct{}{}
+	current := []reflect.Type{t}
+	visited := map[reflect.Type]struct{}{}
+	for len(current) > 0 {
+		next := []reflect.Type{}
+		level := []jsonField{}
+		for _, st := range current {
+			if _, ok := visited[st]; ok {
+				continue
+			}
+			visited[st] = struct{}{}
+			for i := 0; i < st.NumField(); i++ {
+				sf := st.Field(i)
+				tag := sf.Tag.Get("json")
+				if tag == "-" {
+					continue
+				}
+				name, opts, _ := strings.Cut(tag, ",")
+				ft := sf.Type
+				if sf.Anonymous && name == "" {
+					for ft.Kind() == reflect.Pointer {
+						ft = ft.Elem()
+					}
+					if ft.Kind() == reflect.Struct {
+						next = append(next, ft)
+						continue
+					}
+				}
+				if !sf.IsExported() {
+					continue
+				}
+				if name == "" {
+					name = sf.Name
+				}
+				level = append(level, jsonField{
+					name:     name,
+					typ:      sf.Type,
+					quoted:   hasOption(opts, "string") && isQuotable(sf.Type),
+					required: hasOption(sf.Tag.Get(RequiredTag), "required"),
+				})
+			}
+		}
+		for _, field := range level {
+			if _, ok := seen[field.name]; !ok {
+				seen[field.name] = struct{}{}
+				fields = append(fields, field)
+			}
+		}
+		current = next
+	}
+	return fields
+}
+
+// hasOption checks if the comma separated options contain the option.
+func hasOption(opts, option string) bool {
+	for _, opt := range strings.Split(opts, ",") {
+		if opt == option {
+			return true
+		}
+	}
+	return false
+}
+
+// isQuotable checks if the string option applies to the type.
+func isQuotable(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// lookupField returns the key and the value of the field. Like
+// encoding/json an exact match is preferred, otherwise keys are
+// matched case-insensitive.
+func lookupField(obj Object, name string) (Key, Element, bool) {
+	if child, ok := obj[name]; ok {
+		return name, child, true
+	}
+	for _, key := range childKeys(obj) {
+		if strings.EqualFold(key, name) {
+			return key, obj[key], true
+		}
+	}
+	return "", nil, false
+}
+
+// EOF
@@ -0,0 +1,128 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestConformsTo tests checking documents against Go types.
+func TestConformsTo(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	doc := mustUnmarshal(assert, `{
+		"id": 42,
+		"Name": "alpha",
+		"created": "2023-01-02T03:04:05Z",
+		"port": "8080",
+		"tags": ["a", "b"],
+		"limits": {"1": 10.5},
+		"owner": {"email": "jane@example.com"},
+		"unknown": true
+	}`)
+	assert.Length(doc.ConformsTo(&conformingItem{}), 0)
+	assert.Length(doc.ConformsTo(conformingItem{}), 0)
+
+	doc = mustUnmarshal(assert, `{
+		"id": 1.5,
+		"name": 1,
+		"created": 123,
+		"port": 8080,
+		"level": 300,
+		"tags": "a",
+		"limits": {"x": "y"},
+		"owner": {"email": null}
+	}`)
+	// Fields of embedded structs follow the own ones.
+	errs := doc.ConformsTo(&conformingItem{})
+	msgs := make([]string, len(errs))
+	for i, err := range errs {
+		msgs[i] = err.Error()
+	}
+	assert.Length(msgs, 8)
+	assert.Equal(msgs[:7], []string{
+		`invalid value at "/name": number instead of string`,
+		`invalid value at "/port": number instead of quoted int`,
+		`invalid value at "/level": number 300 overflows uint8`,
+		`invalid value at "/tags": string instead of array`,
+		`invalid value at "/limits/x": invalid key for int`,
+		`path not found: missing required value at "/owner/email"`,
+		`invalid value at "/id": number 1.5 is no integer for int`,
+	})
+	assert.ErrorMatch(errs[7], `invalid value at "/created": cannot unmarshal into time.Time: .*`)
+	assert.True(errors.Is(errs[5], dynaj.ErrPathNotFound))
+}
+
+// TestUnmarshalAtStrict tests unmarshalling with reporting unknown keys.
+func TestUnmarshalAtStrict(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"data": {
+		"id": 42,
+		"name": "alpha",
+		"debug": true,
+		"tags": ["a"],
+		"limits": {"1": 2},
+		"owner": {"EMAIL": "jane@example.com", "phone": "123"},
+		"extra": {"x": {"y": 1}}
+	}}`)
+
+	var item conformingItem
+	leftovers, err := doc.UnmarshalAtStrict("/data", &item)
+	assert.NoError(err)
+	assert.Equal(item.ID, 42)
+	assert.Equal(item.Name, "alpha")
+	assert.Equal(item.Owner.Email, "jane@example.com")
+	assert.Equal(leftovers, []dynaj.Path{"/data/debug", "/data/extra/x/y", "/data/owner/phone"})
+
+	// Errors of unmarshalling.
+	_, err = doc.UnmarshalAtStrict("/data/tags", &item)
+	assert.ErrorMatch(err, `cannot unmarshal element at "/data/tags": .*`)
+	_, err = doc.UnmarshalAtStrict("/missing", &item)
+	assert.ErrorMatch(err, `invalid path "/missing": .*`)
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+type conformingOwner struct {
+	Email string `json:"email" dynaj:"required"`
+}
+
+type conformingBase struct {
+	ID      int       `json:"id" dynaj:"required"`
+	Created time.Time `json:"created"`
+}
+
+type conformingItem struct {
+	conformingBase
+	Name   string
+	Port   int `json:"port,string"`
+	Level  uint8
+	Tags   []string            `json:"tags"`
+	Limits map[int]float64     `json:"limits"`
+	Owner  *conformingOwner    `json:"owner,omitempty"`
+	Ignore func()              `json:"-"`
+	Extra  map[string]struct{} `json:"extra"`
+}
+
+// EOF
@@ -0,0 +1,37 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+)
+
+//--------------------
+// CONTEXT
+//--------------------
+
+// contextKey is the type of the key for the document in a context.
+type contextKey struct{}
+
+// NewContext returns a new context carrying the document, e.g. to pass
+// the parsed document of a request from middlewares to handlers.
+func NewContext(ctx context.Context, doc *Document) context.Context {
+	return context.WithValue(ctx, contextKey{}, doc)
+}
+
+// FromContext returns the document stored in the context by NewContext.
+func FromContext(ctx context.Context) (*Document, bool) {
+	doc, ok := ctx.Value(contextKey{}).(*Document)
+	return doc, ok && doc != nil
+}
+
+// EOF
@@ -0,0 +1,50 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestContext tests passing documents in contexts.
+func TestContext(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	none, ok := dynaj.FromContext(context.Background())
+	assert.False(ok)
+	assert.Nil(none)
+
+	doc := mustUnmarshal(assert, `{"a": 1}`)
+	ctx := dynaj.NewContext(context.Background(), doc)
+	found, ok := dynaj.FromContext(ctx)
+	assert.True(ok)
+	assert.True(found == doc)
+
+	// Documents of inner contexts shadow the outer ones.
+	inner := dynaj.NewContext(ctx, doc.Freeze())
+	found, ok = dynaj.FromContext(inner)
+	assert.True(ok)
+	assert.True(found.IsFrozen())
+
+	_, ok = dynaj.FromContext(dynaj.NewContext(ctx, nil))
+	assert.False(ok)
+}
+
+// EOF
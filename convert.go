@@ -0,0 +1,299 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+//--------------------
+// CHECKED CONVERSIONS
+//--------------------
+
+// AsInt64 returns the value as int64. Values not fitting into the
+// type or having a fraction return the default value.
+func (node *Node) AsInt64(dv int64) int64 {
+	i, err := node.AsInt64Strict()
+	if err != nil {
+		return dv
+	}
+	return i
+}
+
+// AsInt64Strict returns the value as int64 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsInt64Strict() (int64, error) {
+	return node.asSigned(64)
+}
+
+// AsInt32 returns the value as int32. Values not fitting into the
+// type or having a fraction return the default value.
+func (node *Node) AsInt32(dv int32) int32 {
+	i, err := node.AsInt32Strict()
+	if err != nil {
+		return dv
+	}
+	return i
+}
+
+// AsInt32Strict returns the value as int32 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsInt32Strict() (int32, error) {
+	i, err := node.asSigned(32)
+	return int32(i), err
+}
+
+// AsInt16 returns the value as int16. Values not fitting into the
+// type or having a fraction return the default value.
+func (node *Node) AsInt16(dv int16) int16 {
+	i, err := node.AsInt16Strict()
+	if err != nil {
+		return dv
+	}
+	return i
+}
+
+// AsInt16Strict returns the value as int16 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsInt16Strict() (int16, error) {
+	i, err := node.asSigned(16)
+	return int16(i), err
+}
+
+// AsInt8 returns the value as int8. Values not fitting into the
+// type or having a fraction return the default value.
+func (node *Node) AsInt8(dv int8) int8 {
+	i, err := node.AsInt8Strict()
+	if err != nil {
+		return dv
+	}
+	return i
+}
+
+// AsInt8Strict returns the value as int8 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsInt8Strict() (int8, error) {
+	i, err := node.asSigned(8)
+	return int8(i), err
+}
+
+// AsUint returns the value as uint. Negative values, values not
+// fitting into the type or having a fraction return the default value.
+func (node *Node) AsUint(dv uint) uint {
+	u, err := node.AsUintStrict()
+	if err != nil {
+		return dv
+	}
+	return u
+}
+
+// AsUintStrict returns the value as uint or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsUintStrict() (uint, error) {
+	u, err := node.asUnsigned(strconv.IntSize)
+	return uint(u), err
+}
+
+// AsUint64 returns the value as uint64. Negative values, values not
+// fitting into the type or having a fraction return the default value.
+func (node *Node) AsUint64(dv uint64) uint64 {
+	u, err := node.AsUint64Strict()
+	if err != nil {
+		return dv
+	}
+	return u
+}
+
+// AsUint64Strict returns the value as uint64 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsUint64Strict() (uint64, error) {
+	return node.asUnsigned(64)
+}
+
+// AsUint32 returns the value as uint32. Negative values, values not
+// fitting into the type or having a fraction return the default value.
+func (node *Node) AsUint32(dv uint32) uint32 {
+	u, err := node.AsUint32Strict()
+	if err != nil {
+		return dv
+	}
+	return u
+}
+
+// AsUint32Strict returns the value as uint32 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsUint32Strict() (uint32, error) {
+	u, err := node.asUnsigned(32)
+	return uint32(u), err
+}
+
+// AsUint16 returns the value as uint16. Negative values, values not
+// fitting into the type or having a fraction return the default value.
+func (node *Node) AsUint16(dv uint16) uint16 {
+	u, err := node.AsUint16Strict()
+	if err != nil {
+		return dv
+	}
+	return u
+}
+
+// AsUint16Strict returns the value as uint16 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsUint16Strict() (uint16, error) {
+	u, err := node.asUnsigned(16)
+	return uint16(u), err
+}
+
+// AsUint8 returns the value as uint8. Negative values, values not
+// fitting into the type or having a fraction return the default value.
+func (node *Node) AsUint8(dv uint8) uint8 {
+	u, err := node.AsUint8Strict()
+	if err != nil {
+		return dv
+	}
+	return u
+}
+
+// AsUint8Strict returns the value as uint8 or an error if it cannot
+// be converted. Values not fitting into the type return an error
+// wrapping ErrRange.
+func (node *Node) AsUint8Strict() (uint8, error) {
+	u, err := node.asUnsigned(8)
+	return uint8(u), err
+}
+
+// asSigned converts the value into a signed integer with the given
+// number of bits.
+func (node *Node) asSigned(bits int) (int64, error) {
+	typeName := fmt.Sprintf("int%d", bits)
+	element, err := node.convertible(typeName)
+	if err != nil {
+		return 0, err
+	}
+	minimum := int64(-1) << (bits - 1)
+	maximum := -(minimum + 1)
+	var i int64
+	switch tv := element.(type) {
+	case string:
+		i, err = strconv.ParseInt(tv, 10, bits)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, node.rangeError(tv, typeName)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %q at %q to %s: invalid syntax", tv, node.path, typeName)
+		}
+	case int:
+		i = int64(tv)
+		if i < minimum || i > maximum {
+			return 0, node.rangeError(tv, typeName)
+		}
+	case float64:
+		if err := node.checkIntegral(tv, typeName); err != nil {
+			return 0, err
+		}
+		if tv < -math.Ldexp(1, bits-1) || tv >= math.Ldexp(1, bits-1) {
+			return 0, node.rangeError(tv, typeName)
+		}
+		i = int64(tv)
+	case bool:
+		if tv {
+			i = 1
+		}
+	default:
+		return 0, fmt.Errorf("cannot convert %T at %q to %s", tv, node.path, typeName)
+	}
+	return i, nil
+}
+
+// asUnsigned converts the value into an unsigned integer with the
+// given number of bits.
+func (node *Node) asUnsigned(bits int) (uint64, error) {
+	typeName := fmt.Sprintf("uint%d", bits)
+	element, err := node.convertible(typeName)
+	if err != nil {
+		return 0, err
+	}
+	maximum := uint64(1)<<(bits-1)<<1 - 1
+	var u uint64
+	switch tv := element.(type) {
+	case string:
+		if len(tv) > 0 && tv[0] == '-' {
+			if _, err := strconv.ParseInt(tv, 10, 64); err == nil || errors.Is(err, strconv.ErrRange) {
+				return 0, node.rangeError(tv, typeName)
+			}
+		}
+		u, err = strconv.ParseUint(tv, 10, bits)
+		if errors.Is(err, strconv.ErrRange) {
+			return 0, node.rangeError(tv, typeName)
+		}
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert %q at %q to %s: invalid syntax", tv, node.path, typeName)
+		}
+	case int:
+		if tv < 0 || uint64(tv) > maximum {
+			return 0, node.rangeError(tv, typeName)
+		}
+		u = uint64(tv)
+	case float64:
+		if err := node.checkIntegral(tv, typeName); err != nil {
+			return 0, err
+		}
+		if tv < 0 || tv >= math.Ldexp(1, bits) {
+			return 0, node.rangeError(tv, typeName)
+		}
+		u = uint64(tv)
+	case bool:
+		if tv {
+			u = 1
+		}
+	default:
+		return 0, fmt.Errorf("cannot convert %T at %q to %s", tv, node.path, typeName)
+	}
+	return u, nil
+}
+
+// convertible returns the element of the node if it can be converted.
+func (node *Node) convertible(typeName string) (Element, error) {
+	if node.IsError() {
+		return nil, node.err
+	}
+	if node.isUndefinedValue() {
+		return nil, fmt.Errorf("cannot convert undefined value at %q to %s", node.path, typeName)
+	}
+	return decodeRaw(node.element)
+}
+
+// checkIntegral checks if a float has no fraction.
+func (node *Node) checkIntegral(f float64, typeName string) error {
+	if math.IsNaN(f) || f != math.Trunc(f) {
+		return fmt.Errorf("cannot convert %v at %q to %s: fraction would be truncated", f, node.path, typeName)
+	}
+	return nil
+}
+
+// rangeError returns the error for a value not fitting into the type.
+func (node *Node) rangeError(value any, typeName string) error {
+	return fmt.Errorf("%w: %v at %q does not fit into %s", ErrRange, value, node.path, typeName)
+}
+
+// EOF
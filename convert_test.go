@@ -0,0 +1,113 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCheckedSigned tests the checked conversions into signed integers.
+func TestCheckedSigned(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"small": 127,
+		"big": 128,
+		"negative": -32768,
+		"fraction": 1.5,
+		"string": "-129",
+		"huge": "9223372036854775808",
+		"bool": true,
+		"text": "foo",
+		"object": {}
+	}`)
+
+	assert.Equal(doc.NodeAt("/small").AsInt8(0), int8(127))
+	assert.Equal(doc.NodeAt("/big").AsInt8(-1), int8(-1))
+	assert.Equal(doc.NodeAt("/big").AsInt16(-1), int16(128))
+	assert.Equal(doc.NodeAt("/negative").AsInt16(0), int16(-32768))
+	assert.Equal(doc.NodeAt("/negative").AsInt8(0), int8(0))
+	assert.Equal(doc.NodeAt("/fraction").AsInt32(7), int32(7))
+	assert.Equal(doc.NodeAt("/string").AsInt32(0), int32(-129))
+	assert.Equal(doc.NodeAt("/string").AsInt8(0), int8(0))
+	assert.Equal(doc.NodeAt("/huge").AsInt64(1), int64(1))
+	assert.Equal(doc.NodeAt("/bool").AsInt8(0), int8(1))
+	assert.Equal(doc.NodeAt("/missing").AsInt32(3), int32(3))
+
+	_, err := doc.NodeAt("/big").AsInt8Strict()
+	assert.True(errors.Is(err, dynaj.ErrRange))
+	assert.ErrorContains(err, `128 at "/big" does not fit into int8`)
+	_, err = doc.NodeAt("/huge").AsInt64Strict()
+	assert.True(errors.Is(err, dynaj.ErrRange))
+	_, err = doc.NodeAt("/fraction").AsInt64Strict()
+	assert.ErrorContains(err, "fraction would be truncated")
+	assert.False(errors.Is(err, dynaj.ErrRange))
+	_, err = doc.NodeAt("/text").AsInt32Strict()
+	assert.ErrorContains(err, "invalid syntax")
+	_, err = doc.NodeAt("/object").AsInt32Strict()
+	assert.ErrorContains(err, "cannot convert")
+	_, err = doc.NodeAt("/missing").AsInt32Strict()
+	assert.ErrorContains(err, "invalid path")
+
+	// Values set directly as int.
+	assert.NoError(doc.SetValueAt("/int", math.MaxInt32+1))
+	_, err = doc.NodeAt("/int").AsInt32Strict()
+	assert.True(errors.Is(err, dynaj.ErrRange))
+	i, err := doc.NodeAt("/int").AsInt64Strict()
+	assert.NoError(err)
+	assert.Equal(i, int64(math.MaxInt32+1))
+}
+
+// TestCheckedUnsigned tests the checked conversions into unsigned integers.
+func TestCheckedUnsigned(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"byte": 255,
+		"big": 256,
+		"negative": -1,
+		"string": "65535",
+		"negstring": "-1",
+		"huge": 18446744073709551616,
+		"fraction": 0.5
+	}`)
+
+	assert.Equal(doc.NodeAt("/byte").AsUint8(0), uint8(255))
+	assert.Equal(doc.NodeAt("/big").AsUint8(1), uint8(1))
+	assert.Equal(doc.NodeAt("/big").AsUint(0), uint(256))
+	assert.Equal(doc.NodeAt("/negative").AsUint32(7), uint32(7))
+	assert.Equal(doc.NodeAt("/string").AsUint16(0), uint16(65535))
+	assert.Equal(doc.NodeAt("/huge").AsUint64(2), uint64(2))
+	assert.Equal(doc.NodeAt("/fraction").AsUint64(2), uint64(2))
+
+	_, err := doc.NodeAt("/negative").AsUintStrict()
+	assert.True(errors.Is(err, dynaj.ErrRange))
+	_, err = doc.NodeAt("/negstring").AsUint16Strict()
+	assert.True(errors.Is(err, dynaj.ErrRange))
+	_, err = doc.NodeAt("/huge").AsUint64Strict()
+	assert.True(errors.Is(err, dynaj.ErrRange))
+	_, err = doc.NodeAt("/big").AsUint8Strict()
+	assert.ErrorContains(err, `256 at "/big" does not fit into uint8`)
+	u, err := doc.NodeAt("/string").AsUint32Strict()
+	assert.NoError(err)
+	assert.Equal(u, uint32(65535))
+}
+
+// EOF
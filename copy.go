@@ -0,0 +1,105 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+//--------------------
+// COPYING
+//--------------------
+
+// CopyFunc is called by CopyWith for each element. It returns the value
+// to copy and false if the element has to be dropped. The values of
+// objects and arrays must not be changed, returning them unchanged lets
+// CopyWith continue with their children. Returned errors stop copying.
+type CopyFunc func(path Path, value Value) (Value, bool, error)
+
+// CopyWith deep-copies the document in one pass while the function
+// replaces or drops elements. This combines cloning, filtering, and
+// transforming without intermediate copies. Dropped array elements
+// shift the following ones, a dropped root leads to an empty document.
+func (d *Document) CopyWith(fn CopyFunc) (*Document, error) {
+	root, _, err := d.copyWith(d.root, Separator, fn)
+	if err != nil {
+		return nil, err
+	}
+	return d.derive(root), nil
+}
+
+// copyWith recursively copies the element. It returns false if the
+// element is dropped.
+func (d *Document) copyWith(element Element, path Path, fn CopyFunc) (Element, bool, error) {
+	element, err := decodeRaw(element)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot copy value at %q: %v", path, err)
+	}
+	value, ok, err := fn(path, element)
+	if err != nil {
+		return nil, false, fmt.Errorf("cannot copy value at %q: %w", path, err)
+	}
+	if !ok {
+		return nil, false, nil
+	}
+	if !sameContainer(value, element) {
+		value, err = normalizeValue(value, path, d.nonFinite)
+		if err != nil {
+			return nil, false, fmt.Errorf("cannot copy value at %q: %v", path, err)
+		}
+		return copyElement(value), true, nil
+	}
+	switch typed := element.(type) {
+	case Object:
+		obj := make(Object, len(typed))
+		for key, child := range typed {
+			copied, ok, err := d.copyWith(child, appendKey(path, key), fn)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				obj[key] = copied
+			}
+		}
+		return obj, true, nil
+	default:
+		arr := element.(Array)
+		copied := make(Array, 0, len(arr))
+		for idx, child := range arr {
+			child, ok, err := d.copyWith(child, appendKey(path, strconv.Itoa(idx)), fn)
+			if err != nil {
+				return nil, false, err
+			}
+			if ok {
+				copied = append(copied, child)
+			}
+		}
+		return copied, true, nil
+	}
+}
+
+// sameContainer checks if both elements are the same object or array.
+func sameContainer(a, b Element) bool {
+	switch ta := a.(type) {
+	case Object:
+		tb, ok := b.(Object)
+		return ok && reflect.ValueOf(ta).Pointer() == reflect.ValueOf(tb).Pointer()
+	case Array:
+		tb, ok := b.(Array)
+		return ok && len(ta) == len(tb) && reflect.ValueOf(ta).Pointer() == reflect.ValueOf(tb).Pointer()
+	}
+	return false
+}
+
+// EOF
@@ -0,0 +1,71 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestCopyWith tests copying with replacing and dropping elements.
+func TestCopyWith(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"name":"alpha","debug":{"trace":true},"tags":["a","tmp","b"],"n":1}`)
+
+	copied, err := doc.CopyWith(func(path dynaj.Path, value dynaj.Value) (dynaj.Value, bool, error) {
+		switch {
+		case path == "/debug" || value == "tmp":
+			return nil, false, nil
+		case path == "/n":
+			return map[string]int{"value": 1}, true, nil
+		}
+		if s, ok := value.(string); ok {
+			return strings.ToUpper(s), true, nil
+		}
+		return value, true, nil
+	})
+	assert.NoError(err)
+	assert.Equal(copied.String(), `{"n":{"value":1},"name":"ALPHA","tags":["A","B"]}`)
+
+	// The copy is independent.
+	assert.NoError(copied.SetValueAt("/tags/0", "x"))
+	assert.Equal(doc.NodeAt("/tags/0").AsString("-"), "a")
+
+	// Dropping the root.
+	copied, err = doc.CopyWith(func(path dynaj.Path, value dynaj.Value) (dynaj.Value, bool, error) {
+		return nil, false, nil
+	})
+	assert.NoError(err)
+	assert.Equal(copied.String(), `null`)
+
+	// Errors stop copying.
+	failure := errors.New("failure")
+	_, err = doc.CopyWith(func(path dynaj.Path, value dynaj.Value) (dynaj.Value, bool, error) {
+		if path == "/tags/1" {
+			return nil, false, failure
+		}
+		return value, true, nil
+	})
+	assert.True(errors.Is(err, failure))
+	assert.ErrorMatch(err, `cannot copy value at "/tags/1": failure`)
+}
+
+// EOF
@@ -0,0 +1,111 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// COUNTING
+//--------------------
+
+// DeepLength returns the number of values in the subtree at the given
+// path. Empty objects and arrays contain no values. Like for Length an
+// invalid path returns -1.
+func (d *Document) DeepLength(path Path) int {
+	element, err := elementAt(d.root, splitPath(path))
+	if err != nil {
+		return -1
+	}
+	count := 0
+	if err := walkValues(element, path, func(Path, Element) { count++ }); err != nil {
+		return -1
+	}
+	return count
+}
+
+// Count returns the number of nodes a query with the pattern would
+// return without creating them.
+func (d *Document) Count(pattern string) (int, error) {
+	count := 0
+	compiled := compilePattern(pattern)
+	err := walkNodes(d.root, Separator, func(path Path) {
+		if compiled.matches(path) {
+			count++
+		}
+	})
+	if err != nil {
+		return 0, fmt.Errorf("cannot count %q: %v", pattern, err)
+	}
+	return count, nil
+}
+
+// walkValues calls the function for all values below the element.
+func walkValues(element Element, path Path, fn func(Path, Element)) error {
+	element, err := decodeRaw(element)
+	if err != nil {
+		return err
+	}
+	switch typed := element.(type) {
+	case Object:
+		for key, child := range typed {
+			if err := walkValues(child, appendKey(path, key), fn); err != nil {
+				return err
+			}
+		}
+	case Array:
+		for idx, child := range typed {
+			if err := walkValues(child, appendKey(path, strconv.Itoa(idx)), fn); err != nil {
+				return err
+			}
+		}
+	default:
+		fn(path, typed)
+	}
+	return nil
+}
+
+// walkNodes calls the function for the paths of all nodes processed by
+// Node.Process, which are the values and the empty containers.
+func walkNodes(element Element, path Path, fn func(Path)) error {
+	element, err := decodeRaw(element)
+	if err != nil {
+		return err
+	}
+	switch typed := element.(type) {
+	case Object:
+		if len(typed) == 0 {
+			fn(path)
+		}
+		for key, child := range typed {
+			if err := walkNodes(child, appendKey(path, key), fn); err != nil {
+				return err
+			}
+		}
+	case Array:
+		if len(typed) == 0 {
+			fn(path)
+		}
+		for idx, child := range typed {
+			if err := walkNodes(child, appendKey(path, strconv.Itoa(idx)), fn); err != nil {
+				return err
+			}
+		}
+	default:
+		fn(path)
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,65 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestDeepLength tests counting the values of subtrees.
+func TestDeepLength(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+
+	assert.Equal(doc.DeepLength("/B/1/S"), 3)
+	assert.Equal(doc.DeepLength("/A"), 1)
+	assert.Equal(doc.DeepLength("/X"), -1)
+
+	doc = mustUnmarshal(assert, `{"a":{"b":[1,2,{"c":null}],"d":{},"e":[]},"f":"g"}`)
+	assert.Equal(doc.DeepLength("/"), 4)
+	assert.Equal(doc.DeepLength("/a"), 3)
+	assert.Equal(doc.DeepLength("/a/d"), 0)
+	assert.Equal(dynaj.NewDocument().DeepLength("/"), 1)
+}
+
+// TestCount tests counting query matches.
+func TestCount(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+
+	for _, pattern := range []string{"*", "/B/*", "*/S/*", "/A", "/nothing", "/B/[01]/*"} {
+		nodes, err := doc.Root().Query(pattern)
+		assert.NoError(err)
+		count, err := doc.Count(pattern)
+		assert.NoError(err)
+		assert.Equal(count, len(nodes), pattern)
+	}
+
+	doc = mustUnmarshal(assert, `{"a":{},"b":[],"c":[1,2]}`)
+	count, err := doc.Count("*")
+	assert.NoError(err)
+	assert.Equal(count, 4)
+}
+
+// EOF
@@ -0,0 +1,262 @@
+// Tideland Go Dynamic JSON - CRDT
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package crdt // import "tideland.dev/go/dynaj/crdt"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// CLOCK
+//--------------------
+
+// Clock is a vector clock counting the changes per replica.
+type Clock map[string]uint64
+
+// HappenedBefore returns true if the clock is causally before the
+// other one.
+func (c Clock) HappenedBefore(other Clock) bool {
+	less := false
+	for id, n := range c {
+		if n > other[id] {
+			return false
+		}
+		if n < other[id] {
+			less = true
+		}
+	}
+	for id, n := range other {
+		if _, ok := c[id]; !ok && n > 0 {
+			less = true
+		}
+	}
+	return less
+}
+
+// sum returns the sum of all counters.
+func (c Clock) sum() uint64 {
+	var s uint64
+	for _, n := range c {
+		s += n
+	}
+	return s
+}
+
+// copy returns a copy of the clock.
+func (c Clock) copy() Clock {
+	out := Clock{}
+	for id, n := range c {
+		out[id] = n
+	}
+	return out
+}
+
+// merge raises the counters to the maximum of both clocks.
+func (c Clock) merge(other Clock) {
+	for id, n := range other {
+		if n > c[id] {
+			c[id] = n
+		}
+	}
+}
+
+//--------------------
+// CHANGE
+//--------------------
+
+// Change is one serializable change of a path made by a replica.
+type Change struct {
+	Path    dynaj.Path  `json:"path"`
+	Value   dynaj.Value `json:"value"`
+	Deleted bool        `json:"deleted,omitempty"`
+	Clock   Clock       `json:"clock"`
+	Replica string      `json:"replica"`
+}
+
+// before defines the total order of changes. It extends the causal
+// order of the clocks, concurrent changes are ordered by the sum of
+// their clocks and the replica IDs.
+func (c Change) before(other Change) bool {
+	if c.Clock.HappenedBefore(other.Clock) {
+		return true
+	}
+	if other.Clock.HappenedBefore(c.Clock) {
+		return false
+	}
+	cs, os := c.Clock.sum(), other.Clock.sum()
+	if cs != os {
+		return cs < os
+	}
+	return c.Replica < other.Replica
+}
+
+//--------------------
+// REPLICA
+//--------------------
+
+// Replica is one copy of a document which can be changed locally and
+// merged with the changes of other replicas.
+type Replica struct {
+	mu      sync.Mutex
+	id      string
+	clock   Clock
+	changes map[dynaj.Path]Change
+	doc     *dynaj.Document
+}
+
+// NewReplica creates an empty replica with the given unique ID.
+func NewReplica(id string) *Replica {
+	return &Replica{
+		id:      id,
+		clock:   Clock{},
+		changes: map[dynaj.Path]Change{},
+		doc:     dynaj.NewDocument(),
+	}
+}
+
+// ID returns the ID of the replica.
+func (r *Replica) ID() string {
+	return r.id
+}
+
+// Clock returns a copy of the current clock of the replica.
+func (r *Replica) Clock() Clock {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.clock.copy()
+}
+
+// SetValueAt sets the value at the path locally.
+func (r *Replica) SetValueAt(path dynaj.Path, value dynaj.Value) error {
+	// Store values in their JSON form to be independent of the caller
+	// and equal to merged ones.
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot set value at %q: %v", path, err)
+	}
+	var stored dynaj.Value
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return fmt.Errorf("cannot set value at %q: %v", path, err)
+	}
+	return r.change(path, stored, false)
+}
+
+// DeleteAt deletes the element at the path locally.
+func (r *Replica) DeleteAt(path dynaj.Path) error {
+	return r.change(path, nil, true)
+}
+
+// Changes returns the latest changes of all paths in their total order.
+// They can be passed to Merge of other replicas.
+func (r *Replica) Changes() []Change {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.sortedChanges()
+}
+
+// Merge merges the changes of other replicas. Merging is idempotent
+// and the order of merges does not matter.
+func (r *Replica) Merge(changes []Change) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, change := range changes {
+		if change.Replica == "" || change.Clock == nil {
+			return fmt.Errorf("cannot merge change of %q: missing replica or clock", change.Path)
+		}
+		change.Path = dynaj.JoinPath(change.Path)
+		r.clock.merge(change.Clock)
+		if current, ok := r.changes[change.Path]; ok && !current.before(change) {
+			continue
+		}
+		r.changes[change.Path] = change
+	}
+	r.rebuild()
+	return nil
+}
+
+// Document returns a frozen copy of the current document.
+func (r *Replica) Document() *dynaj.Document {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.doc.Freeze()
+}
+
+// change applies and stamps a local change. As its clock is after all
+// known ones it is the last one in the total order and can be applied
+// directly.
+func (r *Replica) change(path dynaj.Path, value dynaj.Value, deleted bool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	path = dynaj.JoinPath(path)
+	change := Change{
+		Path:    path,
+		Value:   value,
+		Deleted: deleted,
+		Replica: r.id,
+	}
+	if err := apply(r.doc, change); err != nil {
+		r.rebuild()
+		return err
+	}
+	r.clock[r.id]++
+	change.Clock = r.clock.copy()
+	r.changes[path] = change
+	return nil
+}
+
+// sortedChanges returns the changes in their total order.
+func (r *Replica) sortedChanges() []Change {
+	changes := make([]Change, 0, len(r.changes))
+	for _, change := range r.changes {
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].before(changes[j])
+	})
+	return changes
+}
+
+// rebuild creates the document by applying all changes in their total
+// order. Changes which cannot be applied, e.g. below a deleted path or
+// a simple value, are skipped.
+func (r *Replica) rebuild() {
+	doc := dynaj.NewDocument()
+	for _, change := range r.sortedChanges() {
+		_ = apply(doc, change)
+	}
+	r.doc = doc
+}
+
+// apply applies one change to the document. Objects and arrays at the
+// path are replaced.
+func apply(doc *dynaj.Document, change Change) error {
+	node := doc.NodeAt(change.Path)
+	if change.Deleted {
+		if node.IsError() {
+			return fmt.Errorf("cannot delete element at %q: invalid path", change.Path)
+		}
+		return doc.DeleteElementAt(change.Path)
+	}
+	if node.IsObject() || node.IsArray() {
+		if err := doc.DeleteElementAt(change.Path); err != nil {
+			return err
+		}
+	}
+	return doc.SetValueAt(change.Path, change.Value)
+}
+
+// EOF
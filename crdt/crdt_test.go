@@ -0,0 +1,133 @@
+// Tideland Go Dynamic JSON - CRDT - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package crdt_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj/crdt"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestClock tests the causal order of vector clocks.
+func TestClock(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	assert.True(crdt.Clock{"a": 1}.HappenedBefore(crdt.Clock{"a": 2}))
+	assert.True(crdt.Clock{"a": 1}.HappenedBefore(crdt.Clock{"a": 1, "b": 1}))
+	assert.False(crdt.Clock{"a": 1}.HappenedBefore(crdt.Clock{"a": 1}))
+	assert.False(crdt.Clock{"a": 2}.HappenedBefore(crdt.Clock{"a": 1, "b": 1}))
+	assert.False(crdt.Clock{"a": 1, "b": 1}.HappenedBefore(crdt.Clock{"a": 2}))
+}
+
+// TestLocalChanges tests changing one replica.
+func TestLocalChanges(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	r := crdt.NewReplica("a")
+
+	assert.NoError(r.SetValueAt("/title", "Draft"))
+	assert.NoError(r.SetValueAt("/meta", map[string]any{"tags": []string{"x"}}))
+	assert.NoError(r.SetValueAt("//meta//author", "jane"))
+	assert.NoError(r.SetValueAt("/done", false))
+	assert.NoError(r.DeleteAt("/title"))
+	assert.Equal(r.Document().String(), `{"done":false,"meta":{"author":"jane","tags":["x"]}}`)
+	assert.Equal(r.Clock(), crdt.Clock{"a": 5})
+	assert.Equal(r.ID(), "a")
+
+	// Failing changes are not stamped.
+	assert.ErrorContains(r.DeleteAt("/missing"), "invalid path")
+	assert.ErrorContains(r.SetValueAt("/done/x", 1), "would corrupt")
+	assert.ErrorContains(r.SetValueAt("/x", func() {}), "cannot set value")
+	assert.Equal(r.Clock(), crdt.Clock{"a": 5})
+	assert.Equal(r.Document().String(), `{"done":false,"meta":{"author":"jane","tags":["x"]}}`)
+}
+
+// TestMerge tests merging concurrent changes of replicas.
+func TestMerge(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	alice := crdt.NewReplica("alice")
+	bob := crdt.NewReplica("bob")
+
+	assert.NoError(alice.SetValueAt("/doc", map[string]any{"title": "Draft", "body": "Text"}))
+	assert.NoError(bob.Merge(alice.Changes()))
+	assert.Equal(bob.Document().String(), alice.Document().String())
+
+	// Concurrent offline edits.
+	assert.NoError(alice.SetValueAt("/doc/title", "Alice's title"))
+	assert.NoError(alice.SetValueAt("/doc/author", "alice"))
+	assert.NoError(bob.SetValueAt("/doc/title", "Bob's title"))
+	assert.NoError(bob.DeleteAt("/doc/body"))
+
+	aliceChanges := alice.Changes()
+	assert.NoError(alice.Merge(bob.Changes()))
+	assert.NoError(bob.Merge(aliceChanges))
+	assert.Equal(alice.Document().String(), bob.Document().String())
+	assert.Equal(alice.Document().NodeAt("/doc/title").AsString(""), "Bob's title")
+	assert.Equal(alice.Document().NodeAt("/doc/author").AsString(""), "alice")
+	assert.True(alice.Document().NodeAt("/doc/body").IsError())
+
+	// Changes after seeing the others win.
+	assert.NoError(alice.SetValueAt("/doc/title", "Final"))
+	assert.NoError(bob.Merge(alice.Changes()))
+	assert.Equal(bob.Document().NodeAt("/doc/title").AsString(""), "Final")
+
+	// Merging is idempotent.
+	before := bob.Document().String()
+	assert.NoError(bob.Merge(alice.Changes()))
+	assert.NoError(bob.Merge(bob.Changes()))
+	assert.Equal(bob.Document().String(), before)
+
+	// Invalid changes.
+	assert.ErrorContains(bob.Merge([]crdt.Change{{Path: "/x"}}), "missing replica or clock")
+}
+
+// TestMergeOrder tests that the order of merges does not matter and
+// that changes can be serialized.
+func TestMergeOrder(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	a := crdt.NewReplica("a")
+	b := crdt.NewReplica("b")
+	c := crdt.NewReplica("c")
+
+	assert.NoError(a.SetValueAt("/list/0", "a0"))
+	assert.NoError(a.SetValueAt("/x", 1))
+	assert.NoError(b.SetValueAt("/list/0", "b0"))
+	assert.NoError(b.SetValueAt("/x", map[string]any{"y": 2}))
+	assert.NoError(c.SetValueAt("/x/z", 3))
+	assert.NoError(c.DeleteAt("/x"))
+	assert.NoError(c.SetValueAt("/zero", 0))
+
+	all := [][]crdt.Change{a.Changes(), b.Changes(), c.Changes()}
+	data, err := json.Marshal(all)
+	assert.NoError(err)
+	var loaded [][]crdt.Change
+	assert.NoError(json.Unmarshal(data, &loaded))
+
+	first := crdt.NewReplica("first")
+	for _, changes := range loaded {
+		assert.NoError(first.Merge(changes))
+	}
+	second := crdt.NewReplica("second")
+	for i := len(all) - 1; i >= 0; i-- {
+		assert.NoError(second.Merge(all[i]))
+	}
+	assert.Equal(first.Document().String(), second.Document().String())
+	assert.Equal(first.Document().NodeAt("/zero").AsInt(-1), 0)
+}
+
+// EOF
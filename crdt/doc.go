@@ -0,0 +1,25 @@
+// Tideland Go Dynamic JSON - CRDT
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package crdt allows concurrent changes of JSON documents on multiple
+// replicas, e.g. in collaborative or offline-first applications. The
+// changes are exchanged and merged deterministically, so all replicas
+// having seen the same changes have the same document.
+//
+//	alice := crdt.NewReplica("alice")
+//	bob := crdt.NewReplica("bob")
+//	err := alice.SetValueAt("/title", "Draft")
+//	...
+//	err = bob.Merge(alice.Changes())
+//
+// Each change is stamped with a vector clock. Concurrent changes of the
+// same path are resolved by last writer wins, where the changes are
+// ordered by the sum of their clocks and the replica IDs. Array
+// indices are handled like object keys.
+package crdt // import "tideland.dev/go/dynaj/crdt"
+
+// EOF
@@ -0,0 +1,227 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// EncryptedKey is the key of the object an encrypted value is stored
+// in, the ciphertext is base64 encoded:
+//
+//	{"$encrypted":"…"}
+const EncryptedKey = "$encrypted"
+
+//--------------------
+// ENCRYPTER
+//--------------------
+
+// Encrypter encrypts and decrypts the values of a document.
+type Encrypter interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+// aesEncrypter implements Encrypter with AES-GCM.
+type aesEncrypter struct {
+	aead cipher.AEAD
+}
+
+// NewAESEncrypter returns an Encrypter using AES-GCM. The key has to be
+// 16, 24, or 32 bytes long. The random nonce is prepended to the
+// ciphertext.
+func NewAESEncrypter(key []byte) (Encrypter, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create encrypter: %v", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create encrypter: %v", err)
+	}
+	return &aesEncrypter{aead}, nil
+}
+
+// Encrypt implements Encrypter.
+func (e *aesEncrypter) Encrypt(plaintext []byte) ([]byte, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt implements Encrypter.
+func (e *aesEncrypter) Decrypt(ciphertext []byte) ([]byte, error) {
+	size := e.aead.NonceSize()
+	if len(ciphertext) < size {
+		return nil, errors.New("ciphertext too short")
+	}
+	return e.aead.Open(nil, ciphertext[:size], ciphertext[size:], nil)
+}
+
+//--------------------
+// ENCRYPTION
+//--------------------
+
+// EncryptPaths encrypts the elements at the paths matching the patterns,
+// so secrets can remain in otherwise plaintext documents. Values as well
+// as containers are encrypted as JSON and stored in an object with the
+// EncryptedKey. If a path and its ancestor match only the ancestor is
+// encrypted, already encrypted elements are skipped. If any element
+// cannot be encrypted the document stays unchanged.
+func (d *Document) EncryptPaths(patterns []string, enc Encrypter) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	paths, err := d.cryptPaths(patterns)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt paths: %v", err)
+	}
+	encrypted := map[Path]Element{}
+	var last Path
+	for _, path := range paths {
+		if last != "" && IsAncestor(last, path) {
+			continue
+		}
+		element, err := elementAt(d.root, splitPath(path))
+		if err != nil {
+			continue
+		}
+		if isEncrypted(element) {
+			last = path
+			continue
+		}
+		element, err = normalizeValue(element, path, d.nonFinite)
+		if err != nil {
+			return fmt.Errorf("cannot encrypt value at %q: %v", path, err)
+		}
+		plaintext, err := canonicalJSON(element)
+		if err != nil {
+			return fmt.Errorf("cannot encrypt value at %q: %v", path, err)
+		}
+		ciphertext, err := enc.Encrypt(plaintext)
+		if err != nil {
+			return fmt.Errorf("cannot encrypt value at %q: %v", path, err)
+		}
+		encrypted[path] = Object{EncryptedKey: base64.StdEncoding.EncodeToString(ciphertext)}
+		last = path
+	}
+	return d.setCrypted(paths, encrypted)
+}
+
+// DecryptPaths decrypts the encrypted elements at the paths matching
+// the patterns and replaces them by their original values. If any
+// element cannot be decrypted the document stays unchanged.
+func (d *Document) DecryptPaths(patterns []string, enc Encrypter) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	paths, err := d.cryptPaths(patterns)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt paths: %v", err)
+	}
+	decrypted := map[Path]Element{}
+	for _, path := range paths {
+		element, err := elementAt(d.root, splitPath(path))
+		if err != nil || !isEncrypted(element) {
+			continue
+		}
+		ciphertext, err := base64.StdEncoding.DecodeString(element.(Object)[EncryptedKey].(string))
+		if err != nil {
+			return fmt.Errorf("cannot decrypt value at %q: %v", path, err)
+		}
+		plaintext, err := enc.Decrypt(ciphertext)
+		if err != nil {
+			return fmt.Errorf("cannot decrypt value at %q: %v", path, err)
+		}
+		value, err := decodeRaw(json.RawMessage(plaintext))
+		if err != nil {
+			return fmt.Errorf("cannot decrypt value at %q: %v", path, err)
+		}
+		decrypted[path] = value
+	}
+	return d.setCrypted(paths, decrypted)
+}
+
+// cryptPaths returns the sorted and unique paths matching the patterns.
+func (d *Document) cryptPaths(patterns []string) ([]Path, error) {
+	unique := map[Path]struct{}{}
+	for _, pattern := range patterns {
+		paths, err := d.ExpandPattern(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range paths {
+			unique[path] = struct{}{}
+		}
+	}
+	paths := make([]Path, 0, len(unique))
+	for path := range unique {
+		paths = append(paths, path)
+	}
+	sortPaths(paths)
+	return paths, nil
+}
+
+// setCrypted replaces the elements at the paths by the encrypted or
+// decrypted ones. Access and locked types are checked before any change.
+// Hooks are called like when setting values, rejected elements roll back
+// the already replaced ones.
+func (d *Document) setCrypted(paths []Path, elements map[Path]Element) error {
+	for _, path := range paths {
+		if element, ok := elements[path]; ok {
+			if err := d.checkAccess(splitPath(path)); err != nil {
+				return err
+			}
+			if err := d.checkTypeLock(splitPath(path), element); err != nil {
+				return err
+			}
+		}
+	}
+	return d.atomically(func() error {
+		for _, path := range paths {
+			element, ok := elements[path]
+			if !ok {
+				continue
+			}
+			if err := d.replaceValueAt(path, element); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// isEncrypted checks if the element is an object containing an
+// encrypted value.
+func isEncrypted(element Element) bool {
+	obj, ok := element.(Object)
+	if !ok || len(obj) != 1 {
+		return false
+	}
+	_, ok = obj[EncryptedKey].(string)
+	return ok
+}
+
+// EOF
@@ -0,0 +1,75 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestEncryptPaths tests encrypting and decrypting values at paths.
+func TestEncryptPaths(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	enc, err := dynaj.NewAESEncrypter([]byte("0123456789abcdef"))
+	assert.NoError(err)
+	in := `{"db":{"host":"db.local","password":"s3cret","port":5432},"tokens":["a","b"]}`
+	doc := mustUnmarshal(assert, in)
+
+	assert.NoError(doc.EncryptPaths([]string{"/db/password", "/tokens", "/tokens/*"}, enc))
+	assert.Equal(doc.NodeAt("/db/host").AsString("-"), "db.local")
+	assert.False(doc.NodeAt("/db/password/$encrypted").IsUndefined())
+	assert.False(doc.NodeAt("/tokens/$encrypted").IsUndefined())
+
+	// Encrypted values are not encrypted twice.
+	encrypted := doc.String()
+	assert.NoError(doc.EncryptPaths([]string{"/db/password"}, enc))
+	assert.Equal(doc.String(), encrypted)
+
+	assert.NoError(doc.DecryptPaths([]string{"/db/*", "/tokens"}, enc))
+	assert.Equal(doc.String(), in)
+
+	// Wrong keys keep the document unchanged.
+	assert.NoError(doc.EncryptPaths([]string{"/db/*"}, enc))
+	encrypted = doc.String()
+	wrong, err := dynaj.NewAESEncrypter([]byte("fedcba9876543210"))
+	assert.NoError(err)
+	assert.ErrorMatch(doc.DecryptPaths([]string{"/db/*"}, wrong), `cannot decrypt value at "/db/host": .*`)
+	assert.Equal(doc.String(), encrypted)
+
+	// Hooks are called and can reject encryptions.
+	doc = mustUnmarshal(assert, in)
+	hooked := []dynaj.Path{}
+	doc.AddHook(dynaj.BeforeSet, func(path dynaj.Path, old, value dynaj.Value) error {
+		if path == "/tokens" {
+			return errors.New("tokens stay plain")
+		}
+		hooked = append(hooked, path)
+		return nil
+	})
+	assert.NoError(doc.EncryptPaths([]string{"/db/password"}, enc))
+	assert.Equal(hooked, []dynaj.Path{"/db/password"})
+	encrypted = doc.String()
+	assert.ErrorContains(doc.EncryptPaths([]string{"/db/port", "/tokens"}, enc), "tokens stay plain")
+	assert.Equal(doc.String(), encrypted)
+
+	assert.True(errors.Is(doc.Freeze().EncryptPaths([]string{"/db"}, enc), dynaj.ErrFrozen))
+}
+
+// EOF
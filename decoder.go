@@ -0,0 +1,454 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// maxNestingDepth is the maximum nesting of objects and arrays the
+// scan decoder accepts, the same as for encoding/json.
+const maxNestingDepth = 10000
+
+//--------------------
+// DECODER
+//--------------------
+
+// Decoder parses JSON-encoded data into the element tree of a document.
+// Objects have to be returned as Object, arrays as Array, and numbers
+// as float64.
+type Decoder interface {
+	Decode(data []byte) (Element, error)
+}
+
+// standardDecoder uses encoding/json.
+type standardDecoder struct{}
+
+// NewStandardDecoder returns the decoder based on encoding/json.
+func NewStandardDecoder() Decoder {
+	return standardDecoder{}
+}
+
+// Decode implements Decoder.
+func (standardDecoder) Decode(data []byte) (Element, error) {
+	var root Element
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
+
+// scanDecoder is a hand-rolled single pass scanner.
+type scanDecoder struct{}
+
+// NewScanDecoder returns a decoder using a hand-rolled scanner instead
+// of the reflection based encoding/json. It creates the same elements
+// but is considerably faster and allocates less.
+func NewScanDecoder() Decoder {
+	return scanDecoder{}
+}
+
+// Decode implements Decoder.
+func (scanDecoder) Decode(data []byte) (Element, error) {
+	s := &scanner{data: data}
+	return s.decode()
+}
+
+//--------------------
+// SCANNER
+//--------------------
+
+// scanner scans the JSON-encoded data. In view mode strings reference
+// the data instead of copying it. With an arena the bytes of strings
+// and the elements of arrays are allocated from it.
+type scanner struct {
+	data  []byte
+	pos   int
+	view  bool
+	arena *arena
+}
+
+// decode scans the data as one top-level value.
+func (s *scanner) decode() (Element, error) {
+	s.skipSpace()
+	element, err := s.element(0)
+	if err != nil {
+		return nil, err
+	}
+	s.skipSpace()
+	if s.pos < len(s.data) {
+		return nil, s.errorf("invalid character %q after top-level value", s.data[s.pos])
+	}
+	return element, nil
+}
+
+// errorf creates an error containing the current offset.
+func (s *scanner) errorf(format string, args ...any) error {
+	return fmt.Errorf("offset %d: %s", s.pos, fmt.Sprintf(format, args...))
+}
+
+// unexpectedEnd returns the error for a too early end of the data.
+func (s *scanner) unexpectedEnd() error {
+	return s.errorf("unexpected end of JSON input")
+}
+
+// skipSpace skips the whitespace.
+func (s *scanner) skipSpace() {
+	for s.pos < len(s.data) {
+		switch s.data[s.pos] {
+		case ' ', '\t', '\n', '\r':
+			s.pos++
+		default:
+			return
+		}
+	}
+}
+
+// element scans the next element.
+func (s *scanner) element(depth int) (Element, error) {
+	if s.pos >= len(s.data) {
+		return nil, s.unexpectedEnd()
+	}
+	switch c := s.data[s.pos]; {
+	case c == '{':
+		return s.object(depth + 1)
+	case c == '[':
+		return s.array(depth + 1)
+	case c == '"':
+		return s.string()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return s.number()
+	case c == 't':
+		return true, s.literal("true")
+	case c == 'f':
+		return false, s.literal("false")
+	case c == 'n':
+		return nil, s.literal("null")
+	default:
+		return nil, s.errorf("invalid character %q looking for beginning of value", c)
+	}
+}
+
+// literal scans the expected literal.
+func (s *scanner) literal(literal string) error {
+	for i := 0; i < len(literal); i++ {
+		if s.pos >= len(s.data) {
+			return s.unexpectedEnd()
+		}
+		if s.data[s.pos] != literal[i] {
+			return s.errorf("invalid character %q in literal %s", s.data[s.pos], literal)
+		}
+		s.pos++
+	}
+	return nil
+}
+
+// object scans an object.
+func (s *scanner) object(depth int) (Element, error) {
+	if depth > maxNestingDepth {
+		return nil, s.errorf("exceeded max depth")
+	}
+	s.pos++
+	obj := Object{}
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == '}' {
+		s.pos++
+		return obj, nil
+	}
+	for {
+		if s.pos >= len(s.data) {
+			return nil, s.unexpectedEnd()
+		}
+		if s.data[s.pos] != '"' {
+			return nil, s.errorf("invalid character %q looking for beginning of object key string", s.data[s.pos])
+		}
+		key, err := s.string()
+		if err != nil {
+			return nil, err
+		}
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.unexpectedEnd()
+		}
+		if s.data[s.pos] != ':' {
+			return nil, s.errorf("invalid character %q after object key", s.data[s.pos])
+		}
+		s.pos++
+		s.skipSpace()
+		element, err := s.element(depth)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = element
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.unexpectedEnd()
+		}
+		switch s.data[s.pos] {
+		case ',':
+			s.pos++
+			s.skipSpace()
+		case '}':
+			s.pos++
+			return obj, nil
+		default:
+			return nil, s.errorf("invalid character %q after object key:value pair", s.data[s.pos])
+		}
+	}
+}
+
+// array scans an array.
+func (s *scanner) array(depth int) (Element, error) {
+	if depth > maxNestingDepth {
+		return nil, s.errorf("exceeded max depth")
+	}
+	s.pos++
+	arr := Array{}
+	s.skipSpace()
+	if s.pos < len(s.data) && s.data[s.pos] == ']' {
+		s.pos++
+		return arr, nil
+	}
+	mark := 0
+	if s.arena != nil {
+		// Collect the elements before copying them into the arena.
+		mark = len(s.arena.scratch)
+	}
+	for {
+		element, err := s.element(depth)
+		if err != nil {
+			return nil, err
+		}
+		if s.arena != nil {
+			s.arena.scratch = append(s.arena.scratch, element)
+		} else {
+			arr = append(arr, element)
+		}
+		s.skipSpace()
+		if s.pos >= len(s.data) {
+			return nil, s.unexpectedEnd()
+		}
+		switch s.data[s.pos] {
+		case ',':
+			s.pos++
+			s.skipSpace()
+		case ']':
+			s.pos++
+			if s.arena != nil {
+				arr = s.arena.array(s.arena.scratch[mark:])
+				s.arena.scratch = s.arena.scratch[:mark]
+			}
+			return arr, nil
+		default:
+			return nil, s.errorf("invalid character %q after array element", s.data[s.pos])
+		}
+	}
+}
+
+// number scans a number following the JSON grammar.
+func (s *scanner) number() (Element, error) {
+	start := s.pos
+	if s.data[s.pos] == '-' {
+		s.pos++
+	}
+	switch {
+	case s.pos >= len(s.data):
+		return nil, s.unexpectedEnd()
+	case s.data[s.pos] == '0':
+		s.pos++
+	case isDigit(s.data[s.pos]):
+		s.skipDigits()
+	default:
+		return nil, s.errorf("invalid character %q in numeric literal", s.data[s.pos])
+	}
+	if s.pos < len(s.data) && s.data[s.pos] == '.' {
+		s.pos++
+		if s.pos >= len(s.data) {
+			return nil, s.unexpectedEnd()
+		}
+		if !isDigit(s.data[s.pos]) {
+			return nil, s.errorf("invalid character %q after decimal point in numeric literal", s.data[s.pos])
+		}
+		s.skipDigits()
+	}
+	if s.pos < len(s.data) && (s.data[s.pos] == 'e' || s.data[s.pos] == 'E') {
+		s.pos++
+		if s.pos < len(s.data) && (s.data[s.pos] == '+' || s.data[s.pos] == '-') {
+			s.pos++
+		}
+		if s.pos >= len(s.data) {
+			return nil, s.unexpectedEnd()
+		}
+		if !isDigit(s.data[s.pos]) {
+			return nil, s.errorf("invalid character %q in exponent of numeric literal", s.data[s.pos])
+		}
+		s.skipDigits()
+	}
+	var literal string
+	if s.arena != nil {
+		// The literal is not kept.
+		literal = viewString(s.data[start:s.pos])
+	} else {
+		literal = string(s.data[start:s.pos])
+	}
+	f, err := strconv.ParseFloat(literal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal number %s into float64", literal)
+	}
+	return f, nil
+}
+
+// skipDigits skips all following digits.
+func (s *scanner) skipDigits() {
+	for s.pos < len(s.data) && isDigit(s.data[s.pos]) {
+		s.pos++
+	}
+}
+
+// string scans a string. Strings without escapes and non-ASCII
+// characters are taken directly, in view mode without copying.
+func (s *scanner) string() (string, error) {
+	s.pos++
+	start := s.pos
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == '"':
+			var str string
+			switch {
+			case s.arena != nil:
+				str = s.arena.string(s.data[start:s.pos])
+			case s.view:
+				str = viewString(s.data[start:s.pos])
+			default:
+				str = string(s.data[start:s.pos])
+			}
+			s.pos++
+			return str, nil
+		case c == '\\' || c >= utf8.RuneSelf:
+			return s.unquote(start)
+		case c < ' ':
+			return "", s.errorf("invalid character %q in string literal", c)
+		}
+		s.pos++
+	}
+	return "", s.unexpectedEnd()
+}
+
+// unquote scans the rest of a string containing escapes or non-ASCII
+// characters. Invalid UTF-8 and surrogates are replaced by U+FFFD.
+func (s *scanner) unquote(start int) (string, error) {
+	buf := make([]byte, s.pos-start, s.pos-start+16)
+	copy(buf, s.data[start:s.pos])
+	for s.pos < len(s.data) {
+		c := s.data[s.pos]
+		switch {
+		case c == '"':
+			s.pos++
+			if s.arena != nil {
+				return s.arena.string(buf), nil
+			}
+			return string(buf), nil
+		case c == '\\':
+			s.pos++
+			if s.pos >= len(s.data) {
+				return "", s.unexpectedEnd()
+			}
+			switch e := s.data[s.pos]; e {
+			case '"', '\\', '/':
+				buf = append(buf, e)
+			case 'b':
+				buf = append(buf, '\b')
+			case 'f':
+				buf = append(buf, '\f')
+			case 'n':
+				buf = append(buf, '\n')
+			case 'r':
+				buf = append(buf, '\r')
+			case 't':
+				buf = append(buf, '\t')
+			case 'u':
+				r, err := s.hex4(s.pos + 1)
+				if err != nil {
+					return "", err
+				}
+				s.pos += 4
+				if utf16.IsSurrogate(r) {
+					r1 := r
+					r = unicode.ReplacementChar
+					if s.pos+2 < len(s.data) && s.data[s.pos+1] == '\\' && s.data[s.pos+2] == 'u' {
+						r2, err := s.hex4(s.pos + 3)
+						if err == nil {
+							if dr := utf16.DecodeRune(r1, r2); dr != unicode.ReplacementChar {
+								r = dr
+								s.pos += 6
+							}
+						}
+					}
+				}
+				buf = utf8.AppendRune(buf, r)
+			default:
+				return "", s.errorf("invalid character %q in string escape code", e)
+			}
+			s.pos++
+		case c < ' ':
+			return "", s.errorf("invalid character %q in string literal", c)
+		case c < utf8.RuneSelf:
+			buf = append(buf, c)
+			s.pos++
+		default:
+			r, size := utf8.DecodeRune(s.data[s.pos:])
+			buf = utf8.AppendRune(buf, r)
+			s.pos += size
+		}
+	}
+	return "", s.unexpectedEnd()
+}
+
+// hex4 reads the four hex digits at the given position.
+func (s *scanner) hex4(at int) (rune, error) {
+	if at+4 > len(s.data) {
+		s.pos = len(s.data)
+		return 0, s.unexpectedEnd()
+	}
+	var r rune
+	for _, c := range s.data[at : at+4] {
+		switch {
+		case c >= '0' && c <= '9':
+			c -= '0'
+		case c >= 'a' && c <= 'f':
+			c = c - 'a' + 10
+		case c >= 'A' && c <= 'F':
+			c = c - 'A' + 10
+		default:
+			return 0, s.errorf("invalid character %q in \\u hexadecimal character escape", c)
+		}
+		r = r*16 + rune(c)
+	}
+	return r, nil
+}
+
+// isDigit checks if the byte is a decimal digit.
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// EOF
@@ -0,0 +1,247 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"runtime"
+	"testing"
+	"unicode/utf16"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// decoderInputs contains valid and invalid inputs for the decoders.
+var decoderInputs = []string{
+	`{"a":1,"b":[true,false,null],"c":{"d":"e"}}`,
+	` [ 1 , -2.5e3 , 0.125, 1E+2, -0 ] `,
+	`"plain"`,
+	`"esc\"\\\/\b\f\n\r\t"`,
+	`"ä€😀 äöü 😀"`,
+	`"\ud83d alone \ude00"`,
+	`"\ud83dA"`,
+	`"\ud83d\ude00 pair"`,
+	`"\ud83d\ud83d\ude00"`,
+	"\"invalid \xff utf-8\"",
+	`{"a":1,"a":2}`,
+	`{}`,
+	`[]`,
+	`{"a":[{"b":[[]]}]}`,
+	``,
+	`{`,
+	`{"a"}`,
+	`{"a":1,}`,
+	`[1,]`,
+	`[1 2]`,
+	`01`,
+	`1.`,
+	`1e`,
+	`-`,
+	`tru`,
+	`nul`,
+	`"unterminated`,
+	"\"ctrl \x01\"",
+	`"\x"`,
+	`"\u12"`,
+	`1e999`,
+	`{"a":1} x`,
+	`{1:2}`,
+}
+
+// TestDecoders tests that the scan decoder creates the same elements as
+// the standard decoder.
+func TestDecoders(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	inputs := append([]string{string(bs)}, decoderInputs...)
+
+	for _, input := range inputs {
+		assert.Logf("input: %q", input)
+		std, stdErr := dynaj.NewStandardDecoder().Decode([]byte(input))
+		scan, scanErr := dynaj.NewScanDecoder().Decode([]byte(input))
+		assert.Equal(stdErr == nil, scanErr == nil)
+		assert.True(reflect.DeepEqual(std, scan))
+		arena, arenaErr := dynaj.NewArenaDecoder().Decode([]byte(input))
+		assert.Equal(stdErr == nil, arenaErr == nil)
+		assert.True(reflect.DeepEqual(std, arena))
+	}
+}
+
+// TestArenaDecoder tests documents allocated from an arena.
+func TestArenaDecoder(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte(`{"a":[1,2,3],"b":[["x","y"],["z"]],"c":"\u00e4","d":[1.5,"s"]}`)
+	doc, err := dynaj.Unmarshal(data, dynaj.WithDecoder(dynaj.NewArenaDecoder()))
+	assert.NoError(err)
+
+	// Values survive the garbage collection and changes of the data.
+	copy(data, bytes.Repeat([]byte{' '}, len(data)))
+	runtime.GC()
+	assert.Equal(doc.String(), `{"a":[1,2,3],"b":[["x","y"],["z"]],"c":"ä","d":[1.5,"s"]}`)
+
+	// Arrays sharing a chunk are changed independently.
+	assert.NoError(doc.SetValueAt("/b/0/2", "w"))
+	assert.NoError(doc.SetValueAt("/a/3", 4))
+	assert.NoError(doc.SetValueAt("/b/1/0", "v"))
+	assert.NoError(doc.DeleteValueAt("/a/0"))
+	assert.Equal(doc.String(), `{"a":[2,3,4],"b":[["x","y","w"],["v"]],"c":"ä","d":[1.5,"s"]}`)
+
+	// Fewer allocations than the scan decoder.
+	bs, _ := createDocument(assert)
+	scanAllocs := testing.AllocsPerRun(10, func() {
+		dynaj.NewScanDecoder().Decode(bs)
+	})
+	arenaAllocs := testing.AllocsPerRun(10, func() {
+		dynaj.NewArenaDecoder().Decode(bs)
+	})
+	assert.Logf("allocations: scan %.0f, arena %.0f", scanAllocs, arenaAllocs)
+	assert.True(arenaAllocs < scanAllocs*3/4)
+}
+
+// TestWithDecoder tests unmarshalling with a chosen decoder.
+func TestWithDecoder(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+
+	doc, err := dynaj.Unmarshal(bs, dynaj.WithDecoder(dynaj.NewScanDecoder()))
+	assert.NoError(err)
+	assert.Equal(doc.String(), string(bs))
+	assert.Equal(doc.NodeAt("/B/1/S/2").AsString(""), "white")
+
+	_, err = dynaj.Unmarshal([]byte(`{"a":}`), dynaj.WithDecoder(dynaj.NewScanDecoder()))
+	assert.ErrorContains(err, `cannot unmarshal document: offset 5: invalid character '}'`)
+}
+
+// TestTrailingData tests rejecting data after the first value and
+// unmarshalling concatenated values.
+func TestTrailingData(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte("{\"a\": 1}\n{\"a\": 2} [3]\n\"four\"\n")
+
+	for _, decoder := range []dynaj.Decoder{dynaj.NewStandardDecoder(), dynaj.NewScanDecoder()} {
+		_, err := dynaj.Unmarshal(data, dynaj.WithDecoder(decoder))
+		assert.ErrorContains(err, "after top-level value")
+
+		doc, err := dynaj.UnmarshalAll(data, dynaj.WithDecoder(decoder), dynaj.WithIntegers())
+		assert.NoError(err)
+		assert.Equal(doc.String(), `[{"a":1},{"a":2},[3],"four"]`)
+		assert.Equal(doc.NodeAt("/1/a").AsInt(0), 2)
+	}
+
+	doc, err := dynaj.UnmarshalAll([]byte(" \n"))
+	assert.NoError(err)
+	assert.Equal(doc.String(), `[]`)
+
+	_, err = dynaj.UnmarshalAll([]byte(`{"a": 1} {"a": }`))
+	assert.ErrorContains(err, "cannot unmarshal document: value 1: invalid character '}'")
+	_, err = dynaj.UnmarshalAll([]byte(`[[1]] [2]`), dynaj.WithLimits(dynaj.Limits{MaxDepth: 1}))
+	assert.ErrorContains(err, "value 0: nesting exceeds limit of depth 1")
+	_, err = dynaj.UnmarshalAll([]byte(`[1] [2]`), dynaj.WithLimits(dynaj.Limits{MaxBytes: 4}))
+	assert.ErrorContains(err, "data length 7 exceeds limit of 4 bytes")
+}
+
+// TestEncodings tests unmarshalling data with byte order marks and
+// in UTF-16 or UTF-32.
+func TestEncodings(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	text := `{"name": "Grüße 😀", "n": 1}`
+	utf16be, utf16le, utf32be, utf32le := []byte{}, []byte{}, []byte{}, []byte{}
+	for _, u := range utf16.Encode([]rune(text)) {
+		utf16be = binary.BigEndian.AppendUint16(utf16be, u)
+		utf16le = binary.LittleEndian.AppendUint16(utf16le, u)
+	}
+	for _, r := range text {
+		utf32be = binary.BigEndian.AppendUint32(utf32be, uint32(r))
+		utf32le = binary.LittleEndian.AppendUint32(utf32le, uint32(r))
+	}
+	inputs := [][]byte{
+		[]byte(text),
+		append([]byte{0xEF, 0xBB, 0xBF}, text...),
+		utf16be,
+		utf16le,
+		append([]byte{0xFE, 0xFF}, utf16be...),
+		append([]byte{0xFF, 0xFE}, utf16le...),
+		utf32be,
+		utf32le,
+		append([]byte{0x00, 0x00, 0xFE, 0xFF}, utf32be...),
+		append([]byte{0xFF, 0xFE, 0x00, 0x00}, utf32le...),
+	}
+	for i, input := range inputs {
+		assert.Logf("input %d", i)
+		doc, err := dynaj.Unmarshal(input)
+		assert.NoError(err)
+		assert.Equal(doc.NodeAt("/name").AsString(""), "Grüße 😀")
+		doc, err = dynaj.UnmarshalAll(input, dynaj.WithDecoder(dynaj.NewScanDecoder()))
+		assert.NoError(err)
+		assert.Equal(doc.NodeAt("/0/n").AsInt(0), 1)
+	}
+
+	// Short and invalid data.
+	doc, err := dynaj.Unmarshal([]byte{0x00, '1'})
+	assert.NoError(err)
+	assert.Equal(doc.Root().AsInt(0), 1)
+	_, err = dynaj.Unmarshal(append([]byte{0xFE, 0xFF}, utf16be[:5]...))
+	assert.ErrorContains(err, "invalid UTF-16 data: odd length 5")
+	_, err = dynaj.Unmarshal([]byte{0x00, 0x00, 0x00, '1', 0x00, 0x11, 0x00, 0x00})
+	assert.ErrorContains(err, "invalid UTF-32 data: invalid code point 0x110000 at offset 4")
+}
+
+// FuzzScanDecoder compares the scan decoder with the standard decoder.
+func FuzzScanDecoder(f *testing.F) {
+	for _, input := range decoderInputs {
+		f.Add([]byte(input))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		std, stdErr := dynaj.NewStandardDecoder().Decode(data)
+		scan, scanErr := dynaj.NewScanDecoder().Decode(data)
+		if (stdErr == nil) != (scanErr == nil) {
+			t.Fatalf("different errors for %q: %v / %v", data, stdErr, scanErr)
+		}
+		if !reflect.DeepEqual(std, scan) {
+			t.Fatalf("different elements for %q: %v / %v", data, std, scan)
+		}
+		arena, arenaErr := dynaj.NewArenaDecoder().Decode(data)
+		if (stdErr == nil) != (arenaErr == nil) || !reflect.DeepEqual(std, arena) {
+			t.Fatalf("different arena result for %q: %v / %v", data, std, arena)
+		}
+	})
+}
+
+// BenchmarkDecoders compares the standard, the scan, and the arena
+// decoder.
+func BenchmarkDecoders(b *testing.B) {
+	assert := asserts.NewTesting(b, asserts.FailStop)
+	bs, _ := createDocument(assert)
+
+	for name, decoder := range map[string]dynaj.Decoder{
+		"standard": dynaj.NewStandardDecoder(),
+		"scan":     dynaj.NewScanDecoder(),
+		"arena":    dynaj.NewArenaDecoder(),
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, _ = decoder.Decode(bs)
+			}
+		})
+	}
+}
+
+// EOF
@@ -0,0 +1,341 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+)
+
+//--------------------
+// DEDUPLICATION
+//--------------------
+
+// DeduplicateSubtrees detects structurally identical objects and arrays
+// and lets them share their storage. This cuts the memory of payloads
+// with many identical nested objects. Mutations copy the shared
+// containers along their paths before changing them, so the other
+// occurrences stay unchanged. Raw JSON fragments are not deduplicated.
+// It returns the number of replaced subtrees.
+func (d *Document) DeduplicateSubtrees() (int, error) {
+	if d.frozen {
+		return 0, ErrFrozen
+	}
+	dd := &deduplicator{
+		candidates: map[uint64][]Element{},
+	}
+	root := d.root
+	if d.shared {
+		// Deduplication changes the containers in place.
+		root = copyElement(root)
+	}
+	root, _ = dd.element(root)
+	d.root = root
+	d.shared = true
+	d.owned = nil
+	return dd.replaced, nil
+}
+
+// deduplicator collects the canonical instances of the subtrees by
+// their hashes.
+type deduplicator struct {
+	candidates map[uint64][]Element
+	replaced   int
+}
+
+// element deduplicates the element bottom-up and returns the canonical
+// instance and the hash of it.
+func (dd *deduplicator) element(element Element) (Element, uint64) {
+	h := fnv.New64a()
+	switch typed := element.(type) {
+	case Object:
+		keys := make(Keys, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		h.Write([]byte{'{'})
+		for _, key := range keys {
+			child, ch := dd.element(typed[key])
+			typed[key] = child
+			h.Write([]byte(strconv.Quote(key)))
+			writeHash(h.Write, ch)
+		}
+		return dd.canonical(typed, h.Sum64())
+	case Array:
+		h.Write([]byte{'['})
+		for idx, child := range typed {
+			child, ch := dd.element(child)
+			typed[idx] = child
+			writeHash(h.Write, ch)
+		}
+		return dd.canonical(typed, h.Sum64())
+	case string:
+		h.Write([]byte{'s'})
+		h.Write([]byte(typed))
+	case int:
+		h.Write([]byte{'n'})
+		writeHash(h.Write, math.Float64bits(float64(typed)))
+	case float64:
+		h.Write([]byte{'n'})
+		writeHash(h.Write, math.Float64bits(typed))
+	case bool:
+		h.Write([]byte{'b', boolByte(typed)})
+	case nil:
+		h.Write([]byte{'0'})
+	default:
+		// Raw fragments, attachments, and other values are
+		// compared when containing them.
+		h.Write([]byte{'?'})
+	}
+	return element, h.Sum64()
+}
+
+// canonical returns the first equal container with the same hash or
+// registers the container as canonical instance. Empty containers are
+// not shared.
+func (dd *deduplicator) canonical(container Element, hash uint64) (Element, uint64) {
+	if containerLen(container) == 0 {
+		return container, hash
+	}
+	for _, candidate := range dd.candidates[hash] {
+		if equalElements(candidate, container) {
+			dd.replaced++
+			return candidate, hash
+		}
+	}
+	dd.candidates[hash] = append(dd.candidates[hash], container)
+	return container, hash
+}
+
+//--------------------
+// DUPLICATE DETECTION
+//--------------------
+
+// FindDuplicates groups the structurally identical objects and arrays
+// by their hash, so redundant content can be spotted and factored out.
+// Only subtrees with at least minSize elements, counting the subtree
+// itself and all elements below it, are reported. Duplicates inside
+// reported duplicates are left out. The paths of each group are sorted.
+func (d *Document) FindDuplicates(minSize int) map[string][]Path {
+	df := &duplicateFinder{
+		minSize:    minSize,
+		candidates: map[uint64][]*duplicateGroup{},
+	}
+	df.element(d.root, Separator)
+	// Keep only the outermost duplicates.
+	duplicated := map[Path]struct{}{}
+	for _, groups := range df.candidates {
+		for _, group := range groups {
+			if len(group.paths) > 1 {
+				for _, path := range group.paths {
+					duplicated[path] = struct{}{}
+				}
+			}
+		}
+	}
+	duplicates := map[string][]Path{}
+	for hash, groups := range df.candidates {
+		for idx, group := range groups {
+			if len(group.paths) < 2 || group.nested(duplicated) {
+				continue
+			}
+			key := fmt.Sprintf("%016x", hash)
+			if idx > 0 {
+				// Different subtrees with the same hash.
+				key = fmt.Sprintf("%s-%d", key, idx)
+			}
+			sortPaths(group.paths)
+			duplicates[key] = group.paths
+		}
+	}
+	return duplicates
+}
+
+// duplicateGroup contains the paths of equal subtrees.
+type duplicateGroup struct {
+	element Element
+	paths   []Path
+}
+
+// nested checks if all paths of the group are below duplicated paths.
+func (g *duplicateGroup) nested(duplicated map[Path]struct{}) bool {
+	for _, path := range g.paths {
+		keys := splitPath(path)
+		inside := false
+		for i := len(keys) - 1; i >= 0 && !inside; i-- {
+			_, inside = duplicated[pathify(keys[:i])]
+		}
+		if !inside {
+			return false
+		}
+	}
+	return true
+}
+
+// duplicateFinder collects the groups of equal subtrees by their hashes.
+type duplicateFinder struct {
+	minSize    int
+	candidates map[uint64][]*duplicateGroup
+}
+
+// element hashes the element bottom-up and registers its path. It
+// returns the hash and the number of elements of the subtree.
+func (df *duplicateFinder) element(element Element, path Path) (uint64, int) {
+	if decoded, err := decodeRaw(element); err == nil {
+		element = decoded
+	}
+	h := fnv.New64a()
+	size := 1
+	switch typed := element.(type) {
+	case Object:
+		h.Write([]byte{'{'})
+		for _, key := range childKeys(typed) {
+			ch, cs := df.element(typed[key], appendKey(path, key))
+			h.Write([]byte(strconv.Quote(key)))
+			writeHash(h.Write, ch)
+			size += cs
+		}
+		df.register(typed, path, h.Sum64(), size)
+	case Array:
+		h.Write([]byte{'['})
+		for idx, child := range typed {
+			ch, cs := df.element(child, appendKey(path, strconv.Itoa(idx)))
+			writeHash(h.Write, ch)
+			size += cs
+		}
+		df.register(typed, path, h.Sum64(), size)
+	default:
+		_, vh := (&deduplicator{}).element(element)
+		return vh, size
+	}
+	return h.Sum64(), size
+}
+
+// register adds the path of the container to the group of equal ones.
+func (df *duplicateFinder) register(container Element, path Path, hash uint64, size int) {
+	if size < df.minSize || containerLen(container) == 0 {
+		return
+	}
+	for _, group := range df.candidates[hash] {
+		if equalElements(group.element, container) {
+			group.paths = append(group.paths, path)
+			return
+		}
+	}
+	df.candidates[hash] = append(df.candidates[hash], &duplicateGroup{
+		element: container,
+		paths:   []Path{path},
+	})
+}
+
+// unshare copies the containers along the keys which may be shared
+// with other subtrees or snapshots, so they can be changed in place.
+func (d *Document) unshare(keys Keys) {
+	if !d.shared {
+		return
+	}
+	if d.owned == nil {
+		d.owned = map[uintptr]struct{}{}
+	}
+	d.root = d.own(d.root)
+	parent := d.root
+	for _, key := range keys {
+		child, ok := childElement(parent, key)
+		if !ok {
+			return
+		}
+		owned := d.own(child)
+		switch typed := parent.(type) {
+		case Object:
+			typed[key] = owned
+		case Array:
+			index, _ := asIndex(key)
+			typed[index] = owned
+		}
+		parent = owned
+	}
+}
+
+// own returns a shallow copy of the container if it has not been
+// copied before. Empty containers are always copied, other elements
+// and records are returned unchanged.
+func (d *Document) own(element Element) Element {
+	if _, ok := element.(*records); ok {
+		// Records are never changed in place.
+		return element
+	}
+	if containerLen(element) == 0 {
+		switch element.(type) {
+		case Object:
+			return Object{}
+		case Array:
+			return Array{}
+		}
+		return element
+	}
+	ptr := reflect.ValueOf(element).Pointer()
+	if _, ok := d.owned[ptr]; ok {
+		return element
+	}
+	var owned Element
+	switch typed := element.(type) {
+	case Object:
+		obj := make(Object, len(typed))
+		for key, child := range typed {
+			obj[key] = child
+		}
+		owned = obj
+	case Array:
+		arr := make(Array, len(typed))
+		copy(arr, typed)
+		owned = arr
+	}
+	d.owned[reflect.ValueOf(owned).Pointer()] = struct{}{}
+	return owned
+}
+
+// containerLen returns the length of objects and arrays and 0 for all
+// other elements.
+func containerLen(element Element) int {
+	switch typed := element.(type) {
+	case Object:
+		return len(typed)
+	case Array:
+		return len(typed)
+	case *records:
+		return typed.len()
+	}
+	return 0
+}
+
+// writeHash writes the number into the hash.
+func writeHash(write func([]byte) (int, error), n uint64) {
+	var buf [8]byte
+	binary.LittleEndian.PutUint64(buf[:], n)
+	write(buf[:])
+}
+
+// boolByte returns the byte for a bool.
+func boolByte(b bool) byte {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// EOF
@@ -0,0 +1,110 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sort"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestDeduplicateSubtrees tests sharing identical subtrees and copying
+// them on mutation.
+func TestDeduplicateSubtrees(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	in := `{"a":{"x":[1,2],"y":{"z":true}},"b":{"x":[1,2],"y":{"z":true}},"c":[[1,2],{"z":true},[]]}`
+	doc := mustUnmarshal(assert, in)
+
+	// Either a or b is shared including its children, c shares the
+	// array and the object.
+	n, err := doc.DeduplicateSubtrees()
+	assert.NoError(err)
+	assert.Equal(n, 5)
+	assert.Equal(doc.String(), in)
+	assert.NoError(dynaj.Verify(doc))
+
+	// Mutations only change the addressed occurrence.
+	assert.NoError(doc.SetValueAt("/a/y/z", false))
+	assert.NoError(doc.SetValueAt("/b/x/2", 3))
+	assert.NoError(doc.DeleteValueAt("/c/0/0"))
+	assert.NoError(doc.SetValueAt("/c/1/w", 1))
+	assert.Equal(doc.String(), `{"a":{"x":[1,2],"y":{"z":false}},"b":{"x":[1,2,3],"y":{"z":true}},"c":[[2],{"w":1,"z":true},[]]}`)
+
+	// Repeated mutations of copied containers.
+	assert.NoError(doc.SetValueAt("/a/y/z", true))
+	assert.NoError(doc.DeleteElementAt("/b/y"))
+	assert.Equal(doc.String(), `{"a":{"x":[1,2],"y":{"z":true}},"b":{"x":[1,2,3]},"c":[[2],{"w":1,"z":true},[]]}`)
+
+	// Without duplicates nothing is shared.
+	doc = mustUnmarshal(assert, `{"a":[1],"b":[2]}`)
+	n, err = doc.DeduplicateSubtrees()
+	assert.NoError(err)
+	assert.Equal(n, 0)
+
+	// Frozen documents are not changed.
+	_, err = doc.Freeze().DeduplicateSubtrees()
+	assert.ErrorMatch(err, ".*frozen.*")
+}
+
+// TestFindDuplicates tests grouping identical subtrees.
+func TestFindDuplicates(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"a": {"x": [1, 2], "y": {"z": true}},
+		"b": {"x": [1, 2.0], "y": {"z": true}},
+		"c": [[1, 2], {"z": true}, [], []],
+		"d": "{\"z\": true}"
+	}`)
+
+	// Children of a and b are only reported if they occur elsewhere.
+	assert.Equal(duplicateGroups(doc.FindDuplicates(3)), [][]dynaj.Path{
+		{"/a", "/b"},
+		{"/a/x", "/b/x", "/c/0"},
+	})
+	assert.Equal(duplicateGroups(doc.FindDuplicates(0)), [][]dynaj.Path{
+		{"/a", "/b"},
+		{"/a/x", "/b/x", "/c/0"},
+		{"/a/y", "/b/y", "/c/1"},
+	})
+	assert.Length(doc.FindDuplicates(10), 0)
+
+	for hash, paths := range doc.FindDuplicates(3) {
+		assert.Length(hash, 16)
+		assert.True(len(paths) > 1)
+	}
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// duplicateGroups returns the groups of duplicates sorted by their
+// first paths.
+func duplicateGroups(duplicates map[string][]dynaj.Path) [][]dynaj.Path {
+	groups := [][]dynaj.Path{}
+	for _, paths := range duplicates {
+		groups = append(groups, paths)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i][0] < groups[j][0]
+	})
+	return groups
+}
+
+// EOF
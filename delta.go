@@ -0,0 +1,441 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// deltaMagic starts each binary delta, followed by the format version.
+const deltaMagic = "DJD\x01"
+
+// Operations of binary deltas.
+const (
+	deltaSet    byte = 's'
+	deltaDelete byte = 'd'
+)
+
+// Tags of the binary encoded elements.
+const (
+	tagNull   byte = 'n'
+	tagFalse  byte = 'f'
+	tagTrue   byte = 't'
+	tagInt    byte = 'i'
+	tagFloat  byte = 'F'
+	tagString byte = 's'
+	tagArray  byte = 'a'
+	tagObject byte = 'o'
+	tagJSON   byte = 'j'
+)
+
+//--------------------
+// DELTA
+//--------------------
+
+// Delta returns a compact binary delta changing the old document into
+// the new one, e.g. for syncing large documents over constrained links.
+// It is smaller than a JSON Patch as paths and values are binary encoded
+// and only changed values, deleted keys, and changed array tails are
+// contained. ApplyDelta applies it.
+func Delta(old, new *Document) ([]byte, error) {
+	e := &deltaEncoder{}
+	e.buf.WriteString(deltaMagic)
+	if err := e.diff(Keys{}, old.root, new.root); err != nil {
+		return nil, fmt.Errorf("cannot create delta: %v", err)
+	}
+	return e.buf.Bytes(), nil
+}
+
+// ApplyDelta applies a delta created by Delta to the document. It is
+// applied atomically and recorded as setting the new root. Restricted
+// documents need write access to the root.
+func ApplyDelta(doc *Document, delta []byte) error {
+	if doc.frozen {
+		return ErrFrozen
+	}
+	if err := doc.checkAccess(Keys{}); err != nil {
+		return err
+	}
+	if !bytes.HasPrefix(delta, []byte(deltaMagic)) {
+		return fmt.Errorf("cannot apply delta: invalid format")
+	}
+	d := &deltaDecoder{data: delta, pos: len(deltaMagic)}
+	root := copyElement(doc.root)
+	for d.pos < len(d.data) {
+		var err error
+		root, err = d.operation(root)
+		if err != nil {
+			return fmt.Errorf("cannot apply delta: offset %d: %v", d.pos, err)
+		}
+	}
+	doc.root = root
+	doc.owned = nil
+	doc.changed(nil)
+	doc.record(SetOperation, Separator, root)
+	return nil
+}
+
+//--------------------
+// ENCODING
+//--------------------
+
+// deltaEncoder writes the operations of a delta.
+type deltaEncoder struct {
+	buf bytes.Buffer
+}
+
+// diff writes the operations changing the old into the new element.
+func (e *deltaEncoder) diff(keys Keys, old, new Element) error {
+	old, err := decodeRaw(old)
+	if err != nil {
+		return err
+	}
+	new, err = decodeRaw(new)
+	if err != nil {
+		return err
+	}
+	switch tnew := new.(type) {
+	case Object:
+		told, ok := old.(Object)
+		if !ok {
+			break
+		}
+		deleted := Keys{}
+		for key := range told {
+			if _, ok := tnew[key]; !ok {
+				deleted = append(deleted, key)
+			}
+		}
+		sort.Strings(deleted)
+		for _, key := range deleted {
+			e.operation(deltaDelete, append(keys, key))
+		}
+		for _, key := range childKeys(tnew) {
+			child, ok := told[key]
+			if !ok {
+				e.operation(deltaSet, append(keys, key))
+				if err := e.element(tnew[key]); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.diff(append(keys, key), child, tnew[key]); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Array:
+		told, ok := old.(Array)
+		if !ok {
+			break
+		}
+		for idx := len(told) - 1; idx >= len(tnew); idx-- {
+			e.operation(deltaDelete, append(keys, strconv.Itoa(idx)))
+		}
+		for idx, child := range tnew {
+			key := strconv.Itoa(idx)
+			if idx >= len(told) {
+				e.operation(deltaSet, append(keys, key))
+				if err := e.element(child); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := e.diff(append(keys, key), told[idx], child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if equalElements(old, new) {
+		return nil
+	}
+	e.operation(deltaSet, keys)
+	return e.element(new)
+}
+
+// operation writes the operation and the keys of its path.
+func (e *deltaEncoder) operation(op byte, keys Keys) {
+	e.buf.WriteByte(op)
+	e.uvarint(uint64(len(keys)))
+	for _, key := range keys {
+		e.string(key)
+	}
+}
+
+// element writes the binary encoded element.
+func (e *deltaEncoder) element(element Element) error {
+	switch typed := element.(type) {
+	case nil:
+		e.buf.WriteByte(tagNull)
+	case bool:
+		if typed {
+			e.buf.WriteByte(tagTrue)
+		} else {
+			e.buf.WriteByte(tagFalse)
+		}
+	case int:
+		e.buf.WriteByte(tagInt)
+		var tmp [binary.MaxVarintLen64]byte
+		e.buf.Write(tmp[:binary.PutVarint(tmp[:], int64(typed))])
+	case float64:
+		if typed == math.Trunc(typed) && math.Abs(typed) <= maxExactInt {
+			return e.element(int(typed))
+		}
+		if math.IsNaN(typed) || math.IsInf(typed, 0) {
+			return fmt.Errorf("unsupported value %v", typed)
+		}
+		e.buf.WriteByte(tagFloat)
+		var tmp [8]byte
+		binary.LittleEndian.PutUint64(tmp[:], math.Float64bits(typed))
+		e.buf.Write(tmp[:])
+	case string:
+		e.buf.WriteByte(tagString)
+		e.string(typed)
+	case Array:
+		e.buf.WriteByte(tagArray)
+		e.uvarint(uint64(len(typed)))
+		for _, child := range typed {
+			if err := e.element(child); err != nil {
+				return err
+			}
+		}
+	case Object:
+		e.buf.WriteByte(tagObject)
+		e.uvarint(uint64(len(typed)))
+		for _, key := range childKeys(typed) {
+			e.string(key)
+			if err := e.element(typed[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		// Raw fragments, attachments, and other values.
+		data, err := json.Marshal(typed)
+		if err != nil {
+			return err
+		}
+		e.buf.WriteByte(tagJSON)
+		e.string(string(data))
+	}
+	return nil
+}
+
+// string writes the length and the bytes of the string.
+func (e *deltaEncoder) string(s string) {
+	e.uvarint(uint64(len(s)))
+	e.buf.WriteString(s)
+}
+
+// uvarint writes the unsigned number.
+func (e *deltaEncoder) uvarint(n uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	e.buf.Write(tmp[:binary.PutUvarint(tmp[:], n)])
+}
+
+//--------------------
+// DECODING
+//--------------------
+
+// errDeltaEnd signals a too early end of the delta.
+var errDeltaEnd = errors.New("unexpected end of delta")
+
+// deltaDecoder reads the operations of a delta.
+type deltaDecoder struct {
+	data []byte
+	pos  int
+}
+
+// operation reads and applies the next operation.
+func (d *deltaDecoder) operation(root Element) (Element, error) {
+	op, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	n, err := d.uvarint()
+	if err != nil {
+		return nil, err
+	}
+	keys := make(Keys, 0, n)
+	for i := uint64(0); i < n; i++ {
+		key, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	switch op {
+	case deltaSet:
+		value, err := d.element()
+		if err != nil {
+			return nil, err
+		}
+		return setDeltaElement(root, keys, value)
+	case deltaDelete:
+		if _, err := elementAt(root, keys); err != nil {
+			return nil, fmt.Errorf("invalid path %q: %v", pathify(keys), err)
+		}
+		return deleteElement(root, keys, true)
+	}
+	return nil, fmt.Errorf("invalid operation %q", op)
+}
+
+// element reads the next binary encoded element.
+func (d *deltaDecoder) element() (Element, error) {
+	tag, err := d.byte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagFalse:
+		return false, nil
+	case tagTrue:
+		return true, nil
+	case tagInt:
+		i, n := binary.Varint(d.data[d.pos:])
+		if n <= 0 {
+			return nil, errDeltaEnd
+		}
+		d.pos += n
+		return int(i), nil
+	case tagFloat:
+		if d.pos+8 > len(d.data) {
+			return nil, errDeltaEnd
+		}
+		bits := binary.LittleEndian.Uint64(d.data[d.pos:])
+		d.pos += 8
+		return math.Float64frombits(bits), nil
+	case tagString:
+		return d.string()
+	case tagArray:
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		arr := Array{}
+		for i := uint64(0); i < n; i++ {
+			child, err := d.element()
+			if err != nil {
+				return nil, err
+			}
+			arr = append(arr, child)
+		}
+		return arr, nil
+	case tagObject:
+		n, err := d.uvarint()
+		if err != nil {
+			return nil, err
+		}
+		obj := Object{}
+		for i := uint64(0); i < n; i++ {
+			key, err := d.string()
+			if err != nil {
+				return nil, err
+			}
+			child, err := d.element()
+			if err != nil {
+				return nil, err
+			}
+			obj[key] = child
+		}
+		return obj, nil
+	case tagJSON:
+		data, err := d.string()
+		if err != nil {
+			return nil, err
+		}
+		return decodeRaw(json.RawMessage(data))
+	}
+	return nil, fmt.Errorf("invalid tag %q", tag)
+}
+
+// byte reads the next byte.
+func (d *deltaDecoder) byte() (byte, error) {
+	if d.pos >= len(d.data) {
+		return 0, errDeltaEnd
+	}
+	b := d.data[d.pos]
+	d.pos++
+	return b, nil
+}
+
+// string reads the length and the bytes of a string.
+func (d *deltaDecoder) string() (string, error) {
+	n, err := d.uvarint()
+	if err != nil {
+		return "", err
+	}
+	if uint64(len(d.data)-d.pos) < n {
+		return "", errDeltaEnd
+	}
+	s := string(d.data[d.pos : d.pos+int(n)])
+	d.pos += int(n)
+	return s, nil
+}
+
+// uvarint reads an unsigned number.
+func (d *deltaDecoder) uvarint() (uint64, error) {
+	n, size := binary.Uvarint(d.data[d.pos:])
+	if size <= 0 {
+		return 0, errDeltaEnd
+	}
+	d.pos += size
+	return n, nil
+}
+
+// setDeltaElement sets the value at the keys. The parent has to exist,
+// array elements can be replaced or appended.
+func setDeltaElement(root Element, keys Keys, value Element) (Element, error) {
+	if len(keys) == 0 {
+		return value, nil
+	}
+	parentKeys, last := keys[:len(keys)-1], keys[len(keys)-1]
+	parent, err := elementAt(root, parentKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent %q: %v", pathify(parentKeys), err)
+	}
+	if parent, err = decodeRaw(parent); err != nil {
+		return nil, err
+	}
+	switch typed := parent.(type) {
+	case Object:
+		typed[last] = value
+		return replaceElement(root, parentKeys, typed)
+	case Array:
+		index, ok := asIndex(last)
+		switch {
+		case !ok || index < 0 || index > len(typed):
+			return nil, fmt.Errorf("invalid index %q", last)
+		case index == len(typed):
+			typed = append(typed, value)
+		default:
+			typed[index] = value
+		}
+		return replaceElement(root, parentKeys, typed)
+	}
+	return nil, fmt.Errorf("parent %q is no object or array", pathify(parentKeys))
+}
+
+// EOF
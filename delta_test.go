@@ -0,0 +1,72 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestDelta tests creating and applying binary deltas.
+func TestDelta(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	tests := []struct {
+		old string
+		new string
+	}{
+		{`{"a":1,"b":[1,2,3],"c":{"d":"x"}}`, `{"a":1.5,"b":[1,2],"c":{"d":"x","e":[true,null]},"f":"new"}`},
+		{`{"a":[1]}`, `{"a":[1,{"b":2},3]}`},
+		{`{"a":{"b":1}}`, `{"a":[1]}`},
+		{`[1,2,3]`, `{"a":1}`},
+		{`{"a":1}`, `{"a":1}`},
+		{`{"a":1}`, `null`},
+	}
+	for _, test := range tests {
+		old := mustUnmarshal(assert, test.old)
+		new := mustUnmarshal(assert, test.new)
+		delta, err := dynaj.Delta(old, new)
+		assert.NoError(err)
+		assert.NoError(dynaj.ApplyDelta(old, delta))
+		assert.Equal(old.String(), new.String())
+	}
+
+	// Unchanged documents lead to empty deltas.
+	doc := mustUnmarshal(assert, `{"a":[1,2,{"b":"c"}]}`)
+	delta, err := dynaj.Delta(doc, doc)
+	assert.NoError(err)
+	assert.Length(delta, 4)
+
+	// Deltas are smaller than the documents.
+	old := mustUnmarshal(assert, `{"items":[{"id":1,"name":"alpha"},{"id":2,"name":"beta"}]}`)
+	new := mustUnmarshal(assert, `{"items":[{"id":1,"name":"alpha"},{"id":2,"name":"gamma"}]}`)
+	delta, err = dynaj.Delta(old, new)
+	assert.NoError(err)
+	assert.True(len(delta) < 30)
+
+	// Invalid deltas keep the document unchanged.
+	err = dynaj.ApplyDelta(old, delta[:len(delta)-2])
+	assert.ErrorMatch(err, "cannot apply delta: .*unexpected end of delta")
+	err = dynaj.ApplyDelta(old, []byte(`{}`))
+	assert.ErrorMatch(err, "cannot apply delta: invalid format")
+	assert.Equal(old.NodeAt("/items/1/name").AsString("-"), "beta")
+	assert.True(errors.Is(dynaj.ApplyDelta(old.Freeze(), delta), dynaj.ErrFrozen))
+}
+
+// EOF
@@ -7,19 +7,84 @@
 
 package dynaj // import "tideland.dev/go/dynaj"
 
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+//--------------------
+// COMPARE OPTIONS
+//--------------------
+
+// CompareOption configures the comparison of documents.
+type CompareOption func(d *Diff)
+
+// IgnoreOrder lets the comparison treat arrays as multisets, so the
+// order of their elements does not matter. Without patterns this is
+// done for all arrays, otherwise only for those whose paths match one
+// of the patterns. Unequal arrays are reported with their own path.
+func IgnoreOrder(patterns ...string) CompareOption {
+	return func(d *Diff) {
+		if len(patterns) == 0 {
+			d.ignoreOrder = true
+			return
+		}
+		d.orderPatterns = append(d.orderPatterns, patterns...)
+	}
+}
+
+// IgnorePaths lets the comparison skip the paths matching one of the
+// patterns and all paths below them, e.g. timestamps or versions changing
+// with every update.
+func IgnorePaths(patterns ...string) CompareOption {
+	return func(d *Diff) {
+		d.ignorePatterns = append(d.ignorePatterns, patterns...)
+	}
+}
+
+//--------------------
+// VOLATILE PATHS
+//--------------------
+
+// SetVolatile marks the paths matching the patterns and all paths below
+// them as volatile. They are ignored when comparing the document with
+// others, so repeated comparisons like in reconciliation loops skip
+// known noisy fields. Without patterns the marks are removed. Frozen
+// documents keep their marks.
+func (d *Document) SetVolatile(patterns ...string) {
+	if d.frozen {
+		return
+	}
+	d.volatile = append([]string(nil), patterns...)
+}
+
+// Volatile returns the patterns of the volatile paths.
+func (d *Document) Volatile() []string {
+	return append([]string(nil), d.volatile...)
+}
+
 //--------------------
 // DIFFERENCE
 //--------------------
 
 // Diff manages the two parsed documents and their differences.
 type Diff struct {
-	first  *Document
-	second *Document
-	paths  []string
+	first         *Document
+	second        *Document
+	paths         []string
+	ignoreOrder   bool
+	orderPatterns []string
+
+	ignorePatterns []string
 }
 
 // Compare parses and compares the documents and returns their differences.
-func Compare(first, second []byte) (*Diff, error) {
+func Compare(first, second []byte, opts ...CompareOption) (*Diff, error) {
 	fd, err := Unmarshal(first)
 	if err != nil {
 		return nil, err
@@ -32,6 +97,9 @@ func Compare(first, second []byte) (*Diff, error) {
 		first:  fd,
 		second: sd,
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
 	err = d.compare()
 	if err != nil {
 		return nil, err
@@ -40,11 +108,14 @@ func Compare(first, second []byte) (*Diff, error) {
 }
 
 // CompareDocuments compares the documents and returns their differences.
-func CompareDocuments(first, second *Document) (*Diff, error) {
+func CompareDocuments(first, second *Document, opts ...CompareOption) (*Diff, error) {
 	d := &Diff{
 		first:  first,
 		second: second,
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
 	err := d.compare()
 	if err != nil {
 		return nil, err
@@ -76,11 +147,97 @@ func (d *Diff) DifferenceAt(path string) (*Node, *Node) {
 	return fstNode, sndNode
 }
 
+// ApplyTo copies the values of the second document at the given
+// difference paths into the target document. Paths missing in the
+// second document are deleted in the target. Without paths all
+// differences are applied. This way selected changes can be picked.
+func (d *Diff) ApplyTo(target *Document, paths ...Path) error {
+	differences := map[Path]struct{}{}
+	for _, path := range d.paths {
+		differences[path] = struct{}{}
+	}
+	if len(paths) == 0 {
+		paths = d.paths
+	}
+	deletions := []Keys{}
+	for _, path := range paths {
+		keys := splitPath(path)
+		if _, ok := differences[pathify(keys)]; !ok {
+			return fmt.Errorf("cannot apply path %q: no difference", path)
+		}
+		element, err := elementAt(d.second.root, keys)
+		if err != nil {
+			deletions = append(deletions, keys)
+			continue
+		}
+		if isObjectOrArray(target.NodeAt(path).element) {
+			if err := target.DeleteElementAt(path); err != nil {
+				return fmt.Errorf("cannot apply path %q: %v", path, err)
+			}
+		}
+		if err := target.SetValueAt(path, copyElement(element)); err != nil {
+			return fmt.Errorf("cannot apply path %q: %v", path, err)
+		}
+	}
+	// Delete from the end to keep the array indices valid.
+	sort.Slice(deletions, func(i, j int) bool {
+		return compareKeys(deletions[i], deletions[j]) > 0
+	})
+	for _, keys := range deletions {
+		if _, err := elementAt(target.root, keys); err != nil {
+			// Already missing.
+			continue
+		}
+		if err := target.DeleteElementAt(pathify(keys)); err != nil {
+			return fmt.Errorf("cannot apply path %q: %v", pathify(keys), err)
+		}
+	}
+	return nil
+}
+
+// Ignore removes the paths matching one of the patterns and all paths
+// below them from the differences. It returns the diff for chaining.
+func (d *Diff) Ignore(patterns ...string) *Diff {
+	d.ignorePatterns = append(d.ignorePatterns, patterns...)
+	paths := []Path{}
+	for _, path := range d.paths {
+		if !d.isIgnored(path) {
+			paths = append(paths, path)
+		}
+	}
+	d.paths = paths
+	return d
+}
+
+// isIgnored checks if the path or one of its ancestors matches one of
+// the ignore patterns or the volatile patterns of the documents.
+func (d *Diff) isIgnored(path Path) bool {
+	if len(d.ignorePatterns) == 0 && len(d.first.volatile) == 0 && len(d.second.volatile) == 0 {
+		return false
+	}
+	keys := splitPath(path)
+	for i := len(keys); i >= 0; i-- {
+		ancestor := pathify(keys[:i])
+		for _, patterns := range [][]string{d.ignorePatterns, d.first.volatile, d.second.volatile} {
+			for _, pattern := range patterns {
+				if MatchPattern(pattern, ancestor) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
 // compare iterates over the both documents looking for different
 // values or even paths.
 func (d *Diff) compare() error {
+	unordered := d.compareUnordered()
 	firstPaths := map[string]struct{}{}
 	firstProcessor := func(node *Node) error {
+		if hasAncestorIn(node.path, unordered) || d.isIgnored(node.path) {
+			return nil
+		}
 		firstPaths[node.path] = struct{}{}
 		if !node.Equals(d.second.NodeAt(node.path)) {
 			d.paths = append(d.paths, node.path)
@@ -92,6 +249,9 @@ func (d *Diff) compare() error {
 		return err
 	}
 	secondProcessor := func(node *Node) error {
+		if hasAncestorIn(node.path, unordered) || d.isIgnored(node.path) {
+			return nil
+		}
 		_, ok := firstPaths[node.path]
 		if ok {
 			// Been there, done that.
@@ -103,4 +263,120 @@ func (d *Diff) compare() error {
 	return d.second.Root().Process(secondProcessor)
 }
 
+// compareUnordered compares the arrays existing in both documents which
+// shall be treated as multisets. Unequal ones are added to the paths.
+// The returned set contains the paths of all compared arrays.
+func (d *Diff) compareUnordered() map[Path]struct{} {
+	compared := map[Path]struct{}{}
+	if !d.ignoreOrder && len(d.orderPatterns) == 0 {
+		return compared
+	}
+	var walk func(element Element, path Path)
+	walk = func(element Element, path Path) {
+		if d.isIgnored(path) {
+			return
+		}
+		switch typed := expandRecords(element).(type) {
+		case Object:
+			for key, child := range typed {
+				walk(child, appendKey(path, key))
+			}
+		case Array:
+			if d.isUnordered(path) {
+				other, err := elementAt(d.second.root, splitPath(path))
+				if second, ok := expandRecords(other).(Array); err == nil && ok {
+					compared[path] = struct{}{}
+					if !d.equalUnordered(typed, second) {
+						d.paths = append(d.paths, path)
+					}
+					return
+				}
+			}
+			for idx, child := range typed {
+				walk(child, appendKey(path, strconv.Itoa(idx)))
+			}
+		}
+	}
+	walk(d.first.root, Separator)
+	return compared
+}
+
+// isUnordered checks if the array at the path is a multiset.
+func (d *Diff) isUnordered(path Path) bool {
+	if d.ignoreOrder {
+		return true
+	}
+	for _, pattern := range d.orderPatterns {
+		if MatchPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// equalUnordered compares two arrays as multisets. Nested arrays are
+// compared as multisets too if the order is ignored globally.
+func (d *Diff) equalUnordered(first, second Array) bool {
+	if len(first) != len(second) {
+		return false
+	}
+	used := make([]bool, len(second))
+	for _, fe := range first {
+		found := false
+		for idx, se := range second {
+			if !used[idx] && d.equalElements(fe, se) {
+				used[idx] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// equalElements compares two elements inside of an unordered array.
+func (d *Diff) equalElements(first, second Element) bool {
+	if !d.ignoreOrder {
+		return equalElements(first, second)
+	}
+	second = expandRecords(second)
+	switch ft := expandRecords(first).(type) {
+	case Object:
+		st, ok := second.(Object)
+		if !ok || len(ft) != len(st) {
+			return false
+		}
+		for key, fe := range ft {
+			se, ok := st[key]
+			if !ok || !d.equalElements(fe, se) {
+				return false
+			}
+		}
+		return true
+	case Array:
+		st, ok := second.(Array)
+		return ok && d.equalUnordered(ft, st)
+	default:
+		return equalElements(first, second)
+	}
+}
+
+// hasAncestorIn checks if the path or one of its ancestors is
+// contained in the set of paths.
+func hasAncestorIn(path Path, paths map[Path]struct{}) bool {
+	if len(paths) == 0 {
+		return false
+	}
+	keys := splitPath(path)
+	for i := 0; i <= len(keys); i++ {
+		if _, ok := paths[pathify(keys[:i])]; ok {
+			return true
+		}
+	}
+	return false
+}
+
 // EOF
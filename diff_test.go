@@ -12,6 +12,7 @@ package dynaj_test
 //--------------------
 
 import (
+	"sort"
 	"testing"
 
 	"tideland.dev/go/audit/asserts"
@@ -90,4 +91,243 @@ func TestCompare(t *testing.T) {
 	assert.Length(diff.Differences(), 4)
 }
 
+// TestIgnoreOrder tests comparing arrays as multisets.
+func TestIgnoreOrder(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := []byte(`{"tags":["a","b","c"],"ids":[1,2,3],"users":[{"name":"x","roles":["r1","r2"]},{"name":"y"}]}`)
+	second := []byte(`{"tags":["c","a","b"],"ids":[3,2,1],"users":[{"name":"y"},{"name":"x","roles":["r2","r1"]}]}`)
+
+	// Ordered comparison.
+	diff, err := dynaj.Compare(first, second)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 11)
+
+	// Globally unordered.
+	diff, err = dynaj.Compare(first, second, dynaj.IgnoreOrder())
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 0)
+
+	// Unordered by patterns.
+	diff, err = dynaj.Compare(first, second, dynaj.IgnoreOrder("/tags", "/users"))
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 3)
+	assert.Contains("/users", diff.Differences())
+	diff, err = dynaj.Compare(first, second, dynaj.IgnoreOrder("/tags"), dynaj.IgnoreOrder("/ids"))
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 6)
+
+	// Unequal multisets and type changes.
+	second = []byte(`{"tags":["a","b","b"],"ids":{"x":1},"users":[{"name":"y"},{"name":"x","roles":["r2","r1"]}]}`)
+	firstDoc, err := dynaj.Unmarshal(first)
+	assert.NoError(err)
+	secondDoc, err := dynaj.Unmarshal(second)
+	assert.NoError(err)
+	diff, err = dynaj.CompareDocuments(firstDoc, secondDoc, dynaj.IgnoreOrder())
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 5)
+	assert.Contains("/tags", diff.Differences())
+	assert.Contains("/ids/x", diff.Differences())
+}
+
+// TestApplyTo tests applying selected differences.
+func TestApplyTo(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := []byte(`{"a":1,"b":{"x":"foo","y":[1,2,3]},"c":true}`)
+	second := []byte(`{"a":2,"b":{"x":"bar","y":[1]},"d":{"e":null}}`)
+
+	diff, err := dynaj.Compare(first, second)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 6)
+
+	// Cherry-pick some changes.
+	target, err := dynaj.Unmarshal(first)
+	assert.NoError(err)
+	err = diff.ApplyTo(target, "/a", "/d/e", "/b/y/1")
+	assert.NoError(err)
+	assert.Equal(target.String(), `{"a":2,"b":{"x":"foo","y":[1,3]},"c":true,"d":{"e":null}}`)
+
+	// Apply all changes.
+	target, err = dynaj.Unmarshal(first)
+	assert.NoError(err)
+	err = diff.ApplyTo(target)
+	assert.NoError(err)
+	assert.Equal(target.String(), string(second))
+
+	// Documents of the diff stay unchanged.
+	assert.Equal(diff.FirstDocument().String(), `{"a":1,"b":{"x":"foo","y":[1,2,3]},"c":true}`)
+
+	// Empty containers replace filled ones.
+	diff, err = dynaj.Compare([]byte(`{"a":[1,2]}`), []byte(`{"a":[]}`))
+	assert.NoError(err)
+	target = diff.FirstDocument()
+	err = diff.ApplyTo(target, "/a")
+	assert.NoError(err)
+	assert.Equal(target.String(), `{"a":[]}`)
+
+	// Provoke errors.
+	target, err = dynaj.Unmarshal(first)
+	assert.NoError(err)
+	err = diff.ApplyTo(target, "/c")
+	assert.ErrorContains(err, `cannot apply path "/c": no difference`)
+	err = diff.ApplyTo(target.Freeze(), "/a")
+	assert.ErrorContains(err, dynaj.ErrFrozen.Error())
+}
+
+// TestFormat tests the human-readable formatting of differences.
+func TestFormat(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := []byte(`{"a":1,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":[1,2]}`)
+	second := []byte(`{"a":0,"b":2,"c":3,"d":4,"e":5,"f":6,"g":7,"h":[1],"i":true}`)
+
+	diff, err := dynaj.Compare(first, second)
+	assert.NoError(err)
+
+	unified := diff.Format(dynaj.UnifiedDiff)
+	assert.Equal(unified, `--- first
++++ second
+@@ -1,5 +1,5 @@
+ {
+-  "a": 1,
++  "a": 0,
+   "b": 2,
+   "c": 3,
+   "d": 4,
+@@ -7,7 +7,7 @@
+   "f": 6,
+   "g": 7,
+   "h": [
+-    1,
+-    2
+-  ]
++    1
++  ],
++  "i": true
+ }
+`)
+
+	sideBySide := diff.Format(dynaj.SideBySideDiff)
+	assert.Equal(sideBySide, `{           {
+  "a": 1, |   "a": 0,
+  "b": 2,     "b": 2,
+  "c": 3,     "c": 3,
+  "d": 4,     "d": 4,
+  "e": 5,     "e": 5,
+  "f": 6,     "f": 6,
+  "g": 7,     "g": 7,
+  "h": [      "h": [
+    1,    |     1
+    2     |   ],
+  ]       |   "i": true
+}           }
+`)
+
+	// Added and removed lines only.
+	diff, err = dynaj.Compare([]byte(`[1]`), []byte(`[1,2,3]`))
+	assert.NoError(err)
+	sideBySide = diff.Format(dynaj.SideBySideDiff)
+	assert.Equal(sideBySide, "[     [\n  1 |   1,\n    >   2,\n    >   3\n]     ]\n")
+
+	// No differences.
+	diff, err = dynaj.Compare(first, first)
+	assert.NoError(err)
+	assert.Equal(diff.Format(dynaj.UnifiedDiff), "--- first\n+++ second\n")
+}
+
+// TestSummary tests counting the differences.
+func TestSummary(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := []byte(`{
+		"spec": {"replicas": 1, "image": "app:1", "ports": [80]},
+		"meta": {"name": "app", "labels": {"a": "1"}},
+		"status": "ok"
+	}`)
+	second := []byte(`{
+		"spec": {"replicas": "2", "image": "app:2", "ports": [80, 443]},
+		"meta": {"name": "app"},
+		"status": "ok",
+		"extra": true
+	}`)
+
+	diff, err := dynaj.Compare(first, second)
+	assert.NoError(err)
+	summary := diff.Summary()
+	assert.Equal(summary.DiffCounts, dynaj.DiffCounts{
+		Added:        2,
+		Removed:      1,
+		Changed:      2,
+		TypeChanged:  1,
+		ValueChanged: 1,
+	})
+	assert.Equal(summary.Total(), 5)
+	assert.Equal(summary.SortedKeys(), []string{"extra", "meta", "spec"})
+	assert.Equal(summary.Keys["spec"], dynaj.DiffCounts{
+		Added:        1,
+		Changed:      2,
+		TypeChanged:  1,
+		ValueChanged: 1,
+	})
+	assert.Equal(summary.String(), `+2 -1 ~2 (type 1, value 1)
+/extra: +1 -0 ~0 (type 0, value 0)
+/meta: +0 -1 ~0 (type 0, value 0)
+/spec: +1 -0 ~2 (type 1, value 1)`)
+
+	// Root values.
+	diff, err = dynaj.Compare([]byte(`1`), []byte(`"1"`))
+	assert.NoError(err)
+	summary = diff.Summary()
+	assert.Equal(summary.Keys[""].TypeChanged, 1)
+
+	// No differences.
+	diff, err = dynaj.Compare(first, first)
+	assert.NoError(err)
+	summary = diff.Summary()
+	assert.Equal(summary.Total(), 0)
+	assert.Length(summary.Keys, 0)
+}
+
+// TestIgnore tests skipping volatile paths.
+func TestIgnore(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := []byte(`{
+		"spec": {"replicas": 1},
+		"status": {"lastUpdated": "10:00", "ready": true},
+		"metadata": {"resourceVersion": "1", "managedFields": [{"a": 1}]}
+	}`)
+	second := []byte(`{
+		"spec": {"replicas": 2},
+		"status": {"lastUpdated": "10:05", "ready": true},
+		"metadata": {"resourceVersion": "2", "managedFields": [{"a": 2}, {"b": 3}]}
+	}`)
+
+	// Ignore existing differences.
+	diff, err := dynaj.Compare(first, second)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 5)
+	diff = diff.Ignore("/status/lastUpdated", "/metadata/resourceVersion")
+	differences := diff.Differences()
+	sort.Strings(differences)
+	assert.Equal(differences, []string{"/metadata/managedFields/0/a", "/metadata/managedFields/1/b", "/spec/replicas"})
+
+	// Ignore during comparison including all paths below.
+	diff, err = dynaj.Compare(first, second, dynaj.IgnorePaths("/status/lastUpdated", "/metadata"))
+	assert.NoError(err)
+	assert.Equal(diff.Differences(), []string{"/spec/replicas"})
+
+	// Volatile paths of the documents.
+	desired := mustUnmarshal(assert, string(first))
+	desired.SetVolatile("/status/*", "/metadata")
+	assert.Equal(desired.Volatile(), []string{"/status/*", "/metadata"})
+	actual := mustUnmarshal(assert, string(second))
+	diff, err = dynaj.CompareDocuments(desired, actual)
+	assert.NoError(err)
+	assert.Equal(diff.Differences(), []string{"/spec/replicas"})
+	diff, err = dynaj.CompareDocuments(actual, desired.Freeze(), dynaj.IgnoreOrder())
+	assert.NoError(err)
+	assert.Equal(diff.Differences(), []string{"/spec/replicas"})
+	desired.SetVolatile()
+	diff, err = dynaj.CompareDocuments(desired, actual)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 5)
+}
+
 // EOF
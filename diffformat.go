@@ -0,0 +1,252 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// DiffStyle defines how differences are formatted.
+type DiffStyle int
+
+// Styles for formatting differences.
+const (
+	// UnifiedDiff formats the differences like diff -u with removed
+	// lines prefixed by "-" and added ones by "+".
+	UnifiedDiff DiffStyle = iota
+
+	// SideBySideDiff formats both documents in two columns like sdiff.
+	// Changed lines are marked with "|", removed with "<", and added
+	// with ">".
+	SideBySideDiff
+)
+
+// contextLines is the number of unchanged lines around changes in
+// unified diffs.
+const contextLines = 3
+
+//--------------------
+// DIFF FORMATTING
+//--------------------
+
+// lineOp is one operation of a line diff.
+type lineOp struct {
+	kind  byte
+	left  string
+	right string
+}
+
+// Format renders the differences of the indented marshaled documents
+// in the given style.
+func (d *Diff) Format(style DiffStyle) string {
+	left, err := indentedLines(d.first)
+	if err != nil {
+		return fmt.Sprintf("cannot format diff: %v", err)
+	}
+	right, err := indentedLines(d.second)
+	if err != nil {
+		return fmt.Sprintf("cannot format diff: %v", err)
+	}
+	ops := diffLines(left, right)
+	if style == SideBySideDiff {
+		return formatSideBySide(ops)
+	}
+	return formatUnified(ops)
+}
+
+// indentedLines marshals the document indented and splits it into lines.
+func indentedLines(doc *Document) ([]string, error) {
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, "", "  "); err != nil {
+		return nil, err
+	}
+	return strings.Split(buf.String(), "\n"), nil
+}
+
+// diffLines computes the line operations using the longest common
+// subsequence of both line lists.
+func diffLines(left, right []string) []lineOp {
+	n, m := len(left), len(right)
+	lcs := make([][]int, n+1)
+	for i := range lcs {
+		lcs[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case left[i] == right[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	ops := []lineOp{}
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && left[i] == right[j]:
+			ops = append(ops, lineOp{' ', left[i], right[j]})
+			i++
+			j++
+		case j >= m || (i < n && lcs[i+1][j] >= lcs[i][j+1]):
+			ops = append(ops, lineOp{'-', left[i], ""})
+			i++
+		default:
+			ops = append(ops, lineOp{'+', "", right[j]})
+			j++
+		}
+	}
+	return ops
+}
+
+// formatUnified renders the operations as unified diff with hunks.
+func formatUnified(ops []lineOp) string {
+	var b strings.Builder
+	b.WriteString("--- first\n+++ second\n")
+	for start := 0; start < len(ops); {
+		// Find the next change.
+		for start < len(ops) && ops[start].kind == ' ' {
+			start++
+		}
+		if start == len(ops) {
+			break
+		}
+		// Extend the hunk while changes are close to each other.
+		end := start
+		for end < len(ops) {
+			if ops[end].kind != ' ' {
+				end++
+				continue
+			}
+			next := end
+			for next < len(ops) && ops[next].kind == ' ' {
+				next++
+			}
+			if next == len(ops) || next-end > 2*contextLines {
+				break
+			}
+			end = next
+		}
+		from := start - contextLines
+		if from < 0 {
+			from = 0
+		}
+		to := end + contextLines
+		if to > len(ops) {
+			to = len(ops)
+		}
+		leftStart, rightStart := linePositions(ops, from)
+		leftCount, rightCount := 0, 0
+		for _, op := range ops[from:to] {
+			if op.kind != '+' {
+				leftCount++
+			}
+			if op.kind != '-' {
+				rightCount++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%d,%d +%d,%d @@\n", leftStart, leftCount, rightStart, rightCount)
+		for _, op := range ops[from:to] {
+			switch op.kind {
+			case '-':
+				b.WriteString("-" + op.left + "\n")
+			case '+':
+				b.WriteString("+" + op.right + "\n")
+			default:
+				b.WriteString(" " + op.left + "\n")
+			}
+		}
+		start = to
+	}
+	return b.String()
+}
+
+// linePositions returns the one-based line numbers in the left and
+// right document at the given operation.
+func linePositions(ops []lineOp, at int) (int, int) {
+	left, right := 1, 1
+	for _, op := range ops[:at] {
+		if op.kind != '+' {
+			left++
+		}
+		if op.kind != '-' {
+			right++
+		}
+	}
+	return left, right
+}
+
+// formatSideBySide renders the operations in two columns. Removed and
+// added lines following each other are shown as changed lines.
+func formatSideBySide(ops []lineOp) string {
+	type row struct {
+		marker      byte
+		left, right string
+	}
+	rows := []row{}
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			rows = append(rows, row{' ', ops[i].left, ops[i].right})
+			i++
+			continue
+		}
+		// Pair removed and added lines of one block.
+		removed, added := []string{}, []string{}
+		for ; i < len(ops) && ops[i].kind != ' '; i++ {
+			if ops[i].kind == '-' {
+				removed = append(removed, ops[i].left)
+			} else {
+				added = append(added, ops[i].right)
+			}
+		}
+		for k := 0; k < len(removed) || k < len(added); k++ {
+			switch {
+			case k < len(removed) && k < len(added):
+				rows = append(rows, row{'|', removed[k], added[k]})
+			case k < len(removed):
+				rows = append(rows, row{'<', removed[k], ""})
+			default:
+				rows = append(rows, row{'>', "", added[k]})
+			}
+		}
+	}
+	width := 0
+	for _, r := range rows {
+		if l := utf8.RuneCountInString(r.left); l > width {
+			width = l
+		}
+	}
+	var b strings.Builder
+	for _, r := range rows {
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(r.left))
+		line := fmt.Sprintf("%s%s %c %s", r.left, padding, r.marker, r.right)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	return b.String()
+}
+
+// EOF
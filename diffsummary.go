@@ -0,0 +1,116 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//--------------------
+// DIFF SUMMARY
+//--------------------
+
+// DiffCounts contains the numbers of different paths. Changed paths
+// exist in both documents and are split into those whose value has a
+// different type and those with only a different value.
+type DiffCounts struct {
+	Added        int
+	Removed      int
+	Changed      int
+	TypeChanged  int
+	ValueChanged int
+}
+
+// Total returns the number of all different paths.
+func (c DiffCounts) Total() int {
+	return c.Added + c.Removed + c.Changed
+}
+
+// String implements fmt.Stringer.
+func (c DiffCounts) String() string {
+	return fmt.Sprintf("+%d -%d ~%d (type %d, value %d)",
+		c.Added, c.Removed, c.Changed, c.TypeChanged, c.ValueChanged)
+}
+
+// add counts one difference.
+func (c *DiffCounts) add(first, second Element, inFirst, inSecond bool) {
+	switch {
+	case !inFirst:
+		c.Added++
+	case !inSecond:
+		c.Removed++
+	default:
+		c.Changed++
+		if typeName(first) != typeName(second) {
+			c.TypeChanged++
+		} else {
+			c.ValueChanged++
+		}
+	}
+}
+
+// DiffSummary contains the counts of all differences and grouped by the
+// top-level keys of their paths. Differences of the root itself are
+// grouped by the empty key.
+type DiffSummary struct {
+	DiffCounts
+	Keys map[string]DiffCounts
+}
+
+// SortedKeys returns the top-level keys of the groups in order.
+func (s DiffSummary) SortedKeys() []string {
+	keys := make([]string, 0, len(s.Keys))
+	for key := range s.Keys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// String implements fmt.Stringer. It returns the counts of all
+// differences followed by one line per top-level key.
+func (s DiffSummary) String() string {
+	var sb strings.Builder
+	sb.WriteString(s.DiffCounts.String())
+	for _, key := range s.SortedKeys() {
+		fmt.Fprintf(&sb, "\n%s: %v", appendKey(Separator, key), s.Keys[key])
+	}
+	return sb.String()
+}
+
+// Summary counts the added, removed, and changed paths, so large
+// differences can be shown as overview.
+func (d *Diff) Summary() DiffSummary {
+	summary := DiffSummary{
+		Keys: map[string]DiffCounts{},
+	}
+	for _, path := range d.paths {
+		keys := splitPath(path)
+		first, ferr := elementAt(d.first.root, keys)
+		second, serr := elementAt(d.second.root, keys)
+		first, _ = decodeRaw(first)
+		second, _ = decodeRaw(second)
+		top := ""
+		if len(keys) > 0 {
+			top = keys[0]
+		}
+		counts := summary.Keys[top]
+		counts.add(first, second, ferr == nil, serr == nil)
+		summary.Keys[top] = counts
+		summary.add(first, second, ferr == nil, serr == nil)
+	}
+	return summary
+}
+
+// EOF
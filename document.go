@@ -12,8 +12,11 @@ package dynaj // import "tideland.dev/go/dynaj"
 //--------------------
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"sync"
 )
 
 //--------------------
@@ -22,20 +25,101 @@ import (
 
 // Document represents one JSON document.
 type Document struct {
-	root Element
+	mu        sync.Mutex
+	root      Element
+	nonFinite NonFinitePolicy
+	frozen    bool
+	version   string
+
+	incremental bool
+	encodings   map[encodingKey][]byte
+
+	recording  bool
+	operations []Operation
+
+	strict      bool
+	arrays      ArrayPolicy
+	typesLocked bool
+
+	numbers NumberFormat
+	bools   BoolTable
+	units   map[string]UnitTable
+
+	emptyUndefined bool
+
+	volatile []string
+
+	caseFolding bool
+	sortedKeys  bool
+	suggestions bool
+
+	stats   *accessStats
+	metrics MetricsSink
+
+	shared bool
+	owned  map[uintptr]struct{}
+
+	hooks    []*changeHook
+	setHooks []setHook
+
+	restriction *restriction
+}
+
+// reset turns the document into an empty one with the default settings
+// like a new document, e.g. before reusing it.
+func (d *Document) reset() {
+	*d = Document{}
 }
 
 // Unmarshal parses the JSON-encoded data and stores the result
-// as new document.
-func Unmarshal(data []byte) (*Document, error) {
-	var root any
-	err := json.Unmarshal(data, &root)
+// as new document. The options allow to choose the decoder, limits,
+// and the settings of the document. Byte order marks are stripped and
+// UTF-16 or UTF-32 encoded data is transcoded into UTF-8.
+func Unmarshal(data []byte, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+	root, err := o.decode(data)
 	if err != nil {
 		return nil, fmt.Errorf("cannot unmarshal document: %v", err)
 	}
-	return &Document{
-		root: root,
-	}, nil
+	return o.newDocument(root), nil
+}
+
+// UnmarshalAll parses the concatenated JSON values of the data, like in
+// log files or streams, and returns them as elements of the root array
+// of a new document. Values may be separated by whitespace. Unmarshal
+// instead rejects any data after the first value.
+func UnmarshalAll(data []byte, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+	if o.limits.MaxBytes > 0 && len(data) > o.limits.MaxBytes {
+		return nil, fmt.Errorf("cannot unmarshal document: data length %d exceeds limit of %d bytes", len(data), o.limits.MaxBytes)
+	}
+	data, err := toUTF8(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal document: %v", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	root := Array{}
+	for {
+		var raw json.RawMessage
+		err := dec.Decode(&raw)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshal document: value %d: %v", len(root), err)
+		}
+		element, err := o.decode(raw)
+		if err != nil {
+			return nil, fmt.Errorf("cannot unmarshal document: value %d: %v", len(root), err)
+		}
+		root = append(root, element)
+	}
+	return o.newDocument(root), nil
+}
+
+// New creates a new empty document with the settings of the options.
+func New(opts ...Option) *Document {
+	return newOptions(opts).newDocument(nil)
 }
 
 // NewDocument creates a new empty document.
@@ -43,14 +127,90 @@ func NewDocument() *Document {
 	return &Document{}
 }
 
+// Freeze returns an immutable copy of the document. Its mutation
+// methods return ErrFrozen, so it can be shared and read by many
+// goroutines without locking. Freezing a frozen document returns
+// the document itself.
+func (d *Document) Freeze() *Document {
+	if d.frozen {
+		return d
+	}
+	frozen := d.derive(copyElement(d.root))
+	frozen.frozen = true
+	return frozen
+}
+
+// Snapshot returns a frozen view of the current content in constant
+// time. Unlike Freeze nothing is copied, the document and the snapshot
+// share their tree. Following mutations of the document copy the changed
+// containers on their paths, so readers keep a consistent view while a
+// writer continues to change the document. Snapshots have to be taken
+// by the writer.
+func (d *Document) Snapshot() *Document {
+	if d.frozen {
+		return d
+	}
+	snapshot := d.derive(d.root)
+	snapshot.frozen = true
+	d.shared = true
+	d.owned = nil
+	return snapshot
+}
+
+// derive creates a new document with the root and the settings of
+// the document for reading and marshalling its values.
+func (d *Document) derive(root Element) *Document {
+	return &Document{
+		root:           root,
+		nonFinite:      d.nonFinite,
+		numbers:        d.numbers,
+		bools:          d.bools,
+		units:          d.units,
+		emptyUndefined: d.emptyUndefined,
+		volatile:       d.volatile,
+		caseFolding:    d.caseFolding,
+		sortedKeys:     d.sortedKeys,
+		suggestions:    d.suggestions,
+		stats:          d.stats,
+		metrics:        d.metrics,
+	}
+}
+
+// IsFrozen returns true if the document is frozen.
+func (d *Document) IsFrozen() bool {
+	return d.frozen
+}
+
+// SetNonFinitePolicy sets how NaN and infinite floats are handled
+// when setting values or marshalling the document. Default is to
+// reject them. Frozen documents keep their policy.
+func (d *Document) SetNonFinitePolicy(policy NonFinitePolicy) {
+	if d.frozen {
+		return
+	}
+	d.nonFinite = policy
+	d.changed(nil)
+}
+
+// SetSortedKeys enables or disables the traversal of object keys in
+// sorted order by Process, Range, and Query, so processing and query
+// results are reproducible. Marshalling always sorts the keys. Frozen
+// documents keep their setting.
+func (d *Document) SetSortedKeys(enabled bool) {
+	if d.frozen {
+		return
+	}
+	d.sortedKeys = enabled
+}
+
 // Length returns the number of elements for the given path.
 func (d *Document) Length(path Path) int {
 	node, err := elementAt(d.root, splitPath(path))
-	if err != nil {
+	if err != nil || !d.readable(path, node) {
 		return -1
 	}
 	// Return len based on type.
-	switch n := node.(type) {
+	switch n := decodedElement(node).(type) {
 	case Object:
 		return len(n)
 	case Array:
@@ -60,27 +220,94 @@ func (d *Document) Length(path Path) int {
 	}
 }
 
-// SetValueAt sets the value at the given path.
+// SetValueAt sets the value at the given path. Values not matching the
+// JSON types, like structs or maps with other keys than strings, are
+// normalized before. NaN and infinite floats are handled according
+// to the non-finite policy.
 func (d *Document) SetValueAt(path Path, value Value) error {
+	return d.setValueAt(path, value, false)
+}
+
+// setValueAt sets the value at the given path. If forced locked types
+// are ignored.
+func (d *Document) setValueAt(path Path, value Value, force bool) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	d.countWrite(path)
 	keys := splitPath(path)
-	root, err := insertValue(d.root, keys, value)
+	value, err := normalizeValue(value, pathify(keys), d.nonFinite)
+	if err != nil {
+		return fmt.Errorf("cannot insert value at %q: %v", path, err)
+	}
+	if err := d.checkAccess(keys); err != nil {
+		return err
+	}
+	if !force {
+		if err := d.checkTypeLock(keys, value); err != nil {
+			return err
+		}
+	}
+	old := d.currentValue(keys)
+	if err := d.callHooks(BeforeSet, pathify(keys), old, value); err != nil {
+		return err
+	}
+	if err := d.insertAt(keys, value); err != nil {
+		return err
+	}
+	d.record(SetOperation, path, value)
+	return d.callHooks(AfterSet, pathify(keys), old, value)
+}
+
+// replaceValueAt replaces the existing element at the given path by the
+// normalized value, regardless if it is a value or a container element.
+// Access, locked types, and hooks are handled like by setValueAt.
+func (d *Document) replaceValueAt(path Path, value Value) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	d.countWrite(path)
+	keys := splitPath(path)
+	if err := d.checkAccess(keys); err != nil {
+		return err
+	}
+	if err := d.checkTypeLock(keys, value); err != nil {
+		return err
+	}
+	old := d.currentValue(keys)
+	if err := d.callHooks(BeforeSet, pathify(keys), old, value); err != nil {
+		return err
+	}
+	d.unshare(keys)
+	root, err := replaceElement(d.root, keys, value)
 	if err != nil {
 		return err
 	}
 	d.root = root
-	return nil
+	d.changed(keys)
+	d.record(SetOperation, path, value)
+	return d.callHooks(AfterSet, pathify(keys), old, value)
 }
 
 // DeleteValueAt deletes the value at the given path. If it is inside
 // an object the key is deleted, if it is inside an array the elements
 // are shifted.
 func (d *Document) DeleteValueAt(path Path) error {
+	if d.frozen {
+		return ErrFrozen
+	}
 	keys := splitPath(path)
+	if err := d.checkAccess(keys); err != nil {
+		return err
+	}
+	d.unshare(keys)
 	root, err := deleteElement(d.root, keys, false)
 	if err != nil {
 		return err
 	}
 	d.root = root
+	d.changed(keys)
+	d.record(DeleteValueOperation, path, nil)
 	return nil
 }
 
@@ -88,25 +315,50 @@ func (d *Document) DeleteValueAt(path Path) error {
 // element out of the document tree, regardless if it is a value or
 // a container element.
 func (d *Document) DeleteElementAt(path Path) error {
+	if d.frozen {
+		return ErrFrozen
+	}
 	keys := splitPath(path)
+	if err := d.checkAccess(keys); err != nil {
+		return err
+	}
+	d.unshare(keys)
 	root, err := deleteElement(d.root, keys, true)
 	if err != nil {
 		return err
 	}
 	d.root = root
+	d.changed(keys)
+	d.record(DeleteElementOperation, path, nil)
 	return nil
 }
 
 // NodeAt returns the addressed value.
 func (d *Document) NodeAt(path Path) *Node {
+	d.countRead(path)
 	node := &Node{
 		path: path,
+		doc:  d,
 	}
 	element, err := elementAt(d.root, splitPath(path))
-	if err != nil {
+	if err != nil && d.caseFolding {
+		element, err = elementAtFolded(d.root, splitPath(path))
+	}
+	if !d.readable(path, element) {
+		// Denied reads are undefined.
+		return node
+	}
+	switch {
+	case err == nil:
+		node.element = decodedElement(element)
+	case d.suggestions:
+		if perr := newPathError(d.root, path); perr != nil {
+			node.err = perr
+			break
+		}
+		fallthrough
+	default:
 		node.err = fmt.Errorf("invalid path %q: %v", path, err)
-	} else {
-		node.element = element
 	}
 	return node
 }
@@ -115,25 +367,81 @@ func (d *Document) NodeAt(path Path) *Node {
 func (d *Document) Root() *Node {
 	return &Node{
 		path:    Separator,
-		element: d.root,
+		element: decodedElement(d.root),
+		doc:     d,
 	}
 }
 
-// Clear removes the document data.
+// Clear removes the document data. Frozen documents and restricted
+// documents without write access to the root are not cleared.
 func (d *Document) Clear() {
+	if d.frozen || d.checkAccess(Keys{}) != nil {
+		return
+	}
 	d.root = nil
+	d.shared = false
+	d.owned = nil
+	d.changed(nil)
+	d.record(ClearOperation, "", nil)
 }
 
-// MarshalJSON implements json.Marshaler.
+// changed is called after each mutation of the document with the keys
+// of the changed path. Without keys the whole document is changed.
+func (d *Document) changed(keys Keys) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.version = ""
+	d.invalidateEncodings(keys)
+	d.notifyChange()
+}
+
+// MarshalJSON implements json.Marshaler. NaN and infinite floats are
+// handled according to the non-finite policy.
 func (d *Document) MarshalJSON() ([]byte, error) {
-	return json.Marshal(d.root)
+	if d.incremental {
+		return d.appendIncremental(nil)
+	}
+	root, err := normalizeValue(d.root, Separator, d.nonFinite)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal document: %v", err)
+	}
+	return json.Marshal(root)
+}
+
+// MarshalJSONAt marshals only the element at the given path. NaN
+// and infinite floats are handled according to the non-finite policy.
+func (d *Document) MarshalJSONAt(path Path) ([]byte, error) {
+	keys := splitPath(path)
+	element, err := elementAt(d.root, keys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", path, err)
+	}
+	element, err = normalizeValue(element, pathify(keys), d.nonFinite)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal element at %q: %v", path, err)
+	}
+	return json.Marshal(element)
+}
+
+// MarshalIndentJSONAt is like MarshalJSONAt but applies json.Indent
+// with the prefix and indent to format the output.
+func (d *Document) MarshalIndentJSONAt(path Path, prefix, indent string) ([]byte, error) {
+	data, err := d.MarshalJSONAt(path)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, data, prefix, indent); err != nil {
+		return nil, fmt.Errorf("cannot marshal element at %q: %v", path, err)
+	}
+	return buf.Bytes(), nil
 }
 
 // String implements fmt.Stringer.
 func (d *Document) String() string {
-	data, err := json.Marshal(d.root)
+	data, err := d.MarshalJSON()
 	if err != nil {
-		return fmt.Sprintf("cannot marshal document: %v", err)
+		return err.Error()
 	}
 	return string(data)
 }
@@ -11,6 +11,10 @@ package dynaj // import "tideland.dev/go/dynaj"
 // IMPORTS
 //--------------------
 
+import (
+	"errors"
+)
+
 //--------------------
 // CONSTANTS
 //--------------------
@@ -20,6 +24,42 @@ const (
 	Separator = "/"
 )
 
+//--------------------
+// ERRORS
+//--------------------
+
+var (
+	// ErrFrozen is returned when trying to change a frozen document.
+	ErrFrozen = errors.New("document is frozen")
+
+	// ErrAssertion is returned when an asserted value does not match.
+	ErrAssertion = errors.New("assertion failed")
+
+	// ErrConflict is returned when a conditional update finds an
+	// unexpected version.
+	ErrConflict = errors.New("version conflict")
+
+	// ErrPathNotFound is returned when a path does not exist but is
+	// needed, e.g. for setting values in strict mode.
+	ErrPathNotFound = errors.New("path not found")
+
+	// ErrRange is returned when a number does not fit into the
+	// requested type.
+	ErrRange = errors.New("value out of range")
+
+	// ErrTypeChange is returned when setting a value would change the
+	// type of an existing value while the types are locked.
+	ErrTypeChange = errors.New("type change")
+
+	// ErrSignature is returned when the signature of a signed document
+	// is missing or does not match its content.
+	ErrSignature = errors.New("invalid signature")
+
+	// ErrForbidden is returned when the access rules of a restricted
+	// document deny writing at a path.
+	ErrForbidden = errors.New("access forbidden")
+)
+
 //--------------------
 // TYPES
 //--------------------
@@ -229,6 +229,13 @@ func TestDeleteElementAt(t *testing.T) {
 	node = doc.NodeAt("/obj")
 	assert.ErrorContains(node.Err(), "invalid path")
 
+	err = doc.DeleteElementAt("/arr/2")
+	assert.NoError(err)
+	err = doc.DeleteElementAt("/arr/0")
+	assert.NoError(err)
+	assert.Equal(doc.Length("/arr"), 1)
+	assert.Equal(doc.NodeAt("/arr/0").AsString(""), "bar")
+
 	err = doc.DeleteElementAt("/arr")
 	assert.NoError(err)
 	node = doc.NodeAt("/arr")
@@ -312,6 +319,21 @@ func TestNotFound(t *testing.T) {
 	assert.ErrorContains(node.Err(), "invalid path")
 }
 
+// TestKeys tests retrieving the keys of objects and arrays.
+func TestKeys(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+	keys := doc.Root().Keys()
+	assert.Equal(keys, dynaj.Keys{"A", "B", "D", "T"})
+	keys = doc.NodeAt("/B").Keys()
+	assert.Equal(keys, dynaj.Keys{"0", "1", "2"})
+	keys = doc.NodeAt("/A").Keys()
+	assert.Length(keys, 0)
+}
+
 // TestString verifies the string representation of a document.
 func TestString(t *testing.T) {
 	assert := asserts.NewTesting(t, asserts.FailStop)
@@ -441,6 +463,35 @@ func TestMarshalJSON(t *testing.T) {
 	assert.Equal(bsOut, bsIn)
 }
 
+// TestMarshalJSONAt tests marshalling subtrees.
+func TestMarshalJSONAt(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc, err := dynaj.Unmarshal([]byte(`{"data":{"items":[1,{"a":"b"}],"n":null},"raw":0}`))
+	assert.NoError(err)
+
+	bs, err := doc.MarshalJSONAt("/data/items")
+	assert.NoError(err)
+	assert.Equal(string(bs), `[1,{"a":"b"}]`)
+	bs, err = doc.MarshalJSONAt("/data/n")
+	assert.NoError(err)
+	assert.Equal(string(bs), `null`)
+	bs, err = doc.MarshalJSONAt("/")
+	assert.NoError(err)
+	assert.Equal(string(bs), doc.String())
+	bs, err = doc.MarshalIndentJSONAt("/data/items/1", "", "  ")
+	assert.NoError(err)
+	assert.Equal(string(bs), "{\n  \"a\": \"b\"\n}")
+
+	err = doc.SetRawAt("/raw", []byte(` { "x" : [ 1 , 2 ] } `))
+	assert.NoError(err)
+	bs, err = doc.MarshalJSONAt("/raw")
+	assert.NoError(err)
+	assert.Equal(string(bs), `{"x":[1,2]}`)
+
+	_, err = doc.MarshalJSONAt("/data/missing")
+	assert.ErrorContains(err, "invalid path")
+}
+
 //--------------------
 // HELPERS
 //--------------------
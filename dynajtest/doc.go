@@ -0,0 +1,25 @@
+// Tideland Go Dynamic JSON - Testing
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package dynajtest helps testing code producing JSON documents. Instead
+// of comparing marshalled strings the documents are compared path by
+// path and failures report the differing paths and values.
+//
+//	dynajtest.AssertEqual(t, want, got, "/meta/timestamp")
+//	dynajtest.AssertGolden(t, got, "testdata/response.json")
+//
+// Golden files are written instead of compared if the environment
+// variable DYNAJ_UPDATE_GOLDEN is set. Expectations about single paths
+// are described by matchers.
+//
+//	dynajtest.AssertMatch(t, got,
+//		dynajtest.HasPath("/id"),
+//		dynajtest.PathEquals("/status", "active"),
+//	)
+package dynajtest // import "tideland.dev/go/dynaj/dynajtest"
+
+// EOF
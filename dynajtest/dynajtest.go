@@ -0,0 +1,145 @@
+// Tideland Go Dynamic JSON - Testing
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynajtest // import "tideland.dev/go/dynaj/dynajtest"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// UpdateEnv is the environment variable which lets AssertGolden write
+// the golden files instead of comparing them.
+const UpdateEnv = "DYNAJ_UPDATE_GOLDEN"
+
+//--------------------
+// ASSERTIONS
+//--------------------
+
+// AssertEqual compares the documents path by path and reports all
+// differences as error. Paths matching one of the ignore patterns, like
+// "/meta/timestamp" or "*/id", are not compared. It returns true if the
+// documents are equal.
+func AssertEqual(t testing.TB, want, got *dynaj.Document, ignore ...string) bool {
+	t.Helper()
+	differences, err := Differences(want, got, ignore...)
+	if err != nil {
+		t.Errorf("cannot compare documents: %v", err)
+		return false
+	}
+	if len(differences) == 0 {
+		return true
+	}
+	t.Errorf("documents differ at %d path(s):\n%s", len(differences), strings.Join(differences, "\n"))
+	return false
+}
+
+// AssertGolden compares the document with the one stored in the golden
+// file like AssertEqual. If the environment variable DYNAJ_UPDATE_GOLDEN
+// is set the file is written instead.
+func AssertGolden(t testing.TB, got *dynaj.Document, filename string, ignore ...string) bool {
+	t.Helper()
+	if os.Getenv(UpdateEnv) != "" {
+		if err := WriteGolden(got, filename); err != nil {
+			t.Errorf("cannot update golden file: %v", err)
+			return false
+		}
+		return true
+	}
+	data, err := os.ReadFile(filename)
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("golden file %q does not exist, set %s=1 to create it", filename, UpdateEnv)
+		return false
+	}
+	if err != nil {
+		t.Errorf("cannot read golden file: %v", err)
+		return false
+	}
+	want, err := dynaj.Unmarshal(data)
+	if err != nil {
+		t.Errorf("cannot read golden file %q: %v", filename, err)
+		return false
+	}
+	return AssertEqual(t, want, got, ignore...)
+}
+
+// WriteGolden writes the indented document into the golden file. Missing
+// directories are created.
+func WriteGolden(doc *dynaj.Document, filename string) error {
+	data, err := doc.MarshalIndentJSONAt(dynaj.Separator, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(filename), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(filename, append(data, '\n'), 0o644)
+}
+
+// Differences returns one line per differing path containing the
+// wanted and the got value. Paths matching one of the ignore patterns
+// are skipped.
+func Differences(want, got *dynaj.Document, ignore ...string) ([]string, error) {
+	diff, err := dynaj.CompareDocuments(want, got)
+	if err != nil {
+		return nil, err
+	}
+	differences := []string{}
+	for _, path := range diff.Differences() {
+		if ignored(path, ignore) {
+			continue
+		}
+		wn, gn := diff.DifferenceAt(path)
+		differences = append(differences, fmt.Sprintf("  %s: want %s, got %s", path, describe(wn), describe(gn)))
+	}
+	sort.Strings(differences)
+	return differences, nil
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// ignored checks if the path matches one of the patterns.
+func ignored(path dynaj.Path, patterns []string) bool {
+	for _, pattern := range patterns {
+		if dynaj.MatchPattern(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe returns the JSON of the node or a marker for missing ones.
+func describe(node *dynaj.Node) string {
+	if node.IsError() {
+		return "<missing>"
+	}
+	data, err := node.MarshalJSON()
+	if err != nil {
+		return fmt.Sprintf("<%v>", err)
+	}
+	return string(data)
+}
+
+// EOF
@@ -0,0 +1,99 @@
+// Tideland Go Dynamic JSON - Testing - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynajtest_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/dynajtest"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestAssertEqual tests comparing documents path by path.
+func TestAssertEqual(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	want := mustUnmarshal(assert, `{"a":1,"b":{"c":"x","ts":1},"d":[1,2]}`)
+	got := mustUnmarshal(assert, `{"a":1,"b":{"c":"y","ts":2},"d":[1],"e":true}`)
+
+	rec := &recorder{TB: t}
+	assert.True(dynajtest.AssertEqual(rec, want, want))
+	assert.Length(rec.errors, 0)
+
+	assert.False(dynajtest.AssertEqual(rec, want, got, "/b/ts"))
+	assert.Length(rec.errors, 1)
+	assert.Equal(rec.errors[0], "documents differ at 3 path(s):\n"+
+		"  /b/c: want \"x\", got \"y\"\n"+
+		"  /d/1: want 2, got <missing>\n"+
+		"  /e: want <missing>, got true")
+
+	rec = &recorder{TB: t}
+	assert.True(dynajtest.AssertEqual(rec, want, got, "/b/*", "/d/*", "/e"))
+	assert.Length(rec.errors, 0)
+}
+
+// TestAssertGolden tests comparing documents with golden files.
+func TestAssertGolden(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	filename := filepath.Join(t.TempDir(), "golden", "doc.json")
+	doc := mustUnmarshal(assert, `{"a":1,"b":[true]}`)
+
+	rec := &recorder{TB: t}
+	assert.False(dynajtest.AssertGolden(rec, doc, filename))
+	assert.Substring("does not exist", rec.errors[0])
+
+	t.Setenv(dynajtest.UpdateEnv, "1")
+	rec = &recorder{TB: t}
+	assert.True(dynajtest.AssertGolden(rec, doc, filename))
+	assert.Length(rec.errors, 0)
+	data, err := os.ReadFile(filename)
+	assert.NoError(err)
+	assert.Equal(string(data), "{\n  \"a\": 1,\n  \"b\": [\n    true\n  ]\n}\n")
+
+	t.Setenv(dynajtest.UpdateEnv, "")
+	assert.True(dynajtest.AssertGolden(rec, doc, filename))
+	assert.NoError(doc.SetValueAt("/a", 2))
+	assert.False(dynajtest.AssertGolden(rec, doc, filename))
+	assert.Substring("/a: want 1, got 2", rec.errors[0])
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// recorder records the errors instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+// Errorf implements testing.TB.
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+// mustUnmarshal parses the JSON or stops the test.
+func mustUnmarshal(assert *asserts.Asserts, data string) *dynaj.Document {
+	doc, err := dynaj.Unmarshal([]byte(data))
+	assert.NoError(err)
+	return doc
+}
+
+// EOF
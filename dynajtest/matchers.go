@@ -0,0 +1,144 @@
+// Tideland Go Dynamic JSON - Testing
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynajtest // import "tideland.dev/go/dynaj/dynajtest"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// MATCHERS
+//--------------------
+
+// Matcher checks an expectation about a document.
+type Matcher interface {
+	// Match returns an error describing why the document does
+	// not match, otherwise nil.
+	Match(doc *dynaj.Document) error
+}
+
+// MatcherFunc allows to use functions as matchers.
+type MatcherFunc func(doc *dynaj.Document) error
+
+// Match implements Matcher.
+func (f MatcherFunc) Match(doc *dynaj.Document) error {
+	return f(doc)
+}
+
+// HasPath expects an element at the path.
+func HasPath(path dynaj.Path) Matcher {
+	return MatcherFunc(func(doc *dynaj.Document) error {
+		if doc.NodeAt(path).IsError() {
+			return fmt.Errorf("path %q does not exist", path)
+		}
+		return nil
+	})
+}
+
+// HasNoPath expects no element at the path.
+func HasNoPath(path dynaj.Path) Matcher {
+	return MatcherFunc(func(doc *dynaj.Document) error {
+		if !doc.NodeAt(path).IsError() {
+			return fmt.Errorf("path %q exists", path)
+		}
+		return nil
+	})
+}
+
+// PathEquals expects the element at the path to be equal to the value.
+// The value is normalized like when setting it, so also maps, slices,
+// and structs can be passed. Numbers are compared by their value.
+func PathEquals(path dynaj.Path, value dynaj.Value) Matcher {
+	return MatcherFunc(func(doc *dynaj.Document) error {
+		expected := dynaj.NewDocument()
+		if err := expected.SetValueAt(dynaj.Separator, value); err != nil {
+			return fmt.Errorf("invalid expected value for path %q: %v", path, err)
+		}
+		node := doc.NodeAt(path)
+		if node.IsError() {
+			return fmt.Errorf("path %q does not exist", path)
+		}
+		if !node.Equals(expected.Root()) {
+			return fmt.Errorf("path %q: want %s, got %s", path, describe(expected.Root()), describe(node))
+		}
+		return nil
+	})
+}
+
+// PathMatches expects at least one element with a path matching the
+// pattern and all of them fulfilling the predicate. Patterns are matched
+// against the absolute paths like "/items/*/id".
+func PathMatches(pattern string, pred func(node *dynaj.Node) bool) Matcher {
+	return MatcherFunc(func(doc *dynaj.Document) error {
+		nodes, err := doc.Root().Query(pattern)
+		if err != nil {
+			return fmt.Errorf("cannot query pattern %q: %v", pattern, err)
+		}
+		if len(nodes) == 0 {
+			return fmt.Errorf("no path matches %q", pattern)
+		}
+		failed := []string{}
+		for _, node := range nodes {
+			if !pred(node) {
+				failed = append(failed, node.Path())
+			}
+		}
+		if len(failed) > 0 {
+			return fmt.Errorf("paths matching %q do not fulfill the predicate: %s", pattern, strings.Join(failed, ", "))
+		}
+		return nil
+	})
+}
+
+// All expects all matchers to match.
+func All(matchers ...Matcher) Matcher {
+	return MatcherFunc(func(doc *dynaj.Document) error {
+		return Check(doc, matchers...)
+	})
+}
+
+// Check runs all matchers and returns an error containing all failed
+// expectations. It can be used with any assertion package, e.g. with
+// assert.NoError(dynajtest.Check(doc, ...)).
+func Check(doc *dynaj.Document, matchers ...Matcher) error {
+	failures := []string{}
+	for _, matcher := range matchers {
+		if err := matcher.Match(doc); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) == 0 {
+		return nil
+	}
+	return errors.New(strings.Join(failures, "; "))
+}
+
+// AssertMatch runs all matchers and reports each failed expectation as
+// error. It returns true if all matchers match.
+func AssertMatch(t testing.TB, doc *dynaj.Document, matchers ...Matcher) bool {
+	t.Helper()
+	ok := true
+	for _, matcher := range matchers {
+		if err := matcher.Match(doc); err != nil {
+			t.Errorf("document does not match: %v", err)
+			ok = false
+		}
+	}
+	return ok
+}
+
+// EOF
@@ -0,0 +1,88 @@
+// Tideland Go Dynamic JSON - Testing - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynajtest_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/dynajtest"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMatchers tests the single matchers in a table.
+func TestMatchers(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a":{"b":3,"c":[1,2]},"items":[{"id":1},{"id":2}]}`)
+	positive := func(node *dynaj.Node) bool { return node.AsInt(0) > 0 }
+
+	tests := []struct {
+		name    string
+		matcher dynajtest.Matcher
+		err     string
+	}{
+		{"has path", dynajtest.HasPath("/a/b"), ""},
+		{"has no path", dynajtest.HasPath("/a/x"), `path "/a/x" does not exist`},
+		{"has no path", dynajtest.HasNoPath("/a/x"), ""},
+		{"has no path fails", dynajtest.HasNoPath("/a"), `path "/a" exists`},
+		{"equals int", dynajtest.PathEquals("/a/b", 3), ""},
+		{"equals array", dynajtest.PathEquals("/a/c", []int{1, 2}), ""},
+		{"equals map", dynajtest.PathEquals("/items/0", map[string]any{"id": 1}), ""},
+		{"not equal", dynajtest.PathEquals("/a/b", 4), `path "/a/b": want 4, got 3`},
+		{"equals missing", dynajtest.PathEquals("/a/x", 4), `path "/a/x" does not exist`},
+		{"matches", dynajtest.PathMatches("/items/*/id", positive), ""},
+		{"matches fails", dynajtest.PathMatches("/a/*", func(node *dynaj.Node) bool {
+			return node.AsInt(0) < 3
+		}), `paths matching "/a/*" do not fulfill the predicate: /a/b`},
+		{"matches nothing", dynajtest.PathMatches("/x/*", positive), `no path matches "/x/*"`},
+		{"all", dynajtest.All(dynajtest.HasPath("/a"), dynajtest.HasPath("/x"), dynajtest.HasPath("/y")),
+			`path "/x" does not exist; path "/y" does not exist`},
+	}
+	for _, test := range tests {
+		err := test.matcher.Match(doc)
+		if test.err == "" {
+			assert.NoError(err, test.name)
+		} else {
+			assert.ErrorContains(err, test.err, test.name)
+		}
+	}
+}
+
+// TestAssertMatch tests using matchers with testing and asserts.
+func TestAssertMatch(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"level":"info","port":8080}`)
+
+	assert.NoError(dynajtest.Check(doc,
+		dynajtest.HasPath("/level"),
+		dynajtest.PathEquals("/port", 8080),
+	))
+	assert.True(dynajtest.AssertMatch(t, doc, dynajtest.PathEquals("/level", "info")))
+
+	rec := &recorder{TB: t}
+	assert.False(dynajtest.AssertMatch(rec, doc,
+		dynajtest.PathEquals("/level", "debug"),
+		dynajtest.HasPath("/port"),
+		dynajtest.HasNoPath("/port"),
+	))
+	assert.Equal(rec.errors, []string{
+		`document does not match: path "/level": want "debug", got "info"`,
+		`document does not match: path "/port" exists`,
+	})
+}
+
+// EOF
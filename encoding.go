@@ -0,0 +1,103 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+//--------------------
+// ENCODING
+//--------------------
+
+// Byte order marks of the Unicode encodings.
+var (
+	bomUTF8    = []byte{0xEF, 0xBB, 0xBF}
+	bomUTF16BE = []byte{0xFE, 0xFF}
+	bomUTF16LE = []byte{0xFF, 0xFE}
+	bomUTF32BE = []byte{0x00, 0x00, 0xFE, 0xFF}
+	bomUTF32LE = []byte{0xFF, 0xFE, 0x00, 0x00}
+)
+
+// toUTF8 strips byte order marks and transcodes UTF-16 and UTF-32 data
+// into UTF-8. Without byte order mark the encoding is detected by the
+// pattern of null bytes in the first four bytes as described in RFC
+// 4627, as JSON texts start with ASCII characters.
+func toUTF8(data []byte) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, bomUTF8):
+		return data[len(bomUTF8):], nil
+	case bytes.HasPrefix(data, bomUTF32BE):
+		return decodeUTF32(data[4:], binary.BigEndian)
+	case bytes.HasPrefix(data, bomUTF32LE):
+		return decodeUTF32(data[4:], binary.LittleEndian)
+	case bytes.HasPrefix(data, bomUTF16BE):
+		return decodeUTF16(data[2:], binary.BigEndian)
+	case bytes.HasPrefix(data, bomUTF16LE):
+		return decodeUTF16(data[2:], binary.LittleEndian)
+	}
+	if len(data) >= 4 {
+		switch {
+		case data[0] == 0 && data[1] == 0 && data[2] == 0 && data[3] != 0:
+			return decodeUTF32(data, binary.BigEndian)
+		case data[0] != 0 && data[1] == 0 && data[2] == 0 && data[3] == 0:
+			return decodeUTF32(data, binary.LittleEndian)
+		}
+	}
+	if len(data) >= 2 {
+		switch {
+		case data[0] == 0 && data[1] != 0:
+			return decodeUTF16(data, binary.BigEndian)
+		case data[0] != 0 && data[1] == 0:
+			return decodeUTF16(data, binary.LittleEndian)
+		}
+	}
+	return data, nil
+}
+
+// decodeUTF16 transcodes UTF-16 data into UTF-8.
+func decodeUTF16(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%2 != 0 {
+		return nil, fmt.Errorf("invalid UTF-16 data: odd length %d", len(data))
+	}
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[2*i:])
+	}
+	out := make([]byte, 0, len(units))
+	for _, r := range utf16.Decode(units) {
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
+
+// decodeUTF32 transcodes UTF-32 data into UTF-8.
+func decodeUTF32(data []byte, order binary.ByteOrder) ([]byte, error) {
+	if len(data)%4 != 0 {
+		return nil, fmt.Errorf("invalid UTF-32 data: length %d is no multiple of 4", len(data))
+	}
+	out := make([]byte, 0, len(data)/4)
+	for i := 0; i < len(data); i += 4 {
+		r := rune(order.Uint32(data[i:]))
+		if !utf8.ValidRune(r) {
+			return nil, fmt.Errorf("invalid UTF-32 data: invalid code point %#x at offset %d", uint32(r), i)
+		}
+		out = utf8.AppendRune(out, r)
+	}
+	return out, nil
+}
+
+// EOF
@@ -0,0 +1,673 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+//--------------------
+// EVALUATION
+//--------------------
+
+// Eval evaluates a small expression over the values of the document,
+// e.g. for feature flag rules or configuration checks like
+//
+//	len(/items) > 0 && /server/port != 8080
+//
+// Operands are paths starting with a slash, numbers, strings in double
+// or single quotes, true, false, and null. Paths end at whitespace or
+// an operator except the minus, so subtractions need spaces. Missing
+// paths evaluate to null. Paths containing the wildcards * or ? right
+// after a slash are patterns evaluating to the array of all matching
+// values. Objects {key: x, "other key": y} and arrays [x, y] construct
+// new values. Operators are || && ! == != < <= > >= + - * with the
+// usual precedence, and parentheses. The logical operators handle
+// false, null, zero, empty strings, and empty containers as false.
+// Functions are len(x), exists(path), contains(x, y) for substrings or
+// array elements, and sum(x), avg(x), min(x), and max(x) for arrays of
+// numbers. If the whole expression is a path its node is returned,
+// otherwise an unbound node with the computed value.
+func (d *Document) Eval(expr string) (*Node, error) {
+	p := &exprParser{input: expr}
+	ast, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate %q: %v", expr, err)
+	}
+	if path, ok := ast.(pathExpr); ok && !path.isPattern() {
+		return d.NodeAt(string(path)), nil
+	}
+	value, err := ast.eval(d)
+	if err != nil {
+		return nil, fmt.Errorf("cannot evaluate %q: %v", expr, err)
+	}
+	return &Node{
+		path:    expr,
+		element: value,
+	}, nil
+}
+
+// Select evaluates the expression like Eval and returns its value as
+// new document, e.g. for reports reshaping the document:
+//
+//	{name: /user/name, total: sum(/items/*/price)}
+func (d *Document) Select(expr string) (*Document, error) {
+	p := &exprParser{input: expr}
+	ast, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("cannot select %q: %v", expr, err)
+	}
+	value, err := ast.eval(d)
+	if err != nil {
+		return nil, fmt.Errorf("cannot select %q: %v", expr, err)
+	}
+	return d.derive(copyElement(value)), nil
+}
+
+//--------------------
+// EXPRESSIONS
+//--------------------
+
+// expr is one node of the expression tree.
+type expr interface {
+	eval(d *Document) (Element, error)
+}
+
+// literalExpr is a constant value.
+type literalExpr struct {
+	value Element
+}
+
+func (e literalExpr) eval(d *Document) (Element, error) {
+	return e.value, nil
+}
+
+// pathExpr is the value at a path.
+type pathExpr string
+
+func (e pathExpr) eval(d *Document) (Element, error) {
+	if e.isPattern() {
+		paths, err := d.ExpandPattern(string(e))
+		if err != nil {
+			return nil, err
+		}
+		values := Array{}
+		for _, path := range paths {
+			element, err := elementAt(d.root, splitPath(path))
+			if err != nil {
+				continue
+			}
+			element, err = decodeRaw(element)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, element)
+		}
+		return values, nil
+	}
+	element, err := elementAt(d.root, splitPath(string(e)))
+	if err != nil {
+		return nil, nil
+	}
+	return decodeRaw(element)
+}
+
+// isPattern checks if the path contains wildcards.
+func (e pathExpr) isPattern() bool {
+	return strings.Contains(string(e), "/*") || strings.Contains(string(e), "/?")
+}
+
+// objectExpr constructs an object.
+type objectExpr struct {
+	keys   []string
+	values []expr
+}
+
+func (e objectExpr) eval(d *Document) (Element, error) {
+	obj := Object{}
+	for i, key := range e.keys {
+		value, err := e.values[i].eval(d)
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+	}
+	return obj, nil
+}
+
+// arrayExpr constructs an array.
+type arrayExpr []expr
+
+func (e arrayExpr) eval(d *Document) (Element, error) {
+	arr := Array{}
+	for _, element := range e {
+		value, err := element.eval(d)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+	}
+	return arr, nil
+}
+
+// unaryExpr applies an operator to one operand.
+type unaryExpr struct {
+	op      string
+	operand expr
+}
+
+func (e unaryExpr) eval(d *Document) (Element, error) {
+	value, err := e.operand.eval(d)
+	if err != nil {
+		return nil, err
+	}
+	if e.op == "!" {
+		return !isTruthy(value), nil
+	}
+	f, ok := asNumber(value)
+	if !ok {
+		return nil, fmt.Errorf("cannot negate %s", typeName(value))
+	}
+	return -f, nil
+}
+
+// binaryExpr applies an operator to two operands.
+type binaryExpr struct {
+	op          string
+	left, right expr
+}
+
+func (e binaryExpr) eval(d *Document) (Element, error) {
+	left, err := e.left.eval(d)
+	if err != nil {
+		return nil, err
+	}
+	// Short circuit logical operators.
+	switch e.op {
+	case "&&":
+		if !isTruthy(left) {
+			return false, nil
+		}
+		right, err := e.right.eval(d)
+		return isTruthy(right), err
+	case "||":
+		if isTruthy(left) {
+			return true, nil
+		}
+		right, err := e.right.eval(d)
+		return isTruthy(right), err
+	}
+	right, err := e.right.eval(d)
+	if err != nil {
+		return nil, err
+	}
+	switch e.op {
+	case "==":
+		return equalElements(left, right), nil
+	case "!=":
+		return !equalElements(left, right), nil
+	case "<", "<=", ">", ">=":
+		return compareValues(e.op, left, right)
+	case "+":
+		ls, lok := left.(string)
+		rs, rok := right.(string)
+		if lok && rok {
+			return ls + rs, nil
+		}
+	}
+	lf, lok := asNumber(left)
+	rf, rok := asNumber(right)
+	if !lok || !rok {
+		return nil, fmt.Errorf("invalid operands %s %s %s", typeName(left), e.op, typeName(right))
+	}
+	switch e.op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	default:
+		return lf * rf, nil
+	}
+}
+
+// callExpr calls a function.
+type callExpr struct {
+	name string
+	args []expr
+}
+
+func (e callExpr) eval(d *Document) (Element, error) {
+	arity := map[string]int{"len": 1, "exists": 1, "contains": 2, "sum": 1, "avg": 1, "min": 1, "max": 1}
+	if n, ok := arity[e.name]; !ok {
+		return nil, fmt.Errorf("unknown function %q", e.name)
+	} else if n != len(e.args) {
+		return nil, fmt.Errorf("function %q needs %d arguments", e.name, n)
+	}
+	if e.name == "exists" {
+		path, ok := e.args[0].(pathExpr)
+		if !ok {
+			return nil, fmt.Errorf("function %q needs a path", e.name)
+		}
+		_, err := elementAt(d.root, splitPath(string(path)))
+		return err == nil, nil
+	}
+	args := make([]Element, len(e.args))
+	for i, arg := range e.args {
+		value, err := arg.eval(d)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+	switch e.name {
+	case "sum", "avg", "min", "max":
+		return aggregate(e.name, args[0])
+	}
+	if e.name == "len" {
+		switch typed := args[0].(type) {
+		case string:
+			return float64(utf8.RuneCountInString(typed)), nil
+		case Object:
+			return float64(len(typed)), nil
+		case Array:
+			return float64(len(typed)), nil
+		case nil:
+			return 0.0, nil
+		}
+		return nil, fmt.Errorf("no length of %s", typeName(args[0]))
+	}
+	switch typed := args[0].(type) {
+	case string:
+		sub, ok := args[1].(string)
+		return ok && strings.Contains(typed, sub), nil
+	case Array:
+		for _, element := range typed {
+			if equalElements(element, args[1]) {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	return nil, fmt.Errorf("cannot search in %s", typeName(args[0]))
+}
+
+// aggregate computes the aggregation of an array of numbers. Averages,
+// minimums, and maximums of empty arrays are null.
+func aggregate(name string, arg Element) (Element, error) {
+	arr, ok := arg.(Array)
+	if !ok {
+		if arg == nil {
+			arr = Array{}
+		} else {
+			arr = Array{arg}
+		}
+	}
+	var sum, low, high float64
+	for i, element := range arr {
+		f, ok := asNumber(element)
+		if !ok {
+			return nil, fmt.Errorf("cannot aggregate %s in %s", typeName(element), name)
+		}
+		sum += f
+		if i == 0 || f < low {
+			low = f
+		}
+		if i == 0 || f > high {
+			high = f
+		}
+	}
+	switch {
+	case name == "sum":
+		return sum, nil
+	case len(arr) == 0:
+		return nil, nil
+	case name == "avg":
+		return sum / float64(len(arr)), nil
+	case name == "min":
+		return low, nil
+	}
+	return high, nil
+}
+
+// isTruthy returns the logical value of an element.
+func isTruthy(element Element) bool {
+	switch typed := element.(type) {
+	case nil:
+		return false
+	case bool:
+		return typed
+	case string:
+		return typed != ""
+	case Object:
+		return len(typed) > 0
+	case Array:
+		return len(typed) > 0
+	}
+	if f, ok := asNumber(element); ok {
+		return f != 0
+	}
+	return true
+}
+
+// compareValues compares two numbers or two strings.
+func compareValues(op string, left, right Element) (Element, error) {
+	var cmp int
+	lf, lok := asNumber(left)
+	rf, rok := asNumber(right)
+	ls, lsok := left.(string)
+	rs, rsok := right.(string)
+	switch {
+	case lok && rok:
+		switch {
+		case lf < rf:
+			cmp = -1
+		case lf > rf:
+			cmp = 1
+		}
+	case lsok && rsok:
+		cmp = strings.Compare(ls, rs)
+	default:
+		return nil, fmt.Errorf("cannot compare %s %s %s", typeName(left), op, typeName(right))
+	}
+	switch op {
+	case "<":
+		return cmp < 0, nil
+	case "<=":
+		return cmp <= 0, nil
+	case ">":
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+//--------------------
+// PARSER
+//--------------------
+
+// exprParser is a recursive descent parser for expressions.
+type exprParser struct {
+	input string
+	pos   int
+}
+
+// binaryLevels contains the binary operators by increasing precedence.
+var binaryLevels = [][]string{
+	{"||"},
+	{"&&"},
+	{"==", "!="},
+	{"<=", ">=", "<", ">"},
+	{"+", "-"},
+	{"*"},
+}
+
+// parse parses the whole input.
+func (p *exprParser) parse() (expr, error) {
+	e, err := p.binary(0)
+	if err != nil {
+		return nil, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return nil, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	return e, nil
+}
+
+// errorf creates an error containing the current position.
+func (p *exprParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("position %d: %s", p.pos, fmt.Sprintf(format, args...))
+}
+
+// skipSpace skips the whitespace.
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.input) && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes one of the operators if it follows.
+func (p *exprParser) accept(ops ...string) (string, bool) {
+	p.skipSpace()
+	for _, op := range ops {
+		if strings.HasPrefix(p.input[p.pos:], op) {
+			// Do not take the first part of a longer operator.
+			rest := p.input[p.pos+len(op):]
+			if (op == "<" || op == ">" || op == "!") && strings.HasPrefix(rest, "=") {
+				continue
+			}
+			p.pos += len(op)
+			return op, true
+		}
+	}
+	return "", false
+}
+
+// binary parses the binary operators of the level and above.
+func (p *exprParser) binary(level int) (expr, error) {
+	if level == len(binaryLevels) {
+		return p.unary()
+	}
+	left, err := p.binary(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(binaryLevels[level]...)
+		if !ok {
+			return left, nil
+		}
+		right, err := p.binary(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		left = binaryExpr{op, left, right}
+	}
+}
+
+// unary parses negations.
+func (p *exprParser) unary() (expr, error) {
+	if op, ok := p.accept("!", "-"); ok {
+		operand, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return unaryExpr{op, operand}, nil
+	}
+	return p.primary()
+}
+
+// primary parses operands, function calls, and parentheses.
+func (p *exprParser) primary() (expr, error) {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return nil, p.errorf("unexpected end")
+	}
+	c := p.input[p.pos]
+	switch {
+	case c == '(':
+		p.pos++
+		e, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := p.accept(")"); !ok {
+			return nil, p.errorf("missing ')'")
+		}
+		return e, nil
+	case c == '/':
+		start := p.pos
+		for p.pos < len(p.input) {
+			c := p.input[p.pos]
+			if strings.ContainsRune(" \t\r\n()!=<>&|,+*[]{}", rune(c)) && !(c == '*' && p.input[p.pos-1] == '/') {
+				break
+			}
+			p.pos++
+		}
+		return pathExpr(p.input[start:p.pos]), nil
+	case c == '{':
+		return p.object()
+	case c == '[':
+		return p.array()
+	case c == '"' || c == '\'':
+		return p.string(c)
+	case c >= '0' && c <= '9' || c == '.':
+		start := p.pos
+		for p.pos < len(p.input) && strings.ContainsRune("0123456789.eE", rune(p.input[p.pos])) {
+			p.pos++
+		}
+		f, err := strconv.ParseFloat(p.input[start:p.pos], 64)
+		if err != nil {
+			return nil, p.errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return literalExpr{f}, nil
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos]))) {
+			p.pos++
+		}
+		name := p.input[start:p.pos]
+		switch name {
+		case "true":
+			return literalExpr{true}, nil
+		case "false":
+			return literalExpr{false}, nil
+		case "null":
+			return literalExpr{nil}, nil
+		}
+		return p.call(name)
+	}
+	return nil, p.errorf("unexpected %q", c)
+}
+
+// call parses the arguments of a function call.
+func (p *exprParser) call(name string) (expr, error) {
+	if _, ok := p.accept("("); !ok {
+		return nil, p.errorf("unknown identifier %q", name)
+	}
+	call := callExpr{name: name}
+	if _, ok := p.accept(")"); ok {
+		return call, nil
+	}
+	for {
+		arg, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		call.args = append(call.args, arg)
+		if _, ok := p.accept(")"); ok {
+			return call, nil
+		}
+		if _, ok := p.accept(","); !ok {
+			return nil, p.errorf("missing ',' or ')'")
+		}
+	}
+}
+
+// object parses the keys and values of an object.
+func (p *exprParser) object() (expr, error) {
+	p.pos++
+	obj := objectExpr{}
+	if _, ok := p.accept("}"); ok {
+		return obj, nil
+	}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.input) {
+			return nil, p.errorf("unexpected end")
+		}
+		var key string
+		switch c := p.input[p.pos]; {
+		case c == '"' || c == '\'':
+			quoted, err := p.string(c)
+			if err != nil {
+				return nil, err
+			}
+			key = quoted.(literalExpr).value.(string)
+		default:
+			start := p.pos
+			for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || unicode.IsDigit(rune(p.input[p.pos])) || p.input[p.pos] == '_') {
+				p.pos++
+			}
+			if start == p.pos {
+				return nil, p.errorf("missing key")
+			}
+			key = p.input[start:p.pos]
+		}
+		if _, ok := p.accept(":"); !ok {
+			return nil, p.errorf("missing ':'")
+		}
+		value, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		obj.keys = append(obj.keys, key)
+		obj.values = append(obj.values, value)
+		if _, ok := p.accept("}"); ok {
+			return obj, nil
+		}
+		if _, ok := p.accept(","); !ok {
+			return nil, p.errorf("missing ',' or '}'")
+		}
+	}
+}
+
+// array parses the elements of an array.
+func (p *exprParser) array() (expr, error) {
+	p.pos++
+	arr := arrayExpr{}
+	if _, ok := p.accept("]"); ok {
+		return arr, nil
+	}
+	for {
+		element, err := p.binary(0)
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, element)
+		if _, ok := p.accept("]"); ok {
+			return arr, nil
+		}
+		if _, ok := p.accept(","); !ok {
+			return nil, p.errorf("missing ',' or ']'")
+		}
+	}
+}
+
+// string parses a quoted string. Backslashes escape the next character.
+func (p *exprParser) string(quote byte) (expr, error) {
+	p.pos++
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		switch c {
+		case quote:
+			p.pos++
+			return literalExpr{b.String()}, nil
+		case '\\':
+			p.pos++
+			if p.pos >= len(p.input) {
+				return nil, p.errorf("unterminated string")
+			}
+			c = p.input[p.pos]
+		}
+		b.WriteByte(c)
+		p.pos++
+	}
+	return nil, p.errorf("unterminated string")
+}
+
+// EOF
@@ -0,0 +1,149 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestEval tests the evaluation of expressions.
+func TestEval(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"server": {"port": 8080, "host": "example.com", "debug": false},
+		"items": [1, 2, 3],
+		"tags": ["beta", "eu"],
+		"user-name": "jane",
+		"empty": {}
+	}`)
+
+	tests := []struct {
+		expr     string
+		expected dynaj.Value
+	}{
+		{`len(/items) > 0 && /server/port != 8080`, false},
+		{`len(/items) > 0 && /server/port == 8080`, true},
+		{`/server/port >= 1024 && /server/port < 65536`, true},
+		{`/server/debug || contains(/tags, "beta")`, true},
+		{`!/server/debug`, true},
+		{`!(/server/port == 8080)`, false},
+		{`/missing == null`, true},
+		{`exists(/missing) || exists(/server/host)`, true},
+		{`exists(/items/3)`, false},
+		{`/server/port + 1`, 8081.0},
+		{`/server/port - 80 * 2`, 7920.0},
+		{`-/items/0 * (2 + 1)`, -3.0},
+		{`'host: ' + /server/host`, "host: example.com"},
+		{`contains(/server/host, ".com")`, true},
+		{`len(/server/host) == 11`, true},
+		{`len(/empty) == 0 && !/empty`, true},
+		{`len(/missing)`, 0.0},
+		{`/user-name == "jane"`, true},
+		{`"a" < "b"`, true},
+		{`1.5 <= 1.5 && 2 > 1`, true},
+		{`/items == /items`, true},
+		{`"it's \"quoted\""`, `it's "quoted"`},
+		{`sum(/items)`, 6.0},
+		{`avg(/items) * 2`, 4.0},
+		{`max(/items/*) - min(/items/*)`, 2.0},
+		{`len(/server/*) == 3`, true},
+		{`[1, /items/0] == [1, 1]`, true},
+	}
+	for _, test := range tests {
+		node, err := doc.Eval(test.expr)
+		assert.NoError(err, test.expr)
+		assert.Equal(node.Path(), test.expr)
+		switch e := test.expected.(type) {
+		case bool:
+			assert.Equal(node.AsBool(!e), e, test.expr)
+		case float64:
+			assert.Equal(node.AsFloat64(0), e, test.expr)
+		case string:
+			assert.Equal(node.AsString(""), e, test.expr)
+		}
+	}
+
+	// Plain paths return the bound node.
+	node, err := doc.Eval(" /server/port ")
+	assert.NoError(err)
+	assert.Equal(node.Path(), "/server/port")
+	assert.NoError(node.SetValue(9090))
+	assert.Equal(doc.NodeAt("/server/port").AsInt(0), 9090)
+	node, err = doc.Eval("/missing")
+	assert.NoError(err)
+	assert.True(node.IsError())
+
+	// Errors.
+	errors := map[string]string{
+		`/a ==`:               "unexpected end",
+		`(1 + 2`:              "missing ')'",
+		`1 2`:                 "unexpected",
+		`foo`:                 `unknown identifier "foo"`,
+		`foo(1)`:              `unknown function "foo"`,
+		`len(1, 2)`:           `function "len" needs 1 arguments`,
+		`exists("a")`:         `function "exists" needs a path`,
+		`len(true)`:           "no length of bool",
+		`contains(1, 1)`:      "cannot search in number",
+		`"a" < 1`:             "cannot compare string < number",
+		`/server/host * 2`:    "invalid operands string * number",
+		`-"a"`:                "cannot negate string",
+		`"open`:               "unterminated string",
+		`1..2`:                `invalid number "1..2"`,
+		`contains(/tags, "a"`: "missing ',' or ')'",
+		`@`:                   "unexpected '@'",
+	}
+	for expr, message := range errors {
+		_, err := doc.Eval(expr)
+		assert.ErrorContains(err, message, expr)
+	}
+}
+
+// TestSelect tests selecting new documents with expressions.
+func TestSelect(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"user": {"name": "jane", "id": 42},
+		"items": [{"name": "a", "price": 10}, {"name": "b", "price": 5.5}]
+	}`)
+
+	selected, err := doc.Select(`{name: /user/name, "item count": len(/items), total: sum(/items/*/price), prices: /items/*/price}`)
+	assert.NoError(err)
+	assert.Equal(selected.String(), `{"item count":2,"name":"jane","prices":[10,5.5],"total":15.5}`)
+
+	selected, err = doc.Select(`[/user/id, max(/items/*/price) > 8, {}]`)
+	assert.NoError(err)
+	assert.Equal(selected.String(), `[42,true,{}]`)
+
+	// The selection is independent of the document.
+	selected, err = doc.Select(`{user: /user}`)
+	assert.NoError(err)
+	assert.NoError(selected.SetValueAt("/user/name", "john"))
+	assert.Equal(doc.NodeAt("/user/name").AsString("-"), "jane")
+
+	// Errors.
+	_, err = doc.Select(`{name /user/name}`)
+	assert.ErrorMatch(err, `cannot select .*: position \d+: missing ':'`)
+	_, err = doc.Select(`{name: /user/name`)
+	assert.ErrorMatch(err, `cannot select .*: position \d+: missing ',' or '}'`)
+	_, err = doc.Select(`sum(/items/*/name)`)
+	assert.ErrorMatch(err, `cannot select .*: cannot aggregate string in sum`)
+}
+
+// EOF
@@ -0,0 +1,71 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+//--------------------
+// PATTERN EXPANSION
+//--------------------
+
+// ExpandPattern returns the paths of all elements, containers as well
+// as values, matching the pattern. Like for Omit the pattern is matched
+// against the absolute paths. No nodes are created, so path sets for
+// own logic like deletions, copies, or metrics are cheap. The paths are
+// sorted with array indices in numerical order, so parents come before
+// their children.
+func (d *Document) ExpandPattern(pattern string) ([]Path, error) {
+	paths := []Path{}
+	if err := expandElement(d.root, Separator, compilePattern(pattern), &paths); err != nil {
+		return nil, fmt.Errorf("cannot expand pattern %q: %v", pattern, err)
+	}
+	sort.Slice(paths, func(i, j int) bool {
+		return compareKeys(splitPath(paths[i]), splitPath(paths[j])) < 0
+	})
+	return paths, nil
+}
+
+// expandElement recursively collects the matching paths.
+func expandElement(element Element, path Path, pattern *pathPattern, paths *[]Path) error {
+	if element == nil && path == Separator {
+		// Empty document.
+		return nil
+	}
+	if pattern.matches(path) {
+		*paths = append(*paths, path)
+	}
+	element, err := decodeRaw(element)
+	if err != nil {
+		return err
+	}
+	switch typed := element.(type) {
+	case Object:
+		for key, child := range typed {
+			if err := expandElement(child, appendKey(path, key), pattern, paths); err != nil {
+				return err
+			}
+		}
+	case Array:
+		for idx, child := range typed {
+			if err := expandElement(child, appendKey(path, strconv.Itoa(idx)), pattern, paths); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// EOF
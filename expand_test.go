@@ -0,0 +1,85 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestExpandPattern tests the expansion of patterns into paths.
+func TestExpandPattern(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"users": [
+			{"name": "a", "password": "x"},
+			{"name": "b"}
+		],
+		"admin": {"name": "root", "password": "y"}
+	}`)
+	for i := 2; i < 12; i++ {
+		assert.NoError(doc.SetValueAt("/users/"+strconv.Itoa(i)+"/name", "u"))
+	}
+
+	paths, err := doc.ExpandPattern("*/password")
+	assert.NoError(err)
+	assert.Equal(paths, []dynaj.Path{"/admin/password", "/users/0/password"})
+
+	paths, err = doc.ExpandPattern("/users/?")
+	assert.NoError(err)
+	assert.Length(paths, 10)
+	assert.Equal(paths[0], "/users/0")
+
+	paths, err = doc.ExpandPattern("/admin*")
+	assert.NoError(err)
+	assert.Equal(paths, []dynaj.Path{"/admin", "/admin/name", "/admin/password"})
+
+	paths, err = doc.ExpandPattern("/")
+	assert.NoError(err)
+	assert.Equal(paths, []dynaj.Path{"/"})
+
+	paths, err = doc.ExpandPattern("/nothing")
+	assert.NoError(err)
+	assert.Length(paths, 0)
+
+	paths, err = dynaj.NewDocument().ExpandPattern("*")
+	assert.NoError(err)
+	assert.Length(paths, 0)
+
+	// Indices are sorted numerically and raw fragments are expanded.
+	doc = dynaj.NewDocument()
+	assert.NoError(doc.SetRawAt("/a", json.RawMessage(`[0,1,2,3,4,5,6,7,8,9,10]`)))
+	paths, err = doc.ExpandPattern("/a/1*")
+	assert.NoError(err)
+	assert.Equal(paths, []dynaj.Path{"/a/1", "/a/10"})
+
+	// Paths can drive own logic.
+	paths, err = doc.ExpandPattern("/a/*")
+	assert.NoError(err)
+	for i := len(paths) - 1; i >= 0; i-- {
+		if i%2 == 1 {
+			assert.NoError(doc.DeleteValueAt(paths[i]))
+		}
+	}
+	assert.Equal(doc.String(), `{"a":[0,2,4,6,8,10]}`)
+}
+
+// EOF
@@ -0,0 +1,125 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// ARRAY EXTENSION
+//--------------------
+
+// ArrayPolicy controls how arrays are extended when setting values at
+// indices beyond their length. By default any gap is filled with
+// nulls, so setting "/a/999" allocates 1000 elements.
+type ArrayPolicy struct {
+	// MaxGap is the maximum number of elements between the end of an
+	// array and a new index. Zero means no limit.
+	MaxGap int
+
+	// AppendOnly allows to set values only at existing indices or
+	// directly at the end of arrays.
+	AppendOnly bool
+
+	// Fill is the value for the elements of the gap instead of null.
+	Fill Value
+}
+
+// SetArrayPolicy sets how arrays are extended. An invalid fill value
+// returns an error. Frozen documents keep their policy.
+func (d *Document) SetArrayPolicy(policy ArrayPolicy) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	fill, err := normalizeValue(policy.Fill, Separator, d.nonFinite)
+	if err != nil {
+		return fmt.Errorf("invalid fill value: %v", err)
+	}
+	policy.Fill = fill
+	d.arrays = policy
+	return nil
+}
+
+// extension describes the extension of an array by an insertion.
+type extension struct {
+	keys     Keys
+	from, to int
+}
+
+// checkArrayPolicy checks if inserting at the keys follows the array
+// policy and returns the needed array extensions.
+func (d *Document) checkArrayPolicy(keys Keys) ([]extension, error) {
+	extensions := []extension{}
+	element := d.root
+	for i, key := range keys {
+		element, _ = decodeRaw(element)
+		index, isIndex := asIndex(key)
+		arr, isArray := element.(Array)
+		if isIndex && (isArray || element == nil) && index > len(arr) {
+			gap := index - len(arr)
+			switch {
+			case d.arrays.AppendOnly:
+				return nil, fmt.Errorf("cannot insert value at %q: index %d is not appending to array of length %d",
+					pathify(keys), index, len(arr))
+			case d.arrays.MaxGap > 0 && gap > d.arrays.MaxGap:
+				return nil, fmt.Errorf("cannot insert value at %q: index %d exceeds array length %d by more than %d",
+					pathify(keys), index, len(arr), d.arrays.MaxGap)
+			}
+			extensions = append(extensions, extension{keys[:i], len(arr), index})
+		}
+		element, _ = elementAt(element, Keys{key})
+	}
+	return extensions, nil
+}
+
+// fillExtensions sets the fill value into the gaps of the extended
+// arrays.
+func (d *Document) fillExtensions(extensions []extension) {
+	if d.arrays.Fill == nil {
+		return
+	}
+	for _, ext := range extensions {
+		element, err := elementAt(d.root, ext.keys)
+		if err != nil {
+			continue
+		}
+		if arr, ok := element.(Array); ok {
+			for idx := ext.from; idx < ext.to && idx < len(arr); idx++ {
+				arr[idx] = copyElement(d.arrays.Fill)
+			}
+		}
+	}
+}
+
+// insertAt inserts the normalized value at the keys following the
+// strict mode and the array policy.
+func (d *Document) insertAt(keys Keys, value Value) error {
+	if err := d.checkStrict(keys); err != nil {
+		return err
+	}
+	extensions, err := d.checkArrayPolicy(keys)
+	if err != nil {
+		return err
+	}
+	d.unshare(keys)
+	root, err := insertValue(d.root, keys, value)
+	if err != nil {
+		return err
+	}
+	d.root = root
+	d.fillExtensions(extensions)
+	d.changed(keys)
+	return nil
+}
+
+// EOF
@@ -0,0 +1,66 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestArrayPolicy tests the control of array extensions.
+func TestArrayPolicy(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	// Default extends with nulls.
+	doc := mustUnmarshal(assert, `{"a":[1]}`)
+	assert.NoError(doc.SetValueAt("/a/3", 4))
+	assert.Equal(doc.String(), `{"a":[1,null,null,4]}`)
+
+	// Maximum gap.
+	assert.NoError(doc.SetArrayPolicy(dynaj.ArrayPolicy{MaxGap: 2}))
+	assert.ErrorContains(doc.SetValueAt("/a/999", 1), `cannot insert value at "/a/999": index 999 exceeds array length 4 by more than 2`)
+	assert.ErrorContains(doc.SetValueAt("/b/3", 1), "index 3 exceeds array length 0 by more than 2")
+	assert.ErrorContains(doc.SetRawAt("/a/9", json.RawMessage(`1`)), "exceeds array length")
+	assert.NoError(doc.SetValueAt("/a/6", 7))
+	assert.NoError(doc.SetValueAt("/b/2", 3))
+	assert.Equal(doc.String(), `{"a":[1,null,null,4,null,null,7],"b":[null,null,3]}`)
+
+	// Append only.
+	assert.NoError(doc.SetArrayPolicy(dynaj.ArrayPolicy{AppendOnly: true}))
+	assert.ErrorContains(doc.SetValueAt("/a/8", 1), `index 8 is not appending to array of length 7`)
+	assert.NoError(doc.SetValueAt("/a/7", 8))
+	assert.NoError(doc.SetValueAt("/a/0", 0))
+	assert.NoError(doc.SetValueAt("/c/0/d/0", "x"))
+	assert.ErrorContains(doc.MkdirAll("/e/1"), "is not appending")
+	assert.Equal(doc.NodeAt("/c").String(), "[map[d:[x]]]")
+
+	// Fill values.
+	doc = dynaj.NewDocument()
+	assert.NoError(doc.SetArrayPolicy(dynaj.ArrayPolicy{Fill: dynaj.Object{}}))
+	assert.NoError(doc.SetValueAt("/a/2/b/1", true))
+	assert.Equal(doc.String(), `{"a":[{},{},{"b":[{},true]}]}`)
+	assert.NoError(doc.SetValueAt("/a/0/x", 1))
+	assert.Equal(doc.NodeAt("/a/1").String(), "map[]")
+	assert.ErrorContains(doc.SetArrayPolicy(dynaj.ArrayPolicy{Fill: math.NaN()}), "invalid fill value")
+	assert.Equal(doc.Freeze().SetArrayPolicy(dynaj.ArrayPolicy{}), dynaj.ErrFrozen)
+}
+
+// EOF
@@ -0,0 +1,83 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// DEEP SEARCH
+//--------------------
+
+// FindKey returns the nodes of all objects, arrays, and values stored
+// under the key anywhere in the document. Indices of arrays are keys
+// too. The nodes are sorted by their paths, parents before children.
+func (d *Document) FindKey(key Key) (Nodes, error) {
+	paths := []Path{}
+	var find func(element Element, path Path) error
+	find = func(element Element, path Path) error {
+		element, err := decodeRaw(element)
+		if err != nil {
+			return err
+		}
+		visit := func(k Key, child Element) error {
+			childPath := appendKey(path, k)
+			if k == key {
+				paths = append(paths, childPath)
+			}
+			return find(child, childPath)
+		}
+		switch typed := element.(type) {
+		case Object:
+			for k, child := range typed {
+				if err := visit(k, child); err != nil {
+					return err
+				}
+			}
+		case Array:
+			for idx, child := range typed {
+				if err := visit(strconv.Itoa(idx), child); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+	if err := find(d.root, Separator); err != nil {
+		return nil, fmt.Errorf("cannot find key %q: %v", key, err)
+	}
+	sortPaths(paths)
+	nodes := make(Nodes, len(paths))
+	for i, path := range paths {
+		nodes[i] = d.NodeAt(path)
+	}
+	return nodes, nil
+}
+
+// First returns the first node with a path matching the pattern. Like
+// for ExpandPattern objects and arrays are matched too and paths are
+// ordered with parents before children. If nothing matches the node
+// contains an error.
+func (d *Document) First(pattern string) *Node {
+	paths, err := d.ExpandPattern(pattern)
+	if err != nil {
+		return &Node{path: pattern, err: err}
+	}
+	if len(paths) == 0 {
+		return &Node{path: pattern, err: fmt.Errorf("no path matches %q", pattern)}
+	}
+	return d.NodeAt(paths[0])
+}
+
+// EOF
@@ -0,0 +1,82 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestFindKey tests finding nodes by their key.
+func TestFindKey(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"id": 1,
+		"items": [
+			{"id": 10, "tags": [{"id": "x"}]},
+			{"name": "none"},
+			{"id": {"id": 12}}
+		],
+		"meta": {"owner": {"id": 2}}
+	}`)
+	assert.NoError(doc.SetRawAt("/raw", []byte(`{"id": 3}`)))
+
+	nodes, err := doc.FindKey("id")
+	assert.NoError(err)
+	paths := []string{}
+	for _, node := range nodes {
+		paths = append(paths, node.Path())
+	}
+	assert.Equal(paths, []string{"/id", "/items/0/id", "/items/0/tags/0/id", "/items/2/id", "/items/2/id/id", "/meta/owner/id", "/raw/id"})
+	assert.Equal(nodes[1].AsInt(0), 10)
+	assert.True(nodes[3].IsObject())
+	assert.NoError(nodes[6].SetValue(4))
+	assert.Equal(doc.NodeAt("/raw/id").AsInt(0), 4)
+
+	nodes, err = doc.FindKey("1")
+	assert.NoError(err)
+	assert.Length(nodes, 1)
+	assert.Equal(nodes[0].Path(), "/items/1")
+
+	nodes, err = doc.FindKey("unknown")
+	assert.NoError(err)
+	assert.Length(nodes, 0)
+}
+
+// TestFirst tests finding the first node matching a pattern.
+func TestFirst(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"b": [{"name": "x"}, {"name": "y"}], "a": {"name": "z"}}`)
+
+	node := doc.First("/*/name")
+	assert.NoError(node.Err())
+	assert.Equal(node.Path(), "/a/name")
+	assert.Equal(node.AsString(""), "z")
+
+	node = doc.First("/b/*/name")
+	assert.Equal(node.AsString(""), "x")
+
+	node = doc.First("/b/*")
+	assert.Equal(node.Path(), "/b/0")
+	assert.True(node.IsObject())
+
+	node = doc.First("/c/*")
+	assert.True(node.IsError())
+	assert.ErrorContains(node.Err(), `no path matches "/c/*"`)
+}
+
+// EOF
@@ -0,0 +1,65 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sync"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestFreeze tests frozen documents.
+func TestFreeze(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a":{"b":1,"c":[1,2,3]}}`)
+
+	frozen := doc.Freeze()
+	assert.True(frozen.IsFrozen())
+	assert.False(doc.IsFrozen())
+	assert.True(frozen.Freeze() == frozen)
+
+	// Mutations fail.
+	assert.ErrorMatch(frozen.SetValueAt("/a/b", 2), dynaj.ErrFrozen.Error())
+	assert.Equal(frozen.DeleteValueAt("/a/b"), dynaj.ErrFrozen)
+	assert.Equal(frozen.DeleteElementAt("/a"), dynaj.ErrFrozen)
+	frozen.Clear()
+	assert.Equal(frozen.NodeAt("/a/b").AsInt(0), 1)
+
+	// Changes of the original are not visible.
+	assert.NoError(doc.SetValueAt("/a/b", 2))
+	assert.NoError(doc.DeleteValueAt("/a/c/0"))
+	assert.Equal(frozen.NodeAt("/a/b").AsInt(0), 1)
+	assert.Equal(frozen.Length("/a/c"), 3)
+
+	// Concurrent reading.
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				_ = frozen.NodeAt("/a/c/2").AsInt(0)
+				_ = frozen.String()
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// EOF
@@ -0,0 +1,24 @@
+// Tideland Go Dynamic JSON - Generator
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package gen generates random but valid JSON documents for fuzz and
+// property based testing. The shape of the documents is controlled by
+// options like the maximum depth, the maximum number of entries, and
+// the kinds of values.
+//
+//	r := rand.New(rand.NewSource(seed))
+//	doc := gen.RandomDocument(r, gen.DefaultOptions())
+//
+// Existing documents can be changed randomly with
+//
+//	mutation, err := gen.Mutate(r, doc, gen.DefaultOptions())
+//
+// which changes, deletes, or inserts one element and returns what
+// has been done.
+package gen // import "tideland.dev/go/dynaj/gen"
+
+// EOF
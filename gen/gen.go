@@ -0,0 +1,252 @@
+// Tideland Go Dynamic JSON - Generator
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package gen // import "tideland.dev/go/dynaj/gen"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math/rand"
+	"strconv"
+	"strings"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// OPTIONS
+//--------------------
+
+// Kind describes the kinds of JSON elements to generate.
+type Kind int
+
+// Flags of the kinds to generate. They can be combined.
+const (
+	Null Kind = 1 << iota
+	Bool
+	Number
+	String
+	Object
+	Array
+
+	// Values contains all kinds of simple values.
+	Values = Null | Bool | Number | String
+
+	// AllKinds contains all kinds of elements.
+	AllKinds = Values | Object | Array
+)
+
+// chars are used to build random strings, including those which
+// have to be escaped.
+var chars = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789 _-/\"\\\täöü€😀")
+
+// Options control the shape of generated documents.
+type Options struct {
+	// MaxDepth is the maximum nesting depth of objects and arrays.
+	MaxDepth int
+
+	// MaxEntries is the maximum number of entries of objects and arrays.
+	MaxEntries int
+
+	// MaxStringLength is the maximum length of generated strings.
+	MaxStringLength int
+
+	// Kinds defines which kinds of elements are generated.
+	Kinds Kind
+}
+
+// DefaultOptions returns options generating small documents with
+// all kinds of elements.
+func DefaultOptions() Options {
+	return Options{
+		MaxDepth:        4,
+		MaxEntries:      5,
+		MaxStringLength: 16,
+		Kinds:           AllKinds,
+	}
+}
+
+//--------------------
+// GENERATION
+//--------------------
+
+// RandomDocument creates a random document. If object or array kinds
+// are allowed the root is one of them.
+func RandomDocument(r *rand.Rand, opts Options) *dynaj.Document {
+	kinds := opts.Kinds
+	if kinds&(Object|Array) != 0 {
+		kinds &= Object | Array
+	}
+	doc := dynaj.NewDocument()
+	// Setting the root cannot fail.
+	_ = doc.SetValueAt(dynaj.Separator, randomElement(r, opts, kinds, 0))
+	return doc
+}
+
+// RandomValue creates a random element following the options.
+func RandomValue(r *rand.Rand, opts Options) dynaj.Value {
+	return randomElement(r, opts, opts.Kinds, 0)
+}
+
+// RandomKey creates a random object key.
+func RandomKey(r *rand.Rand) dynaj.Key {
+	key := make([]rune, 1+r.Intn(8))
+	for i := range key {
+		key[i] = rune('a' + r.Intn(26))
+	}
+	return string(key)
+}
+
+// unusedKey draws random keys until one does not exist in the object
+// at the parent path. So inserting never overwrites values or fails
+// on existing containers.
+func unusedKey(r *rand.Rand, doc *dynaj.Document, parent dynaj.Path) dynaj.Key {
+	for {
+		key := RandomKey(r)
+		if doc.NodeAt(dynaj.JoinPath(parent, key)).IsError() {
+			return key
+		}
+	}
+}
+
+// randomElement creates a random element of the given kinds at the
+// given depth.
+func randomElement(r *rand.Rand, opts Options, kinds Kind, depth int) dynaj.Element {
+	if depth >= opts.MaxDepth {
+		kinds &= Values
+	}
+	kind := chooseKind(r, kinds)
+	switch kind {
+	case Bool:
+		return r.Intn(2) == 1
+	case Number:
+		if r.Intn(2) == 0 {
+			return float64(r.Intn(2001) - 1000)
+		}
+		return r.NormFloat64() * 1000
+	case String:
+		s := make([]rune, r.Intn(opts.MaxStringLength+1))
+		for i := range s {
+			s[i] = chars[r.Intn(len(chars))]
+		}
+		return string(s)
+	case Object:
+		obj := dynaj.Object{}
+		for i := r.Intn(opts.MaxEntries + 1); i > 0; i-- {
+			obj[RandomKey(r)] = randomElement(r, opts, opts.Kinds, depth+1)
+		}
+		return obj
+	case Array:
+		arr := make(dynaj.Array, r.Intn(opts.MaxEntries+1))
+		for i := range arr {
+			arr[i] = randomElement(r, opts, opts.Kinds, depth+1)
+		}
+		return arr
+	default:
+		return nil
+	}
+}
+
+// chooseKind randomly chooses one of the kinds. Without any kind
+// it returns Null.
+func chooseKind(r *rand.Rand, kinds Kind) Kind {
+	candidates := []Kind{}
+	for kind := Null; kind <= Array; kind <<= 1 {
+		if kinds&kind != 0 {
+			candidates = append(candidates, kind)
+		}
+	}
+	if len(candidates) == 0 {
+		return Null
+	}
+	return candidates[r.Intn(len(candidates))]
+}
+
+//--------------------
+// MUTATION
+//--------------------
+
+// MutationKind describes the kind of a mutation.
+type MutationKind int
+
+// Kinds of mutations.
+const (
+	ChangeValue MutationKind = iota
+	DeleteElement
+	InsertElement
+)
+
+// String implements fmt.Stringer.
+func (k MutationKind) String() string {
+	switch k {
+	case ChangeValue:
+		return "change"
+	case DeleteElement:
+		return "delete"
+	default:
+		return "insert"
+	}
+}
+
+// Mutation describes one applied mutation.
+type Mutation struct {
+	Kind MutationKind
+	Path dynaj.Path
+}
+
+// String implements fmt.Stringer.
+func (m Mutation) String() string {
+	return fmt.Sprintf("%v %s", m.Kind, m.Path)
+}
+
+// Mutate randomly changes a value, deletes an element, or inserts a
+// new element into the document. New values follow the options.
+func Mutate(r *rand.Rand, doc *dynaj.Document, opts Options) (Mutation, error) {
+	paths := []dynaj.Path{}
+	err := doc.Root().Process(func(node *dynaj.Node) error {
+		paths = append(paths, node.Path())
+		return nil
+	})
+	if err != nil {
+		return Mutation{}, err
+	}
+	path := paths[r.Intn(len(paths))]
+	node := doc.NodeAt(path)
+	container := node.IsObject() || node.IsArray()
+	kind := MutationKind(r.Intn(3))
+	switch {
+	case path == dynaj.Separator && !container:
+		kind = ChangeValue
+	case path == dynaj.Separator:
+		kind = InsertElement
+	case container && kind == ChangeValue:
+		kind = InsertElement
+	}
+	switch kind {
+	case ChangeValue:
+		return Mutation{kind, path}, doc.SetValueAt(path, randomElement(r, opts, opts.Kinds&Values, 0))
+	case DeleteElement:
+		return Mutation{kind, path}, doc.DeleteElementAt(path)
+	}
+	// Insert into the empty container or as sibling of the value.
+	parent := path
+	if !container {
+		parent = dynaj.ParentPath(path)
+	}
+	if doc.NodeAt(parent).IsArray() {
+		path = dynaj.JoinPath(parent, strconv.Itoa(doc.Length(parent)))
+	} else {
+		path = dynaj.JoinPath(parent, unusedKey(r, doc, parent))
+	}
+	depth := len(strings.Split(path, dynaj.Separator)) - 1
+	return Mutation{kind, path}, doc.SetValueAt(path, randomElement(r, opts, opts.Kinds, depth))
+}
+
+// EOF
@@ -0,0 +1,152 @@
+// Tideland Go Dynamic JSON - Generator - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package gen_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math/rand"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/gen"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestRandomDocument tests the generation of random documents.
+func TestRandomDocument(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	opts := gen.DefaultOptions()
+
+	for seed := int64(0); seed < 100; seed++ {
+		doc := gen.RandomDocument(rand.New(rand.NewSource(seed)), opts)
+		root := doc.Root()
+		assert.True(root.IsObject() || root.IsArray())
+		assert.True(depth(doc) <= opts.MaxDepth+1)
+
+		// Same seed, same document.
+		same := gen.RandomDocument(rand.New(rand.NewSource(seed)), opts)
+		assert.Equal(doc.String(), same.String())
+
+		// Marshalling round trip.
+		data, err := doc.MarshalJSON()
+		assert.NoError(err)
+		parsed, err := dynaj.Unmarshal(data)
+		assert.NoError(err)
+		diff, err := dynaj.CompareDocuments(doc, parsed)
+		assert.NoError(err)
+		assert.Length(diff.Differences(), 0)
+	}
+
+	// Only strings.
+	opts.Kinds = gen.String
+	doc := gen.RandomDocument(rand.New(rand.NewSource(1)), opts)
+	assert.True(doc.Root().IsValue())
+	s := doc.Root().AsString("<undefined>")
+	assert.Different(s, "<undefined>")
+}
+
+// TestMutate tests the random mutation of documents.
+func TestMutate(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	r := rand.New(rand.NewSource(4711))
+	opts := gen.DefaultOptions()
+	kinds := map[gen.MutationKind]int{}
+
+	for i := 0; i < 100; i++ {
+		doc := gen.RandomDocument(r, opts)
+		for j := 0; j < 10; j++ {
+			mutation, err := gen.Mutate(r, doc, opts)
+			assert.NoError(err, mutation.String())
+			kinds[mutation.Kind]++
+		}
+		assert.NoError(dynaj.Verify(doc))
+		data, err := doc.MarshalJSON()
+		assert.NoError(err)
+		_, err = dynaj.Unmarshal(data)
+		assert.NoError(err)
+	}
+	assert.Length(kinds, 3)
+}
+
+// TestMutateDenseKeys tests that inserting into an object using all
+// one letter keys for values and containers never uses existing keys.
+func TestMutateDenseKeys(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	r := rand.New(rand.NewSource(42))
+	opts := gen.DefaultOptions()
+	dense := dynaj.NewDocument()
+	for c := 'a'; c <= 'z'; c++ {
+		var value dynaj.Value = int(c)
+		if c%2 == 0 {
+			value = dynaj.Object{}
+		}
+		assert.NoError(dense.SetValueAt(dynaj.JoinPath(string(c)), value))
+	}
+	data, err := dense.MarshalJSON()
+	assert.NoError(err)
+
+	inserts := 0
+	for i := 0; i < 2000; i++ {
+		doc, err := dynaj.Unmarshal(data)
+		assert.NoError(err)
+		mutation, err := gen.Mutate(r, doc, opts)
+		assert.NoError(err, mutation.String())
+		if mutation.Kind != gen.InsertElement {
+			continue
+		}
+		inserts++
+		assert.True(dense.NodeAt(mutation.Path).IsError(), mutation.String())
+	}
+	assert.True(inserts > 0)
+}
+
+// FuzzMutate fuzzes the mutation of random documents and checks
+// that they stay valid.
+func FuzzMutate(f *testing.F) {
+	for seed := int64(0); seed < 10; seed++ {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, seed int64) {
+		assert := asserts.NewTesting(t, asserts.FailStop)
+		r := rand.New(rand.NewSource(seed))
+		opts := gen.DefaultOptions()
+		doc := gen.RandomDocument(r, opts)
+		mutation, err := gen.Mutate(r, doc, opts)
+		assert.NoError(err, mutation.String())
+		data, err := doc.MarshalJSON()
+		assert.NoError(err)
+		_, err = dynaj.Unmarshal(data)
+		assert.NoError(err)
+	})
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// depth returns the maximum depth of the document.
+func depth(doc *dynaj.Document) int {
+	max := 0
+	_ = doc.Root().Process(func(node *dynaj.Node) error {
+		if d := len(node.SplitPath()); d > max {
+			max = d
+		}
+		return nil
+	})
+	return max
+}
+
+// EOF
@@ -3,6 +3,9 @@ module tideland.dev/go/dynaj
 go 1.19
 
 require (
+	github.com/fsnotify/fsnotify v1.6.0
 	tideland.dev/go/audit v0.7.0
 	tideland.dev/go/matcher v0.1.0
 )
+
+require golang.org/x/sys v0.7.0 // indirect
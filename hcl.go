@@ -0,0 +1,463 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// HCL
+//--------------------
+
+// UnmarshalHCL parses configuration data in the HashiCorp Configuration
+// Language into a new document. Attributes become keys of objects, blocks
+// nested objects using their type and labels as keys, so
+//
+//	service "web" {
+//	  port = 8080
+//	}
+//
+// is stored as {"service":{"web":{"port":8080}}}. Repeated blocks with
+// the same type and labels are collected in arrays. Supported values are
+// strings, heredocs, numbers, bools, null, lists, and objects. Variables,
+// function calls, and other expressions are not evaluated but rejected.
+// Interpolations inside of strings are kept as they are.
+func UnmarshalHCL(data []byte) (*Document, error) {
+	p := &hclParser{
+		data: data,
+		line: 1,
+	}
+	root := Object{}
+	if err := p.parseBody(root, false); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal HCL: line %d: %v", p.line, err)
+	}
+	return &Document{
+		root: root,
+	}, nil
+}
+
+// hclParser parses HCL data.
+type hclParser struct {
+	data []byte
+	pos  int
+	line int
+}
+
+// parseBody parses attributes and blocks into the object. Nested bodies
+// are closed by a brace.
+func (p *hclParser) parseBody(obj Object, nested bool) error {
+	for {
+		p.skip(true)
+		if p.eof() {
+			if nested {
+				return fmt.Errorf("missing closing brace")
+			}
+			return nil
+		}
+		if nested && p.peek() == '}' {
+			p.pos++
+			return nil
+		}
+		name, err := p.ident()
+		if err != nil {
+			return err
+		}
+		if !validObjectKey(name) {
+			return fmt.Errorf("invalid name %q", name)
+		}
+		p.skip(false)
+		if p.peek() == '=' {
+			p.pos++
+			if err := p.parseAttribute(obj, name); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := p.parseBlock(obj, name); err != nil {
+			return err
+		}
+	}
+}
+
+// parseAttribute parses the value of an attribute, which has to be
+// followed by the end of the line.
+func (p *hclParser) parseAttribute(obj Object, name string) error {
+	if _, ok := obj[name]; ok {
+		return fmt.Errorf("duplicate attribute %q", name)
+	}
+	value, err := p.parseExpr()
+	if err != nil {
+		return err
+	}
+	obj[name] = value
+	p.skip(false)
+	switch {
+	case p.eof() || p.peek() == '}':
+	case p.peek() == '\n':
+		p.pos++
+		p.line++
+	default:
+		return fmt.Errorf("unexpected %q after attribute %q", p.peek(), name)
+	}
+	return nil
+}
+
+// parseBlock parses the labels and the body of a block.
+func (p *hclParser) parseBlock(obj Object, name string) error {
+	keys := Keys{name}
+	for !p.eof() && p.peek() != '{' {
+		var label string
+		var err error
+		if p.peek() == '"' {
+			label, err = p.parseString()
+		} else {
+			label, err = p.ident()
+		}
+		if err != nil {
+			return err
+		}
+		if !validObjectKey(label) {
+			return fmt.Errorf("invalid label %q", label)
+		}
+		keys = append(keys, label)
+		p.skip(false)
+	}
+	if p.eof() {
+		return fmt.Errorf("missing body of block %q", name)
+	}
+	p.pos++
+	body := Object{}
+	if err := p.parseBody(body, true); err != nil {
+		return err
+	}
+	// Walk along the type and labels.
+	h, t := keys[:len(keys)-1], keys[len(keys)-1]
+	for _, key := range h {
+		switch typed := obj[key].(type) {
+		case nil:
+			sub := Object{}
+			obj[key] = sub
+			obj = sub
+		case Object:
+			obj = typed
+		default:
+			return fmt.Errorf("block %q conflicts with attribute %q", name, key)
+		}
+	}
+	switch typed := obj[t].(type) {
+	case nil:
+		obj[t] = body
+	case Object:
+		obj[t] = Array{typed, body}
+	case Array:
+		obj[t] = append(typed, body)
+	default:
+		return fmt.Errorf("block %q conflicts with attribute %q", name, t)
+	}
+	return nil
+}
+
+// parseExpr parses a value.
+func (p *hclParser) parseExpr() (Element, error) {
+	p.skip(false)
+	if p.eof() {
+		return nil, fmt.Errorf("missing value")
+	}
+	c := p.peek()
+	switch {
+	case c == '"':
+		return p.parseString()
+	case c == '[':
+		return p.parseList()
+	case c == '{':
+		return p.parseObject()
+	case c == '<':
+		return p.parseHeredoc()
+	case c == '-' || (c >= '0' && c <= '9'):
+		return p.parseNumber()
+	}
+	word, err := p.ident()
+	if err != nil {
+		return nil, err
+	}
+	switch word {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported expression %q", word)
+}
+
+// parseString parses a quoted string.
+func (p *hclParser) parseString() (string, error) {
+	start := p.pos
+	p.pos++
+	for !p.eof() {
+		switch p.data[p.pos] {
+		case '\\':
+			p.pos += 2
+		case '"':
+			p.pos++
+			s, err := strconv.Unquote(string(p.data[start:p.pos]))
+			if err != nil {
+				return "", fmt.Errorf("invalid string %s", p.data[start:p.pos])
+			}
+			return s, nil
+		case '\n':
+			return "", fmt.Errorf("unterminated string")
+		default:
+			p.pos++
+		}
+	}
+	return "", fmt.Errorf("unterminated string")
+}
+
+// parseHeredoc parses a heredoc string. With "<<-" the indentation of
+// the lines is removed.
+func (p *hclParser) parseHeredoc() (string, error) {
+	if !strings.HasPrefix(string(p.data[p.pos:]), "<<") {
+		return "", fmt.Errorf("unexpected %q", p.peek())
+	}
+	p.pos += 2
+	indented := false
+	if p.peek() == '-' {
+		indented = true
+		p.pos++
+	}
+	marker, err := p.ident()
+	if err != nil {
+		return "", err
+	}
+	p.skip(false)
+	if p.eof() || p.peek() != '\n' {
+		return "", fmt.Errorf("missing newline after heredoc marker %q", marker)
+	}
+	p.pos++
+	p.line++
+	lines := []string{}
+	for !p.eof() {
+		end := p.pos
+		for end < len(p.data) && p.data[end] != '\n' {
+			end++
+		}
+		text := strings.TrimSuffix(string(p.data[p.pos:end]), "\r")
+		p.pos = end
+		if strings.TrimSpace(text) == marker {
+			if indented {
+				lines = unindent(lines)
+			}
+			if len(lines) == 0 {
+				return "", nil
+			}
+			return strings.Join(lines, "\n") + "\n", nil
+		}
+		lines = append(lines, text)
+		if !p.eof() {
+			p.pos++
+			p.line++
+		}
+	}
+	return "", fmt.Errorf("unterminated heredoc %q", marker)
+}
+
+// parseNumber parses a number.
+func (p *hclParser) parseNumber() (float64, error) {
+	start := p.pos
+	for !p.eof() && strings.IndexByte("+-.0123456789eE", p.peek()) >= 0 {
+		p.pos++
+	}
+	f, err := strconv.ParseFloat(string(p.data[start:p.pos]), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", p.data[start:p.pos])
+	}
+	return f, nil
+}
+
+// parseList parses a list of values.
+func (p *hclParser) parseList() (Array, error) {
+	p.pos++
+	arr := Array{}
+	for {
+		p.skip(true)
+		if p.eof() {
+			return nil, fmt.Errorf("missing closing bracket")
+		}
+		if p.peek() == ']' {
+			p.pos++
+			return arr, nil
+		}
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		arr = append(arr, value)
+		p.skip(true)
+		switch {
+		case p.eof():
+		case p.peek() == ',':
+			p.pos++
+		case p.peek() != ']':
+			return nil, fmt.Errorf("unexpected %q in list", p.peek())
+		}
+	}
+}
+
+// parseObject parses an object value.
+func (p *hclParser) parseObject() (Object, error) {
+	p.pos++
+	obj := Object{}
+	for {
+		p.skip(true)
+		if p.eof() {
+			return nil, fmt.Errorf("missing closing brace")
+		}
+		if p.peek() == '}' {
+			p.pos++
+			return obj, nil
+		}
+		var key string
+		var err error
+		if p.peek() == '"' {
+			key, err = p.parseString()
+		} else {
+			key, err = p.ident()
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !validObjectKey(key) {
+			return nil, fmt.Errorf("invalid key %q", key)
+		}
+		p.skip(false)
+		if p.eof() || (p.peek() != '=' && p.peek() != ':') {
+			return nil, fmt.Errorf("missing value of key %q", key)
+		}
+		p.pos++
+		value, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+		p.skip(false)
+		if !p.eof() && p.peek() == ',' {
+			p.pos++
+		}
+	}
+}
+
+// ident reads an identifier.
+func (p *hclParser) ident() (string, error) {
+	start := p.pos
+	for !p.eof() {
+		c := p.peek()
+		if c != '_' && c != '-' && !(c >= 'a' && c <= 'z') && !(c >= 'A' && c <= 'Z') &&
+			!(c >= '0' && c <= '9' && p.pos > start) {
+			break
+		}
+		p.pos++
+	}
+	if p.pos == start {
+		if p.eof() {
+			return "", fmt.Errorf("unexpected end of data")
+		}
+		return "", fmt.Errorf("unexpected %q", p.peek())
+	}
+	return string(p.data[start:p.pos]), nil
+}
+
+// skip skips white space and comments. Newlines are only skipped
+// if wanted.
+func (p *hclParser) skip(newlines bool) {
+	for !p.eof() {
+		c := p.peek()
+		switch {
+		case c == ' ' || c == '\t' || c == '\r':
+			p.pos++
+		case c == '\n' && newlines:
+			p.pos++
+			p.line++
+		case c == '#' || (c == '/' && p.next() == '/'):
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		case c == '/' && p.next() == '*':
+			p.pos += 2
+			for !p.eof() && !(p.peek() == '*' && p.next() == '/') {
+				if p.peek() == '\n' {
+					p.line++
+				}
+				p.pos++
+			}
+			p.pos += 2
+			if p.pos > len(p.data) {
+				p.pos = len(p.data)
+			}
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the current byte or zero at the end of the data.
+func (p *hclParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// next returns the byte after the current one or zero.
+func (p *hclParser) next() byte {
+	if p.pos+1 < len(p.data) {
+		return p.data[p.pos+1]
+	}
+	return 0
+}
+
+// eof checks if all data is read.
+func (p *hclParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+// unindent removes the common indentation of the lines.
+func unindent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	if indent <= 0 {
+		return lines
+	}
+	out := make([]string, len(lines))
+	for i, line := range lines {
+		if len(line) >= indent {
+			out[i] = line[indent:]
+		} else {
+			out[i] = strings.TrimLeft(line, " \t")
+		}
+	}
+	return out
+}
+
+// EOF
@@ -0,0 +1,87 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestUnmarshalHCL tests parsing HCL data.
+func TestUnmarshalHCL(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := `# Service configuration.
+name    = "demo"
+enabled = true
+ratio   = -1.5
+missing = null
+
+// Blocks with labels.
+service "web" {
+  port  = 8080
+  hosts = [
+    "a.example.com",
+    "b.example.com", // Trailing comma.
+  ]
+  limits = { cpu = 2, "memory": "1GB" }
+}
+
+/* Repeated blocks
+   are collected. */
+rule {
+  allow = "${var.user}"
+}
+rule {
+  allow = "admin"
+}
+
+script = <<-EOT
+    echo "hello"
+      exit 0
+    EOT
+`
+	doc, err := dynaj.UnmarshalHCL([]byte(data))
+	assert.NoError(err)
+	assert.Equal(doc.String(), `{"enabled":true,"missing":null,"name":"demo","ratio":-1.5,`+
+		`"rule":[{"allow":"${var.user}"},{"allow":"admin"}],`+
+		`"script":"echo \"hello\"\n  exit 0\n",`+
+		`"service":{"web":{"hosts":["a.example.com","b.example.com"],"limits":{"cpu":2,"memory":"1GB"},"port":8080}}}`)
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"a = 1\na = 2\n", `line 2: duplicate attribute "a"`},
+		{"a = b\n", `line 1: unsupported expression "b"`},
+		{"a = 1 b = 2\n", `line 1: unexpected 'b' after attribute "a"`},
+		{"block {\n a = 1\n", "missing closing brace"},
+		{"a = [1, 2\n", "missing closing bracket"},
+		{"a = \"open\n", "line 1: unterminated string"},
+		{"a = <<EOT\ntext\n", `unterminated heredoc "EOT"`},
+		{"a = 1\na {\n}\n", `block "a" conflicts with attribute "a"`},
+		{"block", `missing body of block "block"`},
+		{"a = 1.2.3", `invalid number "1.2.3"`},
+	}
+	for _, test := range tests {
+		_, err := dynaj.UnmarshalHCL([]byte(test.data))
+		assert.ErrorContains(err, test.err, test.data)
+	}
+}
+
+// EOF
@@ -0,0 +1,89 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// HOOKS
+//--------------------
+
+// HookKind defines when a hook is called.
+type HookKind int
+
+// Kinds of hooks.
+const (
+	// BeforeSet hooks are called before setting a value. Their errors
+	// veto the change.
+	BeforeSet HookKind = iota
+
+	// AfterSet hooks are called after setting a value. Their errors
+	// are returned, but the value stays set.
+	AfterSet
+)
+
+// HookFunc is called with the path and the old and new value when
+// setting a value. The old value is nil if the path does not exist
+// yet. Values must not be changed by the hook.
+type HookFunc func(path Path, old, new Value) error
+
+// setHook is a registered hook.
+type setHook struct {
+	kind HookKind
+	fn   HookFunc
+}
+
+// AddHook registers the function to be called before or after setting
+// values with SetValueAt, ForceValueAt, SetRawAt, when appending to
+// arrays, or when encrypting and decrypting paths. This way invariants
+// like numeric ports or maximum array lengths can be enforced centrally.
+// Hooks are called in the order of their registration, the first error
+// stops the calls.
+func (d *Document) AddHook(kind HookKind, fn HookFunc) {
+	if d.frozen {
+		return
+	}
+	d.setHooks = append(d.setHooks, setHook{kind, fn})
+}
+
+// callHooks calls the hooks of the kind with the path and the values.
+func (d *Document) callHooks(kind HookKind, path Path, old, new Value) error {
+	for _, hook := range d.setHooks {
+		if hook.kind != kind {
+			continue
+		}
+		if err := hook.fn(path, old, new); err != nil {
+			if kind == BeforeSet {
+				return fmt.Errorf("hook rejected value at %q: %w", path, err)
+			}
+			return fmt.Errorf("hook failed after setting value at %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// currentValue returns the value at the keys for the hooks, nil if it
+// does not exist or no hooks are registered.
+func (d *Document) currentValue(keys Keys) Value {
+	if len(d.setHooks) == 0 {
+		return nil
+	}
+	current, err := elementAt(d.root, keys)
+	if err != nil {
+		return nil
+	}
+	return current
+}
+
+// EOF
@@ -0,0 +1,87 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestHooks tests enforcing invariants with hooks.
+func TestHooks(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"port": 8080, "hosts": ["a"]}`)
+	errNotNumeric := errors.New("port must stay numeric")
+	doc.AddHook(dynaj.BeforeSet, func(path dynaj.Path, old, new dynaj.Value) error {
+		if path != "/port" {
+			return nil
+		}
+		if _, ok := new.(int); !ok {
+			return errNotNumeric
+		}
+		return nil
+	})
+	doc.AddHook(dynaj.BeforeSet, func(path dynaj.Path, old, new dynaj.Value) error {
+		if dynaj.IsAncestor("/hosts", path) && doc.Length("/hosts") >= 2 {
+			return errors.New("at most 2 hosts")
+		}
+		return nil
+	})
+	changes := []string{}
+	doc.AddHook(dynaj.AfterSet, func(path dynaj.Path, old, new dynaj.Value) error {
+		changes = append(changes, fmt.Sprintf("%s: %v -> %v", path, old, new))
+		return nil
+	})
+
+	// Vetoed changes.
+	err := doc.SetValueAt("/port", "80")
+	assert.True(errors.Is(err, errNotNumeric))
+	assert.ErrorContains(err, `hook rejected value at "/port": port must stay numeric`)
+	assert.ErrorContains(doc.ForceValueAt("/port", true), "port must stay numeric")
+	assert.ErrorContains(doc.SetRawAt("/port", []byte(`"80"`)), "port must stay numeric")
+	assert.Equal(doc.NodeAt("/port").AsInt(0), 8080)
+	assert.Length(changes, 0)
+
+	// Accepted changes.
+	assert.NoError(doc.SetValueAt("/port", 80))
+	assert.NoError(doc.AddToArray("/hosts", "b"))
+	assert.ErrorContains(doc.AddToArray("/hosts", "c"), `hook rejected value at "/hosts/2": at most 2 hosts`)
+	assert.ErrorContains(doc.NodeAt("/hosts/1").SetValue("x"), "at most 2 hosts")
+	assert.Equal(doc.Length("/hosts"), 2)
+	assert.Equal(changes, []string{"/port: 8080 -> 80", "/hosts/1: <nil> -> b"})
+}
+
+// TestAfterHookError tests errors of hooks after setting a value.
+func TestAfterHookError(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": 1}`)
+	doc.AddHook(dynaj.AfterSet, func(path dynaj.Path, old, new dynaj.Value) error {
+		return errors.New("ouch")
+	})
+	assert.ErrorContains(doc.SetValueAt("/a", 2), `hook failed after setting value at "/a": ouch`)
+	assert.Equal(doc.NodeAt("/a").AsInt(0), 2)
+
+	// Copies do not take over the hooks.
+	clone := doc.Freeze().Restricted(dynaj.AccessRules{Default: dynaj.FullAccess})
+	assert.NoError(clone.SetValueAt("/a", 3))
+}
+
+// EOF
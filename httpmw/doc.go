@@ -0,0 +1,23 @@
+// Tideland Go Dynamic JSON - HTTP Middleware
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package httpmw provides HTTP middleware standardizing the handling of
+// JSON request bodies. They are parsed into documents, passed through
+// an optional pipeline for validation or redaction, and stored in the
+// request context for the handlers.
+//
+//	pipeline := dynaj.NewPipeline().
+//		Add("verify", dynaj.VerifyStage()).
+//		Add("redact", dynaj.RedactStage("***", "*/password"))
+//	handler = httpmw.New(httpmw.WithPipeline(pipeline))(handler)
+//
+// Handlers retrieve the processed document with FromRequest. The body
+// as sent by the client is available with OriginalFromRequest, so the
+// changes of the pipeline can be compared.
+package httpmw // import "tideland.dev/go/dynaj/httpmw"
+
+// EOF
@@ -0,0 +1,198 @@
+// Tideland Go Dynamic JSON - HTTP Middleware
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package httpmw // import "tideland.dev/go/dynaj/httpmw"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// DefaultMaxBytes is the default maximum size of request bodies.
+const DefaultMaxBytes = 1 << 20
+
+//--------------------
+// OPTIONS
+//--------------------
+
+// ErrorHandler writes the response for failed requests.
+type ErrorHandler func(w http.ResponseWriter, r *http.Request, status int, err error)
+
+// Option configures the middleware.
+type Option func(c *config)
+
+// config contains the configuration of the middleware.
+type config struct {
+	pipeline     *dynaj.Pipeline
+	maxBytes     int64
+	required     bool
+	errorHandler ErrorHandler
+}
+
+// WithPipeline sets the pipeline the documents are passed through. A
+// failing pipeline rejects the request with 422 Unprocessable Entity.
+func WithPipeline(pipeline *dynaj.Pipeline) Option {
+	return func(c *config) {
+		c.pipeline = pipeline
+	}
+}
+
+// WithMaxBytes sets the maximum size of request bodies. Larger ones
+// are rejected with 413 Request Entity Too Large.
+func WithMaxBytes(n int64) Option {
+	return func(c *config) {
+		c.maxBytes = n
+	}
+}
+
+// WithRequiredBody rejects requests without JSON body with 415
+// Unsupported Media Type. Otherwise they are passed without document.
+func WithRequiredBody() Option {
+	return func(c *config) {
+		c.required = true
+	}
+}
+
+// WithErrorHandler sets the handler writing the responses of failed
+// requests. By default a JSON object with the error is written.
+func WithErrorHandler(handler ErrorHandler) Option {
+	return func(c *config) {
+		c.errorHandler = handler
+	}
+}
+
+//--------------------
+// MIDDLEWARE
+//--------------------
+
+// New creates the middleware. Requests with a JSON body, identified by
+// the content type "application/json" or one ending with "+json", are
+// parsed, processed by the pipeline, and stored in the request context.
+func New(opts ...Option) func(http.Handler) http.Handler {
+	c := &config{
+		maxBytes:     DefaultMaxBytes,
+		errorHandler: writeError,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !hasJSONBody(r) {
+				if c.required {
+					c.errorHandler(w, r, http.StatusUnsupportedMediaType, errors.New("missing JSON body"))
+					return
+				}
+				next.ServeHTTP(w, r)
+				return
+			}
+			status, ctx, err := c.process(w, r)
+			if err != nil {
+				c.errorHandler(w, r, status, err)
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// process reads, parses, and processes the body. It returns the
+// context containing the documents or the status code and error.
+func (c *config) process(w http.ResponseWriter, r *http.Request) (int, context.Context, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, c.maxBytes))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			return http.StatusRequestEntityTooLarge, nil, fmt.Errorf("body exceeds %d bytes", c.maxBytes)
+		}
+		return http.StatusBadRequest, nil, fmt.Errorf("cannot read body: %v", err)
+	}
+	doc, err := dynaj.Unmarshal(body)
+	if err != nil {
+		return http.StatusBadRequest, nil, err
+	}
+	original := doc.Freeze()
+	if c.pipeline != nil {
+		doc, err = c.pipeline.Run(r.Context(), doc)
+		if err != nil {
+			return http.StatusUnprocessableEntity, nil, err
+		}
+	}
+	ctx := context.WithValue(r.Context(), originalKey, original)
+	ctx = dynaj.NewContext(ctx, doc)
+	return 0, ctx, nil
+}
+
+//--------------------
+// CONTEXT
+//--------------------
+
+// contextKey is the type of the key for the original document.
+type contextKey int
+
+// originalKey is the key of the original document in the context.
+const originalKey contextKey = 0
+
+// FromContext returns the processed document stored by the middleware.
+// It is stored with dynaj.NewContext, so dynaj.FromContext returns it
+// too.
+func FromContext(ctx context.Context) (*dynaj.Document, bool) {
+	return dynaj.FromContext(ctx)
+}
+
+// FromRequest returns the processed document of the request.
+func FromRequest(r *http.Request) (*dynaj.Document, bool) {
+	return FromContext(r.Context())
+}
+
+// OriginalFromRequest returns the frozen document as parsed from the
+// body of the request before running the pipeline.
+func OriginalFromRequest(r *http.Request) (*dynaj.Document, bool) {
+	doc, ok := r.Context().Value(originalKey).(*dynaj.Document)
+	return doc, ok
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// hasJSONBody checks if the request has a body with JSON content type.
+func hasJSONBody(r *http.Request) bool {
+	if r.Body == nil || r.Body == http.NoBody {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// writeError writes the error as JSON object.
+func writeError(w http.ResponseWriter, r *http.Request, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
+
+// EOF
@@ -0,0 +1,113 @@
+// Tideland Go Dynamic JSON - HTTP Middleware - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package httpmw_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/httpmw"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMiddleware tests parsing and processing request bodies.
+func TestMiddleware(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	pipeline := dynaj.NewPipeline().
+		Add("verify", dynaj.VerifyStage()).
+		Add("assert", dynaj.AssertStage(map[dynaj.Path]dynaj.Value{"/kind": "user"})).
+		Add("redact", dynaj.RedactStage("***", "*/password"))
+	var got, original *dynaj.Document
+	handler := httpmw.New(httpmw.WithPipeline(pipeline), httpmw.WithMaxBytes(64))(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got, _ = httpmw.FromRequest(r)
+			original, _ = httpmw.OriginalFromRequest(r)
+			w.WriteHeader(http.StatusNoContent)
+		}))
+
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+		err         string
+	}{
+		{"valid", "application/json", `{"kind":"user","password":"secret"}`, http.StatusNoContent, ""},
+		{"patch", "application/merge-patch+json; charset=utf-8", `{"kind":"user"}`, http.StatusNoContent, ""},
+		{"invalid", "application/json", `{"kind":`, http.StatusBadRequest, "cannot unmarshal document"},
+		{"rejected", "application/json", `{"kind":"admin"}`, http.StatusUnprocessableEntity, `stage 1 \"assert\" failed`},
+		{"too large", "application/json", `{"kind":"` + strings.Repeat("x", 64) + `"}`, http.StatusRequestEntityTooLarge, "exceeds 64 bytes"},
+		{"no JSON", "text/plain", `hello`, http.StatusNoContent, ""},
+	}
+	for _, test := range tests {
+		got, original = nil, nil
+		req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+		req.Header.Set("Content-Type", test.contentType)
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		assert.Equal(rec.Code, test.status, test.name)
+		if test.err != "" {
+			assert.Substring(test.err, rec.Body.String(), test.name)
+			assert.Equal(rec.Header().Get("Content-Type"), "application/json")
+			assert.Nil(got)
+		}
+	}
+
+	// Check the documents of a valid request.
+	req := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"kind":"user","password":"secret"}`))
+	req.Header.Set("Content-Type", "application/json")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(got.NodeAt("/password").AsString(""), "***")
+	assert.Equal(original.NodeAt("/password").AsString(""), "secret")
+	assert.True(original.IsFrozen())
+	diff, err := dynaj.CompareDocuments(original, got)
+	assert.NoError(err)
+	assert.Equal(diff.Differences(), []string{"/password"})
+}
+
+// TestRequiredBody tests rejecting requests without JSON.
+func TestRequiredBody(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var status int
+	handler := httpmw.New(
+		httpmw.WithRequiredBody(),
+		httpmw.WithErrorHandler(func(w http.ResponseWriter, r *http.Request, code int, err error) {
+			status = code
+			http.Error(w, err.Error(), code)
+		}),
+	)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, ok := httpmw.FromRequest(r)
+		assert.True(ok)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(status, http.StatusUnsupportedMediaType)
+	assert.Substring("missing JSON body", rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/", strings.NewReader(`[1,2]`))
+	req.Header.Set("Content-Type", "application/json")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	assert.Equal(rec.Code, http.StatusOK)
+}
+
+// EOF
@@ -0,0 +1,164 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// INCREMENTAL MARSHALLING
+//--------------------
+
+// SetIncrementalMarshal enables or disables the incremental marshalling
+// of the document. If enabled the encodings of objects and arrays are
+// cached when marshalling. The mutation methods invalidate the cached
+// encodings along the changed path, so marshalling again only encodes
+// the changed subtrees. This speeds up the repeated marshalling of
+// large documents with few changes for the price of memory. Changes of
+// values set as objects or arrays after inserting them are not
+// detected.
+func (d *Document) SetIncrementalMarshal(enabled bool) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.incremental = enabled
+	d.encodings = nil
+}
+
+// appendIncremental appends the JSON encoding of the document reusing
+// and filling the cached encodings.
+func (d *Document) appendIncremental(dst []byte) ([]byte, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.encodings == nil {
+		d.encodings = map[encodingKey][]byte{}
+	}
+	out, err := d.appendCached(dst, d.root, Separator, "")
+	if err != nil {
+		return dst, fmt.Errorf("cannot marshal document: %v", err)
+	}
+	return out, nil
+}
+
+// encodingKey identifies a cached encoding by the keys of its path. Each
+// key is quoted, so keys containing separators do not collide with the
+// keys of nested paths, and the key of a path is a prefix of the keys
+// of all its descendants.
+type encodingKey string
+
+// encodingKeyOf returns the encoding key of the keys.
+func encodingKeyOf(keys Keys) encodingKey {
+	var ek encodingKey
+	for _, key := range keys {
+		ek = ek.child(key)
+	}
+	return ek
+}
+
+// child returns the encoding key of the child with the given key.
+func (ek encodingKey) child(key string) encodingKey {
+	return ek + encodingKey(strconv.Quote(key))
+}
+
+// appendCached recursively appends the element at the path using the
+// cached encodings of objects and arrays.
+func (d *Document) appendCached(dst []byte, element Element, path Path, ek encodingKey) ([]byte, error) {
+	if encoding, ok := d.encodings[ek]; ok {
+		d.countCache(EncodingCache, true)
+		return append(dst, encoding...), nil
+	}
+	start := len(dst)
+	var err error
+	switch typed := element.(type) {
+	case Object:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		dst = append(dst, '{')
+		for i, key := range keys {
+			if i > 0 {
+				dst = append(dst, ',')
+			}
+			dst = appendString(dst, key)
+			dst = append(dst, ':')
+			if dst, err = d.appendCached(dst, typed[key], appendKey(path, key), ek.child(key)); err != nil {
+				return dst, err
+			}
+		}
+		dst = append(dst, '}')
+	case Array:
+		dst = append(dst, '[')
+		for idx, child := range typed {
+			if idx > 0 {
+				dst = append(dst, ',')
+			}
+			index := strconv.Itoa(idx)
+			if dst, err = d.appendCached(dst, child, appendKey(path, index), ek.child(index)); err != nil {
+				return dst, err
+			}
+		}
+		dst = append(dst, ']')
+	case nil, bool, string, int:
+		// Values are not cached.
+		return appendElement(dst, typed)
+	default:
+		value, err := normalizeValue(element, path, d.nonFinite)
+		if err != nil {
+			return dst, err
+		}
+		return appendElement(dst, value)
+	}
+	d.countCache(EncodingCache, false)
+	encoding := make([]byte, len(dst)-start)
+	copy(encoding, dst[start:])
+	d.encodings[ek] = encoding
+	return dst, nil
+}
+
+// invalidateEncodings removes the cached encodings of the changed path,
+// its ancestors, and its descendants. If the changed element may be
+// inside an array all encodings of the parent are removed, as the
+// indices of the siblings may have been shifted. Without keys all
+// encodings are removed. It has to be called with locked mutex.
+func (d *Document) invalidateEncodings(keys Keys) {
+	if len(d.encodings) == 0 {
+		return
+	}
+	if len(keys) == 0 {
+		d.encodings = nil
+		return
+	}
+	for i := 0; i <= len(keys); i++ {
+		delete(d.encodings, encodingKeyOf(keys[:i]))
+	}
+	subtree := keys
+	if _, ok := asIndex(keys[len(keys)-1]); ok {
+		subtree = keys[:len(keys)-1]
+	}
+	if len(subtree) == 0 {
+		d.encodings = nil
+		return
+	}
+	prefix := string(encodingKeyOf(subtree))
+	for ek := range d.encodings {
+		if strings.HasPrefix(string(ek), prefix) {
+			delete(d.encodings, ek)
+		}
+	}
+}
+
+// EOF
@@ -0,0 +1,141 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"math/rand"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/gen"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestIncrementalMarshal tests the reuse of cached encodings.
+func TestIncrementalMarshal(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+	doc.SetIncrementalMarshal(true)
+
+	data, err := doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), string(bs))
+
+	// Changes are encoded, untouched subtrees reused.
+	assert.NoError(doc.SetValueAt("/B/1/S/1", "green"))
+	assert.NoError(doc.DeleteValueAt("/B/0/B"))
+	assert.NoError(doc.SetRawAt("/C", json.RawMessage(`{"x": 1}`)))
+	data, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Substring(`"S":["orange","green","white"]`, string(data))
+	assert.Substring(`"C":{"x":1}`, string(data))
+	buf, err := doc.AppendJSON([]byte("> "))
+	assert.NoError(err)
+	assert.Equal(string(buf), "> "+string(data))
+
+	// Shifted array elements.
+	assert.NoError(doc.DeleteElementAt("/B/0"))
+	data, err = doc.MarshalJSON()
+	assert.NoError(err)
+	doc.SetIncrementalMarshal(false)
+	assert.Equal(string(data), doc.String())
+
+	// Non-finite policy changes are respected.
+	doc.SetIncrementalMarshal(true)
+	doc.SetNonFinitePolicy(dynaj.StringNonFinite)
+	assert.NoError(doc.SetValueAt("/D", math.Inf(1)))
+	assert.Substring(`"D":"+Inf"`, doc.String())
+	doc.Clear()
+	assert.Equal(doc.String(), "null")
+}
+
+// TestIncrementalMarshalSeparatorKeys tests keys containing separators,
+// which must not share cached encodings with nested paths.
+func TestIncrementalMarshalSeparatorKeys(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc, err := dynaj.Unmarshal([]byte(`{"a":{"b":[2]},"a/b":[1]}`))
+	assert.NoError(err)
+	doc.SetIncrementalMarshal(true)
+
+	data, err := doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"a":{"b":[2]},"a/b":[1]}`)
+	data, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"a":{"b":[2]},"a/b":[1]}`)
+
+	assert.NoError(doc.SetValueAt("/a/b/0", 3))
+	data, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"a":{"b":[3]},"a/b":[1]}`)
+}
+
+// TestIncrementalMarshalRandom compares incremental and complete
+// marshalling of randomly mutated documents.
+func TestIncrementalMarshalRandom(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	r := rand.New(rand.NewSource(42))
+	opts := gen.DefaultOptions()
+
+	for i := 0; i < 50; i++ {
+		doc := gen.RandomDocument(r, opts)
+		doc.SetIncrementalMarshal(true)
+		for j := 0; j < 20; j++ {
+			_, err := doc.MarshalJSON()
+			assert.NoError(err)
+			_, err = gen.Mutate(r, doc, opts)
+			assert.NoError(err)
+		}
+		incremental, err := doc.MarshalJSON()
+		assert.NoError(err)
+		doc.SetIncrementalMarshal(false)
+		complete, err := doc.MarshalJSON()
+		assert.NoError(err)
+		assert.Equal(string(incremental), string(complete))
+	}
+}
+
+// BenchmarkIncrementalMarshal compares the marshalling of a large
+// document with few changes with and without incremental marshalling.
+func BenchmarkIncrementalMarshal(b *testing.B) {
+	for _, incremental := range []bool{false, true} {
+		doc := dynaj.NewDocument()
+		for i := 0; i < 1000; i++ {
+			_ = doc.SetValueAt(fmt.Sprintf("/items/%d", i), dynaj.Object{
+				"id":    i,
+				"name":  fmt.Sprintf("item %d", i),
+				"price": float64(i) * 1.5,
+				"tags":  dynaj.Array{"a", "b", "c"},
+			})
+		}
+		doc.SetIncrementalMarshal(incremental)
+		b.Run(map[bool]string{false: "complete", true: "incremental"}[incremental], func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_ = doc.SetValueAt("/items/500/name", fmt.Sprintf("changed %d", i))
+				_, _ = doc.MarshalJSON()
+			}
+		})
+	}
+}
+
+// EOF
@@ -0,0 +1,138 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// INI
+//--------------------
+
+// UnmarshalINI parses INI data into a new document. Sections become
+// objects, dotted section names like "[server.http]" nested ones. Keys
+// before the first section are set at the root. Values are strings,
+// surrounding quotes are removed. Repeated keys collect their values
+// in arrays. Lines starting with ";" or "#" are comments.
+func UnmarshalINI(data []byte) (*Document, error) {
+	root := Object{}
+	section := root
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if line == 1 {
+			text = strings.TrimPrefix(text, "\ufeff")
+		}
+		switch {
+		case text == "" || text[0] == ';' || text[0] == '#':
+			continue
+		case text[0] == '[':
+			if !strings.HasSuffix(text, "]") {
+				return nil, fmt.Errorf("cannot unmarshal INI: line %d: invalid section %q", line, text)
+			}
+			obj, err := iniSection(root, strings.TrimSpace(text[1:len(text)-1]))
+			if err != nil {
+				return nil, fmt.Errorf("cannot unmarshal INI: line %d: %v", line, err)
+			}
+			section = obj
+		default:
+			idx := strings.IndexAny(text, "=:")
+			if idx <= 0 {
+				return nil, fmt.Errorf("cannot unmarshal INI: line %d: invalid key value pair %q", line, text)
+			}
+			key := strings.TrimSpace(text[:idx])
+			if err := iniSet(section, key, iniValue(strings.TrimSpace(text[idx+1:]))); err != nil {
+				return nil, fmt.Errorf("cannot unmarshal INI: line %d: %v", line, err)
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal INI: %v", err)
+	}
+	return &Document{
+		root: root,
+	}, nil
+}
+
+// iniSection returns the object of the section, missing ones are created.
+func iniSection(root Object, name string) (Object, error) {
+	if name == "" {
+		return nil, fmt.Errorf("empty section name")
+	}
+	obj := root
+	for _, key := range strings.Split(name, ".") {
+		key = strings.TrimSpace(key)
+		if !validObjectKey(key) {
+			return nil, fmt.Errorf("invalid section %q", name)
+		}
+		switch typed := obj[key].(type) {
+		case nil:
+			sub := Object{}
+			obj[key] = sub
+			obj = sub
+		case Object:
+			obj = typed
+		default:
+			return nil, fmt.Errorf("section %q conflicts with key %q", name, key)
+		}
+	}
+	return obj, nil
+}
+
+// iniSet sets the value in the section. Repeated keys create arrays.
+func iniSet(section Object, key, value string) error {
+	if !validObjectKey(key) {
+		return fmt.Errorf("invalid key %q", key)
+	}
+	switch typed := section[key].(type) {
+	case nil:
+		section[key] = value
+	case string:
+		section[key] = Array{typed, value}
+	case Array:
+		section[key] = append(typed, value)
+	default:
+		return fmt.Errorf("key %q conflicts with section", key)
+	}
+	return nil
+}
+
+// iniValue removes surrounding quotes of the value.
+func iniValue(value string) string {
+	if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			return unquoted
+		}
+	}
+	if len(value) >= 2 && value[0] == '\'' && value[len(value)-1] == '\'' {
+		return value[1 : len(value)-1]
+	}
+	return value
+}
+
+// validObjectKey checks if the key can be used in a path.
+func validObjectKey(key string) bool {
+	if key == "" || strings.Contains(key, Separator) {
+		return false
+	}
+	_, isIndex := asIndex(key)
+	return !isIndex
+}
+
+// EOF
@@ -0,0 +1,70 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestUnmarshalINI tests parsing INI data.
+func TestUnmarshalINI(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := "\ufeffname = demo\n" +
+		"; comment\n" +
+		"[server]\n" +
+		"host = localhost\n" +
+		"port: 8080\n" +
+		"\n" +
+		"[server.tls]\n" +
+		"# another comment\n" +
+		"cert = \"/etc/cert.pem\"\n" +
+		"alias = 'x'\n" +
+		"[peers]\n" +
+		"peer = a\n" +
+		"peer = b\n" +
+		"peer = c\n" +
+		"[server]\n" +
+		"debug = yes\n"
+
+	doc, err := dynaj.UnmarshalINI([]byte(data))
+	assert.NoError(err)
+	assert.Equal(doc.String(), `{"name":"demo","peers":{"peer":["a","b","c"]},`+
+		`"server":{"debug":"yes","host":"localhost","port":"8080","tls":{"alias":"x","cert":"/etc/cert.pem"}}}`)
+	assert.Equal(doc.NodeAt("/server/port").AsInt(0), 8080)
+
+	tests := []struct {
+		data string
+		err  string
+	}{
+		{"[server\n", "line 1: invalid section"},
+		{"a = 1\n[]\n", "line 2: empty section name"},
+		{"novalue\n", "line 1: invalid key value pair"},
+		{"= 1\n", "line 1: invalid key value pair"},
+		{"a = 1\n[a]\n", `line 2: section "a" conflicts with key "a"`},
+		{"[a]\n[a.0]\n", `line 2: invalid section "a.0"`},
+		{"[a.b]\n[a]\nb = 1\n", `line 3: key "b" conflicts with section`},
+	}
+	for _, test := range tests {
+		_, err := dynaj.UnmarshalINI([]byte(test.data))
+		assert.ErrorContains(err, test.err, test.data)
+	}
+}
+
+// EOF
@@ -0,0 +1,200 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//--------------------
+// RECORD INTERNING
+//--------------------
+
+// InternRecords stores arrays of at least two objects with the same
+// keys, like homogeneous record streams, as rows of values indexed by
+// a shared key table instead of individual objects. Without the maps
+// and their keys the memory of uniform records shrinks to about the
+// half or less. Reading single values stays direct, reading whole
+// records or arrays returns expanded copies, and changing an interned
+// array expands it into objects again. It returns the number of
+// interned arrays.
+func (d *Document) InternRecords() (int, error) {
+	if d.frozen {
+		return 0, ErrFrozen
+	}
+	in := newInterner()
+	root := d.root
+	if d.shared {
+		// Interning changes the containers in place.
+		root = copyElement(root)
+	}
+	d.root = in.element(root)
+	d.shared = false
+	d.owned = nil
+	d.changed(nil)
+	return in.interned, nil
+}
+
+// keyTable contains the sorted keys shared by records and their indices.
+type keyTable struct {
+	keys    Keys
+	indices map[string]int
+}
+
+// records stores the values of uniform objects row by row in the order
+// of the keys of their table. They are never changed after creation.
+type records struct {
+	table  *keyTable
+	values []Element
+}
+
+// len returns the number of records.
+func (r *records) len() int {
+	return len(r.values) / len(r.table.keys)
+}
+
+// value returns the value of the key in the record with the index.
+func (r *records) value(index int, key string) (Element, bool) {
+	col, ok := r.table.indices[key]
+	if !ok || index < 0 || index >= r.len() {
+		return nil, false
+	}
+	return r.values[index*len(r.table.keys)+col], true
+}
+
+// record returns a copy of the record with the index as object.
+func (r *records) record(index int) Object {
+	width := len(r.table.keys)
+	obj := make(Object, width)
+	for col, key := range r.table.keys {
+		obj[key] = copyElement(r.values[index*width+col])
+	}
+	return obj
+}
+
+// expand returns a copy of the records as array of objects.
+func (r *records) expand() Array {
+	arr := make(Array, r.len())
+	for idx := range arr {
+		arr[idx] = r.record(idx)
+	}
+	return arr
+}
+
+// MarshalJSON implements json.Marshaler.
+func (r *records) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.expand())
+}
+
+// String implements fmt.Stringer, so nested records are printed like
+// arrays of objects.
+func (r *records) String() string {
+	return fmt.Sprintf("%v", r.expand())
+}
+
+// expandRecords returns interned records as array of objects, all
+// other elements unchanged.
+func expandRecords(element Element) Element {
+	if r, ok := element.(*records); ok {
+		return r.expand()
+	}
+	return element
+}
+
+// interner replaces the arrays of uniform objects by records sharing
+// the key tables of the same keys.
+type interner struct {
+	tables   map[string]*keyTable
+	interned int
+}
+
+// newInterner creates an interner without key tables.
+func newInterner() *interner {
+	return &interner{
+		tables: map[string]*keyTable{},
+	}
+}
+
+// element recursively interns the arrays of uniform objects.
+func (in *interner) element(element Element) Element {
+	switch typed := element.(type) {
+	case Object:
+		for key, child := range typed {
+			typed[key] = in.element(child)
+		}
+	case Array:
+		for idx, child := range typed {
+			typed[idx] = in.element(child)
+		}
+		if r := in.records(typed); r != nil {
+			in.interned++
+			return r
+		}
+	}
+	return element
+}
+
+// records returns the records of the array if it contains at least two
+// non-empty objects with the same keys, otherwise nil.
+func (in *interner) records(arr Array) *records {
+	if len(arr) < 2 {
+		return nil
+	}
+	first, ok := arr[0].(Object)
+	if !ok || len(first) == 0 {
+		return nil
+	}
+	keys := make(Keys, 0, len(first))
+	for key := range first {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, element := range arr[1:] {
+		obj, ok := element.(Object)
+		if !ok || len(obj) != len(keys) {
+			return nil
+		}
+		for _, key := range keys {
+			if _, ok := obj[key]; !ok {
+				return nil
+			}
+		}
+	}
+	signature := strings.Join(keys, "\x00")
+	table, ok := in.tables[signature]
+	if !ok {
+		table = &keyTable{
+			keys:    keys,
+			indices: make(map[string]int, len(keys)),
+		}
+		for col, key := range keys {
+			table.indices[key] = col
+		}
+		in.tables[signature] = table
+	}
+	values := make([]Element, 0, len(arr)*len(keys))
+	for _, element := range arr {
+		obj := element.(Object)
+		for _, key := range table.keys {
+			values = append(values, obj[key])
+		}
+	}
+	return &records{
+		table:  table,
+		values: values,
+	}
+}
+
+// EOF
@@ -0,0 +1,153 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestInternRecords tests storing uniform objects as records and
+// reading and changing them.
+func TestInternRecords(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	in := `{"mixed":[{"a":1},{"b":2}],"single":[{"a":1}],"users":[{"id":1,"name":"x","tags":[{"k":"a","v":1},{"k":"b","v":2}]},{"id":2,"name":"y","tags":[]}]}`
+	doc := mustUnmarshal(assert, in)
+
+	// Users and the tags of the first user are interned.
+	n, err := doc.InternRecords()
+	assert.NoError(err)
+	assert.Equal(n, 2)
+	assert.Equal(doc.String(), in)
+	assert.NoError(dynaj.Verify(doc))
+
+	// Reading values, records, and arrays.
+	assert.Equal(doc.NodeAt("/users/1/name").AsString("-"), "y")
+	assert.Equal(doc.NodeAt("/users/0/tags/1/v").AsInt(0), 2)
+	assert.True(doc.NodeAt("/users/0").IsObject())
+	assert.True(doc.NodeAt("/users").IsArray())
+	assert.Equal(doc.Length("/users"), 2)
+	assert.Equal(doc.Length("/users/0"), 3)
+	assert.True(doc.NodeAt("/users/2").IsError())
+	assert.True(doc.NodeAt("/users/0/email").IsError())
+	assert.Equal(doc.NodeAt("/users").NodeAt("/1/id").AsInt(0), 2)
+
+	// Reading through views.
+	view, err := doc.ViewAt("/")
+	assert.NoError(err)
+	assert.True(view.NodeAt("/users").IsArray())
+	assert.True(view.NodeAt("/users/0").IsObject())
+	assert.Equal(view.NodeAt("/users/0/tags/1/k").AsString("-"), "b")
+	users, err := doc.ViewAt("/users")
+	assert.NoError(err)
+	assert.Equal(users.NodeAt("/1/name").AsString("-"), "y")
+	data, err := users.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `[{"id":1,"name":"x","tags":[{"k":"a","v":1},{"k":"b","v":2}]},{"id":2,"name":"y","tags":[]}]`)
+	assert.False(strings.Contains(view.NodeAt("/users").String(), "0x"))
+
+	nodes, err := doc.Root().Query("/users/*/name")
+	assert.NoError(err)
+	assert.Length(nodes, 2)
+	count := 0
+	err = doc.Root().Process(func(node *dynaj.Node) error {
+		count++
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(count, 12)
+
+	// Comparisons see the records as objects.
+	plain := mustUnmarshal(assert, in)
+	diff, err := dynaj.CompareDocuments(doc, plain)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 0)
+
+	// Changes expand the records and leave frozen copies unchanged.
+	frozen := doc.Freeze()
+	assert.NoError(doc.SetValueAt("/users/1/name", "z"))
+	assert.NoError(doc.SetValueAt("/users/0/tags/2/k", "c"))
+	assert.NoError(doc.DeleteElementAt("/users/0/id"))
+	assert.Equal(doc.String(), `{"mixed":[{"a":1},{"b":2}],"single":[{"a":1}],"users":[{"name":"x","tags":[{"k":"a","v":1},{"k":"b","v":2},{"k":"c"}]},{"id":2,"name":"z","tags":[]}]}`)
+	assert.Equal(frozen.String(), in)
+	assert.NoError(dynaj.Verify(doc))
+
+	// Frozen documents are not changed.
+	_, err = frozen.InternRecords()
+	assert.ErrorMatch(err, ".*frozen.*")
+}
+
+// TestWithInternedRecords tests interning records when unmarshalling
+// and the reduction of the used memory.
+func TestWithInternedRecords(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	var b strings.Builder
+	b.WriteString("[")
+	for i := 0; i < 1000; i++ {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		fmt.Fprintf(&b, `{"id":%d,"name":"n%d","active":true,"score":%d.5,"group":"g","level":%d}`, i, i, i, i%10)
+	}
+	b.WriteString("]")
+	data := []byte(b.String())
+
+	plainSize := retainedSize(func() any {
+		doc, err := dynaj.Unmarshal(data)
+		assert.NoError(err)
+		return doc
+	})
+	var doc *dynaj.Document
+	internedSize := retainedSize(func() any {
+		var err error
+		doc, err = dynaj.Unmarshal(data, dynaj.WithInternedRecords())
+		assert.NoError(err)
+		return doc
+	})
+	assert.Logf("plain %d bytes, interned %d bytes", plainSize, internedSize)
+	assert.True(internedSize*3 < plainSize*2)
+
+	assert.Equal(doc.Length(dynaj.Separator), 1000)
+	assert.Equal(doc.NodeAt("/999/name").AsString("-"), "n999")
+	assert.Equal(doc.NodeAt("/42/score").AsFloat64(0), 42.5)
+	plain, err := dynaj.Unmarshal(data)
+	assert.NoError(err)
+	assert.Equal(doc.String(), plain.String())
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// retainedSize returns the heap size retained by the created value.
+func retainedSize(create func() any) int64 {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	value := create()
+	runtime.GC()
+	runtime.ReadMemStats(&after)
+	runtime.KeepAlive(value)
+	return int64(after.HeapAlloc) - int64(before.HeapAlloc)
+}
+
+// EOF
@@ -0,0 +1,48 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+//--------------------
+// JWT
+//--------------------
+
+// ParseJWTClaims decodes the claims of a JSON Web Token into a new
+// document, e.g. for inspecting them by path while debugging.
+//
+// ATTENTION: The signature is NOT verified! The claims of the token
+// cannot be trusted and must not be used for any authorization.
+func ParseJWTClaims(token string) (*Document, error) {
+	parts := strings.Split(strings.TrimSpace(token), ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("cannot parse JWT claims: token has %d instead of 3 parts", len(parts))
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse JWT claims: invalid payload encoding: %v", err)
+	}
+	doc, err := Unmarshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse JWT claims: %v", err)
+	}
+	if _, ok := doc.root.(Object); !ok {
+		return nil, fmt.Errorf("cannot parse JWT claims: payload is no object")
+	}
+	return doc, nil
+}
+
+// EOF
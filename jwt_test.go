@@ -0,0 +1,64 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestParseJWTClaims tests decoding the claims of tokens.
+func TestParseJWTClaims(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	encode := func(s string) string {
+		return base64.RawURLEncoding.EncodeToString([]byte(s))
+	}
+	header := encode(`{"alg":"HS256","typ":"JWT"}`)
+	token := header + "." + encode(`{"sub":"1234567890","name":"John Doe","roles":["admin"],"iat":1516239022}`) + ".c2lnbmF0dXJl"
+
+	doc, err := dynaj.ParseJWTClaims(token)
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/sub").AsString(""), "1234567890")
+	assert.Equal(doc.NodeAt("/roles/0").AsString(""), "admin")
+	assert.Equal(doc.NodeAt("/iat").AsInt(0), 1516239022)
+
+	// Padded payloads are accepted too.
+	padded := header + "." + base64.URLEncoding.EncodeToString([]byte(`{"a":"?"}`)) + "."
+	doc, err = dynaj.ParseJWTClaims(padded)
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/a").AsString(""), "?")
+
+	tests := []struct {
+		token string
+		err   string
+	}{
+		{"abc", "token has 1 instead of 3 parts"},
+		{"a.b.c.d.e", "token has 5 instead of 3 parts"},
+		{header + ".!!!.sig", "invalid payload encoding"},
+		{header + "." + encode(`{"a":`) + ".sig", "cannot unmarshal document"},
+		{header + "." + encode(`[1,2]`) + ".sig", "payload is no object"},
+	}
+	for _, test := range tests {
+		_, err := dynaj.ParseJWTClaims(test.token)
+		assert.ErrorContains(err, test.err, test.token)
+	}
+}
+
+// EOF
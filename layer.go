@@ -0,0 +1,66 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// LAYERING
+//--------------------
+
+// Layer merges the documents into a new one. Later documents override
+// earlier ones, so the typical order is defaults, file, environment, and
+// flags. Objects are merged key by key, all other elements including
+// arrays are replaced. The merged document is a copy with the settings
+// of the first document, later changes of the layers are not visible in
+// it. Nil documents are ignored.
+func Layer(docs ...*Document) *Document {
+	var first *Document
+	var root Element
+	for _, doc := range docs {
+		if doc == nil {
+			continue
+		}
+		if first == nil {
+			first = doc
+		}
+		root = mergeElement(root, doc.root)
+	}
+	if first == nil {
+		return NewDocument()
+	}
+	return first.derive(root)
+}
+
+// WithDefaults returns a new document with the values of the document
+// merged over the defaults. It has the settings of the document.
+func (d *Document) WithDefaults(defaults *Document) *Document {
+	return d.derive(Layer(defaults, d).root)
+}
+
+// mergeElement merges the upper element over the lower one and returns
+// the result as copy.
+func mergeElement(lower, upper Element) Element {
+	lowerObj, lok := decodedElement(lower).(Object)
+	upperObj, uok := decodedElement(upper).(Object)
+	if !lok || !uok {
+		return copyElement(upper)
+	}
+	obj := make(Object, len(lowerObj)+len(upperObj))
+	for key, child := range lowerObj {
+		obj[key] = child
+	}
+	for key, child := range upperObj {
+		if lowerChild, ok := obj[key]; ok {
+			obj[key] = mergeElement(lowerChild, child)
+		} else {
+			obj[key] = copyElement(child)
+		}
+	}
+	return obj
+}
+
+// EOF
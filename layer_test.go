@@ -0,0 +1,76 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestLayer tests the layering of documents.
+func TestLayer(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	defaults := mustUnmarshal(assert, `{"server":{"host":"localhost","port":8080,"tls":false},"tags":["a","b"],"log":"info"}`)
+	file := mustUnmarshal(assert, `{"server":{"host":"example.com"},"tags":["c"]}`)
+	env := mustUnmarshal(assert, `{"server":{"port":9090}}`)
+	flags := mustUnmarshal(assert, `{"log":"debug"}`)
+
+	doc := dynaj.Layer(defaults, file, nil, env, flags)
+	assert.Equal(doc.String(), `{"log":"debug","server":{"host":"example.com","port":9090,"tls":false},"tags":["c"]}`)
+
+	// Layers are not changed.
+	assert.NoError(doc.SetValueAt("/server/tls", true))
+	assert.False(defaults.NodeAt("/server/tls").AsBool(true))
+
+	// Type changes replace the lower element.
+	upper := mustUnmarshal(assert, `{"server":"unix:///tmp/socket"}`)
+	doc = dynaj.Layer(defaults, upper)
+	assert.Equal(doc.NodeAt("/server").AsString(""), "unix:///tmp/socket")
+
+	// Defaults.
+	doc = file.WithDefaults(defaults)
+	assert.Equal(doc.NodeAt("/server/host").AsString(""), "example.com")
+	assert.Equal(doc.NodeAt("/server/port").AsInt(0), 8080)
+	assert.Equal(doc.NodeAt("/log").AsString(""), "info")
+
+	// Settings are taken from the first document.
+	folded, err := dynaj.Unmarshal([]byte(`{"Metrics":{"Port":9100}}`), dynaj.WithCaseFolding())
+	assert.NoError(err)
+	doc = dynaj.Layer(folded, file)
+	assert.Equal(doc.NodeAt("/metrics/port").AsInt(0), 9100)
+	doc = file.WithDefaults(folded)
+	assert.True(doc.NodeAt("/metrics/port").IsError())
+
+	// Nothing to layer.
+	doc = dynaj.Layer()
+	assert.True(doc.Root().IsUndefined())
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// mustUnmarshal parses the document and fails if this is not possible.
+func mustUnmarshal(assert *asserts.Asserts, data string) *dynaj.Document {
+	doc, err := dynaj.Unmarshal([]byte(data))
+	assert.NoError(err)
+	return doc
+}
+
+// EOF
@@ -0,0 +1,22 @@
+// Tideland Go Dynamic JSON - Lint
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package lint checks documents for suspicious structures, e.g. in CI
+// checks over JSON fixtures and configurations.
+//
+//	report := lint.Lint(doc)
+//	for _, issue := range report.Issues() {
+//		fmt.Println(issue)
+//	}
+//
+// Without explicit rules the built-in ones are used. They detect
+// inconsistent key casing, mixed types in arrays, numbers stored as
+// strings, deep nesting, and duplicate values in arrays. Own rules
+// are functions checking one node after another.
+package lint // import "tideland.dev/go/dynaj/lint"
+
+// EOF
@@ -0,0 +1,292 @@
+// Tideland Go Dynamic JSON - Lint
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package lint // import "tideland.dev/go/dynaj/lint"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// RULES
+//--------------------
+
+// DefaultMaxDepth is the nesting depth checked by the default rules.
+const DefaultMaxDepth = 8
+
+// Issue describes a problem found by a rule.
+type Issue struct {
+	Rule    string
+	Path    dynaj.Path
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (i Issue) String() string {
+	return fmt.Sprintf("%s (%s): %s", i.Path, i.Rule, i.Message)
+}
+
+// Rule checks each node of a document. The returned issues need no
+// rule name, it is set by Lint.
+type Rule struct {
+	Name  string
+	Check func(node *dynaj.Node) []Issue
+}
+
+// DefaultRules returns all built-in rules.
+func DefaultRules() []Rule {
+	return []Rule{
+		KeyCasing(),
+		MixedArrayTypes(),
+		NumericStrings(),
+		MaxDepth(DefaultMaxDepth),
+		DuplicateValues(),
+	}
+}
+
+// keyStyles contains the patterns of the key casing styles. Keys of
+// only lowercase letters and digits match any style.
+var keyStyles = []struct {
+	name    string
+	pattern *regexp.Regexp
+}{
+	{"camelCase", regexp.MustCompile(`^[a-z][a-z0-9]*([A-Z][a-z0-9]*)+$`)},
+	{"PascalCase", regexp.MustCompile(`^([A-Z][a-z0-9]*)+$`)},
+	{"snake_case", regexp.MustCompile(`^[a-z0-9]+(_[a-z0-9]+)+$`)},
+	{"kebab-case", regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)+$`)},
+}
+
+// KeyCasing reports objects whose keys use different casing styles
+// like camelCase and snake_case.
+func KeyCasing() Rule {
+	return Rule{
+		Name: "key-casing",
+		Check: func(node *dynaj.Node) []Issue {
+			if !node.IsObject() {
+				return nil
+			}
+			examples := map[string]string{}
+			styles := []string{}
+			for _, key := range node.Keys() {
+				for _, style := range keyStyles {
+					if !style.pattern.MatchString(key) {
+						continue
+					}
+					if _, ok := examples[style.name]; !ok {
+						examples[style.name] = key
+						styles = append(styles, style.name)
+					}
+					break
+				}
+			}
+			if len(styles) < 2 {
+				return nil
+			}
+			sort.Strings(styles)
+			found := make([]string, len(styles))
+			for i, style := range styles {
+				found[i] = fmt.Sprintf("%s (%q)", style, examples[style])
+			}
+			return []Issue{{
+				Path:    node.Path(),
+				Message: "inconsistent key casing: " + strings.Join(found, ", "),
+			}}
+		},
+	}
+}
+
+// MixedArrayTypes reports arrays containing elements of different
+// types. Nulls are ignored.
+func MixedArrayTypes() Rule {
+	return Rule{
+		Name: "mixed-array-types",
+		Check: func(node *dynaj.Node) []Issue {
+			if !node.IsArray() {
+				return nil
+			}
+			types := map[string]struct{}{}
+			for _, key := range node.Keys() {
+				if kind := kindOf(node.NodeAt(key)); kind != "null" {
+					types[kind] = struct{}{}
+				}
+			}
+			if len(types) < 2 {
+				return nil
+			}
+			names := make([]string, 0, len(types))
+			for name := range types {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			return []Issue{{
+				Path:    node.Path(),
+				Message: "mixed types in array: " + strings.Join(names, ", "),
+			}}
+		},
+	}
+}
+
+// numberPattern matches strings in the syntax of JSON numbers.
+var numberPattern = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// NumericStrings reports strings containing numbers in JSON syntax.
+// Strings with leading zeros like postal codes are not reported.
+func NumericStrings() Rule {
+	return Rule{
+		Name: "numeric-string",
+		Check: func(node *dynaj.Node) []Issue {
+			if kindOf(node) != "string" {
+				return nil
+			}
+			s := node.AsString("")
+			if !numberPattern.MatchString(s) {
+				return nil
+			}
+			return []Issue{{
+				Path:    node.Path(),
+				Message: fmt.Sprintf("number %q stored as string", s),
+			}}
+		},
+	}
+}
+
+// MaxDepth reports elements nested deeper than the maximum depth. Only
+// the first exceeding level is reported.
+func MaxDepth(max int) Rule {
+	return Rule{
+		Name: "max-depth",
+		Check: func(node *dynaj.Node) []Issue {
+			if node.Depth() != max+1 {
+				return nil
+			}
+			return []Issue{{
+				Path:    node.Path(),
+				Message: fmt.Sprintf("nesting deeper than %d levels", max),
+			}}
+		},
+	}
+}
+
+// DuplicateValues reports array elements equal to a preceding sibling.
+func DuplicateValues() Rule {
+	return Rule{
+		Name: "duplicate-value",
+		Check: func(node *dynaj.Node) []Issue {
+			if !node.IsArray() {
+				return nil
+			}
+			first := map[string]dynaj.Path{}
+			issues := []Issue{}
+			for _, key := range node.Keys() {
+				child := node.NodeAt(key)
+				data, err := child.MarshalJSON()
+				if err != nil {
+					continue
+				}
+				if path, ok := first[string(data)]; ok {
+					issues = append(issues, Issue{
+						Path:    child.Path(),
+						Message: fmt.Sprintf("duplicate of %s", path),
+					})
+					continue
+				}
+				first[string(data)] = child.Path()
+			}
+			return issues
+		},
+	}
+}
+
+//--------------------
+// LINTING
+//--------------------
+
+// Report contains the issues keyed by path.
+type Report map[dynaj.Path][]Issue
+
+// Paths returns the sorted paths with issues.
+func (r Report) Paths() []dynaj.Path {
+	paths := make([]dynaj.Path, 0, len(r))
+	for path := range r {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Issues returns all issues sorted by path. Issues of the same path
+// are in the order of the rules.
+func (r Report) Issues() []Issue {
+	issues := []Issue{}
+	for _, path := range r.Paths() {
+		issues = append(issues, r[path]...)
+	}
+	return issues
+}
+
+// Lint checks all nodes of the document with the rules, with the
+// default rules if none are given.
+func Lint(doc *dynaj.Document, rules ...Rule) Report {
+	if len(rules) == 0 {
+		rules = DefaultRules()
+	}
+	report := Report{}
+	lintNode(doc.Root(), rules, report)
+	return report
+}
+
+// lintNode checks the node and recursively its children.
+func lintNode(node *dynaj.Node, rules []Rule, report Report) {
+	for _, rule := range rules {
+		for _, issue := range rule.Check(node) {
+			issue.Rule = rule.Name
+			report[issue.Path] = append(report[issue.Path], issue)
+		}
+	}
+	if !node.IsObject() && !node.IsArray() {
+		return
+	}
+	for _, key := range node.Keys() {
+		lintNode(node.NodeAt(key), rules, report)
+	}
+}
+
+// kindOf returns the JSON type of the node.
+func kindOf(node *dynaj.Node) string {
+	switch {
+	case node.IsObject():
+		return "object"
+	case node.IsArray():
+		return "array"
+	case node.IsUndefined():
+		return "null"
+	}
+	data, err := node.MarshalJSON()
+	if err != nil || len(data) == 0 {
+		return "invalid"
+	}
+	switch data[0] {
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	case 'n':
+		return "null"
+	}
+	return "number"
+}
+
+// EOF
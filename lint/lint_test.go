@@ -0,0 +1,108 @@
+// Tideland Go Dynamic JSON - Lint - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package lint_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/lint"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestLint tests the built-in rules.
+func TestLint(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"userName": "alice",
+		"last_name": "smith",
+		"id": 1,
+		"port": "8080",
+		"zip": "01234",
+		"tags": ["a", "b", "a", 1, null],
+		"items": [{"x": 1}, {"x": 1}, {"x": 2}, 4, 5, 6, 7, 8, 9, 10, {"x": 2}],
+		"a": {"b": {"c": {"d": {"e": {"f": {"g": {"h": {"i": {"j": 1}}}}}}}}}
+	}`)
+	report := lint.Lint(doc)
+	assert.Equal(report.Paths(), []dynaj.Path{
+		"/", "/a/b/c/d/e/f/g/h/i", "/items", "/items/1", "/items/10", "/port", "/tags", "/tags/2",
+	})
+	assert.Equal(issues(report), []string{
+		`/ (key-casing): inconsistent key casing: camelCase ("userName"), snake_case ("last_name")`,
+		`/a/b/c/d/e/f/g/h/i (max-depth): nesting deeper than 8 levels`,
+		`/items (mixed-array-types): mixed types in array: number, object`,
+		`/items/1 (duplicate-value): duplicate of /items/0`,
+		`/items/10 (duplicate-value): duplicate of /items/2`,
+		`/port (numeric-string): number "8080" stored as string`,
+		`/tags (mixed-array-types): mixed types in array: number, string`,
+		`/tags/2 (duplicate-value): duplicate of /tags/0`,
+	})
+}
+
+// TestLintRules tests linting with selected and own rules.
+func TestLintRules(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": {"b": {"c": 1}}, "TODO": "x", "list": [1, 1]}`)
+
+	report := lint.Lint(doc, lint.MaxDepth(1))
+	assert.Equal(issues(report), []string{
+		`/a/b (max-depth): nesting deeper than 1 levels`,
+		`/list/0 (max-depth): nesting deeper than 1 levels`,
+		`/list/1 (max-depth): nesting deeper than 1 levels`,
+	})
+
+	upper := lint.Rule{
+		Name: "no-upper-keys",
+		Check: func(node *dynaj.Node) []lint.Issue {
+			if key := node.Key(); strings.ToLower(key) != key {
+				return []lint.Issue{{Path: node.Path(), Message: "uppercase key"}}
+			}
+			return nil
+		},
+	}
+	report = lint.Lint(doc, upper, lint.DuplicateValues())
+	assert.Equal(issues(report), []string{
+		`/TODO (no-upper-keys): uppercase key`,
+		`/list/1 (duplicate-value): duplicate of /list/0`,
+	})
+
+	report = lint.Lint(mustUnmarshal(assert, `{"fine": [1, 2, 3], "alsoFine": "text"}`))
+	assert.Length(report, 0)
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// mustUnmarshal parses the JSON and stops the test in case of errors.
+func mustUnmarshal(assert *asserts.Asserts, data string) *dynaj.Document {
+	doc, err := dynaj.Unmarshal([]byte(data))
+	assert.NoError(err)
+	return doc
+}
+
+// issues returns the issues of the report as strings.
+func issues(report lint.Report) []string {
+	strs := []string{}
+	for _, issue := range report.Issues() {
+		strs = append(strs, issue.String())
+	}
+	return strs
+}
+
+// EOF
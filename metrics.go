@@ -0,0 +1,128 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"expvar"
+	"time"
+)
+
+//--------------------
+// METRICS
+//--------------------
+
+// Names of the caches reported to metrics sinks.
+const (
+	// DocumentCache is the cache of parsed documents.
+	DocumentCache = "documents"
+
+	// EncodingCache is the cache of the incremental marshalling.
+	EncodingCache = "encodings"
+)
+
+// MetricsSink receives measurements of the JSON processing, e.g. to
+// export them to a monitoring system. Implementations must be safe
+// for concurrent use.
+type MetricsSink interface {
+	// ObserveParse is called after parsing a JSON value with the size
+	// of the data in bytes, the duration, and the error if it failed.
+	ObserveParse(size int, duration time.Duration, err error)
+
+	// CountQuery is called after each query with the pattern and the
+	// number of results.
+	CountQuery(pattern string, results int)
+
+	// CountCache is called for each lookup in one of the caches.
+	CountCache(cache string, hit bool)
+}
+
+// WithMetrics sets the sink receiving the measurements of parsing the
+// data and of the created documents. Documents derived from them, e.g.
+// by freezing, report to the same sink.
+func WithMetrics(sink MetricsSink) Option {
+	return func(o *options) {
+		o.metrics = sink
+	}
+}
+
+// observeParse reports the parsing of the data started at the time.
+func (o *options) observeParse(data []byte, start time.Time, err error) {
+	if o.metrics != nil {
+		o.metrics.ObserveParse(len(data), time.Since(start), err)
+	}
+}
+
+// countQuery reports a query if the document has a metrics sink.
+func (d *Document) countQuery(pattern string, results int) {
+	if d != nil && d.metrics != nil {
+		d.metrics.CountQuery(pattern, results)
+	}
+}
+
+// countCache reports a cache lookup if the document has a metrics sink.
+func (d *Document) countCache(cache string, hit bool) {
+	if d.metrics != nil {
+		d.metrics.CountCache(cache, hit)
+	}
+}
+
+//--------------------
+// EXPVAR METRICS
+//--------------------
+
+// ExpvarMetrics is a metrics sink publishing the measurements as
+// expvar map. Its keys are "parses", "parse_errors", "parse_bytes",
+// "parse_nanoseconds", "queries", "query_results", and
+// "cache_hits_<cache>" and "cache_misses_<cache>" for each cache.
+type ExpvarMetrics struct {
+	vars *expvar.Map
+}
+
+// NewExpvarMetrics creates a metrics sink published under the name.
+// Like expvar.Publish it panics if the name is already in use.
+func NewExpvarMetrics(name string) *ExpvarMetrics {
+	return &ExpvarMetrics{
+		vars: expvar.NewMap(name),
+	}
+}
+
+// Map returns the published map.
+func (m *ExpvarMetrics) Map() *expvar.Map {
+	return m.vars
+}
+
+// ObserveParse implements MetricsSink.
+func (m *ExpvarMetrics) ObserveParse(size int, duration time.Duration, err error) {
+	m.vars.Add("parses", 1)
+	if err != nil {
+		m.vars.Add("parse_errors", 1)
+	}
+	m.vars.Add("parse_bytes", int64(size))
+	m.vars.Add("parse_nanoseconds", duration.Nanoseconds())
+}
+
+// CountQuery implements MetricsSink.
+func (m *ExpvarMetrics) CountQuery(pattern string, results int) {
+	m.vars.Add("queries", 1)
+	m.vars.Add("query_results", int64(results))
+}
+
+// CountCache implements MetricsSink.
+func (m *ExpvarMetrics) CountCache(cache string, hit bool) {
+	if hit {
+		m.vars.Add("cache_hits_"+cache, 1)
+		return
+	}
+	m.vars.Add("cache_misses_"+cache, 1)
+}
+
+// EOF
@@ -0,0 +1,126 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMetrics tests reporting measurements to a metrics sink.
+func TestMetrics(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	sink := &recordingSink{}
+
+	doc, err := dynaj.Unmarshal([]byte(`{"a": [1, 2], "b": {"c": 3}}`), dynaj.WithMetrics(sink))
+	assert.NoError(err)
+	_, err = dynaj.Unmarshal([]byte(`{"a":`), dynaj.WithMetrics(sink))
+	assert.ErrorContains(err, "cannot unmarshal document")
+	_, err = dynaj.UnmarshalAll([]byte(`1 2`), dynaj.WithMetrics(sink))
+	assert.NoError(err)
+	assert.Equal(sink.events[:4], []string{"parse 28 ok", "parse 5 failed", "parse 1 ok", "parse 1 ok"})
+
+	nodes, err := doc.Freeze().Root().Query("/a/*")
+	assert.NoError(err)
+	assert.Length(nodes, 2)
+	assert.Equal(sink.events[4], "query /a/* 2")
+
+	doc.SetIncrementalMarshal(true)
+	_, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.NoError(doc.SetValueAt("/a/0", 5))
+	_, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(sink.events[5:], []string{
+		"cache encodings miss", "cache encodings miss", "cache encodings miss",
+		"cache encodings miss", "cache encodings hit", "cache encodings miss",
+	})
+
+	sink.events = nil
+	cache := dynaj.NewCache(2, dynaj.WithMetrics(sink))
+	cache.Parse([]byte(`{}`))
+	cache.Parse([]byte(`{}`))
+	assert.Equal(sink.events, []string{"cache documents miss", "parse 2 ok", "cache documents hit"})
+}
+
+// TestExpvarMetrics tests publishing measurements with expvar.
+func TestExpvarMetrics(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	metrics := dynaj.NewExpvarMetrics("dynaj-test")
+
+	doc, err := dynaj.Unmarshal([]byte(`{"a": [1, 2]}`), dynaj.WithMetrics(metrics))
+	assert.NoError(err)
+	_, err = dynaj.Unmarshal([]byte(`[`), dynaj.WithMetrics(metrics))
+	assert.NotNil(err)
+	_, err = doc.Root().Query("/a/*")
+	assert.NoError(err)
+	cache := dynaj.NewCache(1, dynaj.WithMetrics(metrics))
+	cache.Parse([]byte(`1`))
+	cache.Parse([]byte(`1`))
+
+	vars := metrics.Map()
+	assert.Equal(vars.Get("parses").String(), "3")
+	assert.Equal(vars.Get("parse_errors").String(), "1")
+	assert.Equal(vars.Get("parse_bytes").String(), "15")
+	assert.Equal(vars.Get("queries").String(), "1")
+	assert.Equal(vars.Get("query_results").String(), "2")
+	assert.Equal(vars.Get("cache_hits_documents").String(), "1")
+	assert.Equal(vars.Get("cache_misses_documents").String(), "1")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// recordingSink records the measurements as strings.
+type recordingSink struct {
+	mu     sync.Mutex
+	events []string
+}
+
+func (s *recordingSink) ObserveParse(size int, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "failed"
+	}
+	s.record(fmt.Sprintf("parse %d %s", size, status))
+}
+
+func (s *recordingSink) CountQuery(pattern string, results int) {
+	s.record(fmt.Sprintf("query %s %d", pattern, results))
+}
+
+func (s *recordingSink) CountCache(cache string, hit bool) {
+	status := "miss"
+	if hit {
+		status = "hit"
+	}
+	s.record(fmt.Sprintf("cache %s %s", cache, status))
+}
+
+func (s *recordingSink) record(event string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = append(s.events, event)
+}
+
+// EOF
@@ -0,0 +1,112 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// MUST ACCESSORS
+//--------------------
+
+// MustUnmarshal is like Unmarshal but panics if the data cannot be
+// parsed. It is intended for the initialization of programs.
+func MustUnmarshal(data []byte, opts ...Option) *Document {
+	doc, err := Unmarshal(data, opts...)
+	if err != nil {
+		panic(fmt.Sprintf("dynaj: %v", err))
+	}
+	return doc
+}
+
+// MustString returns the value as string. It panics if the value is
+// missing or no simple value.
+func (node *Node) MustString() string {
+	node.mustValue("string")
+	return node.AsString("")
+}
+
+// MustInt returns the value as int. It panics if the value is missing
+// or cannot be converted without losing information.
+func (node *Node) MustInt() int {
+	node.mustValue("int")
+	i, err := node.asSigned(strconv.IntSize)
+	if err != nil {
+		panic(fmt.Sprintf("dynaj: %v", err))
+	}
+	return int(i)
+}
+
+// MustFloat64 returns the value as float64. It panics if the value is
+// missing or cannot be converted.
+func (node *Node) MustFloat64() float64 {
+	var f float64
+	var err error
+	switch tv := node.mustValue("float64").(type) {
+	case string:
+		f, err = node.numberFormat().parseFloat(tv)
+	case int:
+		f = float64(tv)
+	case float64:
+		f = tv
+	case bool:
+		f = node.AsFloat64(0)
+	default:
+		err = fmt.Errorf("invalid type %T", tv)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("dynaj: cannot convert value at %q to float64: %v", node.path, err))
+	}
+	return f
+}
+
+// MustBool returns the value as bool. It panics if the value is
+// missing or cannot be converted.
+func (node *Node) MustBool() bool {
+	var b bool
+	var err error
+	switch tv := node.mustValue("bool").(type) {
+	case string:
+		b, err = node.boolTable().parseBool(tv)
+	case int, float64, bool:
+		b = node.AsBool(false)
+	default:
+		err = fmt.Errorf("invalid type %T", tv)
+	}
+	if err != nil {
+		panic(fmt.Sprintf("dynaj: cannot convert value at %q to bool: %v", node.path, err))
+	}
+	return b
+}
+
+// mustValue returns the element of the node. It panics if it is
+// missing or no simple value.
+func (node *Node) mustValue(typeName string) Element {
+	if node.IsError() {
+		panic(fmt.Sprintf("dynaj: missing %s at %q: %v", typeName, node.path, node.err))
+	}
+	if node.isUndefinedValue() {
+		panic(fmt.Sprintf("dynaj: missing %s at %q", typeName, node.path))
+	}
+	element, err := decodeRaw(node.element)
+	if err != nil {
+		panic(fmt.Sprintf("dynaj: cannot read %s at %q: %v", typeName, node.path, err))
+	}
+	if isObjectOrArray(element) {
+		panic(fmt.Sprintf("dynaj: cannot convert value at %q to %s: is object or array", node.path, typeName))
+	}
+	return element
+}
+
+// EOF
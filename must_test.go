@@ -0,0 +1,68 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMustAccessors tests the panicking accessors.
+func TestMustAccessors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := dynaj.MustUnmarshal([]byte(`{"s":"foo","i":42,"f":"1.5","b":"true","x":1.5,"o":{}}`))
+
+	assert.Equal(doc.NodeAt("/s").MustString(), "foo")
+	assert.Equal(doc.NodeAt("/i").MustString(), "42")
+	assert.Equal(doc.NodeAt("/i").MustInt(), 42)
+	assert.Equal(doc.NodeAt("/f").MustFloat64(), 1.5)
+	assert.Equal(doc.NodeAt("/b").MustBool(), true)
+
+	tests := []struct {
+		name string
+		fn   func()
+		msg  string
+	}{
+		{"missing", func() { doc.NodeAt("/missing").MustString() }, `dynaj: missing string at "/missing"`},
+		{"fraction", func() { doc.NodeAt("/x").MustInt() }, "fraction would be truncated"},
+		{"text", func() { doc.NodeAt("/s").MustFloat64() }, `cannot convert value at "/s" to float64`},
+		{"bool", func() { doc.NodeAt("/s").MustBool() }, `cannot convert value at "/s" to bool`},
+		{"object", func() { doc.NodeAt("/o").MustInt() }, "is object or array"},
+		{"unmarshal", func() { dynaj.MustUnmarshal([]byte(`{`)) }, "dynaj: cannot unmarshal document"},
+	}
+	for _, test := range tests {
+		assert.Substring(test.msg, catchPanic(test.fn), test.name)
+	}
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// catchPanic runs the function and returns the recovered panic message.
+func catchPanic(fn func()) (msg string) {
+	defer func() {
+		msg = fmt.Sprint(recover())
+	}()
+	fn()
+	return ""
+}
+
+// EOF
@@ -12,12 +12,12 @@ package dynaj // import "tideland.dev/go/dynaj"
 //--------------------
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
-	"reflect"
+	"sort"
 	"strconv"
 	"strings"
-
-	"tideland.dev/go/matcher"
 )
 
 //--------------------
@@ -29,11 +29,32 @@ import (
 // document or one object or array.
 type Processor func(n *Node) error
 
-// Node is the combination of path and its value.
+// ProcessError is returned by Process and Range if processing a node
+// fails. It contains the path of the failing node and the cause.
+type ProcessError struct {
+	Path Path
+	Err  error
+}
+
+// Error implements error.
+func (e *ProcessError) Error() string {
+	return fmt.Sprintf("cannot process %q: %v", e.Path, e.Err)
+}
+
+// Unwrap returns the cause.
+func (e *ProcessError) Unwrap() error {
+	return e.Err
+}
+
+// Node is the combination of path and its value. Nodes retrieved
+// from a document are bound to it, so their values can be changed.
 type Node struct {
 	path    Path
 	element Element
 	err     error
+	doc     *Document
+	base    Path
+	keys    Keys
 }
 
 // IsUndefined returns true if this value is undefined.
@@ -75,7 +96,7 @@ func (node *Node) Err() error {
 
 // AsString returns the value as string.
 func (node *Node) AsString(dv string) string {
-	if node.IsUndefined() {
+	if node.isUndefinedValue() {
 		return dv
 	}
 	switch tv := node.element.(type) {
@@ -93,12 +114,12 @@ func (node *Node) AsString(dv string) string {
 
 // AsInt returns the value as int.
 func (node *Node) AsInt(dv int) int {
-	if node.IsUndefined() {
+	if node.isUndefinedValue() {
 		return dv
 	}
 	switch tv := node.element.(type) {
 	case string:
-		i, err := strconv.Atoi(tv)
+		i, err := node.numberFormat().parseInt(tv)
 		if err != nil {
 			return dv
 		}
@@ -118,12 +139,12 @@ func (node *Node) AsInt(dv int) int {
 
 // AsFloat64 returns the value as float64.
 func (node *Node) AsFloat64(dv float64) float64 {
-	if node.IsUndefined() {
+	if node.isUndefinedValue() {
 		return dv
 	}
 	switch tv := node.element.(type) {
 	case string:
-		f, err := strconv.ParseFloat(tv, 64)
+		f, err := node.numberFormat().parseFloat(tv)
 		if err != nil {
 			return dv
 		}
@@ -143,12 +164,12 @@ func (node *Node) AsFloat64(dv float64) float64 {
 
 // AsBool returns the value as bool.
 func (node *Node) AsBool(dv bool) bool {
-	if node.IsUndefined() {
+	if node.isUndefinedValue() {
 		return dv
 	}
 	switch tv := node.element.(type) {
 	case string:
-		b, err := strconv.ParseBool(tv)
+		b, err := node.boolTable().parseBool(tv)
 		if err != nil {
 			return dv
 		}
@@ -163,7 +184,8 @@ func (node *Node) AsBool(dv bool) bool {
 	return dv
 }
 
-// Equals compares a value with the passed one.
+// Equals compares a value with the passed one. Numbers are compared
+// by their value, so an int and a float64 can be equal.
 func (node *Node) Equals(other *Node) bool {
 	switch {
 	case node.IsUndefined() && other.IsUndefined():
@@ -171,7 +193,7 @@ func (node *Node) Equals(other *Node) bool {
 	case node.IsUndefined() || other.IsUndefined():
 		return false
 	default:
-		return reflect.DeepEqual(node.element, other.element)
+		return equalElements(node.element, other.element)
 	}
 }
 
@@ -185,6 +207,69 @@ func (node *Node) SplitPath() Keys {
 	return splitPath(node.path)
 }
 
+// IsRoot returns true if the node is the root of its document or view.
+func (node *Node) IsRoot() bool {
+	return len(node.pathKeys()) == 0
+}
+
+// Depth returns the number of keys of the path. The root has depth 0.
+func (node *Node) Depth() int {
+	return len(node.pathKeys())
+}
+
+// Key returns the last key of the path. It is empty for the root.
+func (node *Node) Key() Key {
+	keys := node.pathKeys()
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[len(keys)-1]
+}
+
+// Index returns the last key of the path as index. The flag is false
+// if the key is no non-negative number. Object keys consisting of
+// digits are returned as index too.
+func (node *Node) Index() (int, bool) {
+	index, ok := asIndex(node.Key())
+	if !ok || index < 0 {
+		return 0, false
+	}
+	return index, true
+}
+
+// pathKeys returns the keys of the path. They are split only once.
+func (node *Node) pathKeys() Keys {
+	if node.keys == nil {
+		node.keys = splitPath(node.path)
+	}
+	return node.keys
+}
+
+// SetValue sets the value of the node in the document it has been
+// retrieved from. This way query results can be changed directly.
+func (node *Node) SetValue(value Value) error {
+	if node.doc == nil {
+		return fmt.Errorf("cannot set value at %q: node is not bound to a document", node.path)
+	}
+	path := joinPaths(node.base, node.path)
+	if err := node.doc.SetValueAt(path, value); err != nil {
+		return err
+	}
+	element, err := elementAt(node.doc.root, splitPath(path))
+	if err != nil {
+		return err
+	}
+	node.element = decodedElement(element)
+	node.err = nil
+	return nil
+}
+
+// Keys returns the sorted keys of an object or the indices of an
+// array. Values have no keys.
+func (node *Node) Keys() Keys {
+	return childKeys(node.element)
+}
+
 // NodeAt returns the node at the passed path.
 func (node *Node) NodeAt(path Path) *Node {
 	if node.IsUndefined() {
@@ -202,68 +287,83 @@ func (node *Node) NodeAt(path Path) *Node {
 	// Navigate downstream.
 	nodeAt := &Node{
 		path: joinPaths(node.path, path),
+		doc:  node.doc,
+		base: node.base,
 	}
 	value, err := elementAt(node.element, splitPath(path))
 	if err != nil {
 		nodeAt.err = fmt.Errorf("invalid path %q: %v", path, err)
 	} else {
-		nodeAt.element = value
+		nodeAt.element = decodedElement(value)
 	}
 	return nodeAt
 }
 
 // Process iterates over the node and all its subnodes and
-// processes them with the passed processor function.
+// processes them with the passed processor function. The first
+// error stops processing and is returned as ProcessError with the
+// path of the failing node.
 func (node *Node) Process(process Processor) error {
 	if node.err != nil {
 		return node.err
 	}
-	switch typed := node.element.(type) {
+	element, err := decodeRaw(node.element)
+	if err != nil {
+		return &ProcessError{Path: node.path, Err: err}
+	}
+	switch typed := element.(type) {
 	case Object:
 		// A JSON object.
 		if len(typed) == 0 {
-			return process(&Node{
-				path:    node.path,
-				element: Object{},
-			})
+			return node.process(process, Object{})
 		}
-		for key, subvalue := range typed {
+		for _, key := range node.objectKeys(typed) {
 			subpath := appendKey(node.path, key)
 			subnode := &Node{
 				path:    subpath,
-				element: subvalue,
+				element: typed[key],
+				doc:     node.doc,
+				base:    node.base,
 			}
 			if err := subnode.Process(process); err != nil {
-				return fmt.Errorf("cannot process %q: %v", subpath, err)
+				return err
 			}
 		}
 	case Array:
 		// A JSON array.
 		if len(typed) == 0 {
-			return process(&Node{
-				path:    node.path,
-				element: Array{},
-			})
+			return node.process(process, Array{})
 		}
 		for idx, subvalue := range typed {
 			subpath := appendKey(node.path, strconv.Itoa(idx))
 			subnode := &Node{
 				path:    subpath,
 				element: subvalue,
+				doc:     node.doc,
+				base:    node.base,
 			}
 			if err := subnode.Process(process); err != nil {
-				return fmt.Errorf("cannot process %q: %v", subpath, err)
+				return err
 			}
 		}
 	default:
 		// A single value at the end.
-		err := process(&Node{
-			path:    node.path,
-			element: typed,
-		})
-		if err != nil {
-			return fmt.Errorf("cannot process %q: %v", node.path, err)
-		}
+		return node.process(process, typed)
+	}
+	return nil
+}
+
+// process calls the processor with a node for the element at the path
+// of the node. Errors are returned as ProcessError.
+func (node *Node) process(process Processor, element Element) error {
+	err := process(&Node{
+		path:    node.path,
+		element: element,
+		doc:     node.doc,
+		base:    node.base,
+	})
+	if err != nil {
+		return &ProcessError{Path: node.path, Err: err}
 	}
 	return nil
 }
@@ -271,24 +371,31 @@ func (node *Node) Process(process Processor) error {
 // Range takes  the node and processes it with the passed processor
 // function. In case of an object all keys and in case of an array
 // all indices will be processed. It is not working recursively.
+// Errors are returned as ProcessError.
 func (node *Node) Range(process Processor) error {
 	if node.err != nil {
 		return node.err
 	}
-	switch typed := node.element.(type) {
+	element, err := decodeRaw(node.element)
+	if err != nil {
+		return &ProcessError{Path: node.path, Err: err}
+	}
+	switch typed := element.(type) {
 	case Object:
 		// A JSON object.
-		for key := range typed {
+		for _, key := range node.objectKeys(typed) {
 			keypath := appendKey(node.path, key)
 			if isObjectOrArray(typed[key]) {
-				return fmt.Errorf("cannot process %q: is object or array", keypath)
+				return &ProcessError{Path: keypath, Err: errors.New("is object or array")}
 			}
 			err := process(&Node{
 				path:    keypath,
 				element: typed[key],
+				doc:     node.doc,
+				base:    node.base,
 			})
 			if err != nil {
-				return fmt.Errorf("cannot process %q: %v", keypath, err)
+				return &ProcessError{Path: keypath, Err: err}
 			}
 		}
 	case Array:
@@ -296,46 +403,80 @@ func (node *Node) Range(process Processor) error {
 		for idx := range typed {
 			idxpath := appendKey(node.path, strconv.Itoa(idx))
 			if isObjectOrArray(typed[idx]) {
-				return fmt.Errorf("cannot process %q: is object or array", idxpath)
+				return &ProcessError{Path: idxpath, Err: errors.New("is object or array")}
 			}
 			err := process(&Node{
 				path:    idxpath,
 				element: typed[idx],
+				doc:     node.doc,
+				base:    node.base,
 			})
 			if err != nil {
-				return fmt.Errorf("cannot process %q: %v", idxpath, err)
+				return &ProcessError{Path: idxpath, Err: err}
 			}
 		}
 	default:
 		// A single value at the end.
-		err := process(&Node{
-			path:    node.path,
-			element: typed,
-		})
-		if err != nil {
-			return fmt.Errorf("cannot process %q: %v", node.path, err)
-		}
+		return node.process(process, typed)
 	}
 	return nil
 }
 
+// objectKeys returns the keys of the object, sorted if the document
+// of the node has sorted keys enabled.
+func (node *Node) objectKeys(obj Object) Keys {
+	if node.doc != nil && node.doc.sortedKeys {
+		return childKeys(obj)
+	}
+	keys := make(Keys, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
 // Query iterates over the node and all its subnodes and returns
-// all values with paths matching the passed pattern.
+// all values with paths matching the passed pattern, see MatchPattern
+// for its syntax. The nodes are sorted by their paths.
 func (node *Node) Query(pattern string) (Nodes, error) {
 	nodes := Nodes{}
+	compiled := compilePattern(pattern)
 	err := node.Process(func(pnode *Node) error {
 		trimmedPath := strings.TrimPrefix(pnode.path, node.path+Separator)
-		if matcher.Matches(pattern, trimmedPath, false) {
+		if compiled.matches(trimmedPath) {
 			nodes = append(nodes, &Node{
 				path:    pnode.path,
 				element: pnode.element,
+				doc:     pnode.doc,
+				base:    pnode.base,
 			})
 		}
 		return nil
 	})
+	nodes.SortByPath()
+	node.doc.countQuery(pattern, len(nodes))
 	return nodes, err
 }
 
+// MarshalJSON implements json.Marshaler. It marshals the element
+// of the node including all subnodes. NaN and infinite floats are
+// handled according to the non-finite policy of the document.
+// Nodes not bound to a document reject them.
+func (node *Node) MarshalJSON() ([]byte, error) {
+	if node.IsError() {
+		return nil, node.err
+	}
+	policy := RejectNonFinite
+	if node.doc != nil {
+		policy = node.doc.nonFinite
+	}
+	element, err := normalizeValue(node.element, node.path, policy)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal node: %v", err)
+	}
+	return json.Marshal(element)
+}
+
 // String implements fmt.Stringer.
 func (node *Node) String() string {
 	if node.IsUndefined() {
@@ -350,4 +491,20 @@ func (node *Node) String() string {
 // Nodes contains a list of paths and their value.
 type Nodes []*Node
 
+// SortByPath sorts the nodes by their paths. Indices are compared
+// numerically, keys alphabetically.
+func (nodes Nodes) SortByPath() {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return compareKeys(splitPath(nodes[i].path), splitPath(nodes[j].path)) < 0
+	})
+}
+
+// SortByValue sorts the nodes with the less function, typically
+// comparing their values. Nodes with equal values keep their order.
+func (nodes Nodes) SortByValue(less func(a, b *Node) bool) {
+	sort.SliceStable(nodes, func(i, j int) bool {
+		return less(nodes[i], nodes[j])
+	})
+}
+
 // EOF
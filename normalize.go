@@ -0,0 +1,265 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+//--------------------
+// NON-FINITE FLOATS
+//--------------------
+
+// NonFinitePolicy defines how NaN and infinite floats are handled when
+// setting or marshalling values, as JSON cannot represent them.
+type NonFinitePolicy int
+
+// Policies for NaN and infinite floats.
+const (
+	// RejectNonFinite returns an error naming the path of the float.
+	RejectNonFinite NonFinitePolicy = iota
+
+	// NullNonFinite replaces the float by null.
+	NullNonFinite
+
+	// StringNonFinite replaces the float by the string "NaN", "+Inf",
+	// or "-Inf".
+	StringNonFinite
+)
+
+//--------------------
+// NORMALIZATION
+//--------------------
+
+// normalizer converts Go values into the JSON type system of the
+// documents. Objects, arrays, strings, bools, ints, float64s, raw
+// JSON, attachments, and nil are kept, other numbers are converted
+// into ints or float64s. All other values like structs or typed maps
+// and slices are converted by marshalling them to JSON and back.
+// Objects and arrays are only copied if one of their elements has to
+// be converted.
+type normalizer struct {
+	policy  NonFinitePolicy
+	visited map[uintptr]struct{}
+}
+
+// normalizeValue normalizes a value to be stored at the path.
+func normalizeValue(value Value, path Path, policy NonFinitePolicy) (Value, error) {
+	n := &normalizer{
+		policy:  policy,
+		visited: map[uintptr]struct{}{},
+	}
+	normalized, _, err := n.element(value, path)
+	return normalized, err
+}
+
+// element recursively normalizes an element and returns if it has
+// been changed.
+func (n *normalizer) element(element Element, path Path) (Element, bool, error) {
+	switch typed := element.(type) {
+	case nil, bool, string, int:
+		return element, false, nil
+	case float64:
+		return n.float(typed, path)
+	case json.RawMessage, *Attachment:
+		return element, false, nil
+	case int8:
+		return int(typed), true, nil
+	case int16:
+		return int(typed), true, nil
+	case int32:
+		return int(typed), true, nil
+	case int64:
+		if typed < math.MinInt || typed > math.MaxInt {
+			return float64(typed), true, nil
+		}
+		return int(typed), true, nil
+	case uint8:
+		return int(typed), true, nil
+	case uint16:
+		return int(typed), true, nil
+	case uint32:
+		return n.element(int64(typed), path)
+	case uint:
+		return n.element(uint64(typed), path)
+	case uint64:
+		if typed > math.MaxInt {
+			return float64(typed), true, nil
+		}
+		return int(typed), true, nil
+	case float32:
+		f, _, err := n.float(float64(typed), path)
+		return f, true, err
+	case Object:
+		return n.object(typed, path)
+	case Array:
+		return n.array(typed, path)
+	case *records:
+		normalized, _, err := n.array(typed.expand(), path)
+		return normalized, true, err
+	}
+	// Convert any other value via JSON.
+	data, err := json.Marshal(element)
+	if err != nil {
+		return nil, false, fmt.Errorf("unsupported value of type %T at %q: %v", element, path, err)
+	}
+	var converted Element
+	if err := json.Unmarshal(data, &converted); err != nil {
+		return nil, false, fmt.Errorf("unsupported value of type %T at %q: %v", element, path, err)
+	}
+	return converted, true, nil
+}
+
+// float handles NaN and infinite floats according to the policy.
+func (n *normalizer) float(f float64, path Path) (Element, bool, error) {
+	if !math.IsNaN(f) && !math.IsInf(f, 0) {
+		return f, false, nil
+	}
+	switch n.policy {
+	case NullNonFinite:
+		return nil, true, nil
+	case StringNonFinite:
+		switch {
+		case math.IsNaN(f):
+			return "NaN", true, nil
+		case f > 0:
+			return "+Inf", true, nil
+		default:
+			return "-Inf", true, nil
+		}
+	default:
+		return nil, false, fmt.Errorf("unsupported value %v at %q", f, path)
+	}
+}
+
+// object normalizes the values of an object.
+func (n *normalizer) object(obj Object, path Path) (Element, bool, error) {
+	ptr := reflect.ValueOf(obj).Pointer()
+	if _, ok := n.visited[ptr]; ok {
+		return nil, false, fmt.Errorf("unsupported value at %q: object contains itself", path)
+	}
+	n.visited[ptr] = struct{}{}
+	defer delete(n.visited, ptr)
+	var out Object
+	for key, child := range obj {
+		normalized, changed, err := n.element(child, appendKey(path, key))
+		if err != nil {
+			return nil, false, err
+		}
+		if changed && out == nil {
+			out = make(Object, len(obj))
+			for k, v := range obj {
+				out[k] = v
+			}
+		}
+		if out != nil {
+			out[key] = normalized
+		}
+	}
+	if out == nil {
+		return obj, false, nil
+	}
+	return out, true, nil
+}
+
+// array normalizes the values of an array.
+func (n *normalizer) array(arr Array, path Path) (Element, bool, error) {
+	if len(arr) == 0 {
+		return arr, false, nil
+	}
+	ptr := reflect.ValueOf(arr).Pointer()
+	if _, ok := n.visited[ptr]; ok {
+		return nil, false, fmt.Errorf("unsupported value at %q: array contains itself", path)
+	}
+	n.visited[ptr] = struct{}{}
+	defer delete(n.visited, ptr)
+	var out Array
+	for idx, child := range arr {
+		normalized, changed, err := n.element(child, appendKey(path, strconv.Itoa(idx)))
+		if err != nil {
+			return nil, false, err
+		}
+		if changed && out == nil {
+			out = make(Array, len(arr))
+			copy(out, arr)
+		}
+		if out != nil {
+			out[idx] = normalized
+		}
+	}
+	if out == nil {
+		return arr, false, nil
+	}
+	return out, true, nil
+}
+
+//--------------------
+// EQUALITY
+//--------------------
+
+// equalElements recursively compares two elements. Numbers are
+// compared by their value regardless if int or float64.
+func equalElements(a, b Element) bool {
+	if da, err := decodeRaw(a); err == nil {
+		a = da
+	}
+	if db, err := decodeRaw(b); err == nil {
+		b = db
+	}
+	switch ta := a.(type) {
+	case Object:
+		tb, ok := b.(Object)
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for key, av := range ta {
+			bv, ok := tb[key]
+			if !ok || !equalElements(av, bv) {
+				return false
+			}
+		}
+		return true
+	case Array:
+		tb, ok := b.(Array)
+		if !ok || len(ta) != len(tb) {
+			return false
+		}
+		for idx := range ta {
+			if !equalElements(ta[idx], tb[idx]) {
+				return false
+			}
+		}
+		return true
+	}
+	if af, ok := asNumber(a); ok {
+		bf, ok := asNumber(b)
+		return ok && af == bf
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// asNumber returns the float64 value of ints and float64s.
+func asNumber(element Element) (float64, bool) {
+	switch typed := element.(type) {
+	case int:
+		return float64(typed), true
+	case float64:
+		return typed, true
+	}
+	return 0, false
+}
+
+// EOF
@@ -0,0 +1,119 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestNormalization tests the normalization of Go values when
+// setting them.
+func TestNormalization(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	type point struct {
+		X int    `json:"x"`
+		Y int    `json:"y"`
+		L string `json:"label,omitempty"`
+	}
+
+	doc := dynaj.NewDocument()
+	assert.NoError(doc.SetValueAt("/int8", int8(8)))
+	assert.NoError(doc.SetValueAt("/uint64", uint64(64)))
+	assert.NoError(doc.SetValueAt("/float32", float32(0.5)))
+	assert.NoError(doc.SetValueAt("/map", map[int]string{1: "one", 2: "two"}))
+	assert.NoError(doc.SetValueAt("/struct", point{1, 2, ""}))
+	assert.NoError(doc.SetValueAt("/slice", []point{{3, 4, "a"}}))
+	assert.NoError(doc.SetValueAt("/nested", dynaj.Object{"p": &point{5, 6, "b"}, "s": []string{"x"}}))
+	assert.NoError(dynaj.Verify(doc))
+
+	assert.Equal(doc.NodeAt("/int8").AsInt(0), 8)
+	assert.Equal(doc.NodeAt("/uint64").AsInt(0), 64)
+	assert.Equal(doc.NodeAt("/float32").AsFloat64(0), 0.5)
+	assert.Equal(doc.NodeAt("/map/2").AsString(""), "two")
+	assert.True(doc.NodeAt("/struct").IsObject())
+	assert.Equal(doc.NodeAt("/struct/y").AsInt(0), 2)
+	assert.Equal(doc.NodeAt("/slice/0/label").AsString(""), "a")
+	assert.Equal(doc.NodeAt("/nested/p/x").AsInt(0), 5)
+	assert.Equal(doc.NodeAt("/nested/s/0").AsString(""), "x")
+
+	// Marshalling and comparing work.
+	data, err := doc.MarshalJSON()
+	assert.NoError(err)
+	parsed, err := dynaj.Unmarshal(data)
+	assert.NoError(err)
+	diff, err := dynaj.CompareDocuments(doc, parsed)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 0)
+
+	// Unsupported values.
+	err = doc.SetValueAt("/chan", make(chan int))
+	assert.ErrorContains(err, `cannot insert value at "/chan": unsupported value of type chan int at "/chan"`)
+	obj := dynaj.Object{}
+	obj["self"] = obj
+	err = doc.SetValueAt("/self", obj)
+	assert.ErrorContains(err, "object contains itself")
+}
+
+// TestNonFinite tests the handling of NaN and infinite floats.
+func TestNonFinite(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	// Default is rejection.
+	doc := dynaj.NewDocument()
+	err := doc.SetValueAt("/a/b", math.NaN())
+	assert.ErrorContains(err, `cannot insert value at "/a/b": unsupported value NaN at "/a/b"`)
+	err = doc.SetValueAt("a", dynaj.Object{"x": []any{float32(1), math.Inf(1)}})
+	assert.ErrorContains(err, `unsupported value +Inf at "/a/x/1"`)
+	err = doc.SetValueAt("/a", struct{ F float64 }{math.Inf(-1)})
+	assert.ErrorContains(err, `unsupported value of type struct { F float64 } at "/a"`)
+
+	// Values changed after inserting are detected when marshalling.
+	obj := dynaj.Object{"x": 1.0}
+	assert.NoError(doc.SetValueAt("/a", obj))
+	obj["x"] = math.Inf(-1)
+	_, err = doc.MarshalJSON()
+	assert.ErrorContains(err, `cannot marshal document: unsupported value -Inf at "/a/x"`)
+	_, err = doc.NodeAt("/a").MarshalJSON()
+	assert.ErrorContains(err, `cannot marshal node: unsupported value -Inf at "/a/x"`)
+	assert.Contains("cannot marshal document", doc.String())
+
+	// Convert to null.
+	doc.SetNonFinitePolicy(dynaj.NullNonFinite)
+	assert.Equal(doc.String(), `{"a":{"x":null}}`)
+	data, err := doc.NodeAt("/a").MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"x":null}`)
+	assert.NoError(doc.SetValueAt("/b", math.NaN()))
+	assert.True(doc.NodeAt("/b").IsUndefined())
+
+	// Convert to string.
+	doc.SetNonFinitePolicy(dynaj.StringNonFinite)
+	assert.Equal(doc.String(), `{"a":{"x":"-Inf"},"b":null}`)
+	data, err = doc.NodeAt("/a/x").MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `"-Inf"`)
+	assert.NoError(doc.SetValueAt("/b", []any{math.NaN(), math.Inf(1)}))
+	assert.Equal(doc.NodeAt("/b/0").AsString(""), "NaN")
+	assert.Equal(doc.NodeAt("/b/1").AsString(""), "+Inf")
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/x"`)
+}
+
+// EOF
@@ -0,0 +1,140 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// NUMBER FORMAT
+//--------------------
+
+// NumberFormat describes how strings are parsed by AsInt and AsFloat64.
+// The zero value only accepts numbers as understood by strconv.
+type NumberFormat struct {
+	// Decimal is the decimal separator, e.g. '.' or ','. Without
+	// one it is the point.
+	Decimal rune
+
+	// Grouping contains the separators allowed between digit groups,
+	// e.g. ",_" for "1,234_567". They are ignored when parsing.
+	Grouping string
+
+	// Prefixes allows integers with the prefixes "0x", "0o", and "0b".
+	Prefixes bool
+}
+
+var (
+	// PointNumbers parses numbers like "1,234.56", "1_000", or "0x1F".
+	PointNumbers = NumberFormat{Decimal: '.', Grouping: ",_ ", Prefixes: true}
+
+	// CommaNumbers parses numbers like "1.234,56", "1_000", or "0x1F".
+	CommaNumbers = NumberFormat{Decimal: ',', Grouping: "._ ", Prefixes: true}
+)
+
+// SetNumberFormat sets how strings are parsed when reading them with
+// AsInt or AsFloat64 from nodes of the document. Frozen documents keep
+// their format.
+func (d *Document) SetNumberFormat(format NumberFormat) {
+	if d.frozen {
+		return
+	}
+	d.numbers = format
+}
+
+// numberFormat returns the number format of the document the node
+// has been retrieved from.
+func (node *Node) numberFormat() NumberFormat {
+	if node.doc == nil {
+		return NumberFormat{}
+	}
+	return node.doc.numbers
+}
+
+// parseInt parses the string as integer following the format.
+func (f NumberFormat) parseInt(s string) (int, error) {
+	if f == (NumberFormat{}) {
+		return strconv.Atoi(s)
+	}
+	clean, err := f.clean(s)
+	if err != nil {
+		return 0, err
+	}
+	base := 10
+	if f.Prefixes {
+		base = 0
+	}
+	i, err := strconv.ParseInt(clean, base, strconv.IntSize)
+	if err != nil {
+		return 0, err
+	}
+	return int(i), nil
+}
+
+// parseFloat parses the string as float following the format.
+func (f NumberFormat) parseFloat(s string) (float64, error) {
+	if f == (NumberFormat{}) {
+		return strconv.ParseFloat(s, 64)
+	}
+	clean, err := f.clean(s)
+	if err != nil {
+		return 0, err
+	}
+	if fl, err := strconv.ParseFloat(clean, 64); err == nil {
+		return fl, nil
+	}
+	if !f.Prefixes {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	i, err := strconv.ParseInt(clean, 0, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", s)
+	}
+	return float64(i), nil
+}
+
+// clean removes the group separators and replaces the decimal
+// separator by a point.
+func (f NumberFormat) clean(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	decimal := f.Decimal
+	if decimal == 0 {
+		decimal = '.'
+	}
+	var b strings.Builder
+	decimals := 0
+	for i, r := range s {
+		switch {
+		case r == decimal:
+			decimals++
+			b.WriteRune('.')
+		case strings.ContainsRune(f.Grouping, r):
+			if i == 0 || i == len(s)-1 || decimals > 0 {
+				return "", fmt.Errorf("invalid number %q", s)
+			}
+		case r == '.':
+			// Points are no decimal separator here.
+			return "", fmt.Errorf("invalid number %q", s)
+		default:
+			b.WriteRune(r)
+		}
+	}
+	if decimals > 1 {
+		return "", fmt.Errorf("invalid number %q", s)
+	}
+	return b.String(), nil
+}
+
+// EOF
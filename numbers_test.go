@@ -0,0 +1,75 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestNumberFormats tests parsing strings with number formats.
+func TestNumberFormats(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"point": "1,234.56",
+		"comma": "1.234,56",
+		"underscore": "1_000",
+		"hex": "0x1F",
+		"plain": "42",
+		"broken": ",12"
+	}`)
+
+	// Default is strict parsing.
+	assert.Equal(doc.NodeAt("/point").AsFloat64(-1), -1.0)
+	assert.Equal(doc.NodeAt("/hex").AsInt(-1), -1)
+	assert.Equal(doc.NodeAt("/plain").AsInt(-1), 42)
+
+	doc.SetNumberFormat(dynaj.PointNumbers)
+	assert.Equal(doc.NodeAt("/point").AsFloat64(-1), 1234.56)
+	assert.Equal(doc.NodeAt("/point").AsInt(-1), -1)
+	assert.Equal(doc.NodeAt("/comma").AsFloat64(-1), -1.0)
+	assert.Equal(doc.NodeAt("/underscore").AsInt(-1), 1000)
+	assert.Equal(doc.NodeAt("/underscore").AsFloat64(-1), 1000.0)
+	assert.Equal(doc.NodeAt("/hex").AsInt(-1), 31)
+	assert.Equal(doc.NodeAt("/hex").AsFloat64(-1), 31.0)
+	assert.Equal(doc.NodeAt("/plain").AsInt(-1), 42)
+	assert.Equal(doc.NodeAt("/broken").AsInt(-1), -1)
+
+	doc.SetNumberFormat(dynaj.CommaNumbers)
+	assert.Equal(doc.NodeAt("/comma").AsFloat64(-1), 1234.56)
+	assert.Equal(doc.NodeAt("/point").AsFloat64(-1), -1.0)
+	assert.Equal(doc.NodeAt("/underscore").AsInt(-1), 1000)
+
+	// Hexadecimal numbers have to be allowed.
+	doc.SetNumberFormat(dynaj.NumberFormat{Grouping: ","})
+	assert.Equal(doc.NodeAt("/point").AsFloat64(-1), 1234.56)
+	assert.Equal(doc.NodeAt("/hex").AsInt(-1), -1)
+
+	// Derived documents keep the format, frozen ones cannot change it.
+	frozen := doc.Freeze()
+	assert.Equal(frozen.NodeAt("/point").AsFloat64(-1), 1234.56)
+	frozen.SetNumberFormat(dynaj.NumberFormat{})
+	assert.Equal(frozen.NodeAt("/point").AsFloat64(-1), 1234.56)
+	nodes, err := doc.Root().Query("/point")
+	assert.NoError(err)
+	assert.Length(nodes, 1)
+	assert.Equal(nodes[0].AsFloat64(-1), 1234.56)
+}
+
+// EOF
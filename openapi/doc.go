@@ -0,0 +1,23 @@
+// Tideland Go Dynamic JSON - OpenAPI
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package openapi validates documents against the response schemas of
+// an OpenAPI 3 specification, e.g. in tests of services or clients.
+//
+//	v := openapi.NewValidator()
+//	err := v.LoadSpec(spec)
+//	...
+//	violations, err := v.Validate("/users/42", http.MethodGet, 200, body)
+//
+// The schemas support the keywords for types, nullable values, enums,
+// properties, required and additional properties, items, the limits of
+// strings, numbers, arrays, and objects, patterns, the combinations
+// allOf, anyOf, oneOf, and not, as well as local references. Formats
+// are not checked.
+package openapi // import "tideland.dev/go/dynaj/openapi"
+
+// EOF
@@ -0,0 +1,243 @@
+// Tideland Go Dynamic JSON - OpenAPI - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package openapi_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"net/http"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/openapi"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestValidate tests validating response bodies.
+func TestValidate(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	v := openapi.NewValidator()
+	assert.NoError(v.LoadSpec(mustUnmarshal(assert, spec)))
+
+	tests := []struct {
+		name       string
+		path       string
+		method     string
+		status     int
+		body       string
+		violations []string
+	}{
+		{
+			name:   "valid user",
+			path:   "/users/42",
+			method: http.MethodGet,
+			status: 200,
+			body:   `{"id":42,"name":"alice","role":"admin","tags":["a","b"],"manager":null}`,
+		}, {
+			name:   "invalid user",
+			path:   "/users/42",
+			method: http.MethodGet,
+			status: 200,
+			body:   `{"id":4.2,"role":"guest","tags":["a","a"],"extra":true,"manager":{"id":1}}`,
+			violations: []string{
+				`/: missing required key "name"`,
+				`/extra: key "extra" is not allowed`,
+				`/id: integer expected, got number`,
+				`/manager: missing required key "name"`,
+				`/role: value "guest" is not one of ["admin", "user"]`,
+				`/tags/1: duplicate item`,
+			},
+		}, {
+			name:   "list with range status",
+			path:   "/users",
+			method: http.MethodGet,
+			status: 200,
+			body:   `[{"id":1,"name":"bob"},{"id":2,"name":""}]`,
+			violations: []string{
+				`/1/name: string shorter than 1`,
+			},
+		}, {
+			name:   "exact path preferred",
+			path:   "/users/me",
+			method: http.MethodGet,
+			status: 200,
+			body:   `{"self":true}`,
+		}, {
+			name:   "error by range",
+			path:   "/users/42",
+			method: http.MethodGet,
+			status: 404,
+			body:   `{"code":404,"message":"not found"}`,
+		}, {
+			name:   "error by default",
+			path:   "/users/42",
+			method: http.MethodGet,
+			status: 500,
+			body:   `{"code":"oops"}`,
+			violations: []string{
+				`/: value matches none of the anyOf schemas`,
+				`/code: integer expected, got string`,
+			},
+		}, {
+			name:   "oneOf and limits",
+			path:   "/items",
+			method: http.MethodPost,
+			status: 201,
+			body:   `{"price":10.005,"count":0,"code":"ab-1"}`,
+			violations: []string{
+				`/: value matches 2 instead of one of the oneOf schemas`,
+				`/code: string does not match "^[a-z]+$"`,
+				`/count: number 0 is not greater than 0`,
+				`/price: number 10.005 is no multiple of 0.01`,
+			},
+		},
+	}
+	for _, test := range tests {
+		violations, err := v.Validate(test.path, test.method, test.status, mustUnmarshal(assert, test.body))
+		assert.NoError(err, test.name)
+		got := []string{}
+		for _, violation := range violations {
+			got = append(got, violation.String())
+		}
+		if test.violations == nil {
+			test.violations = []string{}
+		}
+		assert.Equal(sorted(got), test.violations, test.name)
+	}
+}
+
+// TestValidateErrors tests failing to find the schemas.
+func TestValidateErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	v := openapi.NewValidator()
+	body := mustUnmarshal(assert, `{}`)
+	_, err := v.Validate("/users", http.MethodGet, 200, body)
+	assert.ErrorContains(err, "no spec loaded")
+
+	assert.ErrorContains(v.LoadSpec(mustUnmarshal(assert, `{"swagger":"2.0","paths":{}}`)), "unsupported OpenAPI version")
+	assert.ErrorContains(v.LoadSpec(mustUnmarshal(assert, `{"openapi":"3.0.3"}`)), "missing paths")
+	assert.NoError(v.LoadSpec(mustUnmarshal(assert, spec)))
+
+	tests := []struct {
+		path   string
+		method string
+		status int
+		err    string
+	}{
+		{"/unknown", http.MethodGet, 200, `no path matches "/unknown"`},
+		{"/users", http.MethodDelete, 200, "no operation DELETE /users"},
+		{"/items", http.MethodPost, 400, "no response 400 for POST /items"},
+		{"/users", http.MethodPost, 204, "no JSON schema of response 204"},
+		{"/broken", http.MethodGet, 200, `invalid reference "#/components/schemas/Missing"`},
+	}
+	for _, test := range tests {
+		_, err := v.Validate(test.path, test.method, test.status, body)
+		assert.ErrorContains(err, test.err, test.path)
+	}
+}
+
+// TestViolationPaths tests retrieving the paths of violations.
+func TestViolationPaths(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	violations := openapi.Violations{{"/a", "x"}, {"/b/0", "y"}}
+	assert.Equal(violations.Paths(), []dynaj.Path{"/a", "/b/0"})
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+const spec = `{
+	"openapi": "3.0.3",
+	"paths": {
+		"/users": {
+			"get": {"responses": {"2XX": {"content": {"application/json": {
+				"schema": {"type": "array", "items": {"$ref": "#/components/schemas/User"}}
+			}}}}},
+			"post": {"responses": {"204": {"description": "created"}}}
+		},
+		"/users/{id}": {
+			"get": {"responses": {
+				"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/User"}}}},
+				"4XX": {"$ref": "#/components/responses/Error"},
+				"default": {"$ref": "#/components/responses/Error"}
+			}}
+		},
+		"/users/me": {
+			"get": {"responses": {"200": {"content": {"application/json": {
+				"schema": {"type": "object", "required": ["self"]}
+			}}}}}
+		},
+		"/items": {
+			"post": {"responses": {"201": {"content": {"application/problem+json": {"schema": {
+				"type": "object",
+				"properties": {
+					"price": {"type": "number", "multipleOf": 0.01},
+					"count": {"type": "integer", "minimum": 0, "exclusiveMinimum": true},
+					"code": {"type": "string", "pattern": "^[a-z]+$"}
+				},
+				"oneOf": [{"required": ["price"]}, {"required": ["count"]}]
+			}}}}}}
+		},
+		"/broken": {
+			"get": {"responses": {"200": {"content": {"application/json": {
+				"schema": {"$ref": "#/components/schemas/Missing"}
+			}}}}}
+		}
+	},
+	"components": {
+		"schemas": {
+			"User": {
+				"type": "object",
+				"required": ["id", "name"],
+				"additionalProperties": false,
+				"properties": {
+					"id": {"type": "integer"},
+					"name": {"type": "string", "minLength": 1},
+					"role": {"type": "string", "enum": ["admin", "user"]},
+					"tags": {"type": "array", "items": {"type": "string"}, "uniqueItems": true},
+					"manager": {"allOf": [{"$ref": "#/components/schemas/User"}], "nullable": true}
+				}
+			},
+			"Error": {
+				"type": "object",
+				"properties": {"code": {"type": "integer"}, "message": {"type": "string"}},
+				"anyOf": [{"required": ["message"]}, {"properties": {"code": {"type": "integer"}}}]
+			}
+		},
+		"responses": {
+			"Error": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/Error"}}}}
+		}
+	}
+}`
+
+// mustUnmarshal parses the JSON or stops the test.
+func mustUnmarshal(assert *asserts.Asserts, data string) *dynaj.Document {
+	doc, err := dynaj.Unmarshal([]byte(data))
+	assert.NoError(err)
+	return doc
+}
+
+// sorted returns the sorted strings.
+func sorted(ss []string) []string {
+	for i := 1; i < len(ss); i++ {
+		for j := i; j > 0 && ss[j] < ss[j-1]; j-- {
+			ss[j], ss[j-1] = ss[j-1], ss[j]
+		}
+	}
+	return ss
+}
+
+// EOF
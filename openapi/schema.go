@@ -0,0 +1,339 @@
+// Tideland Go Dynamic JSON - OpenAPI
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package openapi // import "tideland.dev/go/dynaj/openapi"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"sort"
+	"strconv"
+	"unicode/utf8"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// maxDepth limits the nesting of schemas, e.g. by recursive references.
+const maxDepth = 256
+
+//--------------------
+// SCHEMA VALIDATOR
+//--------------------
+
+// schemaValidator validates one value against a schema.
+type schemaValidator struct {
+	validator  *Validator
+	violations Violations
+	err        error
+}
+
+// violate adds a violation.
+func (s *schemaValidator) violate(path dynaj.Path, format string, args ...any) {
+	s.violations = append(s.violations, Violation{path, fmt.Sprintf(format, args...)})
+}
+
+// validate recursively validates the value against the schema.
+func (s *schemaValidator) validate(schema, value any, path dynaj.Path, depth int) {
+	if s.err != nil {
+		return
+	}
+	if depth > maxDepth {
+		s.err = fmt.Errorf("schema at %q nested too deep", path)
+		return
+	}
+	schema, err := s.validator.resolve(schema)
+	if err != nil {
+		s.err = err
+		return
+	}
+	switch typed := schema.(type) {
+	case bool:
+		if !typed {
+			s.violate(path, "no value allowed")
+		}
+		return
+	case map[string]any:
+		s.validateSchema(typed, value, path, depth)
+	default:
+		s.err = fmt.Errorf("invalid schema at %q", path)
+	}
+}
+
+// validateSchema validates the value against a schema object.
+func (s *schemaValidator) validateSchema(schema map[string]any, value any, path dynaj.Path, depth int) {
+	if value == nil && schema["nullable"] == true {
+		return
+	}
+	if !s.validateType(schema, value, path) {
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !containsValue(enum, value) {
+		s.violate(path, "value %s is not one of %s", describe(value), describeAll(enum))
+	}
+	if c, ok := schema["const"]; ok && !reflect.DeepEqual(c, value) {
+		s.violate(path, "value %s is not %s", describe(value), describe(c))
+	}
+	switch typed := value.(type) {
+	case string:
+		s.validateString(schema, typed, path)
+	case float64:
+		s.validateNumber(schema, typed, path)
+	case []any:
+		s.validateArray(schema, typed, path, depth)
+	case map[string]any:
+		s.validateObject(schema, typed, path, depth)
+	}
+	s.validateCombinations(schema, value, path, depth)
+}
+
+// validateType checks the type of the value. It returns false if the
+// type does not match, so no further checks are done.
+func (s *schemaValidator) validateType(schema map[string]any, value any, path dynaj.Path) bool {
+	var types []string
+	switch typed := schema["type"].(type) {
+	case string:
+		types = []string{typed}
+	case []any:
+		for _, t := range typed {
+			if ts, ok := t.(string); ok {
+				types = append(types, ts)
+			}
+		}
+	default:
+		return true
+	}
+	actual := typeOf(value)
+	for _, t := range types {
+		if t == actual || (t == "number" && actual == "integer") {
+			return true
+		}
+	}
+	if len(types) == 1 {
+		s.violate(path, "%s expected, got %s", types[0], actual)
+	} else {
+		s.violate(path, "one of %v expected, got %s", types, actual)
+	}
+	return false
+}
+
+// validateString checks the limits and the pattern of strings.
+func (s *schemaValidator) validateString(schema map[string]any, str string, path dynaj.Path) {
+	length := float64(utf8.RuneCountInString(str))
+	if lower, ok := schema["minLength"].(float64); ok && length < lower {
+		s.violate(path, "string shorter than %v", lower)
+	}
+	if upper, ok := schema["maxLength"].(float64); ok && length > upper {
+		s.violate(path, "string longer than %v", upper)
+	}
+	if expr, ok := schema["pattern"].(string); ok {
+		re, err := s.validator.pattern(expr)
+		if err != nil {
+			s.err = fmt.Errorf("invalid pattern %q at %q: %v", expr, path, err)
+			return
+		}
+		if !re.MatchString(str) {
+			s.violate(path, "string does not match %q", expr)
+		}
+	}
+}
+
+// validateNumber checks the limits of numbers.
+func (s *schemaValidator) validateNumber(schema map[string]any, f float64, path dynaj.Path) {
+	if lower, ok := schema["minimum"].(float64); ok {
+		if schema["exclusiveMinimum"] == true && f <= lower {
+			s.violate(path, "number %v is not greater than %v", f, lower)
+		} else if f < lower {
+			s.violate(path, "number %v is less than %v", f, lower)
+		}
+	}
+	if upper, ok := schema["maximum"].(float64); ok {
+		if schema["exclusiveMaximum"] == true && f >= upper {
+			s.violate(path, "number %v is not less than %v", f, upper)
+		} else if f > upper {
+			s.violate(path, "number %v is greater than %v", f, upper)
+		}
+	}
+	if lower, ok := schema["exclusiveMinimum"].(float64); ok && f <= lower {
+		s.violate(path, "number %v is not greater than %v", f, lower)
+	}
+	if upper, ok := schema["exclusiveMaximum"].(float64); ok && f >= upper {
+		s.violate(path, "number %v is not less than %v", f, upper)
+	}
+	if m, ok := schema["multipleOf"].(float64); ok && m > 0 {
+		if q := f / m; math.Abs(q-math.Round(q)) > 1e-9 {
+			s.violate(path, "number %v is no multiple of %v", f, m)
+		}
+	}
+}
+
+// validateArray checks the limits and the items of arrays.
+func (s *schemaValidator) validateArray(schema map[string]any, arr []any, path dynaj.Path, depth int) {
+	length := float64(len(arr))
+	if lower, ok := schema["minItems"].(float64); ok && length < lower {
+		s.violate(path, "less than %v items", lower)
+	}
+	if upper, ok := schema["maxItems"].(float64); ok && length > upper {
+		s.violate(path, "more than %v items", upper)
+	}
+	if schema["uniqueItems"] == true {
+		for i := 1; i < len(arr); i++ {
+			if containsValue(arr[:i], arr[i]) {
+				s.violate(dynaj.JoinPath(path, strconv.Itoa(i)), "duplicate item")
+			}
+		}
+	}
+	if items, ok := schema["items"]; ok {
+		for i, item := range arr {
+			s.validate(items, item, dynaj.JoinPath(path, strconv.Itoa(i)), depth+1)
+		}
+	}
+}
+
+// validateObject checks the properties of objects.
+func (s *schemaValidator) validateObject(schema map[string]any, obj map[string]any, path dynaj.Path, depth int) {
+	count := float64(len(obj))
+	if lower, ok := schema["minProperties"].(float64); ok && count < lower {
+		s.violate(path, "less than %v properties", lower)
+	}
+	if upper, ok := schema["maxProperties"].(float64); ok && count > upper {
+		s.violate(path, "more than %v properties", upper)
+	}
+	if required, ok := schema["required"].([]any); ok {
+		for _, r := range required {
+			key, _ := r.(string)
+			if _, ok := obj[key]; !ok {
+				s.violate(path, "missing required key %q", key)
+			}
+		}
+	}
+	properties, _ := schema["properties"].(map[string]any)
+	keys := make([]string, 0, len(obj))
+	for key := range obj {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		subpath := dynaj.JoinPath(path, key)
+		if property, ok := properties[key]; ok {
+			s.validate(property, obj[key], subpath, depth+1)
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				s.violate(subpath, "key %q is not allowed", key)
+			}
+		case map[string]any:
+			s.validate(additional, obj[key], subpath, depth+1)
+		}
+	}
+}
+
+// validateCombinations checks allOf, anyOf, oneOf, and not.
+func (s *schemaValidator) validateCombinations(schema map[string]any, value any, path dynaj.Path, depth int) {
+	if all, ok := schema["allOf"].([]any); ok {
+		for _, sub := range all {
+			s.validate(sub, value, path, depth+1)
+		}
+	}
+	if anyOf, ok := schema["anyOf"].([]any); ok && s.countMatches(anyOf, value, path, depth) == 0 {
+		s.violate(path, "value matches none of the anyOf schemas")
+	}
+	if oneOf, ok := schema["oneOf"].([]any); ok {
+		if n := s.countMatches(oneOf, value, path, depth); n != 1 {
+			s.violate(path, "value matches %d instead of one of the oneOf schemas", n)
+		}
+	}
+	if not, ok := schema["not"]; ok && s.countMatches([]any{not}, value, path, depth) == 1 {
+		s.violate(path, "value matches the not schema")
+	}
+}
+
+// countMatches returns the number of schemas the value matches.
+func (s *schemaValidator) countMatches(schemas []any, value any, path dynaj.Path, depth int) int {
+	n := 0
+	for _, sub := range schemas {
+		probe := &schemaValidator{
+			validator: s.validator,
+		}
+		probe.validate(sub, value, path, depth+1)
+		if probe.err != nil {
+			s.err = probe.err
+			return 0
+		}
+		if len(probe.violations) == 0 {
+			n++
+		}
+	}
+	return n
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// typeOf returns the JSON schema type of the value.
+func typeOf(value any) string {
+	switch typed := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if typed == math.Trunc(typed) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	default:
+		return "object"
+	}
+}
+
+// containsValue checks if the value is contained in the list.
+func containsValue(values []any, value any) bool {
+	for _, v := range values {
+		if reflect.DeepEqual(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// describe returns a short description of the value.
+func describe(value any) string {
+	if s, ok := value.(string); ok {
+		return strconv.Quote(s)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// describeAll describes all values.
+func describeAll(values []any) string {
+	out := "["
+	for i, v := range values {
+		if i > 0 {
+			out += ", "
+		}
+		out += describe(v)
+	}
+	return out + "]"
+}
+
+// EOF
@@ -0,0 +1,257 @@
+// Tideland Go Dynamic JSON - OpenAPI
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package openapi // import "tideland.dev/go/dynaj/openapi"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// VIOLATIONS
+//--------------------
+
+// Violation describes where and why a document does not match
+// its schema.
+type Violation struct {
+	Path    dynaj.Path
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (v Violation) String() string {
+	return v.Path + ": " + v.Message
+}
+
+// Violations contains all violations of a validation.
+type Violations []Violation
+
+// Paths returns the paths of the violations.
+func (vs Violations) Paths() []dynaj.Path {
+	paths := make([]dynaj.Path, len(vs))
+	for i, v := range vs {
+		paths[i] = v.Path
+	}
+	return paths
+}
+
+//--------------------
+// VALIDATOR
+//--------------------
+
+// Validator validates documents against the response schemas of a
+// loaded OpenAPI specification. It can be used concurrently.
+type Validator struct {
+	mu   sync.RWMutex
+	spec map[string]any
+
+	patternsMu sync.Mutex
+	patterns   map[string]*regexp.Regexp
+}
+
+// NewValidator creates a validator without specification.
+func NewValidator() *Validator {
+	return &Validator{
+		patterns: map[string]*regexp.Regexp{},
+	}
+}
+
+// LoadSpec loads the OpenAPI 3 specification. A previously loaded one
+// is replaced.
+func (v *Validator) LoadSpec(spec *dynaj.Document) error {
+	data, err := spec.MarshalJSON()
+	if err != nil {
+		return fmt.Errorf("cannot load spec: %v", err)
+	}
+	var root map[string]any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return fmt.Errorf("cannot load spec: %v", err)
+	}
+	version, _ := root["openapi"].(string)
+	if !strings.HasPrefix(version, "3.") {
+		return fmt.Errorf("cannot load spec: unsupported OpenAPI version %q", version)
+	}
+	if _, ok := root["paths"].(map[string]any); !ok {
+		return fmt.Errorf("cannot load spec: missing paths")
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.spec = root
+	return nil
+}
+
+// Validate checks the body against the JSON response schema of the
+// operation with the path, method, and status. Path templates like
+// "/users/{id}" are matched, status ranges like "4XX" and the default
+// response are used if there is no response for the exact status. The
+// error reports problems finding the schema.
+func (v *Validator) Validate(path, method string, status int, body *dynaj.Document) (Violations, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if v.spec == nil {
+		return nil, fmt.Errorf("cannot validate: no spec loaded")
+	}
+	schema, err := v.responseSchema(path, method, status)
+	if err != nil {
+		return nil, fmt.Errorf("cannot validate: %v", err)
+	}
+	data, err := body.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("cannot validate: %v", err)
+	}
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, fmt.Errorf("cannot validate: %v", err)
+	}
+	s := &schemaValidator{
+		validator: v,
+	}
+	s.validate(schema, value, dynaj.Separator, 0)
+	return s.violations, s.err
+}
+
+// responseSchema finds the schema of the JSON response.
+func (v *Validator) responseSchema(path, method string, status int) (any, error) {
+	item, err := v.pathItem(path)
+	if err != nil {
+		return nil, err
+	}
+	operation, ok := item[strings.ToLower(method)].(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("no operation %s %s", strings.ToUpper(method), path)
+	}
+	responses, _ := operation["responses"].(map[string]any)
+	code := strconv.Itoa(status)
+	response, ok := responses[code]
+	if !ok {
+		response, ok = responses[code[:1]+"XX"]
+	}
+	if !ok {
+		response, ok = responses["default"]
+	}
+	if !ok {
+		return nil, fmt.Errorf("no response %d for %s %s", status, strings.ToUpper(method), path)
+	}
+	resolved, err := v.resolve(response)
+	if err != nil {
+		return nil, err
+	}
+	content, _ := resolved.(map[string]any)["content"].(map[string]any)
+	for mediaType, media := range content {
+		if mediaType != "application/json" && !strings.HasSuffix(mediaType, "+json") {
+			continue
+		}
+		if schema, ok := media.(map[string]any)["schema"]; ok {
+			return schema, nil
+		}
+	}
+	return nil, fmt.Errorf("no JSON schema of response %d for %s %s", status, strings.ToUpper(method), path)
+}
+
+// pathItem finds the path item matching the path. Paths without
+// templates are preferred, otherwise the one with the least ones.
+func (v *Validator) pathItem(path string) (map[string]any, error) {
+	paths := v.spec["paths"].(map[string]any)
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	var found map[string]any
+	least := -1
+	for tmpl, item := range paths {
+		params, ok := matchTemplate(strings.Split(strings.Trim(tmpl, "/"), "/"), segments)
+		if !ok || (least >= 0 && params >= least) {
+			continue
+		}
+		resolved, err := v.resolve(item)
+		if err != nil {
+			return nil, err
+		}
+		obj, ok := resolved.(map[string]any)
+		if !ok {
+			continue
+		}
+		found, least = obj, params
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no path matches %q", path)
+	}
+	return found, nil
+}
+
+// matchTemplate matches the segments of a path template and returns
+// the number of template parameters.
+func matchTemplate(tmpl, segments []string) (int, bool) {
+	if len(tmpl) != len(segments) {
+		return 0, false
+	}
+	params := 0
+	for i, t := range tmpl {
+		if strings.HasPrefix(t, "{") && strings.HasSuffix(t, "}") {
+			params++
+			continue
+		}
+		if t != segments[i] {
+			return 0, false
+		}
+	}
+	return params, true
+}
+
+// resolve follows local references like "#/components/schemas/User".
+func (v *Validator) resolve(element any) (any, error) {
+	for hops := 0; hops < 32; hops++ {
+		obj, ok := element.(map[string]any)
+		if !ok {
+			return element, nil
+		}
+		ref, ok := obj["$ref"].(string)
+		if !ok {
+			return element, nil
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, fmt.Errorf("unsupported reference %q", ref)
+		}
+		element = v.spec
+		for _, token := range strings.Split(ref[2:], "/") {
+			token = strings.ReplaceAll(strings.ReplaceAll(token, "~1", "/"), "~0", "~")
+			obj, ok := element.(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("invalid reference %q", ref)
+			}
+			if element, ok = obj[token]; !ok {
+				return nil, fmt.Errorf("invalid reference %q", ref)
+			}
+		}
+	}
+	return nil, fmt.Errorf("too many references")
+}
+
+// pattern returns the compiled regular expression.
+func (v *Validator) pattern(expr string) (*regexp.Regexp, error) {
+	v.patternsMu.Lock()
+	defer v.patternsMu.Unlock()
+	if re, ok := v.patterns[expr]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, err
+	}
+	v.patterns[expr] = re
+	return re, nil
+}
+
+// EOF
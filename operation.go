@@ -0,0 +1,124 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// OPERATIONS
+//--------------------
+
+// OperationKind describes the kind of a mutation.
+type OperationKind string
+
+// Kinds of operations.
+const (
+	// SetOperation sets a value, see SetValueAt.
+	SetOperation OperationKind = "set"
+
+	// DeleteValueOperation deletes a value, see DeleteValueAt.
+	DeleteValueOperation OperationKind = "delete-value"
+
+	// DeleteElementOperation deletes an element, see DeleteElementAt.
+	DeleteElementOperation OperationKind = "delete-element"
+
+	// ClearOperation clears the document, see Clear.
+	ClearOperation OperationKind = "clear"
+)
+
+// Operation is one serializable mutation of a document.
+type Operation struct {
+	Kind  OperationKind `json:"op"`
+	Path  Path          `json:"path,omitempty"`
+	Value Value         `json:"value"`
+}
+
+// RecordOperations starts recording the mutations of the document as
+// operations. A previous log is dropped. If the document is not empty
+// the log starts with setting its current root, so that replaying the
+// log creates the same document.
+func (d *Document) RecordOperations() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.recording = true
+	d.operations = nil
+	if d.root != nil {
+		d.operations = append(d.operations, Operation{SetOperation, Separator, copyElement(d.root)})
+	}
+}
+
+// Operations returns the recorded operations. They can be persisted,
+// e.g. as append-only log, and replayed to reconstruct the document.
+func (d *Document) Operations() []Operation {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	operations := make([]Operation, len(d.operations))
+	copy(operations, d.operations)
+	return operations
+}
+
+// Replay creates a new document by applying the operations in order.
+func Replay(operations []Operation) (*Document, error) {
+	doc := NewDocument()
+	for idx, operation := range operations {
+		if err := doc.applyOperation(operation); err != nil {
+			return nil, fmt.Errorf("cannot replay operation %d: %v", idx, err)
+		}
+	}
+	return doc, nil
+}
+
+// ApplyOperations applies the operations in order to the document, e.g.
+// the recorded changes of a restricted copy. It stops at the first
+// failing operation.
+func (d *Document) ApplyOperations(operations []Operation) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	for idx, operation := range operations {
+		if err := d.applyOperation(operation); err != nil {
+			return fmt.Errorf("cannot apply operation %d: %w", idx, err)
+		}
+	}
+	return nil
+}
+
+// applyOperation applies one operation to the document.
+func (d *Document) applyOperation(operation Operation) error {
+	switch operation.Kind {
+	case SetOperation:
+		return d.SetValueAt(operation.Path, operation.Value)
+	case DeleteValueOperation:
+		return d.DeleteValueAt(operation.Path)
+	case DeleteElementOperation:
+		return d.DeleteElementAt(operation.Path)
+	case ClearOperation:
+		d.Clear()
+		return nil
+	}
+	return fmt.Errorf("invalid operation kind %q", operation.Kind)
+}
+
+// record appends the operation to the log if recording. Values are
+// copied, so later changes of them are not visible in the log.
+func (d *Document) record(kind OperationKind, path Path, value Value) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if !d.recording {
+		return
+	}
+	d.operations = append(d.operations, Operation{kind, path, copyElement(value)})
+}
+
+// EOF
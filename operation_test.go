@@ -0,0 +1,78 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestOperations tests recording and replaying operations.
+func TestOperations(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a":{"b":1},"c":[1,2,3]}`)
+
+	// Not recording by default.
+	assert.NoError(doc.SetValueAt("/x", 1))
+	assert.Length(doc.Operations(), 0)
+
+	doc.RecordOperations()
+	obj := dynaj.Object{"e": "f"}
+	assert.NoError(doc.SetValueAt("/d", obj))
+	obj["e"] = "changed afterwards"
+	assert.NoError(doc.SetValueAt("/a/b", false))
+	assert.NoError(doc.SetValueAt("/n", nil))
+	assert.NoError(doc.DeleteValueAt("/c/1"))
+	assert.NoError(doc.DeleteElementAt("/x"))
+	assert.NoError(doc.SetRawAt("/r", json.RawMessage(`{"s":[true]}`)))
+	assert.ErrorContains(doc.DeleteValueAt("/does/not/exist"), "cannot delete value")
+	ops := doc.Operations()
+	assert.Length(ops, 7)
+	assert.Equal(ops[0].Kind, dynaj.SetOperation)
+	assert.Equal(ops[0].Path, "/")
+	assert.Equal(ops[1].Value, dynaj.Object{"e": "f"})
+	assert.Equal(ops[4].Kind, dynaj.DeleteValueOperation)
+	assert.Equal(ops[5].Kind, dynaj.DeleteElementOperation)
+
+	// Persist the log and replay it.
+	data, err := json.Marshal(ops)
+	assert.NoError(err)
+	assert.Substring(`{"op":"delete-value","path":"/c/1","value":null}`, string(data))
+	var loaded []dynaj.Operation
+	assert.NoError(json.Unmarshal(data, &loaded))
+	replayed, err := dynaj.Replay(loaded)
+	assert.NoError(err)
+	assert.Equal(replayed.String(), `{"a":{"b":false},"c":[1,3],"d":{"e":"f"},"n":null,"r":{"s":[true]}}`)
+
+	// Clearing.
+	doc.Clear()
+	assert.NoError(doc.SetValueAt("/z", 1))
+	replayed, err = dynaj.Replay(doc.Operations())
+	assert.NoError(err)
+	assert.Equal(replayed.String(), `{"z":1}`)
+
+	// Invalid operations.
+	_, err = dynaj.Replay([]dynaj.Operation{{Kind: "fly", Path: "/a"}})
+	assert.ErrorContains(err, `cannot replay operation 0: invalid operation kind "fly"`)
+	_, err = dynaj.Replay([]dynaj.Operation{{Kind: dynaj.DeleteValueOperation, Path: "/a"}})
+	assert.ErrorContains(err, "cannot replay operation 0")
+}
+
+// EOF
@@ -0,0 +1,297 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+//--------------------
+// OPTIONS
+//--------------------
+
+// Option configures the creation of documents.
+type Option func(o *options)
+
+// Limits restricts the JSON-encoded data accepted when unmarshalling.
+// Zero values mean no limit.
+type Limits struct {
+	// MaxBytes is the maximum length of the data.
+	MaxBytes int
+
+	// MaxDepth is the maximum nesting of objects and arrays.
+	MaxDepth int
+}
+
+// options contains the configuration set by the options.
+type options struct {
+	decoder        Decoder
+	nonFinite      NonFinitePolicy
+	numbers        NumberFormat
+	bools          BoolTable
+	emptyUndefined bool
+	strict         bool
+	integers       bool
+	interned       bool
+	limits         Limits
+	caseFolding    bool
+	sortedKeys     bool
+	suggestions    bool
+	stats          bool
+	metrics        MetricsSink
+}
+
+// newOptions applies the options to the defaults.
+func newOptions(opts []Option) *options {
+	o := &options{
+		decoder: NewStandardDecoder(),
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
+
+// newDocument creates a document with the configured settings.
+func (o *options) newDocument(root Element) *Document {
+	d := &Document{
+		root:           root,
+		nonFinite:      o.nonFinite,
+		numbers:        o.numbers,
+		emptyUndefined: o.emptyUndefined,
+		strict:         o.strict,
+		caseFolding:    o.caseFolding,
+		sortedKeys:     o.sortedKeys,
+		suggestions:    o.suggestions,
+		metrics:        o.metrics,
+	}
+	d.SetBoolTable(o.bools)
+	d.SetAccessStats(o.stats)
+	return d
+}
+
+// decode decodes the data and reports it to the metrics sink.
+func (o *options) decode(data []byte) (Element, error) {
+	start := time.Now()
+	root, err := o.decodeData(data)
+	o.observeParse(data, start, err)
+	return root, err
+}
+
+// decodeData decodes the data respecting the limits and the number mode.
+// Byte order marks are stripped and UTF-16 or UTF-32 data is transcoded.
+func (o *options) decodeData(data []byte) (Element, error) {
+	if o.limits.MaxBytes > 0 && len(data) > o.limits.MaxBytes {
+		return nil, fmt.Errorf("data length %d exceeds limit of %d bytes", len(data), o.limits.MaxBytes)
+	}
+	data, err := toUTF8(data)
+	if err != nil {
+		return nil, err
+	}
+	root, err := o.decoder.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	if o.limits.MaxDepth > 0 && depthOf(root) > o.limits.MaxDepth {
+		return nil, fmt.Errorf("nesting exceeds limit of depth %d", o.limits.MaxDepth)
+	}
+	if o.integers {
+		root = integersOf(root)
+	}
+	if o.interned {
+		root = newInterner().element(root)
+	}
+	return root, nil
+}
+
+// WithDecoder sets the decoder used to parse JSON-encoded data. Default
+// is the standard decoder.
+func WithDecoder(decoder Decoder) Option {
+	return func(o *options) {
+		if decoder != nil {
+			o.decoder = decoder
+		}
+	}
+}
+
+// WithNonFinitePolicy sets the handling of NaN and infinite floats,
+// see SetNonFinitePolicy.
+func WithNonFinitePolicy(policy NonFinitePolicy) Option {
+	return func(o *options) {
+		o.nonFinite = policy
+	}
+}
+
+// WithNumberFormat sets the format of numbers in strings, see
+// SetNumberFormat.
+func WithNumberFormat(format NumberFormat) Option {
+	return func(o *options) {
+		o.numbers = format
+	}
+}
+
+// WithBoolTable sets the table of bool strings, see SetBoolTable.
+func WithBoolTable(table BoolTable) Option {
+	return func(o *options) {
+		o.bools = table
+	}
+}
+
+// WithEmptyAsUndefined lets empty strings be read as undefined, see
+// SetEmptyAsUndefined.
+func WithEmptyAsUndefined() Option {
+	return func(o *options) {
+		o.emptyUndefined = true
+	}
+}
+
+// WithStrict enables the strict mode, see SetStrict.
+func WithStrict() Option {
+	return func(o *options) {
+		o.strict = true
+	}
+}
+
+// WithIntegers lets unmarshalling store integral numbers as ints
+// instead of float64s, as long as they are exactly representable.
+func WithIntegers() Option {
+	return func(o *options) {
+		o.integers = true
+	}
+}
+
+// WithLimits restricts the size and the nesting of the data accepted
+// when unmarshalling.
+func WithLimits(limits Limits) Option {
+	return func(o *options) {
+		o.limits = limits
+	}
+}
+
+// WithCaseFolding lets NodeAt find object keys case-insensitive if
+// there is no exact match. Setting values still uses the exact keys.
+func WithCaseFolding() Option {
+	return func(o *options) {
+		o.caseFolding = true
+	}
+}
+
+// WithSortedKeys lets Process, Range, and Query traverse object keys
+// in sorted order, see SetSortedKeys.
+func WithSortedKeys() Option {
+	return func(o *options) {
+		o.sortedKeys = true
+	}
+}
+
+// WithPathSuggestions lets nodes of paths not found contain a PathError
+// with suggestions, see SetPathSuggestions.
+func WithPathSuggestions() Option {
+	return func(o *options) {
+		o.suggestions = true
+	}
+}
+
+// WithAccessStats enables counting the reads and writes per path, see
+// SetAccessStats.
+func WithAccessStats() Option {
+	return func(o *options) {
+		o.stats = true
+	}
+}
+
+// WithInternedRecords lets unmarshalling store arrays of objects with
+// the same keys as records, see InternRecords.
+func WithInternedRecords() Option {
+	return func(o *options) {
+		o.interned = true
+	}
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// depthOf returns the nesting depth of objects and arrays.
+func depthOf(element Element) int {
+	depth := 0
+	switch typed := element.(type) {
+	case Object:
+		for _, child := range typed {
+			if d := depthOf(child); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	case Array:
+		for _, child := range typed {
+			if d := depthOf(child); d > depth {
+				depth = d
+			}
+		}
+		return depth + 1
+	}
+	return 0
+}
+
+// maxExactInt is the largest integer exactly representable as float64.
+const maxExactInt = 1 << 53
+
+// integersOf converts the integral float64s into ints.
+func integersOf(element Element) Element {
+	switch typed := element.(type) {
+	case Object:
+		for key, child := range typed {
+			typed[key] = integersOf(child)
+		}
+	case Array:
+		for idx, child := range typed {
+			typed[idx] = integersOf(child)
+		}
+	case float64:
+		if typed == math.Trunc(typed) && math.Abs(typed) <= maxExactInt {
+			return int(typed)
+		}
+	}
+	return element
+}
+
+// elementAtFolded returns the element at the keys, looking up object
+// keys case-insensitive if there is no exact match.
+func elementAtFolded(element Element, keys Keys) (Element, error) {
+	for i, key := range keys {
+		element, _ = decodeRaw(element)
+		obj, ok := element.(Object)
+		if !ok {
+			return elementAt(element, keys[i:])
+		}
+		child, ok := obj[key]
+		if !ok {
+			for k, c := range obj {
+				if strings.EqualFold(k, key) {
+					child, ok = c, true
+					break
+				}
+			}
+		}
+		if !ok {
+			return nil, fmt.Errorf("invalid path %q", pathify(keys[i:]))
+		}
+		element = child
+	}
+	return element, nil
+}
+
+// EOF
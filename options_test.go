@@ -0,0 +1,91 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestNewWithOptions tests creating documents with options.
+func TestNewWithOptions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := dynaj.New(
+		dynaj.WithNonFinitePolicy(dynaj.NullNonFinite),
+		dynaj.WithNumberFormat(dynaj.CommaNumbers),
+		dynaj.WithBoolTable(dynaj.ExtendedBools),
+		dynaj.WithEmptyAsUndefined(),
+		dynaj.WithStrict(),
+	)
+	assert.True(doc.IsStrict())
+	assert.ErrorContains(doc.SetValueAt("/a/b", 1), "does not exist")
+	assert.NoError(doc.SetValueAt("/nan", math.NaN()))
+	assert.NoError(doc.SetValueAt("/amount", "1.234,5"))
+	assert.NoError(doc.SetValueAt("/flag", "Yes"))
+	assert.NoError(doc.SetValueAt("/empty", ""))
+
+	assert.True(doc.NodeAt("/nan").IsUndefined())
+	assert.Equal(doc.NodeAt("/amount").AsFloat64(0), 1234.5)
+	assert.True(doc.NodeAt("/flag").AsBool(false))
+	assert.Equal(doc.NodeAt("/empty").AsString("none"), "none")
+
+	plain := dynaj.New()
+	assert.False(plain.IsStrict())
+	assert.NoError(plain.SetValueAt("/a/b", 1))
+}
+
+// TestUnmarshalWithOptions tests unmarshalling with options.
+func TestUnmarshalWithOptions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte(`{"a": 1, "b": 1.5, "c": [2, 1e3, 9007199254740993], "Name": "x"}`)
+
+	doc, err := dynaj.Unmarshal(data, dynaj.WithIntegers(), dynaj.WithCaseFolding())
+	assert.NoError(err)
+	numbers, err := doc.MarshalJSONAt("/c")
+	assert.NoError(err)
+	assert.Equal(string(numbers), `[2,1000,9007199254740992]`)
+	n, err := doc.NodeAt("/a").AsInt8Strict()
+	assert.NoError(err)
+	assert.Equal(n, int8(1))
+	assert.Equal(doc.NodeAt("/b").AsFloat64(0), 1.5)
+	assert.Equal(doc.NodeAt("/name").AsString(""), "x")
+	assert.Equal(doc.NodeAt("/NAME").AsString(""), "x")
+	assert.True(doc.NodeAt("/nom").IsError())
+	assert.Equal(doc.Freeze().NodeAt("/name").AsString(""), "x")
+
+	doc, err = dynaj.Unmarshal(data)
+	assert.NoError(err)
+	assert.True(doc.NodeAt("/name").IsError())
+}
+
+// TestUnmarshalLimits tests rejecting too large or deep data.
+func TestUnmarshalLimits(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte(`{"a": [{"b": [1]}]}`)
+
+	_, err := dynaj.Unmarshal(data, dynaj.WithLimits(dynaj.Limits{MaxBytes: 10}))
+	assert.ErrorContains(err, "data length 19 exceeds limit of 10 bytes")
+	_, err = dynaj.Unmarshal(data, dynaj.WithLimits(dynaj.Limits{MaxDepth: 3}))
+	assert.ErrorContains(err, "nesting exceeds limit of depth 3")
+	_, err = dynaj.Unmarshal(data, dynaj.WithLimits(dynaj.Limits{MaxBytes: 19, MaxDepth: 4}))
+	assert.NoError(err)
+}
+
+// EOF
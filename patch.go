@@ -0,0 +1,377 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// PATCHES
+//--------------------
+
+// PatchOperation is one operation of a JSON Patch as defined by RFC 6902.
+// The path and from fields are JSON Pointers.
+type PatchOperation struct {
+	Op    string `json:"op"`
+	Path  string `json:"path"`
+	From  string `json:"from,omitempty"`
+	Value Value  `json:"value,omitempty"`
+}
+
+// Patch is either a JSON Patch with its operations or, if there are no
+// operations, a JSON Merge Patch as defined by RFC 7386.
+type Patch struct {
+	Operations []PatchOperation
+	Merge      Value
+}
+
+// ParseJSONPatch parses a JSON Patch document.
+func ParseJSONPatch(data []byte) (Patch, error) {
+	var raws []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raws); err != nil {
+		return Patch{}, fmt.Errorf("cannot parse JSON Patch: %v", err)
+	}
+	operations := make([]PatchOperation, len(raws))
+	for idx, raw := range raws {
+		op := &operations[idx]
+		for field, target := range map[string]*string{"op": &op.Op, "path": &op.Path, "from": &op.From} {
+			if value, ok := raw[field]; ok {
+				if err := json.Unmarshal(value, target); err != nil {
+					return Patch{}, fmt.Errorf("cannot parse JSON Patch: operation %d: invalid %s: %v", idx, field, err)
+				}
+			}
+		}
+		value, ok := raw["value"]
+		switch op.Op {
+		case "add", "replace", "test":
+			if !ok {
+				return Patch{}, fmt.Errorf("cannot parse JSON Patch: operation %d: missing value", idx)
+			}
+		case "move", "copy":
+			if _, ok := raw["from"]; !ok {
+				return Patch{}, fmt.Errorf("cannot parse JSON Patch: operation %d: missing from", idx)
+			}
+		}
+		if ok {
+			if err := json.Unmarshal(value, &op.Value); err != nil {
+				return Patch{}, fmt.Errorf("cannot parse JSON Patch: operation %d: invalid value: %v", idx, err)
+			}
+		}
+	}
+	return Patch{Operations: operations}, nil
+}
+
+// ParseMergePatch parses a JSON Merge Patch document.
+func ParseMergePatch(data []byte) (Patch, error) {
+	var merge Value
+	if err := json.Unmarshal(data, &merge); err != nil {
+		return Patch{}, fmt.Errorf("cannot parse JSON Merge Patch: %v", err)
+	}
+	return Patch{Merge: merge}, nil
+}
+
+//--------------------
+// APPLY OPTIONS
+//--------------------
+
+// ConflictPolicy defines how ApplyAll continues when a patch fails.
+type ConflictPolicy int
+
+// Policies for failing patches. Each patch is applied atomically, so
+// a failing patch never leaves parts of its changes.
+const (
+	// AbortOnConflict keeps the document unchanged if any patch fails.
+	AbortOnConflict ConflictPolicy = iota
+
+	// StopOnConflict keeps the patches applied before the failing one.
+	StopOnConflict
+
+	// SkipConflicts skips failing patches and applies all others.
+	SkipConflicts
+)
+
+// ApplyOption configures the application of patches.
+type ApplyOption func(a *applier)
+
+// OnConflict sets the policy for failing patches. Default is to abort.
+func OnConflict(policy ConflictPolicy) ApplyOption {
+	return func(a *applier) {
+		a.policy = policy
+	}
+}
+
+// applier contains the settings of ApplyAll.
+type applier struct {
+	policy ConflictPolicy
+}
+
+//--------------------
+// APPLYING PATCHES
+//--------------------
+
+// PatchError describes a failed patch. For merge patches the operation
+// is -1.
+type PatchError struct {
+	Patch     int
+	Operation int
+	Path      string
+	Err       error
+}
+
+// Error implements error.
+func (e *PatchError) Error() string {
+	if e.Operation < 0 {
+		return fmt.Sprintf("patch %d failed: %v", e.Patch, e.Err)
+	}
+	return fmt.Sprintf("patch %d failed at operation %d on %q: %v", e.Patch, e.Operation, e.Path, e.Err)
+}
+
+// Unwrap returns the error of the operation.
+func (e *PatchError) Unwrap() error {
+	return e.Err
+}
+
+// PatchReport contains the indices of the applied patches and the
+// errors of the failed ones.
+type PatchReport struct {
+	Applied []int
+	Failed  []*PatchError
+}
+
+// ApplyAll applies the patches in their order. Each patch is applied
+// atomically and recorded as setting the new root. The report tells
+// which patches are applied and which failed at which path. With the
+// default AbortOnConflict policy the document stays unchanged if a
+// patch fails. Errors are returned as PatchError. Restricted documents
+// need write access to the root.
+func (d *Document) ApplyAll(patches []Patch, opts ...ApplyOption) (*PatchReport, error) {
+	if d.frozen {
+		return nil, ErrFrozen
+	}
+	if err := d.checkAccess(Keys{}); err != nil {
+		return nil, err
+	}
+	a := &applier{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	report := &PatchReport{}
+	root := d.root
+	for idx, patch := range patches {
+		patched, err := applyPatch(copyElement(root), patch)
+		if err != nil {
+			var perr *PatchError
+			if !errors.As(err, &perr) {
+				perr = &PatchError{Operation: -1, Err: err}
+			}
+			perr.Patch = idx
+			report.Failed = append(report.Failed, perr)
+			switch a.policy {
+			case AbortOnConflict:
+				report.Applied = nil
+				return report, perr
+			case StopOnConflict:
+				d.setPatchedRoot(root, report)
+				return report, perr
+			}
+			continue
+		}
+		root = patched
+		report.Applied = append(report.Applied, idx)
+	}
+	d.setPatchedRoot(root, report)
+	return report, nil
+}
+
+// setPatchedRoot sets the patched root if any patch has been applied.
+func (d *Document) setPatchedRoot(root Element, report *PatchReport) {
+	if len(report.Applied) == 0 {
+		return
+	}
+	d.root = root
+	d.changed(nil)
+	d.record(SetOperation, Separator, root)
+}
+
+// applyPatch applies one patch to a copy of the root.
+func applyPatch(root Element, patch Patch) (Element, error) {
+	if patch.Operations == nil {
+		merge, err := normalizeValue(patch.Merge, Separator, RejectNonFinite)
+		if err != nil {
+			return nil, err
+		}
+		return mergePatch(root, copyElement(merge)), nil
+	}
+	for idx, op := range patch.Operations {
+		var err error
+		root, err = applyPatchOperation(root, op)
+		if err != nil {
+			return nil, &PatchError{Operation: idx, Path: op.Path, Err: err}
+		}
+	}
+	return root, nil
+}
+
+// applyPatchOperation applies one JSON Patch operation.
+func applyPatchOperation(root Element, op PatchOperation) (Element, error) {
+	keys, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case "add", "replace", "test":
+		value, err := normalizeValue(op.Value, pathify(keys), RejectNonFinite)
+		if err != nil {
+			return nil, err
+		}
+		value = copyElement(value)
+		switch op.Op {
+		case "add":
+			return addElement(root, keys, value)
+		case "replace":
+			if root, err = removeElement(root, keys); err != nil {
+				return nil, err
+			}
+			return addElement(root, keys, value)
+		default:
+			current, err := elementAt(root, keys)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path: %v", err)
+			}
+			if !equalElements(current, value) {
+				return nil, fmt.Errorf("test failed: value is %s, expected %s", preview(current), preview(value))
+			}
+			return root, nil
+		}
+	case "remove":
+		return removeElement(root, keys)
+	case "move", "copy":
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := elementAt(root, from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid from %q: %v", op.From, err)
+		}
+		value, err = decodeRaw(value)
+		if err != nil {
+			return nil, err
+		}
+		value = copyElement(value)
+		if op.Op == "move" {
+			if IsAncestor(pathify(from), pathify(keys)) {
+				return nil, fmt.Errorf("cannot move %q into itself", op.From)
+			}
+			if root, err = removeElement(root, from); err != nil {
+				return nil, err
+			}
+		}
+		return addElement(root, keys, value)
+	}
+	return nil, fmt.Errorf("invalid operation %q", op.Op)
+}
+
+// addElement adds the value at the keys. In arrays the following
+// elements are shifted, the index "-" appends the value.
+func addElement(root Element, keys Keys, value Element) (Element, error) {
+	if len(keys) == 0 {
+		return value, nil
+	}
+	parentKeys, last := keys[:len(keys)-1], keys[len(keys)-1]
+	parent, err := elementAt(root, parentKeys)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parent: %v", err)
+	}
+	if parent, err = decodeRaw(parent); err != nil {
+		return nil, err
+	}
+	switch typed := parent.(type) {
+	case Object:
+		typed[last] = value
+		return replaceElement(root, parentKeys, typed)
+	case Array:
+		index := len(typed)
+		if last != "-" {
+			i, err := strconv.Atoi(last)
+			if err != nil || i < 0 || i > len(typed) || (last != "0" && strings.HasPrefix(last, "0")) {
+				return nil, fmt.Errorf("invalid index %q", last)
+			}
+			index = i
+		}
+		arr := make(Array, 0, len(typed)+1)
+		arr = append(arr, typed[:index]...)
+		arr = append(arr, value)
+		arr = append(arr, typed[index:]...)
+		return replaceElement(root, parentKeys, arr)
+	}
+	return nil, fmt.Errorf("parent %q is no object or array", pathify(parentKeys))
+}
+
+// removeElement removes the existing element at the keys.
+func removeElement(root Element, keys Keys) (Element, error) {
+	if _, err := elementAt(root, keys); err != nil {
+		return nil, fmt.Errorf("invalid path: %v", err)
+	}
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	return deleteElement(root, keys, true)
+}
+
+// mergePatch merges the patch into the target following RFC 7386.
+func mergePatch(target, patch Element) Element {
+	obj, ok := patch.(Object)
+	if !ok {
+		return patch
+	}
+	target, _ = decodeRaw(target)
+	targetObj, ok := target.(Object)
+	if !ok {
+		targetObj = Object{}
+	}
+	for key, value := range obj {
+		if value == nil {
+			delete(targetObj, key)
+			continue
+		}
+		targetObj[key] = mergePatch(targetObj[key], value)
+	}
+	return targetObj
+}
+
+// parsePointer converts a JSON Pointer into keys. Keys containing the
+// separator are not supported.
+func parsePointer(pointer string) (Keys, error) {
+	if pointer == "" {
+		return Keys{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid JSON Pointer %q", pointer)
+	}
+	parts := strings.Split(pointer[1:], "/")
+	keys := make(Keys, len(parts))
+	for idx, part := range parts {
+		if strings.Contains(part, "~1") {
+			return nil, fmt.Errorf("unsupported key %q in JSON Pointer %q", part, pointer)
+		}
+		keys[idx] = strings.ReplaceAll(part, "~0", "~")
+	}
+	return keys, nil
+}
+
+// EOF
@@ -0,0 +1,162 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestApplyJSONPatch tests applying the operations of RFC 6902.
+func TestApplyJSONPatch(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": {"b": 1, "c": [1, 2, 3]}, "d": "x", "e~f": true}`)
+	patch := mustParseJSONPatch(assert, `[
+		{"op": "test", "path": "/a/b", "value": 1},
+		{"op": "add", "path": "/a/c/1", "value": 9},
+		{"op": "add", "path": "/a/c/-", "value": {"z": null}},
+		{"op": "replace", "path": "/d", "value": ["y"]},
+		{"op": "remove", "path": "/a/b"},
+		{"op": "copy", "from": "/a/c/0", "path": "/f"},
+		{"op": "move", "from": "/e~0f", "path": "/g"}
+	]`)
+
+	report, err := doc.ApplyAll([]dynaj.Patch{patch})
+	assert.NoError(err)
+	assert.Equal(report.Applied, []int{0})
+	assert.Length(report.Failed, 0)
+	data, err := doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"a":{"c":[1,9,2,3,{"z":null}]},"d":["y"],"f":1,"g":true}`)
+
+	// Replace the root.
+	patch = mustParseJSONPatch(assert, `[{"op": "replace", "path": "", "value": [1]}]`)
+	_, err = doc.ApplyAll([]dynaj.Patch{patch})
+	assert.NoError(err)
+	data, err = doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `[1]`)
+}
+
+// TestApplyMergePatch tests applying merge patches of RFC 7386.
+func TestApplyMergePatch(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"title": "Goodbye!", "author": {"givenName": "John", "familyName": "Doe"}, "tags": ["example", "sample"], "content": "text"}`)
+	patch, err := dynaj.ParseMergePatch([]byte(`{"title": "Hello!", "phoneNumber": "+01-123-456-7890", "author": {"familyName": null}, "tags": ["example"]}`))
+	assert.NoError(err)
+
+	_, err = doc.ApplyAll([]dynaj.Patch{patch})
+	assert.NoError(err)
+	data, err := doc.MarshalJSON()
+	assert.NoError(err)
+	assert.Equal(string(data), `{"author":{"givenName":"John"},"content":"text","phoneNumber":"+01-123-456-7890","tags":["example"],"title":"Hello!"}`)
+}
+
+// TestApplyAllConflicts tests the policies for failing patches.
+func TestApplyAllConflicts(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	patches := []dynaj.Patch{
+		mustParseJSONPatch(assert, `[{"op": "add", "path": "/a", "value": 1}]`),
+		mustParseJSONPatch(assert, `[{"op": "add", "path": "/b", "value": 2}, {"op": "remove", "path": "/x"}]`),
+		{Merge: map[string]any{"c": 3}},
+	}
+
+	tests := []struct {
+		policy  dynaj.ConflictPolicy
+		applied []int
+		doc     string
+		err     bool
+	}{
+		{dynaj.AbortOnConflict, nil, `{}`, true},
+		{dynaj.StopOnConflict, []int{0}, `{"a":1}`, true},
+		{dynaj.SkipConflicts, []int{0, 2}, `{"a":1,"c":3}`, false},
+	}
+	for _, test := range tests {
+		doc := mustUnmarshal(assert, `{}`)
+		doc.RecordOperations()
+		report, err := doc.ApplyAll(patches, dynaj.OnConflict(test.policy))
+		assert.Equal(report.Applied, test.applied)
+		assert.Length(report.Failed, 1)
+		perr := report.Failed[0]
+		assert.Equal(perr.Patch, 1)
+		assert.Equal(perr.Operation, 1)
+		assert.Equal(perr.Path, "/x")
+		assert.ErrorContains(perr, `patch 1 failed at operation 1 on "/x": invalid path`)
+		if test.err {
+			assert.True(errors.Is(err, perr.Err))
+		} else {
+			assert.NoError(err)
+		}
+		data, err := doc.MarshalJSON()
+		assert.NoError(err)
+		assert.Equal(string(data), test.doc)
+		replayed, err := dynaj.Replay(doc.Operations())
+		assert.NoError(err)
+		assert.Equal(replayed.String(), test.doc)
+	}
+}
+
+// TestApplyAllErrors tests invalid patches.
+func TestApplyAllErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	_, err := dynaj.ParseJSONPatch([]byte(`[{"op": "add", "path": "/a"}]`))
+	assert.ErrorContains(err, "operation 0: missing value")
+	_, err = dynaj.ParseJSONPatch([]byte(`[{"op": "move", "path": "/a"}]`))
+	assert.ErrorContains(err, "operation 0: missing from")
+	_, err = dynaj.ParseJSONPatch([]byte(`{}`))
+	assert.ErrorContains(err, "cannot parse JSON Patch")
+
+	doc := mustUnmarshal(assert, `{"a": {"b": [1]}, "s": "x"}`)
+	tests := []struct {
+		patch string
+		err   string
+	}{
+		{`[{"op": "test", "path": "/s", "value": "y"}]`, `test failed: value is "x", expected "y"`},
+		{`[{"op": "add", "path": "/a/b/5", "value": 1}]`, `invalid index "5"`},
+		{`[{"op": "add", "path": "/x/y", "value": 1}]`, `invalid parent`},
+		{`[{"op": "add", "path": "/s/y", "value": 1}]`, `parent "/s" is no object or array`},
+		{`[{"op": "replace", "path": "/y", "value": 1}]`, `invalid path`},
+		{`[{"op": "move", "from": "/a", "path": "/a/b/c"}]`, `cannot move "/a" into itself`},
+		{`[{"op": "add", "path": "/a~1b", "value": 1}]`, `unsupported key "a~1b"`},
+		{`[{"op": "add", "path": "a", "value": 1}]`, `invalid JSON Pointer "a"`},
+		{`[{"op": "drop", "path": "/a"}]`, `invalid operation "drop"`},
+	}
+	for _, test := range tests {
+		_, err := doc.ApplyAll([]dynaj.Patch{mustParseJSONPatch(assert, test.patch)})
+		assert.ErrorContains(err, test.err)
+	}
+	assert.Equal(doc.String(), `{"a":{"b":[1]},"s":"x"}`)
+
+	_, err = doc.Freeze().ApplyAll(nil)
+	assert.ErrorMatch(err, ".*frozen.*")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// mustParseJSONPatch parses a JSON Patch.
+func mustParseJSONPatch(assert *asserts.Asserts, data string) dynaj.Patch {
+	patch, err := dynaj.ParseJSONPatch([]byte(data))
+	assert.NoError(err)
+	return patch
+}
+
+// EOF
@@ -12,11 +12,72 @@ package dynaj // import "tideland.dev/go/dynaj"
 //--------------------
 
 import (
+	"encoding/json"
 	"fmt"
 	"strconv"
 	"strings"
 )
 
+//--------------------
+// PATH FUNCTIONS
+//--------------------
+
+// JoinPath joins the parts into one clean absolute path. The parts
+// may be keys or paths themselves.
+//
+//	JoinPath("/a/", "b", "/c/d") == "/a/b/c/d"
+func JoinPath(parts ...string) Path {
+	return joinPaths(parts...)
+}
+
+// ParentPath returns the path of the parent element. The parent of
+// the root is the root.
+func ParentPath(path Path) Path {
+	keys := splitPath(path)
+	if len(keys) == 0 {
+		return Separator
+	}
+	return pathify(keys[:len(keys)-1])
+}
+
+// BasePath returns the last key of the path. It is empty for the root.
+func BasePath(path Path) Key {
+	keys := splitPath(path)
+	if len(keys) == 0 {
+		return ""
+	}
+	return keys[len(keys)-1]
+}
+
+// IsAncestor returns true if the first path is an ancestor of the
+// second one. Paths are no ancestors of themselves.
+func IsAncestor(ancestor, path Path) bool {
+	akeys := splitPath(ancestor)
+	pkeys := splitPath(path)
+	if len(akeys) >= len(pkeys) {
+		return false
+	}
+	for i, key := range akeys {
+		if pkeys[i] != key {
+			return false
+		}
+	}
+	return true
+}
+
+// RelativePath returns the path relative to the base path, e.g. for
+// the access via views. It returns an error if the path is not the
+// base path or below it.
+func RelativePath(base, path Path) (Path, error) {
+	if pathify(splitPath(base)) == pathify(splitPath(path)) {
+		return Separator, nil
+	}
+	if !IsAncestor(base, path) {
+		return "", fmt.Errorf("path %q is not below %q", path, base)
+	}
+	return pathify(splitPath(path)[len(splitPath(base)):]), nil
+}
+
 //--------------------
 // PROCESSING FUNCTIONS
 //--------------------
@@ -93,11 +154,58 @@ func elementAt(element Element, keys Keys) (Element, error) {
 			return nil, fmt.Errorf("invalid path %q: index out of range", pathify(keys))
 		}
 		return elementAt(typed[index], t)
+	case *records:
+		// Interned records.
+		index, ok := asIndex(h)
+		if !ok {
+			return nil, fmt.Errorf("invalid path %q: no index", pathify(keys))
+		}
+		if index < 0 || index >= typed.len() {
+			return nil, fmt.Errorf("invalid path %q: index out of range", pathify(keys))
+		}
+		if len(t) == 0 || t[0] == "" {
+			return typed.record(index), nil
+		}
+		field, ok := typed.value(index, t[0])
+		if !ok {
+			return nil, fmt.Errorf("invalid path %q", pathify(keys))
+		}
+		return elementAt(field, t[1:])
+	case json.RawMessage:
+		// Raw JSON fragment.
+		decoded, err := decodeRaw(typed)
+		if err != nil {
+			return nil, err
+		}
+		return elementAt(decoded, keys)
 	}
 	// Path is longer than existing node structure.
 	return nil, fmt.Errorf("key or index not found")
 }
 
+// compareKeys compares two lists of keys. Indices are compared
+// numerically, all other keys alphabetically.
+func compareKeys(a, b Keys) int {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] == b[i] {
+			continue
+		}
+		ai, aok := asIndex(a[i])
+		bi, bok := asIndex(b[i])
+		switch {
+		case aok && bok && ai < bi:
+			return -1
+		case aok && bok:
+			return 1
+		case a[i] < b[i]:
+			return -1
+		default:
+			return 1
+		}
+	}
+	return len(a) - len(b)
+}
+
 // pathify creates a path out of keys.
 func pathify(keys Keys) Path {
 	return Separator + strings.Join(keys, Separator)
@@ -115,7 +223,7 @@ func appendKey(path Path, key Key) Path {
 // isObjectOrArray checks if the element is an object or an array.
 func isObjectOrArray(element Element) bool {
 	switch element.(type) {
-	case Object, Array:
+	case Object, Array, *records:
 		return true
 	default:
 		return false
@@ -125,7 +233,7 @@ func isObjectOrArray(element Element) bool {
 // isValue checks if the element is a single value.
 func isValue(element Element) bool {
 	switch element.(type) {
-	case Object, Array, nil:
+	case Object, Array, *records, nil:
 		return false
 	default:
 		return true
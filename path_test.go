@@ -0,0 +1,72 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestPathFunctions tests the exported path helpers.
+func TestPathFunctions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	assert.Equal(dynaj.JoinPath("/a/", "b", "/c/d"), "/a/b/c/d")
+	assert.Equal(dynaj.JoinPath("", "//a//b"), "/a/b")
+	assert.Equal(dynaj.JoinPath(), "/")
+
+	assert.Equal(dynaj.ParentPath("/a/b/c"), "/a/b")
+	assert.Equal(dynaj.ParentPath("a/b/"), "/a")
+	assert.Equal(dynaj.ParentPath("/a"), "/")
+	assert.Equal(dynaj.ParentPath("/"), "/")
+
+	assert.Equal(dynaj.BasePath("/a/b/c"), "c")
+	assert.Equal(dynaj.BasePath("/a/3/"), "3")
+	assert.Equal(dynaj.BasePath("/"), "")
+
+	assert.True(dynaj.IsAncestor("/", "/a"))
+	assert.True(dynaj.IsAncestor("/a", "/a/b/c"))
+	assert.True(dynaj.IsAncestor("a/", "/a/b"))
+	assert.False(dynaj.IsAncestor("/a", "/a"))
+	assert.False(dynaj.IsAncestor("/a/b", "/a"))
+	assert.False(dynaj.IsAncestor("/a", "/ab/c"))
+	assert.False(dynaj.IsAncestor("/", "/"))
+
+	rel, err := dynaj.RelativePath("/a/b", "/a/b/c/d")
+	assert.NoError(err)
+	assert.Equal(rel, "/c/d")
+	rel, err = dynaj.RelativePath("/a/b/", "a/b")
+	assert.NoError(err)
+	assert.Equal(rel, "/")
+	rel, err = dynaj.RelativePath("/", "/x")
+	assert.NoError(err)
+	assert.Equal(rel, "/x")
+	_, err = dynaj.RelativePath("/a/b", "/a/c")
+	assert.ErrorContains(err, `path "/a/c" is not below "/a/b"`)
+
+	// Relative paths work with views.
+	doc := mustUnmarshal(assert, `{"a":{"b":{"c":1}}}`)
+	view, err := doc.ViewAt("/a")
+	assert.NoError(err)
+	rel, err = dynaj.RelativePath(view.Base(), "/a/b/c")
+	assert.NoError(err)
+	assert.Equal(view.NodeAt(rel).AsInt(0), 1)
+}
+
+// EOF
@@ -0,0 +1,164 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"strings"
+
+	"tideland.dev/go/matcher"
+)
+
+//--------------------
+// PATTERNS
+//--------------------
+
+// MatchPattern checks if the path matches the pattern. It is used by
+// queries and all other functions selecting paths by patterns. Besides
+// the wildcards "*" for any characters including the separator, "?"
+// for one character, and "[...]" for groups of characters, patterns
+// support alternatives like "/{users,groups}/*/name", the segment "**"
+// matching any number of segments including none like "/a/**/name",
+// and a leading "!" negating the whole pattern like "!/internal/*".
+// Negated patterns match neither the ancestors of paths the pattern
+// may match, like the root, nor the paths below the matched ones. So
+// omitting "!/keep" keeps "/keep" with its content and the way to it.
+// Special characters can be escaped with a backslash.
+func MatchPattern(pattern string, path Path) bool {
+	return compilePattern(pattern).matches(path)
+}
+
+// pathPattern contains the alternatives a pattern is expanded to.
+type pathPattern struct {
+	negated      bool
+	alternatives []string
+}
+
+// compilePattern expands the alternatives and segments matching any
+// number of segments of the pattern.
+func compilePattern(pattern string) *pathPattern {
+	p := &pathPattern{}
+	if strings.HasPrefix(pattern, "!") {
+		p.negated = true
+		pattern = pattern[1:]
+	}
+	for _, braced := range expandBraces(pattern) {
+		p.alternatives = append(p.alternatives, expandGlobstars(braced)...)
+	}
+	return p
+}
+
+// matches checks if the path matches one of the alternatives or, if
+// negated, none of them, none of its ancestors, and none of the paths
+// below it.
+func (p *pathPattern) matches(path Path) bool {
+	for _, alternative := range p.alternatives {
+		if matcher.Matches(alternative, path, false) {
+			return !p.negated
+		}
+	}
+	if !p.negated {
+		return false
+	}
+	for _, alternative := range p.alternatives {
+		if matchesBelow(alternative, path) || matchesAncestor(alternative, path) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesBelow checks if the alternative may match paths below the
+// path, which is if its leading segments match the path.
+func matchesBelow(alternative string, path Path) bool {
+	segments := strings.Split(strings.TrimSuffix(path, Separator), Separator)
+	leading := strings.Split(alternative, Separator)
+	if len(leading) <= len(segments) {
+		return false
+	}
+	return matcher.Matches(strings.Join(leading[:len(segments)], Separator), strings.Join(segments, Separator), false)
+}
+
+// matchesAncestor checks if the alternative matches an ancestor of
+// the path.
+func matchesAncestor(alternative string, path Path) bool {
+	for i := len(path) - 1; i > 0; i-- {
+		if path[i] == Separator[0] && matcher.Matches(alternative, path[:i], false) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandBraces expands the first group of alternatives in braces and
+// recursively the following ones. Unclosed braces stay unchanged.
+func expandBraces(pattern string) []string {
+	open := -1
+	depth := 0
+	commas := []int{}
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '\\':
+			i++
+		case '{':
+			if open < 0 {
+				open = i
+			}
+			depth++
+		case ',':
+			if depth == 1 {
+				commas = append(commas, i)
+			}
+		case '}':
+			if depth == 0 {
+				continue
+			}
+			depth--
+			if depth > 0 {
+				continue
+			}
+			prefix, suffix := pattern[:open], pattern[i+1:]
+			bounds := append(append([]int{open}, commas...), i)
+			expanded := []string{}
+			for j := 1; j < len(bounds); j++ {
+				alternative := prefix + pattern[bounds[j-1]+1:bounds[j]] + suffix
+				expanded = append(expanded, expandBraces(alternative)...)
+			}
+			return expanded
+		}
+	}
+	return []string{pattern}
+}
+
+// expandGlobstars expands each segment "**" into no segment and a
+// segment "*", which matches one or more segments.
+func expandGlobstars(pattern string) []string {
+	expanded := [][]string{{}}
+	for _, segment := range strings.Split(pattern, Separator) {
+		next := make([][]string, 0, len(expanded))
+		for _, segments := range expanded {
+			if segment == "**" {
+				next = append(next, segments)
+				next = append(next, append(segments[:len(segments):len(segments)], "*"))
+				continue
+			}
+			next = append(next, append(segments[:len(segments):len(segments)], segment))
+		}
+		expanded = next
+	}
+	patterns := make([]string, len(expanded))
+	for i, segments := range expanded {
+		patterns[i] = strings.Join(segments, Separator)
+	}
+	return patterns
+}
+
+// EOF
@@ -0,0 +1,115 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestMatchPattern tests matching paths with the pattern syntax.
+func TestMatchPattern(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	tests := []struct {
+		pattern string
+		path    dynaj.Path
+		matches bool
+	}{
+		// Wildcards of the matcher.
+		{"/a/*", "/a/b/c", true},
+		{"/a/?", "/a/b", true},
+		{"/a/[0-9]", "/a/b", false},
+		// Negation.
+		{"!/internal/*", "/internal/key", false},
+		{"!/internal/*", "/public/key", true},
+		{"\\!/a", "!/a", true},
+		{"!/keep", "/", false},
+		{"!/keep", "/keep", false},
+		{"!/keep", "/keep/x", false},
+		{"!/keep", "/drop", true},
+		{"!/a/keep", "/a", false},
+		{"!/a/keep", "/a/drop", true},
+		{"!/*/keep", "/b", false},
+		// Alternatives.
+		{"/{users,groups}/*/name", "/users/0/name", true},
+		{"/{users,groups}/*/name", "/groups/1/name", true},
+		{"/{users,groups}/*/name", "/roles/1/name", false},
+		{"/a/{b,c{1,2}}", "/a/c2", true},
+		{"/a/{b,c{1,2}}", "/a/c", false},
+		{"/a/{}", "/a/", true},
+		{"/a/{b", "/a/{b", true},
+		{"/a/\\{b,c}", "/a/{b,c}", true},
+		// Any number of segments.
+		{"/a/**/name", "/a/name", true},
+		{"/a/**/name", "/a/b/c/name", true},
+		{"/a/*/name", "/a/name", false},
+		{"/a/**", "/a", true},
+		{"/a/**", "/ab", false},
+		{"**/name", "name", true},
+		{"**/name", "x/name", true},
+		{"/a/**/b/**/c", "/a/b/c", true},
+		{"!/{a,b}/**", "/b/x", false},
+		{"!/{a,b}/**", "/c/x", true},
+	}
+	for _, test := range tests {
+		assert.Logf("pattern %q path %q", test.pattern, test.path)
+		assert.Equal(dynaj.MatchPattern(test.pattern, test.path), test.matches)
+	}
+}
+
+// TestQueryPatterns tests the extended pattern syntax in queries.
+func TestQueryPatterns(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"users": [{"name": "x", "id": 1}, {"name": "y", "id": 2}],
+		"groups": [{"name": "g", "members": [{"name": "x"}]}],
+		"internal": {"name": "i"},
+		"name": "n"
+	}`)
+
+	nodes, err := doc.Root().Query("/{users,groups}/*/name")
+	assert.NoError(err)
+	assert.Equal(nodePaths(nodes), []string{"/groups/0/members/0/name", "/groups/0/name", "/users/0/name", "/users/1/name"})
+
+	nodes, err = doc.Root().Query("/**/name")
+	assert.NoError(err)
+	assert.Length(nodes, 6)
+	nodes, err = doc.Root().Query("/*/name")
+	assert.NoError(err)
+	assert.Length(nodes, 5)
+
+	nodes, err = doc.Root().Query("!/{users,groups}/**")
+	assert.NoError(err)
+	assert.Equal(nodePaths(nodes), []string{"/internal/name", "/name"})
+
+	// Queries on nodes match the relative paths.
+	nodes, err = doc.NodeAt("/groups").Query("**/name")
+	assert.NoError(err)
+	assert.Equal(nodePaths(nodes), []string{"/groups/0/members/0/name", "/groups/0/name"})
+
+	// Omit and Count use the same syntax.
+	assert.Equal(doc.Omit("/{users,groups}/**/name").String(), `{"groups":[{"members":[{}]}],"internal":{"name":"i"},"name":"n","users":[{"id":1},{"id":2}]}`)
+	assert.Equal(doc.Omit("!/internal").String(), `{"internal":{"name":"i"}}`)
+	assert.Equal(doc.Omit("!/users/1/name").String(), `{"users":[{"name":"y"}]}`)
+	count, err := doc.Count("/users/**/id")
+	assert.NoError(err)
+	assert.Equal(count, 2)
+}
+
+// EOF
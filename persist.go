@@ -0,0 +1,144 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+//--------------------
+// CHANGE NOTIFICATION
+//--------------------
+
+// OnChange registers the function to be called with a snapshot of the
+// document after it has been changed. All changes within the delay
+// after the first one are coalesced into one call with the latest
+// snapshot, calls are done one after another in an own goroutine.
+// Each change takes a snapshot, so the following mutation copies the
+// containers on its path. The returned function stops the notification
+// and calls the function for a still pending change.
+func (d *Document) OnChange(delay time.Duration, fn func(snapshot *Document)) (stop func()) {
+	h := &changeHook{
+		delay: delay,
+		fn:    fn,
+	}
+	d.mu.Lock()
+	d.hooks = append(d.hooks, h)
+	d.mu.Unlock()
+	return func() {
+		d.mu.Lock()
+		for i, hook := range d.hooks {
+			if hook == h {
+				d.hooks = append(d.hooks[:i:i], d.hooks[i+1:]...)
+				break
+			}
+		}
+		d.mu.Unlock()
+		h.stop()
+	}
+}
+
+// Persist writes the document to the writer after it has been changed,
+// e.g. for keeping a state file of a long-running service. Changes are
+// coalesced like for OnChange, each state is written as one line of
+// JSON. The returned function stops persisting, writes a still pending
+// state, and returns the first error.
+func (d *Document) Persist(w io.Writer, every time.Duration) (stop func() error) {
+	var mu sync.Mutex
+	var werr error
+	stopChange := d.OnChange(every, func(snapshot *Document) {
+		data, err := snapshot.MarshalJSON()
+		if err == nil {
+			_, err = w.Write(append(data, '\n'))
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if werr == nil {
+			werr = err
+		}
+	})
+	return func() error {
+		stopChange()
+		mu.Lock()
+		defer mu.Unlock()
+		return werr
+	}
+}
+
+// notifyChange passes a snapshot to the registered change functions.
+// It is called with the locked document.
+func (d *Document) notifyChange() {
+	if len(d.hooks) == 0 {
+		return
+	}
+	snapshot := d.Snapshot()
+	for _, h := range d.hooks {
+		h.notify(snapshot)
+	}
+}
+
+//--------------------
+// CHANGE HOOK
+//--------------------
+
+// changeHook coalesces the changes of a document for one function.
+type changeHook struct {
+	mu      sync.Mutex
+	calling sync.Mutex
+	delay   time.Duration
+	fn      func(snapshot *Document)
+	timer   *time.Timer
+	pending *Document
+	stopped bool
+}
+
+// notify stores the snapshot and starts the timer if not yet running.
+func (h *changeHook) notify(snapshot *Document) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.stopped {
+		return
+	}
+	h.pending = snapshot
+	if h.timer == nil {
+		h.timer = time.AfterFunc(h.delay, h.fire)
+	}
+}
+
+// fire calls the function with the pending snapshot.
+func (h *changeHook) fire() {
+	h.calling.Lock()
+	defer h.calling.Unlock()
+	h.mu.Lock()
+	snapshot := h.pending
+	h.pending = nil
+	h.timer = nil
+	h.mu.Unlock()
+	if snapshot != nil {
+		h.fn(snapshot)
+	}
+}
+
+// stop stops the timer and calls the function for a pending snapshot.
+func (h *changeHook) stop() {
+	h.mu.Lock()
+	h.stopped = true
+	if h.timer != nil {
+		h.timer.Stop()
+	}
+	h.mu.Unlock()
+	h.fire()
+}
+
+// EOF
@@ -0,0 +1,86 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestOnChange tests the coalesced notification about changes.
+func TestOnChange(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"n":0}`)
+	snapshots := make(chan *dynaj.Document, 10)
+	stop := doc.OnChange(20*time.Millisecond, func(snapshot *dynaj.Document) {
+		snapshots <- snapshot
+	})
+
+	// Changes within the delay are coalesced.
+	for i := 1; i <= 5; i++ {
+		assert.NoError(doc.SetValueAt("/n", i))
+	}
+	snapshot := <-snapshots
+	assert.Equal(snapshot.String(), `{"n":5}`)
+	assert.True(snapshot.IsFrozen())
+
+	// Stopping calls for pending changes.
+	assert.NoError(doc.SetValueAt("/n", 6))
+	stop()
+	assert.Equal((<-snapshots).String(), `{"n":6}`)
+
+	// No more notifications after stopping.
+	assert.NoError(doc.SetValueAt("/n", 7))
+	time.Sleep(40 * time.Millisecond)
+	assert.Length(snapshots, 0)
+}
+
+// TestPersist tests writing the changed states.
+func TestPersist(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"state":"init"}`)
+	var buf bytes.Buffer
+	stop := doc.Persist(&buf, time.Hour)
+
+	assert.NoError(doc.SetValueAt("/state", "running"))
+	assert.NoError(doc.SetValueAt("/count", 1))
+	assert.NoError(stop())
+	assert.Equal(buf.String(), "{\"count\":1,\"state\":\"running\"}\n")
+
+	// Write errors are returned when stopping.
+	stop = doc.Persist(failingWriter{}, time.Millisecond)
+	assert.NoError(doc.SetValueAt("/state", "stopped"))
+	assert.ErrorMatch(stop(), "cannot write")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// failingWriter fails writing.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("cannot write")
+}
+
+// EOF
@@ -0,0 +1,160 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"fmt"
+)
+
+//--------------------
+// PIPELINE
+//--------------------
+
+// Stage processes a document and returns the resulting document. This
+// may be the changed passed one or a new one.
+type Stage func(doc *Document) (*Document, error)
+
+// StageError is returned when a stage of a pipeline fails.
+type StageError struct {
+	Stage string
+	Index int
+	Err   error
+}
+
+// Error implements error.
+func (e *StageError) Error() string {
+	return fmt.Sprintf("stage %d %q failed: %v", e.Index, e.Stage, e.Err)
+}
+
+// Unwrap returns the error of the stage.
+func (e *StageError) Unwrap() error {
+	return e.Err
+}
+
+// namedStage is a stage with its name for error reporting.
+type namedStage struct {
+	name  string
+	stage Stage
+}
+
+// Pipeline runs documents through a sequence of stages, e.g. for
+// validating, redacting, and transforming them in ETL jobs.
+type Pipeline struct {
+	stages []namedStage
+}
+
+// NewPipeline creates a pipeline with the given stages named by
+// their position.
+func NewPipeline(stages ...Stage) *Pipeline {
+	p := &Pipeline{}
+	for _, stage := range stages {
+		p.Add(fmt.Sprintf("stage-%d", len(p.stages)), stage)
+	}
+	return p
+}
+
+// Add appends a named stage and returns the pipeline for chaining.
+func (p *Pipeline) Add(name string, stage Stage) *Pipeline {
+	p.stages = append(p.stages, namedStage{name, stage})
+	return p
+}
+
+// Run passes the document through all stages. The context is checked
+// before each stage, so long running pipelines can be cancelled. The
+// first failing stage stops the pipeline and is reported as StageError.
+func (p *Pipeline) Run(ctx context.Context, doc *Document) (*Document, error) {
+	for idx, ns := range p.stages {
+		if err := ctx.Err(); err != nil {
+			return nil, &StageError{ns.name, idx, err}
+		}
+		out, err := ns.stage(doc)
+		if err != nil {
+			return nil, &StageError{ns.name, idx, err}
+		}
+		if out == nil {
+			return nil, &StageError{ns.name, idx, fmt.Errorf("no document returned")}
+		}
+		doc = out
+	}
+	return doc, nil
+}
+
+//--------------------
+// STAGES
+//--------------------
+
+// VerifyStage checks the invariants of the document with Verify.
+func VerifyStage() Stage {
+	return func(doc *Document) (*Document, error) {
+		return doc, Verify(doc)
+	}
+}
+
+// AssertStage checks the expected values of the document with AssertAll.
+func AssertStage(expectations map[Path]Value) Stage {
+	return func(doc *Document) (*Document, error) {
+		return doc, doc.AssertAll(expectations)
+	}
+}
+
+// RedactStage replaces all values with paths matching one of the
+// patterns by the replacement.
+func RedactStage(replacement Value, patterns ...string) Stage {
+	return func(doc *Document) (*Document, error) {
+		for _, pattern := range patterns {
+			_, err := doc.UpdateWhere(pattern, nil, func(node *Node) (Value, error) {
+				return replacement, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+		}
+		return doc, nil
+	}
+}
+
+// OmitStage returns a copy of the document without the elements with
+// paths matching one of the patterns.
+func OmitStage(patterns ...string) Stage {
+	return func(doc *Document) (*Document, error) {
+		return doc.Omit(patterns...), nil
+	}
+}
+
+// ProjectStage returns a copy of the document containing only the
+// elements at the given paths.
+func ProjectStage(paths ...Path) Stage {
+	return func(doc *Document) (*Document, error) {
+		return doc.Project(paths...)
+	}
+}
+
+// DefaultsStage returns the document with the missing elements taken
+// from the defaults.
+func DefaultsStage(defaults *Document) Stage {
+	return func(doc *Document) (*Document, error) {
+		return doc.WithDefaults(defaults), nil
+	}
+}
+
+// TransformStage changes the document in place with the function.
+func TransformStage(transform func(doc *Document) error) Stage {
+	return func(doc *Document) (*Document, error) {
+		if err := transform(doc); err != nil {
+			return nil, err
+		}
+		return doc, nil
+	}
+}
+
+// EOF
@@ -0,0 +1,93 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestPipeline tests running documents through pipelines.
+func TestPipeline(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	defaults := mustUnmarshal(assert, `{"region":"eu","user":{"role":"guest"}}`)
+	pipeline := dynaj.NewPipeline(dynaj.VerifyStage()).
+		Add("defaults", dynaj.DefaultsStage(defaults)).
+		Add("redact", dynaj.RedactStage("***", "/user/password", "/user/tokens/*")).
+		Add("omit", dynaj.OmitStage("/internal*")).
+		Add("check", dynaj.AssertStage(map[dynaj.Path]dynaj.Value{"/region": "eu"})).
+		Add("stamp", dynaj.TransformStage(func(doc *dynaj.Document) error {
+			return doc.SetValueAt("/processed", true)
+		}))
+
+	doc := mustUnmarshal(assert, `{
+		"user": {"name": "jane", "password": "secret", "tokens": ["a", "b"]},
+		"internal": {"id": 1}
+	}`)
+	out, err := pipeline.Run(context.Background(), doc)
+	assert.NoError(err)
+	assert.Equal(out.String(), `{"processed":true,"region":"eu","user":{"name":"jane","password":"***","role":"guest","tokens":["***","***"]}}`)
+
+	// Projection.
+	out, err = dynaj.NewPipeline(dynaj.ProjectStage("/user/name")).Run(context.Background(), out)
+	assert.NoError(err)
+	assert.Equal(out.String(), `{"user":{"name":"jane"}}`)
+}
+
+// TestPipelineErrors tests the error reporting of pipelines.
+func TestPipelineErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"region":"us"}`)
+	failure := errors.New("failure")
+	called := false
+
+	pipeline := dynaj.NewPipeline().
+		Add("check", dynaj.AssertStage(map[dynaj.Path]dynaj.Value{"/region": "eu"})).
+		Add("never", dynaj.TransformStage(func(doc *dynaj.Document) error {
+			called = true
+			return nil
+		}))
+	_, err := pipeline.Run(context.Background(), doc)
+	assert.ErrorContains(err, `stage 0 "check" failed: assertion failed`)
+	assert.True(errors.Is(err, dynaj.ErrAssertion))
+	var stageErr *dynaj.StageError
+	assert.True(errors.As(err, &stageErr))
+	assert.Equal(stageErr.Stage, "check")
+	assert.False(called)
+
+	_, err = dynaj.NewPipeline(dynaj.TransformStage(func(doc *dynaj.Document) error {
+		return failure
+	})).Run(context.Background(), doc)
+	assert.ErrorContains(err, `stage 0 "stage-0" failed: failure`)
+	assert.True(errors.Is(err, failure))
+
+	_, err = dynaj.NewPipeline(func(doc *dynaj.Document) (*dynaj.Document, error) {
+		return nil, nil
+	}).Run(context.Background(), doc)
+	assert.ErrorContains(err, "no document returned")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = dynaj.NewPipeline(dynaj.VerifyStage()).Run(ctx, doc)
+	assert.True(errors.Is(err, context.Canceled))
+}
+
+// EOF
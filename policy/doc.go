@@ -0,0 +1,27 @@
+// Tideland Go Dynamic JSON - Policy
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+// Package policy enforces rules on documents. The rules are declared
+// as data, so they can be maintained centrally and loaded from JSON.
+//
+//	{"rules": [
+//		{"name": "integer-amounts", "pattern": "/amounts/*",
+//		 "when": {"type": "float"}, "action": "reject",
+//		 "message": "amounts must be integers"},
+//		{"name": "strip-debug", "pattern": "/debug", "action": "rewrite",
+//		 "delete": true},
+//		{"name": "legacy", "pattern": "/v1/*", "action": "annotate",
+//		 "message": "deprecated field"}
+//	]}
+//
+// Each rule selects the elements whose absolute paths match the pattern
+// and fulfill the optional predicate. Rejections fail the enforcement,
+// rewrites set a new value or delete the elements, and annotations are
+// only reported.
+package policy // import "tideland.dev/go/dynaj/policy"
+
+// EOF
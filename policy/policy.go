@@ -0,0 +1,371 @@
+// Tideland Go Dynamic JSON - Policy
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package policy // import "tideland.dev/go/dynaj/policy"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// ERRORS
+//--------------------
+
+// ErrRejected is returned when a document is rejected by a rule.
+var ErrRejected = errors.New("document rejected by policy")
+
+//--------------------
+// RULES
+//--------------------
+
+// Action defines what happens with the elements selected by a rule.
+type Action string
+
+// Actions of the rules.
+const (
+	// Reject fails the enforcement.
+	Reject Action = "reject"
+
+	// Rewrite sets a new value or deletes the elements.
+	Rewrite Action = "rewrite"
+
+	// Annotate only reports the elements.
+	Annotate Action = "annotate"
+)
+
+// Predicate selects elements. All given conditions have to be fulfilled.
+type Predicate struct {
+	// Type is one of "null", "bool", "string", "number", "integer",
+	// "float", "object", or "array". Integers and floats are numbers.
+	Type string
+
+	// Equals compares the element with the value.
+	Equals dynaj.Value
+
+	// HasEquals signals that Equals has to be compared, as it may be nil.
+	HasEquals bool
+
+	// Matches is a regular expression the string value has to match.
+	Matches string
+}
+
+// Rule selects elements by pattern and predicate and defines the action.
+type Rule struct {
+	Name    string
+	Pattern string
+	When    *Predicate
+	Action  Action
+	Message string
+
+	// Value is set by rewrites not deleting the elements.
+	Value dynaj.Value
+
+	// Delete lets rewrites delete the elements.
+	Delete bool
+}
+
+//--------------------
+// POLICY
+//--------------------
+
+// Policy contains the ordered rules.
+type Policy struct {
+	rules    []Rule
+	patterns []*regexp.Regexp
+}
+
+// New creates a policy with the rules.
+func New(rules ...Rule) (*Policy, error) {
+	p := &Policy{}
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = "rule-" + strconv.Itoa(i)
+		}
+		switch rule.Action {
+		case Reject, Rewrite, Annotate:
+		default:
+			return nil, fmt.Errorf("invalid action %q of rule %q", rule.Action, rule.Name)
+		}
+		if rule.Pattern == "" {
+			return nil, fmt.Errorf("missing pattern of rule %q", rule.Name)
+		}
+		var re *regexp.Regexp
+		if rule.When != nil && rule.When.Matches != "" {
+			var err error
+			re, err = regexp.Compile(rule.When.Matches)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expression of rule %q: %v", rule.Name, err)
+			}
+		}
+		p.rules = append(p.rules, rule)
+		p.patterns = append(p.patterns, re)
+	}
+	return p, nil
+}
+
+// Load creates a policy with the rules declared in the document. It
+// contains an array "rules" of objects with the fields "name", "pattern",
+// "when", "action", "message", "value", and "delete". The predicate
+// "when" has the fields "type", "equals", and "matches".
+func Load(doc *dynaj.Document) (*Policy, error) {
+	rules := []Rule{}
+	count := doc.Length("/rules")
+	if count < 0 || !doc.NodeAt("/rules").IsArray() {
+		return nil, fmt.Errorf("cannot load policy: missing rules")
+	}
+	for i := 0; i < count; i++ {
+		node := doc.NodeAt(dynaj.JoinPath("/rules", strconv.Itoa(i)))
+		rule := Rule{
+			Name:    node.NodeAt("name").AsString(""),
+			Pattern: node.NodeAt("pattern").AsString(""),
+			Action:  Action(node.NodeAt("action").AsString("")),
+			Message: node.NodeAt("message").AsString(""),
+			Delete:  node.NodeAt("delete").AsBool(false),
+		}
+		if value := node.NodeAt("value"); !value.IsError() {
+			v, err := plain(value)
+			if err != nil {
+				return nil, fmt.Errorf("cannot load policy: %v", err)
+			}
+			rule.Value = v
+		}
+		if when := node.NodeAt("when"); when.IsObject() {
+			rule.When = &Predicate{
+				Type:    when.NodeAt("type").AsString(""),
+				Matches: when.NodeAt("matches").AsString(""),
+			}
+			if equals := when.NodeAt("equals"); !equals.IsError() {
+				v, err := plain(equals)
+				if err != nil {
+					return nil, fmt.Errorf("cannot load policy: %v", err)
+				}
+				rule.When.Equals = v
+				rule.When.HasEquals = true
+			}
+		}
+		rules = append(rules, rule)
+	}
+	p, err := New(rules...)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load policy: %v", err)
+	}
+	return p, nil
+}
+
+//--------------------
+// ENFORCEMENT
+//--------------------
+
+// Finding describes an element selected by a rule.
+type Finding struct {
+	Rule    string
+	Path    dynaj.Path
+	Action  Action
+	Message string
+}
+
+// String implements fmt.Stringer.
+func (f Finding) String() string {
+	if f.Message == "" {
+		return fmt.Sprintf("%s %s (%s)", f.Action, f.Path, f.Rule)
+	}
+	return fmt.Sprintf("%s %s (%s): %s", f.Action, f.Path, f.Rule, f.Message)
+}
+
+// Report contains the findings of an enforcement in the order of the
+// rules and paths.
+type Report struct {
+	Findings []Finding
+}
+
+// Rejections returns the findings of rejecting rules.
+func (r *Report) Rejections() []Finding {
+	return r.filter(Reject)
+}
+
+// Rewrites returns the findings of rewriting rules.
+func (r *Report) Rewrites() []Finding {
+	return r.filter(Rewrite)
+}
+
+// Annotations returns the findings of annotating rules.
+func (r *Report) Annotations() []Finding {
+	return r.filter(Annotate)
+}
+
+// filter returns the findings with the action.
+func (r *Report) filter(action Action) []Finding {
+	findings := []Finding{}
+	for _, f := range r.Findings {
+		if f.Action == action {
+			findings = append(findings, f)
+		}
+	}
+	return findings
+}
+
+// Enforce applies the rules in their order to the document. Rewrites
+// are seen by the following rules. If any rule rejects the document an
+// error wrapping ErrRejected is returned and the document is unchanged.
+func (p *Policy) Enforce(doc *dynaj.Document) (*Report, error) {
+	data, err := doc.MarshalJSON()
+	if err != nil {
+		return nil, fmt.Errorf("cannot enforce policy: %v", err)
+	}
+	work, err := dynaj.Unmarshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot enforce policy: %v", err)
+	}
+	report := &Report{}
+	changes := []func(doc *dynaj.Document) error{}
+	for i, rule := range p.rules {
+		paths, err := work.ExpandPattern(rule.Pattern)
+		if err != nil {
+			return nil, fmt.Errorf("cannot enforce rule %q: %v", rule.Name, err)
+		}
+		selected := []dynaj.Path{}
+		for _, path := range paths {
+			node := work.NodeAt(path)
+			if node.IsError() || !p.fulfills(i, node) {
+				continue
+			}
+			selected = append(selected, path)
+			report.Findings = append(report.Findings, Finding{rule.Name, path, rule.Action, rule.Message})
+		}
+		if rule.Action != Rewrite {
+			continue
+		}
+		change := rewrite(rule, selected)
+		if err := change(work); err != nil {
+			return nil, fmt.Errorf("cannot enforce rule %q: %v", rule.Name, err)
+		}
+		changes = append(changes, change)
+	}
+	if rejections := report.Rejections(); len(rejections) > 0 {
+		return report, fmt.Errorf("%w: %v", ErrRejected, rejections[0])
+	}
+	for _, change := range changes {
+		if err := change(doc); err != nil {
+			return nil, fmt.Errorf("cannot enforce policy: %v", err)
+		}
+	}
+	return report, nil
+}
+
+// fulfills checks the predicate of the rule with the index.
+func (p *Policy) fulfills(i int, node *dynaj.Node) bool {
+	pred := p.rules[i].When
+	if pred == nil {
+		return true
+	}
+	value, err := plain(node)
+	if err != nil {
+		return false
+	}
+	if pred.Type != "" && !hasType(value, pred.Type) {
+		return false
+	}
+	if pred.HasEquals {
+		expected, err := plain(pred.Equals)
+		if err != nil || !reflect.DeepEqual(value, expected) {
+			return false
+		}
+	}
+	if re := p.patterns[i]; re != nil {
+		s, ok := value.(string)
+		if !ok || !re.MatchString(s) {
+			return false
+		}
+	}
+	return true
+}
+
+// rewrite returns the change of the rewriting rule for the paths. The
+// paths are changed from the last to the first, so deletions in arrays
+// do not shift the following paths.
+func rewrite(rule Rule, paths []dynaj.Path) func(doc *dynaj.Document) error {
+	return func(doc *dynaj.Document) error {
+		for i := len(paths) - 1; i >= 0; i-- {
+			path := paths[i]
+			node := doc.NodeAt(path)
+			if node.IsError() {
+				// Already deleted with an ancestor.
+				continue
+			}
+			if rule.Delete {
+				if err := doc.DeleteElementAt(path); err != nil {
+					return err
+				}
+				continue
+			}
+			if node.IsObject() || node.IsArray() {
+				// Containers cannot be overwritten directly.
+				if err := doc.DeleteElementAt(path); err != nil {
+					return err
+				}
+			}
+			if err := doc.SetValueAt(path, rule.Value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// plain returns the value in its plain JSON form, so numbers are
+// float64 and objects are maps.
+func plain(value dynaj.Value) (dynaj.Value, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var out dynaj.Value
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// hasType checks the JSON type of the plain value.
+func hasType(value dynaj.Value, t string) bool {
+	switch typed := value.(type) {
+	case nil:
+		return t == "null"
+	case bool:
+		return t == "bool"
+	case string:
+		return t == "string"
+	case float64:
+		switch t {
+		case "number":
+			return true
+		case "integer":
+			return typed == math.Trunc(typed)
+		case "float":
+			return typed != math.Trunc(typed)
+		}
+	case map[string]any:
+		return t == "object"
+	case []any:
+		return t == "array"
+	}
+	return false
+}
+
+// EOF
@@ -0,0 +1,144 @@
+// Tideland Go Dynamic JSON - Policy - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package policy_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+	"tideland.dev/go/dynaj/policy"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestLoadAndEnforce tests enforcing rules declared in JSON.
+func TestLoadAndEnforce(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	p := mustLoad(assert, `{"rules": [
+		{"name": "strip-debug", "pattern": "/debug", "action": "rewrite", "delete": true},
+		{"name": "mask", "pattern": "/user/password", "action": "rewrite", "value": "***"},
+		{"name": "legacy", "pattern": "/v1/*", "action": "annotate", "message": "deprecated"},
+		{"name": "test-env", "pattern": "/env", "when": {"equals": "test"}, "action": "annotate"}
+	]}`)
+	doc := mustUnmarshal(assert, `{
+		"debug": {"trace": [1, 2, 3]},
+		"user": {"name": "alice", "password": "secret"},
+		"v1": {"a": 1, "b": 2},
+		"env": "production"
+	}`)
+
+	report, err := p.Enforce(doc)
+	assert.NoError(err)
+	assert.Length(report.Rewrites(), 2)
+	assert.Length(report.Annotations(), 2)
+	assert.Equal(report.Annotations()[0].Path, "/v1/a")
+	assert.Equal(report.Annotations()[0].String(), "annotate /v1/a (legacy): deprecated")
+	assert.True(doc.NodeAt("/debug").IsError())
+	assert.Equal(doc.NodeAt("/user/password").AsString(""), "***")
+	assert.Equal(doc.NodeAt("/user/name").AsString(""), "alice")
+	assert.Equal(doc.NodeAt("/v1/b").AsInt(0), 2)
+}
+
+// TestReject tests rejecting documents without changing them.
+func TestReject(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	p := mustLoad(assert, `{"rules": [
+		{"name": "strip-debug", "pattern": "/debug", "action": "rewrite", "delete": true},
+		{"name": "integer-amounts", "pattern": "/amounts/*", "when": {"type": "float"},
+		 "action": "reject", "message": "amounts must be integers"}
+	]}`)
+
+	doc := mustUnmarshal(assert, `{"debug": true, "amounts": [1, 2.5, 3, 4.25]}`)
+	report, err := p.Enforce(doc)
+	assert.True(errors.Is(err, policy.ErrRejected))
+	assert.ErrorContains(err, "/amounts/1")
+	assert.Length(report.Rejections(), 2)
+	assert.Equal(report.Rejections()[1].Path, "/amounts/3")
+	assert.True(doc.NodeAt("/debug").AsBool(false))
+
+	doc = mustUnmarshal(assert, `{"debug": true, "amounts": [1, 2, 3]}`)
+	report, err = p.Enforce(doc)
+	assert.NoError(err)
+	assert.Length(report.Rejections(), 0)
+	assert.True(doc.NodeAt("/debug").IsError())
+}
+
+// TestPredicates tests the conditions of the predicates.
+func TestPredicates(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": "abc", "b": 1, "c": 1.5, "d": true, "e": null, "f": [], "g": {}}`)
+	tests := []struct {
+		when  policy.Predicate
+		paths []string
+	}{
+		{policy.Predicate{Type: "string"}, []string{"/a"}},
+		{policy.Predicate{Type: "number"}, []string{"/b", "/c"}},
+		{policy.Predicate{Type: "integer"}, []string{"/b"}},
+		{policy.Predicate{Type: "bool"}, []string{"/d"}},
+		{policy.Predicate{Type: "null"}, []string{"/e"}},
+		{policy.Predicate{Type: "array"}, []string{"/f"}},
+		{policy.Predicate{Type: "object"}, []string{"/", "/g"}},
+		{policy.Predicate{Matches: "^a.c$"}, []string{"/a"}},
+		{policy.Predicate{Equals: 1, HasEquals: true}, []string{"/b"}},
+		{policy.Predicate{Equals: nil, HasEquals: true}, []string{"/e"}},
+	}
+	for _, test := range tests {
+		when := test.when
+		p, err := policy.New(policy.Rule{Pattern: "*", When: &when, Action: policy.Annotate})
+		assert.NoError(err)
+		report, err := p.Enforce(doc)
+		assert.NoError(err)
+		paths := []string{}
+		for _, f := range report.Findings {
+			paths = append(paths, f.Path)
+		}
+		assert.Equal(paths, test.paths)
+	}
+}
+
+// TestInvalidRules tests loading invalid rules.
+func TestInvalidRules(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	_, err := policy.Load(mustUnmarshal(assert, `{"rules": {}}`))
+	assert.ErrorContains(err, "missing rules")
+	_, err = policy.Load(mustUnmarshal(assert, `{"rules": [{"pattern": "/a", "action": "drop"}]}`))
+	assert.ErrorContains(err, `invalid action "drop" of rule "rule-0"`)
+	_, err = policy.Load(mustUnmarshal(assert, `{"rules": [{"name": "x", "action": "reject"}]}`))
+	assert.ErrorContains(err, `missing pattern of rule "x"`)
+	_, err = policy.New(policy.Rule{Pattern: "*", Action: policy.Reject, When: &policy.Predicate{Matches: "("}})
+	assert.ErrorContains(err, "invalid expression")
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// mustLoad loads a policy from JSON.
+func mustLoad(assert *asserts.Asserts, data string) *policy.Policy {
+	p, err := policy.Load(mustUnmarshal(assert, data))
+	assert.NoError(err)
+	return p
+}
+
+// mustUnmarshal unmarshals a document.
+func mustUnmarshal(assert *asserts.Asserts, data string) *dynaj.Document {
+	doc, err := dynaj.Unmarshal([]byte(data))
+	assert.NoError(err)
+	return doc
+}
+
+// EOF
@@ -0,0 +1,165 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+//--------------------
+// POOL
+//--------------------
+
+// Pool reuses documents and their internal objects and arrays for
+// transient documents, e.g. those parsed and dropped per request. This
+// reduces the pressure on the garbage collector. Documents returned by
+// Put must not be used anymore, also not via nodes or views. Values set
+// as objects or arrays are returned to the pool too, so they must not
+// be used outside of the document.
+type Pool struct {
+	docs    sync.Pool
+	objects sync.Pool
+	arrays  sync.Pool
+}
+
+// NewPool creates a new pool.
+func NewPool() *Pool {
+	return &Pool{}
+}
+
+// Get returns an empty document.
+func (p *Pool) Get() *Document {
+	if doc, ok := p.docs.Get().(*Document); ok {
+		return doc
+	}
+	return NewDocument()
+}
+
+// Unmarshal parses the JSON-encoded data into a document using the
+// pooled objects and arrays.
+func (p *Pool) Unmarshal(data []byte) (*Document, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	root, err := p.decodeElement(dec)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal document: %v", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		p.release(root)
+		return nil, fmt.Errorf("cannot unmarshal document: invalid data after top-level value")
+	}
+	doc := p.Get()
+	doc.root = root
+	return doc, nil
+}
+
+// Put returns the document and its objects and arrays to the pool.
+// Frozen documents may be shared and are not returned.
+func (p *Pool) Put(doc *Document) {
+	if doc == nil || doc.frozen {
+		return
+	}
+	// Subtrees shared by deduplication or snapshots cannot be released.
+	if !doc.shared {
+		p.release(doc.root)
+	}
+	doc.reset()
+	p.docs.Put(doc)
+}
+
+// decodeElement recursively decodes the next element.
+func (p *Pool) decodeElement(dec *json.Decoder) (Element, error) {
+	token, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	switch token {
+	case json.Delim('{'):
+		obj := p.getObject()
+		for dec.More() {
+			keyToken, err := dec.Token()
+			if err != nil {
+				p.release(obj)
+				return nil, err
+			}
+			element, err := p.decodeElement(dec)
+			if err != nil {
+				p.release(obj)
+				return nil, err
+			}
+			obj[keyToken.(string)] = element
+		}
+		if _, err := dec.Token(); err != nil {
+			p.release(obj)
+			return nil, err
+		}
+		return obj, nil
+	case json.Delim('['):
+		arr := p.getArray()
+		for dec.More() {
+			element, err := p.decodeElement(dec)
+			if err != nil {
+				p.release(arr)
+				return nil, err
+			}
+			arr = append(arr, element)
+		}
+		if _, err := dec.Token(); err != nil {
+			p.release(arr)
+			return nil, err
+		}
+		return arr, nil
+	default:
+		return token, nil
+	}
+}
+
+// getObject returns an empty pooled object.
+func (p *Pool) getObject() Object {
+	if obj, ok := p.objects.Get().(Object); ok {
+		return obj
+	}
+	return Object{}
+}
+
+// getArray returns an empty pooled array.
+func (p *Pool) getArray() Array {
+	if arr, ok := p.arrays.Get().(*Array); ok {
+		return *arr
+	}
+	return Array{}
+}
+
+// release recursively clears objects and arrays and puts them into
+// the pools.
+func (p *Pool) release(element Element) {
+	switch typed := element.(type) {
+	case Object:
+		for key, child := range typed {
+			p.release(child)
+			delete(typed, key)
+		}
+		p.objects.Put(typed)
+	case Array:
+		for idx, child := range typed {
+			p.release(child)
+			typed[idx] = nil
+		}
+		arr := typed[:0]
+		p.arrays.Put(&arr)
+	}
+}
+
+// EOF
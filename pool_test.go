@@ -0,0 +1,89 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestPool tests the pooling of documents.
+func TestPool(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	pool := dynaj.NewPool()
+
+	for i := 0; i < 10; i++ {
+		doc, err := pool.Unmarshal(bs)
+		assert.NoError(err)
+		assert.Equal(doc.String(), string(bs))
+		assert.Equal(doc.NodeAt("/B/1/S/2").AsString(""), "white")
+		assert.NoError(doc.SetValueAt("/B/1/S/3", "black"))
+		pool.Put(doc)
+
+		// Settings do not leak into reused documents.
+		doc = pool.Get()
+		assert.True(doc.Root().IsUndefined())
+		assert.False(doc.IsStrict())
+		assert.False(doc.TypesLocked())
+		assert.Length(doc.Operations(), 0)
+		assert.NoError(doc.SetValueAt("/a", i))
+		doc.SetStrict(true)
+		doc.LockTypes()
+		doc.RecordOperations()
+		pool.Put(doc)
+	}
+
+	// Special values and errors.
+	doc, err := pool.Unmarshal([]byte(`[{},[],null,true,"x",1.5]`))
+	assert.NoError(err)
+	assert.Equal(doc.String(), `[{},[],null,true,"x",1.5]`)
+	pool.Put(doc)
+	pool.Put(nil)
+
+	_, err = pool.Unmarshal([]byte(`{"a":[1,2}`))
+	assert.ErrorContains(err, "cannot unmarshal document")
+	_, err = pool.Unmarshal([]byte(`{"a":1} {}`))
+	assert.ErrorContains(err, "invalid data after top-level value")
+	_, err = pool.Unmarshal([]byte(``))
+	assert.ErrorContains(err, "cannot unmarshal document")
+}
+
+// BenchmarkPool compares pooled and unpooled parsing.
+func BenchmarkPool(b *testing.B) {
+	assert := asserts.NewTesting(b, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	pool := dynaj.NewPool()
+
+	b.Run("pooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			doc, _ := pool.Unmarshal(bs)
+			pool.Put(doc)
+		}
+	})
+	b.Run("unpooled", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			_, _ = dynaj.Unmarshal(bs)
+		}
+	})
+}
+
+// EOF
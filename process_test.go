@@ -53,6 +53,30 @@ func TestProcess(t *testing.T) {
 	}
 	err = doc.Root().Process(processor)
 	assert.ErrorContains(err, "ouch")
+
+	// Verify the error contains only the path of the failing node.
+	ouch := errors.New("ouch")
+	processor = func(node *dynaj.Node) error {
+		if node.Path() == "/B/1/S/2" {
+			return ouch
+		}
+		return nil
+	}
+	err = doc.Root().Process(processor)
+	assert.Equal(err.Error(), `cannot process "/B/1/S/2": ouch`)
+	var perr *dynaj.ProcessError
+	assert.True(errors.As(err, &perr))
+	assert.Equal(perr.Path, "/B/1/S/2")
+	assert.True(errors.Is(err, ouch))
+
+	// Also for empty objects and arrays.
+	doc = mustUnmarshal(assert, `{"a":{"b":[]}}`)
+	err = doc.Root().Process(processor)
+	assert.NoError(err)
+	err = doc.Root().Process(func(node *dynaj.Node) error {
+		return ouch
+	})
+	assert.Equal(err.Error(), `cannot process "/a/b": ouch`)
 }
 
 // TestValueAtProcess tests the processing of documents starting at a
@@ -141,6 +165,9 @@ func TestRange(t *testing.T) {
 	// Verify range of mixed types.
 	err = doc.NodeAt("/B/0").Range(processor)
 	assert.ErrorContains(err, "is object or array")
+	var perr *dynaj.ProcessError
+	assert.True(errors.As(err, &perr))
+	assert.True(perr.Path == "/B/0/D" || perr.Path == "/B/0/S")
 
 	// Verify procesing error.
 	processor = func(node *dynaj.Node) error {
@@ -189,6 +216,39 @@ func TestRootQuery(t *testing.T) {
 	assert.Equal(nodes[0].AsString(""), "Level One")
 }
 
+// TestQuerySorting tests the order of query results and sorting nodes.
+func TestQuerySorting(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"c": 3, "a": {"z": 1, "y": 2},
+		"b": [10, 9, 8, 7, 6, 5, 4, 3, 2, 1, 0]
+	}`)
+
+	// Query results are sorted by path with numerical indices.
+	for i := 0; i < 5; i++ {
+		nodes, err := doc.Root().Query("*")
+		assert.NoError(err)
+		assert.Equal(nodePaths(nodes), []string{
+			"/a/y", "/a/z",
+			"/b/0", "/b/1", "/b/2", "/b/3", "/b/4", "/b/5",
+			"/b/6", "/b/7", "/b/8", "/b/9", "/b/10",
+			"/c",
+		})
+	}
+
+	// Sorting by value keeps the order of equal values.
+	nodes, err := doc.Root().Query("*")
+	assert.NoError(err)
+	nodes.SortByValue(func(a, b *dynaj.Node) bool {
+		return a.AsInt(0) < b.AsInt(0)
+	})
+	assert.Equal(nodePaths(nodes)[:5], []string{"/b/10", "/a/z", "/b/9", "/a/y", "/b/8"})
+
+	// And sorting by path again.
+	nodes.SortByPath()
+	assert.Equal(nodePaths(nodes)[:3], []string{"/a/y", "/a/z", "/b/0"})
+}
+
 // TestValueAtQuery tests querying a document starting at a deeper node.
 func TestValueAtQuery(t *testing.T) {
 	assert := asserts.NewTesting(t, asserts.FailStop)
@@ -224,4 +284,121 @@ func TestValueAtQuery(t *testing.T) {
 	assert.Length(nodes, 0)
 }
 
+// TestQuerySetValue tests changing the values of query results.
+func TestQuerySetValue(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc, err := dynaj.Unmarshal([]byte(`{"a":{"timeout":10},"b":[{"timeout":20},{"timeout":30}],"c":{"d":1}}`))
+	assert.NoError(err)
+
+	// Double all timeouts.
+	nodes, err := doc.Root().Query("*/timeout")
+	assert.NoError(err)
+	assert.Length(nodes, 3)
+	for _, node := range nodes {
+		assert.NoError(node.SetValue(node.AsInt(0) * 2))
+		assert.Equal(node.AsInt(0)%20, 0)
+	}
+	assert.Equal(doc.String(), `{"a":{"timeout":20},"b":[{"timeout":40},{"timeout":60}],"c":{"d":1}}`)
+
+	// Nodes of deeper nodes and processing.
+	err = doc.NodeAt("/b").Process(func(node *dynaj.Node) error {
+		return node.SetValue(node.AsInt(0) + 1)
+	})
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/b/1/timeout").AsInt(0), 61)
+	assert.NoError(doc.NodeAt("/c").NodeAt("d").SetValue("x"))
+	assert.Equal(doc.NodeAt("/c/d").AsString(""), "x")
+
+	// Nodes of views.
+	view, err := doc.ViewAt("/b/0")
+	assert.NoError(err)
+	assert.NoError(view.NodeAt("timeout").SetValue(0))
+	assert.Equal(doc.NodeAt("/b/0/timeout").AsInt(-1), 0)
+
+	// Provoke errors.
+	assert.ErrorContains(doc.NodeAt("/c").SetValue(1), "cannot insert value")
+	assert.Equal(doc.Freeze().NodeAt("/a/timeout").SetValue(1), dynaj.ErrFrozen)
+	unbound := doc.NodeAt("/a/timeout").NodeAt("/x")
+	assert.ErrorContains(unbound.SetValue(1), "node is not bound to a document")
+}
+
+// TestNodeMetadata tests the information about the position of nodes.
+func TestNodeMetadata(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": {"b": [10, 20]}, "7": true}`)
+
+	root := doc.Root()
+	assert.True(root.IsRoot())
+	assert.Equal(root.Depth(), 0)
+	assert.Equal(root.Key(), "")
+	_, ok := root.Index()
+	assert.False(ok)
+
+	found := map[string]string{}
+	err := doc.Root().Process(func(node *dynaj.Node) error {
+		idx, ok := node.Index()
+		found[node.Path()] = fmt.Sprintf("%d %s %d %v %v", node.Depth(), node.Key(), idx, ok, node.IsRoot())
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(found, map[string]string{
+		"/a/b/0": "3 0 0 true false",
+		"/a/b/1": "3 1 1 true false",
+		"/7":     "1 7 7 true false",
+	})
+
+	node := doc.NodeAt("/a").NodeAt("b")
+	assert.Equal(node.Depth(), 2)
+	assert.Equal(node.Key(), "b")
+	assert.False(node.IsRoot())
+}
+
+// TestSortedKeys tests the traversal in sorted order.
+func TestSortedKeys(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte(`{"d": 1, "b": {"z": 1, "y": 2, "x": [3, 4]}, "a": 5, "c": 6, "e": 7, "f": 8}`)
+	expected := []string{"/a", "/b/x/0", "/b/x/1", "/b/y", "/b/z", "/c", "/d", "/e", "/f"}
+
+	doc, err := dynaj.Unmarshal(data, dynaj.WithSortedKeys())
+	assert.NoError(err)
+	for i := 0; i < 10; i++ {
+		paths := []string{}
+		err = doc.Root().Process(func(node *dynaj.Node) error {
+			paths = append(paths, node.Path())
+			return nil
+		})
+		assert.NoError(err)
+		assert.Equal(paths, expected)
+
+		nodes, err := doc.Root().Query("/b/*")
+		assert.NoError(err)
+		assert.Length(nodes, 4)
+		assert.Equal(nodes[0].Path(), "/b/x/0")
+		assert.Equal(nodes[3].Path(), "/b/z")
+	}
+
+	doc = mustUnmarshal(assert, `{"d": 1, "b": 2, "a": 3, "c": 4, "e": 5, "f": 6}`)
+	doc.SetSortedKeys(true)
+	keys := []string{}
+	err = doc.Root().Range(func(node *dynaj.Node) error {
+		keys = append(keys, node.Key())
+		return nil
+	})
+	assert.NoError(err)
+	assert.Equal(keys, []string{"a", "b", "c", "d", "e", "f"})
+}
+
+//--------------------
+// HELPERS
+//--------------------
+
+// nodePaths returns the paths of the nodes.
+func nodePaths(nodes dynaj.Nodes) []string {
+	paths := make([]string, len(nodes))
+	for i, node := range nodes {
+		paths[i] = node.Path()
+	}
+	return paths
+}
+
 // EOF
@@ -0,0 +1,111 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+)
+
+//--------------------
+// PROJECTION
+//--------------------
+
+// Project returns a new document only containing copies of the elements
+// at the given paths. Objects and arrays on the way to them are kept
+// with the same types, so array elements keep their indices and
+// missing ones before are filled with null.
+func (d *Document) Project(paths ...Path) (*Document, error) {
+	var root Element
+	for _, path := range paths {
+		keys := splitPath(path)
+		if _, err := elementAt(d.root, keys); err != nil {
+			return nil, fmt.Errorf("cannot project path %q: %v", path, err)
+		}
+		root = projectElement(root, d.root, keys)
+	}
+	return d.derive(root), nil
+}
+
+// projectElement copies the source element at the end of the keys
+// into the destination element. The path must exist in the source.
+func projectElement(dst, src Element, keys Keys) Element {
+	if len(keys) == 0 {
+		return copyElement(src)
+	}
+	h, t := headTail(keys)
+	switch typed := decodedElement(src).(type) {
+	case Object:
+		obj, ok := dst.(Object)
+		if !ok {
+			obj = Object{}
+		}
+		obj[h] = projectElement(obj[h], typed[h], t)
+		return obj
+	case Array:
+		index, _ := asIndex(h)
+		arr, ok := dst.(Array)
+		if !ok {
+			arr = Array{}
+		}
+		if index >= len(arr) {
+			tmp := make(Array, index+1)
+			copy(tmp, arr)
+			arr = tmp
+		}
+		arr[index] = projectElement(arr[index], typed[index], t)
+		return arr
+	default:
+		return copyElement(src)
+	}
+}
+
+// Omit returns a copy of the document without all elements whose paths
+// match one of the patterns. Patterns are the same as for queries and
+// matched against the absolute paths, so "/debug" as well as "*/password"
+// are possible. Removed array elements shift the following ones.
+func (d *Document) Omit(patterns ...string) *Document {
+	root, _ := omitElement(d.root, Separator, patterns)
+	return d.derive(root)
+}
+
+// omitElement recursively copies the element without the matching
+// paths. It returns false if the element itself is omitted.
+func omitElement(element Element, path Path, patterns []string) (Element, bool) {
+	for _, pattern := range patterns {
+		if MatchPattern(pattern, path) {
+			return nil, false
+		}
+	}
+	switch typed := decodedElement(element).(type) {
+	case Object:
+		obj := make(Object, len(typed))
+		for key, child := range typed {
+			if kept, ok := omitElement(child, appendKey(path, key), patterns); ok {
+				obj[key] = kept
+			}
+		}
+		return obj, true
+	case Array:
+		arr := make(Array, 0, len(typed))
+		for idx, child := range typed {
+			if kept, ok := omitElement(child, appendKey(path, strconv.Itoa(idx)), patterns); ok {
+				arr = append(arr, kept)
+			}
+		}
+		return arr, true
+	default:
+		return typed, true
+	}
+}
+
+// EOF
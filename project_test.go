@@ -0,0 +1,86 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestProject tests the projection of documents.
+func TestProject(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	in := []byte(`{"user":{"name":"foo","age":42,"tags":["a","b","c"]},"1":{"x":true},"debug":{"trace":[1,2]}}`)
+	doc, err := dynaj.Unmarshal(in)
+	assert.NoError(err)
+
+	pdoc, err := doc.Project("/user/name", "user/tags/1", "/1/x")
+	assert.NoError(err)
+	assert.Equal(pdoc.String(), `{"1":{"x":true},"user":{"name":"foo","tags":[null,"b"]}}`)
+
+	// Ancestors and descendants.
+	pdoc, err = doc.Project("/user/tags/0", "/user", "/user/name")
+	assert.NoError(err)
+	assert.Equal(pdoc.String(), `{"user":{"age":42,"name":"foo","tags":["a","b","c"]}}`)
+
+	// Root and nothing.
+	pdoc, err = doc.Project("/")
+	assert.NoError(err)
+	assert.Equal(pdoc.String(), doc.String())
+	pdoc, err = doc.Project()
+	assert.NoError(err)
+	assert.Equal(pdoc.String(), "null")
+
+	// Projection is a copy.
+	pdoc, err = doc.Project("/user")
+	assert.NoError(err)
+	assert.NoError(pdoc.SetValueAt("/user/name", "bar"))
+	assert.Equal(doc.NodeAt("/user/name").AsString(""), "foo")
+
+	// Invalid paths.
+	_, err = doc.Project("/user/name", "/user/email")
+	assert.ErrorContains(err, `cannot project path "/user/email"`)
+}
+
+// TestOmit tests the omitting of paths.
+func TestOmit(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	in := []byte(`{"user":{"name":"foo","password":"secret","keys":[{"id":1,"password":"x"},{"id":2}]},"debug":{"trace":[1,2]}}`)
+	doc, err := dynaj.Unmarshal(in)
+	assert.NoError(err)
+
+	odoc := doc.Omit("/debug", "*/password")
+	assert.Equal(odoc.String(), `{"user":{"keys":[{"id":1},{"id":2}],"name":"foo"}}`)
+
+	odoc = doc.Omit("/user/keys/0", "/debug/trace/*")
+	assert.Equal(odoc.String(), `{"debug":{"trace":[]},"user":{"keys":[{"id":2}],"name":"foo","password":"secret"}}`)
+
+	odoc = doc.Omit()
+	assert.Equal(odoc.String(), doc.String())
+	odoc = doc.Omit("*")
+	assert.Equal(odoc.String(), "null")
+
+	// Omitting is done on a copy.
+	odoc = doc.Omit("/debug")
+	assert.NoError(odoc.SetValueAt("/user/name", "bar"))
+	assert.Equal(doc.NodeAt("/user/name").AsString(""), "foo")
+	assert.Equal(doc.NodeAt("/debug/trace/1").AsInt(0), 2)
+}
+
+// EOF
@@ -0,0 +1,103 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//--------------------
+// RAW JSON
+//--------------------
+
+// RawAt returns the JSON encoding of the element at the given path.
+// Fragments set with SetRawAt are returned as they are, without
+// decoding and encoding them again.
+func (d *Document) RawAt(path Path) (json.RawMessage, error) {
+	element, err := elementAt(d.root, splitPath(path))
+	if err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", path, err)
+	}
+	if raw, ok := element.(json.RawMessage); ok {
+		return raw, nil
+	}
+	element, err = normalizeValue(element, path, d.nonFinite)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal element at %q: %v", path, err)
+	}
+	return json.Marshal(element)
+}
+
+// SetRawAt splices the pre-encoded JSON fragment in at the given path.
+// It is stored as it is and only decoded when elements inside of it
+// are accessed. It is first changed into elements when changing
+// elements inside of it. This way large untouched subtrees can be
+// passed through efficiently.
+func (d *Document) SetRawAt(path Path, raw json.RawMessage) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	if !json.Valid(raw) {
+		return fmt.Errorf("cannot set raw JSON at %q: invalid JSON", path)
+	}
+	fragment := make(json.RawMessage, len(raw))
+	copy(fragment, raw)
+	if err := d.checkAccess(splitPath(path)); err != nil {
+		return err
+	}
+	if err := d.checkTypeLock(splitPath(path), fragment); err != nil {
+		return err
+	}
+	keys := splitPath(path)
+	old := d.currentValue(keys)
+	if err := d.callHooks(BeforeSet, pathify(keys), old, fragment); err != nil {
+		return err
+	}
+	if err := d.insertAt(keys, fragment); err != nil {
+		return err
+	}
+	d.record(SetOperation, path, fragment)
+	return d.callHooks(AfterSet, pathify(keys), old, fragment)
+}
+
+// decodeRaw decodes a raw JSON fragment into elements and expands
+// interned records into objects. All other elements are returned
+// unchanged.
+func decodeRaw(element Element) (Element, error) {
+	if r, ok := element.(*records); ok {
+		return r.expand(), nil
+	}
+	raw, ok := element.(json.RawMessage)
+	if !ok {
+		return element, nil
+	}
+	var decoded Element
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return nil, fmt.Errorf("invalid raw JSON: %v", err)
+	}
+	return decoded, nil
+}
+
+// decodedElement returns the element with a raw JSON fragment decoded
+// and interned records expanded, so tree walkers see their content.
+// Fragments are validated when they are set, so if decoding fails
+// nevertheless the element is returned unchanged.
+func decodedElement(element Element) Element {
+	decoded, err := decodeRaw(element)
+	if err != nil {
+		return element
+	}
+	return decoded
+}
+
+// EOF
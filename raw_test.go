@@ -0,0 +1,124 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestRaw tests accessing and splicing raw JSON fragments.
+func TestRaw(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+
+	// Encoding of existing elements.
+	raw, err := doc.RawAt("/B/1/S")
+	assert.NoError(err)
+	assert.Equal(string(raw), `["orange","blue","white"]`)
+	raw, err = doc.RawAt("/A")
+	assert.NoError(err)
+	assert.Equal(string(raw), `"Level One"`)
+	_, err = doc.RawAt("/X/Y")
+	assert.ErrorContains(err, `invalid path "/X/Y"`)
+
+	// Splicing fragments.
+	fragment := json.RawMessage(`{"b": [1, 2, {"c": "d"}]}`)
+	assert.NoError(doc.SetRawAt("/raw", fragment))
+	fragment[0] = 'x'
+	raw, err = doc.RawAt("/raw")
+	assert.NoError(err)
+	assert.Equal(string(raw), `{"b": [1, 2, {"c": "d"}]}`)
+	assert.Equal(doc.NodeAt("/raw/b/2/c").AsString(""), "d")
+	assert.Equal(doc.Length("/raw/b"), 3)
+	assert.Substring(`"raw":{"b":[1,2,{"c":"d"}]}`, doc.String())
+	buf, err := doc.AppendJSON(nil)
+	assert.NoError(err)
+	assert.Equal(string(buf), doc.String())
+	assert.NoError(dynaj.Verify(doc))
+	assert.ErrorContains(doc.SetRawAt("/raw", json.RawMessage(`{"b":`)), "invalid JSON")
+
+	// Comparison with decoded elements.
+	other, err := dynaj.Unmarshal([]byte(doc.String()))
+	assert.NoError(err)
+	diff, err := dynaj.CompareDocuments(doc, other)
+	assert.NoError(err)
+	assert.Length(diff.Differences(), 0)
+
+	// Changing inside of fragments decodes them.
+	assert.NoError(doc.SetValueAt("/raw/b/2/c", "e"))
+	assert.Equal(doc.NodeAt("/raw/b/2/c").AsString(""), "e")
+	raw, err = doc.RawAt("/raw")
+	assert.NoError(err)
+	assert.Equal(string(raw), `{"b":[1,2,{"c":"e"}]}`)
+	assert.NoError(doc.SetRawAt("/raw2", json.RawMessage(`[1,2,3]`)))
+	assert.NoError(doc.DeleteValueAt("/raw2/1"))
+	assert.Equal(doc.NodeAt("/raw2").String(), "[1 3]")
+
+	// Frozen documents.
+	frozen := doc.Freeze()
+	assert.Equal(frozen.SetRawAt("/raw", json.RawMessage(`1`)), dynaj.ErrFrozen)
+}
+
+// TestRawWalkers tests that walking the document sees the content of
+// raw JSON fragments.
+func TestRawWalkers(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := dynaj.NewDocument()
+	assert.NoError(doc.SetValueAt("/id", 1))
+	assert.NoError(doc.SetRawAt("/user", json.RawMessage(`{"name":"joe","password":"secret","tags":["a","b","c"]}`)))
+
+	// Nodes and lengths.
+	user := doc.NodeAt("/user")
+	assert.True(user.IsObject())
+	assert.False(user.IsValue())
+	assert.Equal(user.Keys(), dynaj.Keys{"name", "password", "tags"})
+	assert.Equal(doc.Length("/user"), 3)
+	assert.Equal(user.NodeAt("/name").AsString(""), "joe")
+	assert.True(doc.Root().NodeAt("/user").IsObject())
+
+	// Omitting and projecting.
+	assert.Equal(doc.Omit("*/password").String(), `{"id":1,"user":{"name":"joe","tags":["a","b","c"]}}`)
+	projected, err := doc.Project("/user/name")
+	assert.NoError(err)
+	assert.Equal(projected.String(), `{"user":{"name":"joe"}}`)
+
+	// Truncating.
+	truncated := doc.Truncate(dynaj.TruncateOptions{MaxArrayLength: 1})
+	assert.Equal(truncated.NodeAt("/user/tags/1").AsString(""), "…(+2 items)")
+
+	// Layering.
+	upper := dynaj.NewDocument()
+	assert.NoError(upper.SetRawAt("/user", json.RawMessage(`{"name":"jane"}`)))
+	layered := dynaj.Layer(doc, upper)
+	assert.Equal(layered.NodeAt("/user/name").AsString(""), "jane")
+	assert.Equal(layered.NodeAt("/user/password").AsString(""), "secret")
+
+	// Rendering.
+	tree := doc.TreeString()
+	assert.Substring("user (object[3])", tree)
+	assert.Substring("name: \"joe\" (string)", tree)
+	assert.False(strings.Contains(tree, "RawMessage"))
+}
+
+// EOF
@@ -0,0 +1,60 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"unsafe"
+)
+
+//--------------------
+// READ-ONLY DOCUMENTS
+//--------------------
+
+// UnmarshalReadOnly parses the JSON-encoded data into a frozen document.
+// Strings without escapes and non-ASCII characters, including object
+// keys, reference the data instead of being copied. This drastically
+// reduces the allocations when documents are only parsed to be read,
+// e.g. from memory-mapped files. The data must not be changed or
+// unmapped as long as the document or any value read from it is in
+// use. The decoder option is ignored, all other options apply. Data
+// needing transcoding into UTF-8 is copied once.
+func UnmarshalReadOnly(data []byte, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+	o.decoder = viewDecoder{}
+	root, err := o.decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal document: %v", err)
+	}
+	doc := o.newDocument(root)
+	doc.frozen = true
+	return doc, nil
+}
+
+// viewDecoder is the scan decoder in view mode.
+type viewDecoder struct{}
+
+// Decode implements Decoder.
+func (viewDecoder) Decode(data []byte) (Element, error) {
+	s := &scanner{data: data, view: true}
+	return s.decode()
+}
+
+// viewString returns a string sharing the memory of the bytes.
+func viewString(b []byte) string {
+	if len(b) == 0 {
+		return ""
+	}
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// EOF
@@ -0,0 +1,53 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestUnmarshalReadOnly tests parsing read-only documents referencing
+// the input data.
+func TestUnmarshalReadOnly(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte(`{"name":"alpha","escaped":"a\"b","umlaut":"über","n":[1,2.5,true,null]}`)
+
+	doc, err := dynaj.UnmarshalReadOnly(data, dynaj.WithIntegers())
+	assert.NoError(err)
+	assert.True(doc.IsFrozen())
+	assert.Equal(doc.NodeAt("/name").AsString("-"), "alpha")
+	assert.Equal(doc.NodeAt("/escaped").AsString("-"), `a"b`)
+	assert.Equal(doc.NodeAt("/umlaut").AsString("-"), "über")
+	assert.Equal(doc.NodeAt("/n/0").AsInt(0), 1)
+	assert.Equal(doc.NodeAt("/n/1").AsFloat64(0), 2.5)
+	assert.True(errors.Is(doc.SetValueAt("/name", "beta"), dynaj.ErrFrozen))
+
+	// Plain strings reference the data, escaped ones are copied.
+	copy(data[9:], "omega")
+	assert.Equal(doc.NodeAt("/name").AsString("-"), "omega")
+	assert.Equal(doc.NodeAt("/escaped").AsString("-"), `a"b`)
+
+	// Invalid data.
+	_, err = dynaj.UnmarshalReadOnly([]byte(`{"a":`))
+	assert.ErrorMatch(err, "cannot unmarshal document: .*unexpected end.*")
+}
+
+// EOF
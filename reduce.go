@@ -0,0 +1,46 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// REDUCTION
+//--------------------
+
+// Reducer combines the accumulated value with the node and returns the
+// new accumulated value.
+type Reducer func(acc any, node *Node) (any, error)
+
+// Reduce folds the nodes with paths matching the pattern into one value,
+// starting with init. Aggregations like sums or counts need no external
+// variables captured by a processor this way. Like for ExpandPattern
+// objects and arrays are matched too and the nodes are reduced in the
+// order of their paths. Errors of the reducer stop the reduction.
+func (d *Document) Reduce(pattern string, init any, fn Reducer) (any, error) {
+	paths, err := d.ExpandPattern(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("cannot reduce: %v", err)
+	}
+	acc := init
+	for _, path := range paths {
+		acc, err = fn(acc, d.NodeAt(path))
+		if err != nil {
+			return nil, fmt.Errorf("cannot reduce at %q: %w", path, err)
+		}
+	}
+	return acc, nil
+}
+
+// EOF
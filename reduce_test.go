@@ -0,0 +1,63 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestReduce tests folding matching nodes into one value.
+func TestReduce(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"items":[{"name":"a","price":10},{"name":"b","price":5.5},{"name":"c","price":2}]}`)
+
+	sum, err := doc.Reduce("/items/*/price", 0.0, func(acc any, node *dynaj.Node) (any, error) {
+		return acc.(float64) + node.AsFloat64(0), nil
+	})
+	assert.NoError(err)
+	assert.Equal(sum, 17.5)
+
+	names, err := doc.Reduce("/items/*/name", "", func(acc any, node *dynaj.Node) (any, error) {
+		return acc.(string) + node.AsString("-"), nil
+	})
+	assert.NoError(err)
+	assert.Equal(names, "abc")
+
+	// Without matches the initial value is returned.
+	count, err := doc.Reduce("/missing/*", 0, func(acc any, node *dynaj.Node) (any, error) {
+		return acc.(int) + 1, nil
+	})
+	assert.NoError(err)
+	assert.Equal(count, 0)
+
+	// Errors stop the reduction.
+	failure := errors.New("failure")
+	_, err = doc.Reduce("/items/*/price", 0.0, func(acc any, node *dynaj.Node) (any, error) {
+		if node.AsFloat64(0) < 6 {
+			return nil, failure
+		}
+		return acc, nil
+	})
+	assert.True(errors.Is(err, failure))
+	assert.ErrorMatch(err, `cannot reduce at "/items/1/price": failure`)
+}
+
+// EOF
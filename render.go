@@ -0,0 +1,322 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+const (
+	// previewLength is the maximum length of value previews.
+	previewLength = 40
+
+	treeBranch = "├── "
+	treeLast   = "└── "
+	treeIndent = "│   "
+	treeSpace  = "    "
+)
+
+//--------------------
+// TREE RENDERING
+//--------------------
+
+// TreeString returns the document as ASCII tree with the keys, the
+// types, and a preview of the values, like the tree command does it
+// for directories.
+func (d *Document) TreeString() string {
+	var b strings.Builder
+	root := decodedElement(d.root)
+	b.WriteString(Separator)
+	writeTreeLabel(&b, root)
+	writeTreeChildren(&b, "", root)
+	return b.String()
+}
+
+// TreeString returns the union of both documents as ASCII tree. Added
+// branches are marked with "+", removed ones with "-", and changed ones
+// with "~".
+func (d *Diff) TreeString() string {
+	changed := map[Path]struct{}{}
+	for _, path := range d.paths {
+		keys := splitPath(path)
+		for i := 0; i <= len(keys); i++ {
+			changed[pathify(keys[:i])] = struct{}{}
+		}
+	}
+	var b strings.Builder
+	writeDiffTree(&b, "", "", Separator, Separator, d.first.root, d.second.root, true, true, changed)
+	return b.String()
+}
+
+// writeTreeLabel writes the type and the preview of an element.
+func writeTreeLabel(b *strings.Builder, element Element) {
+	switch typed := element.(type) {
+	case Object:
+		fmt.Fprintf(b, " (object[%d])\n", len(typed))
+	case Array:
+		fmt.Fprintf(b, " (array[%d])\n", len(typed))
+	case *records:
+		fmt.Fprintf(b, " (array[%d])\n", typed.len())
+	default:
+		fmt.Fprintf(b, ": %s (%s)\n", preview(typed), typeName(typed))
+	}
+}
+
+// writeTreeChildren writes the children of an object or array.
+func writeTreeChildren(b *strings.Builder, prefix string, element Element) {
+	keys := childKeys(element)
+	for i, key := range keys {
+		child, _ := childElement(element, key)
+		child = decodedElement(child)
+		connector, indent := treeConnector(i == len(keys)-1)
+		b.WriteString(prefix + connector + key)
+		writeTreeLabel(b, child)
+		writeTreeChildren(b, prefix+indent, child)
+	}
+}
+
+// writeDiffTree writes one line of a difference tree and recursively
+// its children.
+func writeDiffTree(
+	b *strings.Builder,
+	prefix, connector, label string,
+	path Path,
+	fst, snd Element,
+	fstOK, sndOK bool,
+	changed map[Path]struct{},
+) {
+	fst, snd = decodedElement(fst), decodedElement(snd)
+	marker := "  "
+	_, isChanged := changed[path]
+	switch {
+	case fstOK && !sndOK:
+		marker = "- "
+	case !fstOK && sndOK:
+		marker = "+ "
+	case isChanged:
+		marker = "~ "
+	}
+	line := func(m string, element Element) {
+		b.WriteString(m + prefix + connector + label)
+		writeTreeLabel(b, element)
+	}
+	switch {
+	case fstOK && sndOK && typeName(fst) != typeName(snd):
+		// Type has changed, so show as removed and added.
+		line("- ", fst)
+		line("+ ", snd)
+		return
+	case fstOK && sndOK && !isObjectOrArray(fst):
+		if isChanged {
+			fmt.Fprintf(b, "%s%s%s%s: %s → %s (%s)\n", marker, prefix, connector, label,
+				preview(fst), preview(snd), typeName(snd))
+			return
+		}
+		line(marker, snd)
+		return
+	case fstOK && sndOK:
+		line(marker, snd)
+	case fstOK:
+		line(marker, fst)
+	default:
+		line(marker, snd)
+	}
+	// Recurse into the union of the children.
+	keys := unionKeys(fst, snd, fstOK, sndOK)
+	childPrefix := prefix
+	if connector != "" {
+		_, indent := treeConnector(connector == treeLast)
+		childPrefix += indent
+	}
+	for i, key := range keys {
+		fstChild, fstChildOK := childElementIf(fst, key, fstOK)
+		sndChild, sndChildOK := childElementIf(snd, key, sndOK)
+		childConnector, _ := treeConnector(i == len(keys)-1)
+		writeDiffTree(b, childPrefix, childConnector, key, appendKey(path, key),
+			fstChild, sndChild, fstChildOK, sndChildOK, changed)
+	}
+}
+
+// treeConnector returns the connector and the indent for the next
+// level depending on the position of an entry.
+func treeConnector(last bool) (string, string) {
+	if last {
+		return treeLast, treeSpace
+	}
+	return treeBranch, treeIndent
+}
+
+// unionKeys returns the sorted union of the child keys of two elements.
+func unionKeys(fst, snd Element, fstOK, sndOK bool) Keys {
+	set := map[Key]struct{}{}
+	if fstOK {
+		for _, key := range childKeys(fst) {
+			set[key] = struct{}{}
+		}
+	}
+	if sndOK {
+		for _, key := range childKeys(snd) {
+			set[key] = struct{}{}
+		}
+	}
+	keys := make(Keys, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sortKeys(keys)
+	return keys
+}
+
+// childElementIf returns the child element only if the parent exists.
+func childElementIf(element Element, key Key, ok bool) (Element, bool) {
+	if !ok {
+		return nil, false
+	}
+	return childElement(element, key)
+}
+
+// childKeys returns the sorted keys of an object or the indices of
+// an array. Other elements have no child keys.
+func childKeys(element Element) Keys {
+	switch typed := element.(type) {
+	case Object:
+		keys := make(Keys, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		return keys
+	case Array:
+		keys := make(Keys, len(typed))
+		for i := range typed {
+			keys[i] = strconv.Itoa(i)
+		}
+		return keys
+	case *records:
+		keys := make(Keys, typed.len())
+		for i := range keys {
+			keys[i] = strconv.Itoa(i)
+		}
+		return keys
+	default:
+		return nil
+	}
+}
+
+// childElement returns the direct child of an object or array.
+func childElement(element Element, key Key) (Element, bool) {
+	switch typed := element.(type) {
+	case Object:
+		child, ok := typed[key]
+		return child, ok
+	case Array:
+		index, ok := asIndex(key)
+		if !ok || index < 0 || index >= len(typed) {
+			return nil, false
+		}
+		return typed[index], true
+	case *records:
+		index, ok := asIndex(key)
+		if !ok || index < 0 || index >= typed.len() {
+			return nil, false
+		}
+		return typed.record(index), true
+	default:
+		return nil, false
+	}
+}
+
+// sortKeys sorts keys with numerical indices in numerical order and
+// all others alphabetically.
+func sortKeys(keys Keys) {
+	sort.Slice(keys, func(i, j int) bool {
+		ii, iok := asIndex(keys[i])
+		ji, jok := asIndex(keys[j])
+		switch {
+		case iok && jok:
+			return ii < ji
+		case iok != jok:
+			return iok
+		default:
+			return keys[i] < keys[j]
+		}
+	})
+}
+
+// typeName returns the JSON type name of an element.
+func typeName(element Element) string {
+	switch typed := element.(type) {
+	case nil:
+		return "null"
+	case Object:
+		return "object"
+	case Array, *records:
+		return "array"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return "number"
+	case json.RawMessage:
+		return rawTypeName(typed)
+	default:
+		return fmt.Sprintf("%T", element)
+	}
+}
+
+// rawTypeName returns the JSON type name of a raw JSON fragment by its
+// first byte, without decoding it.
+func rawTypeName(raw json.RawMessage) string {
+	trimmed := bytes.TrimLeft(raw, " \t\r\n")
+	if len(trimmed) == 0 {
+		return "null"
+	}
+	switch trimmed[0] {
+	case 'n':
+		return "null"
+	case '{':
+		return "object"
+	case '[':
+		return "array"
+	case '"':
+		return "string"
+	case 't', 'f':
+		return "bool"
+	default:
+		return "number"
+	}
+}
+
+// preview returns a shortened JSON representation of a value.
+func preview(value Value) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	runes := []rune(string(data))
+	if len(runes) > previewLength {
+		return string(runes[:previewLength-1]) + "…"
+	}
+	return string(runes)
+}
+
+// EOF
@@ -0,0 +1,75 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestDocumentTreeString tests the tree rendering of documents.
+func TestDocumentTreeString(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc, err := dynaj.Unmarshal([]byte(`{"b":[1,"two",{"x":true}],"a":"foo","c":null}`))
+	assert.NoError(err)
+
+	tree := doc.TreeString()
+	assert.Equal(tree, `/ (object[3])
+├── a: "foo" (string)
+├── b (array[3])
+│   ├── 0: 1 (number)
+│   ├── 1: "two" (string)
+│   └── 2 (object[1])
+│       └── x: true (bool)
+└── c: null (null)
+`)
+
+	// Single value and long value preview.
+	doc = dynaj.NewDocument()
+	err = doc.SetValueAt("/", "the quick brown fox jumps over the lazy dog")
+	assert.NoError(err)
+	tree = doc.TreeString()
+	assert.Equal(tree, "/: \"the quick brown fox jumps over the laz… (string)\n")
+}
+
+// TestDiffTreeString tests the tree rendering of differences.
+func TestDiffTreeString(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := []byte(`{"a":"foo","b":{"x":1,"y":2},"c":[1,2],"d":true}`)
+	second := []byte(`{"a":"foo","b":{"x":1,"y":3},"c":[1],"e":"new","d":{"z":1}}`)
+
+	diff, err := dynaj.Compare(first, second)
+	assert.NoError(err)
+
+	tree := diff.TreeString()
+	assert.Equal(tree, `~ / (object[5])
+  ├── a: "foo" (string)
+~ ├── b (object[2])
+  │   ├── x: 1 (number)
+~ │   └── y: 2 → 3 (number)
+~ ├── c (array[1])
+  │   ├── 0: 1 (number)
+- │   └── 1: 2 (number)
+- ├── d: true (bool)
++ ├── d (object[1])
++ └── e: "new" (string)
+`)
+}
+
+// EOF
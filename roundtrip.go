@@ -0,0 +1,182 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strconv"
+)
+
+//--------------------
+// ROUND TRIP
+//--------------------
+
+// RoundTripEqual unmarshals the input with the options, marshals the
+// document again, and compares both. It returns if they are equal and
+// the sorted paths of the differences. These are numbers losing their
+// precision, objects whose keys are reordered, duplicate keys dropped
+// by keeping only the last value, and all other changed values. So it
+// can be checked if data is safe to be handled by documents. Invalid
+// input returns the root path.
+func RoundTripEqual(input []byte, opts ...Option) (bool, []Path) {
+	in, err := scanLayout(input)
+	if err != nil {
+		return false, []Path{Separator}
+	}
+	doc, err := Unmarshal(input, opts...)
+	if err != nil {
+		return false, []Path{Separator}
+	}
+	output, err := doc.MarshalJSON()
+	if err != nil {
+		return false, []Path{Separator}
+	}
+	out, err := scanLayout(output)
+	if err != nil {
+		return false, []Path{Separator}
+	}
+	differences := map[Path]struct{}{}
+	for path := range in.duplicates {
+		differences[path] = struct{}{}
+	}
+	for path, keys := range in.keys {
+		if !equalKeyOrder(keys, out.keys[path]) {
+			differences[path] = struct{}{}
+		}
+	}
+	for path, value := range in.values {
+		if !equalLayoutValues(value, out.values[path]) {
+			differences[path] = struct{}{}
+		}
+	}
+	for path := range out.values {
+		if _, ok := in.values[path]; !ok {
+			differences[path] = struct{}{}
+		}
+	}
+	paths := make([]Path, 0, len(differences))
+	for path := range differences {
+		paths = append(paths, path)
+	}
+	sortPaths(paths)
+	return len(paths) == 0, paths
+}
+
+// layout contains the values of JSON data as written by their paths,
+// the order of the object keys, and the paths of duplicate keys.
+type layout struct {
+	values     map[Path]string
+	keys       map[Path]Keys
+	duplicates map[Path]struct{}
+}
+
+// scanLayout reads the layout of JSON data.
+func scanLayout(data []byte) (*layout, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	l := &layout{
+		values:     map[Path]string{},
+		keys:       map[Path]Keys{},
+		duplicates: map[Path]struct{}{},
+	}
+	if err := l.scan(dec, Separator); err != nil {
+		return nil, err
+	}
+	if _, err := dec.Token(); err == nil {
+		return nil, fmt.Errorf("invalid data after JSON value")
+	}
+	return l, nil
+}
+
+// scan recursively reads the value at the path. Values are stored
+// with a prefix of their kind, objects and arrays by their delimiters.
+func (l *layout) scan(dec *json.Decoder, path Path) error {
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	switch typed := token.(type) {
+	case json.Delim:
+		l.values[path] = typed.String()
+		if typed == '{' {
+			keys := Keys{}
+			seen := map[string]struct{}{}
+			for dec.More() {
+				token, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				key := token.(string)
+				if _, ok := seen[key]; ok {
+					l.duplicates[appendKey(path, key)] = struct{}{}
+				} else {
+					seen[key] = struct{}{}
+					keys = append(keys, key)
+				}
+				if err := l.scan(dec, appendKey(path, key)); err != nil {
+					return err
+				}
+			}
+			l.keys[path] = keys
+		} else {
+			for idx := 0; dec.More(); idx++ {
+				if err := l.scan(dec, appendKey(path, strconv.Itoa(idx))); err != nil {
+					return err
+				}
+			}
+		}
+		// Closing delimiter.
+		_, err = dec.Token()
+		return err
+	case json.Number:
+		l.values[path] = "#" + typed.String()
+	case string:
+		l.values[path] = "s" + typed
+	case bool:
+		l.values[path] = "b" + strconv.FormatBool(typed)
+	case nil:
+		l.values[path] = "null"
+	}
+	return nil
+}
+
+// equalKeyOrder compares the order of the keys.
+func equalKeyOrder(a, b Keys) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for idx := range a {
+		if a[idx] != b[idx] {
+			return false
+		}
+	}
+	return true
+}
+
+// equalLayoutValues compares two values of layouts. Numbers are
+// compared exactly by their values, not by their notation.
+func equalLayoutValues(a, b string) bool {
+	if a == b {
+		return true
+	}
+	if len(a) < 2 || len(b) < 2 || a[0] != '#' || b[0] != '#' {
+		return false
+	}
+	ra, oka := new(big.Rat).SetString(a[1:])
+	rb, okb := new(big.Rat).SetString(b[1:])
+	return oka && okb && ra.Cmp(rb) == 0
+}
+
+// EOF
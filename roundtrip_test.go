@@ -0,0 +1,76 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestRoundTripEqual tests detecting differences after unmarshalling
+// and marshalling data.
+func TestRoundTripEqual(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	tests := []struct {
+		name  string
+		input string
+		opts  []dynaj.Option
+		paths []dynaj.Path
+	}{
+		{
+			name:  "equal",
+			input: `{"a":[1,2.50,1e2,{"b":null}],"c":"ä","d":true}`,
+			paths: []dynaj.Path{},
+		}, {
+			name:  "reordered keys",
+			input: `{"b":1,"a":{"y":1,"x":2},"c":{"a":1,"b":2}}`,
+			paths: []dynaj.Path{"/", "/a"},
+		}, {
+			name:  "lost precision",
+			input: `{"a":12345678901234567890,"b":[0.1,9007199254740993]}`,
+			paths: []dynaj.Path{"/a", "/b/1"},
+		}, {
+			name:  "integers",
+			input: `{"a":9007199254740992,"b":1.5}`,
+			opts:  []dynaj.Option{dynaj.WithIntegers()},
+			paths: []dynaj.Path{},
+		}, {
+			name:  "rejected by options",
+			input: `{"a":[1]}`,
+			opts:  []dynaj.Option{dynaj.WithLimits(dynaj.Limits{MaxDepth: 1})},
+			paths: []dynaj.Path{"/"},
+		}, {
+			name:  "dropped duplicates",
+			input: `{"a":1,"b":{"x":1},"b":{"y":2}}`,
+			paths: []dynaj.Path{"/b", "/b/x"},
+		}, {
+			name:  "invalid",
+			input: `{"a":`,
+			paths: []dynaj.Path{"/"},
+		},
+	}
+	for _, test := range tests {
+		assert.Logf("test %q", test.name)
+		equal, paths := dynaj.RoundTripEqual([]byte(test.input), test.opts...)
+		assert.Equal(equal, len(test.paths) == 0)
+		assert.Equal(paths, test.paths)
+	}
+}
+
+// EOF
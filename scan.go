@@ -0,0 +1,62 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+)
+
+//--------------------
+// SCAN
+//--------------------
+
+// Scan traverses the document once and passes each node to all
+// handlers whose pattern matches its path. Like Process it visits the
+// values and the empty objects and arrays, the patterns are the same
+// as for queries on the root. Handlers of the same node are called in
+// the sorted order of their patterns. Scanning stops at the first
+// error of a handler. This way multiple patterns are processed much
+// faster than by querying each of them on large documents.
+func (d *Document) Scan(handlers map[string]Processor) error {
+	patterns := make([]string, 0, len(handlers))
+	for pattern := range handlers {
+		patterns = append(patterns, pattern)
+	}
+	sort.Strings(patterns)
+	compiled := make([]*pathPattern, len(patterns))
+	for idx, pattern := range patterns {
+		compiled[idx] = compilePattern(pattern)
+	}
+	results := make([]int, len(patterns))
+	err := d.Root().Process(func(node *Node) error {
+		for idx, pattern := range patterns {
+			if !compiled[idx].matches(node.path) {
+				continue
+			}
+			results[idx]++
+			if err := handlers[pattern](node); err != nil {
+				return fmt.Errorf("pattern %q: %w", pattern, err)
+			}
+		}
+		return nil
+	})
+	for idx, pattern := range patterns {
+		d.countQuery(pattern, results[idx])
+	}
+	if err != nil {
+		return fmt.Errorf("cannot scan document: %w", err)
+	}
+	return nil
+}
+
+// EOF
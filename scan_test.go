@@ -0,0 +1,92 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestScan tests dispatching nodes to multiple handlers in one pass.
+func TestScan(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"users": [{"name": "x", "age": 30}, {"name": "y", "age": 40}],
+		"tags": [],
+		"meta": {"name": "users"}
+	}`)
+
+	names := []string{}
+	ages := 0
+	all := 0
+	var calls []string
+	err := doc.Scan(map[string]dynaj.Processor{
+		"/users/*/name": func(node *dynaj.Node) error {
+			names = append(names, node.AsString("-"))
+			calls = append(calls, "users")
+			return nil
+		},
+		"*/name": func(node *dynaj.Node) error {
+			calls = append(calls, "name")
+			return nil
+		},
+		"/users/*/age": func(node *dynaj.Node) error {
+			ages += node.AsInt(0)
+			return nil
+		},
+		"*": func(node *dynaj.Node) error {
+			all++
+			return nil
+		},
+	})
+	assert.NoError(err)
+	assert.Length(names, 2)
+	assert.Equal(ages, 70)
+	assert.Equal(all, 6)
+
+	// Nodes are dispatched to all matching handlers sorted by pattern.
+	assert.Length(calls, 5)
+	for i := 0; i < len(calls); i++ {
+		if calls[i] == "users" {
+			assert.Equal(calls[i-1], "name")
+		}
+	}
+
+	// Results equal the ones of queries.
+	queried, err := doc.Root().Query("*/name")
+	assert.NoError(err)
+	assert.Length(queried, 3)
+
+	// The first error stops scanning.
+	visited := 0
+	err = doc.Scan(map[string]dynaj.Processor{
+		"/users/*": func(node *dynaj.Node) error {
+			visited++
+			return errors.New("ouch")
+		},
+	})
+	assert.ErrorMatch(err, `cannot scan document: .*pattern "/users/\*": ouch`)
+	assert.Equal(visited, 1)
+
+	// No handlers are fine.
+	assert.NoError(doc.Scan(nil))
+}
+
+// EOF
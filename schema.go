@@ -0,0 +1,220 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+//--------------------
+// CONSTANTS
+//--------------------
+
+// SchemaDialect is the JSON Schema dialect of inferred schemas.
+const SchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// maxEnumValues is the maximum number of distinct values listed as enum.
+const maxEnumValues = 8
+
+//--------------------
+// SCHEMA INFERENCE
+//--------------------
+
+// InferSchema infers a JSON Schema from the sample documents, e.g. for
+// documenting payloads of third-party APIs. It contains the observed
+// types, the keys of objects present in all samples as required, and
+// the types of array items. Integers and floats at the same position
+// are numbers. Strings are listed as enum if at least one value repeats
+// and there are no more than eight distinct values.
+func InferSchema(docs ...*Document) (*Document, error) {
+	if len(docs) == 0 {
+		return nil, errors.New("cannot infer schema: no documents")
+	}
+	root := &inferredSchema{}
+	for idx, doc := range docs {
+		if err := root.observe(doc.root); err != nil {
+			return nil, fmt.Errorf("cannot infer schema: document %d: %v", idx, err)
+		}
+	}
+	schema := root.schema()
+	schema["$schema"] = SchemaDialect
+	doc := NewDocument()
+	doc.root = schema
+	return doc, nil
+}
+
+// inferredSchema collects the observations at one position.
+type inferredSchema struct {
+	types      map[string]struct{}
+	objects    int
+	properties map[string]*inferredSchema
+	keyCounts  map[string]int
+	items      *inferredSchema
+	strings    map[string]int
+	overflow   bool
+}
+
+// observe adds the element to the observations.
+func (s *inferredSchema) observe(element Element) error {
+	element, err := decodeRaw(element)
+	if err != nil {
+		return err
+	}
+	if s.types == nil {
+		s.types = map[string]struct{}{}
+	}
+	switch typed := element.(type) {
+	case nil:
+		s.types["null"] = struct{}{}
+	case bool:
+		s.types["boolean"] = struct{}{}
+	case string:
+		s.types["string"] = struct{}{}
+		s.observeString(typed)
+	case int:
+		s.types["integer"] = struct{}{}
+	case float64:
+		if typed == math.Trunc(typed) {
+			s.types["integer"] = struct{}{}
+		} else {
+			s.types["number"] = struct{}{}
+		}
+	case Object:
+		s.types["object"] = struct{}{}
+		s.objects++
+		if s.properties == nil {
+			s.properties = map[string]*inferredSchema{}
+			s.keyCounts = map[string]int{}
+		}
+		for key, child := range typed {
+			property, ok := s.properties[key]
+			if !ok {
+				property = &inferredSchema{}
+				s.properties[key] = property
+			}
+			s.keyCounts[key]++
+			if err := property.observe(child); err != nil {
+				return err
+			}
+		}
+	case Array:
+		s.types["array"] = struct{}{}
+		if s.items == nil {
+			s.items = &inferredSchema{}
+		}
+		for _, child := range typed {
+			if err := s.items.observe(child); err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unsupported value of type %T", element)
+	}
+	return nil
+}
+
+// observeString counts the distinct strings as long as they may become
+// an enum.
+func (s *inferredSchema) observeString(str string) {
+	if s.overflow {
+		return
+	}
+	if s.strings == nil {
+		s.strings = map[string]int{}
+	}
+	s.strings[str]++
+	if len(s.strings) > maxEnumValues {
+		s.overflow = true
+		s.strings = nil
+	}
+}
+
+// schema returns the JSON Schema of the observations.
+func (s *inferredSchema) schema() Object {
+	schema := Object{}
+	if _, ok := s.types["number"]; ok {
+		delete(s.types, "integer")
+	}
+	types := make([]string, 0, len(s.types))
+	for t := range s.types {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	switch len(types) {
+	case 0:
+		// Only observed in empty arrays, anything is allowed.
+		return schema
+	case 1:
+		schema["type"] = types[0]
+	default:
+		arr := make(Array, len(types))
+		for i, t := range types {
+			arr[i] = t
+		}
+		schema["type"] = arr
+	}
+	if s.properties != nil {
+		properties := Object{}
+		required := Array{}
+		keys := make(Keys, 0, len(s.properties))
+		for key := range s.properties {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			properties[key] = s.properties[key].schema()
+			if s.keyCounts[key] == s.objects {
+				required = append(required, key)
+			}
+		}
+		schema["properties"] = properties
+		if len(required) > 0 {
+			schema["required"] = required
+		}
+	}
+	if s.items != nil {
+		schema["items"] = s.items.schema()
+	}
+	if enum := s.enum(); enum != nil && len(types) == 1 {
+		schema["enum"] = enum
+	}
+	return schema
+}
+
+// enum returns the sorted observed strings if they qualify as enum.
+func (s *inferredSchema) enum() Array {
+	if s.overflow || len(s.strings) == 0 {
+		return nil
+	}
+	repeated := false
+	values := make([]string, 0, len(s.strings))
+	for value, count := range s.strings {
+		if count > 1 {
+			repeated = true
+		}
+		values = append(values, value)
+	}
+	if !repeated {
+		return nil
+	}
+	sort.Strings(values)
+	enum := make(Array, len(values))
+	for i, value := range values {
+		enum[i] = value
+	}
+	return enum
+}
+
+// EOF
@@ -0,0 +1,59 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestInferSchema tests inferring a JSON Schema from samples.
+func TestInferSchema(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	first := mustUnmarshal(assert, `{"id":1,"status":"open","price":10,"tags":["a"],"owner":{"name":"jane"}}`)
+	second := mustUnmarshal(assert, `{"id":2,"status":"open","price":2.5,"tags":[],"note":null}`)
+	third := mustUnmarshal(assert, `{"id":3,"status":"closed","price":1,"tags":["b",1],"note":"x"}`)
+
+	schema, err := dynaj.InferSchema(first, second, third)
+	assert.NoError(err)
+	jsonAt := func(path dynaj.Path) string {
+		data, err := schema.MarshalJSONAt(path)
+		assert.NoError(err)
+		return string(data)
+	}
+	assert.Equal(schema.NodeAt("/$schema").AsString("-"), dynaj.SchemaDialect)
+	assert.Equal(schema.NodeAt("/type").AsString("-"), "object")
+	assert.Equal(jsonAt("/required"), `["id","price","status","tags"]`)
+	assert.Equal(jsonAt("/properties/id"), `{"type":"integer"}`)
+	assert.Equal(jsonAt("/properties/price"), `{"type":"number"}`)
+	assert.Equal(jsonAt("/properties/status"), `{"enum":["closed","open"],"type":"string"}`)
+	assert.Equal(jsonAt("/properties/note"), `{"type":["null","string"]}`)
+	assert.Equal(jsonAt("/properties/tags"), `{"items":{"type":["integer","string"]},"type":"array"}`)
+	assert.Equal(jsonAt("/properties/owner"), `{"properties":{"name":{"type":"string"}},"required":["name"],"type":"object"}`)
+
+	// Empty arrays allow any items.
+	schema, err = dynaj.InferSchema(mustUnmarshal(assert, `[]`))
+	assert.NoError(err)
+	assert.Equal(schema.String(), `{"$schema":"`+dynaj.SchemaDialect+`","items":{},"type":"array"}`)
+
+	_, err = dynaj.InferSchema()
+	assert.ErrorMatch(err, "cannot infer schema: no documents")
+}
+
+// EOF
@@ -0,0 +1,137 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+//--------------------
+// SHAPES
+//--------------------
+
+// AnyIndex replaces the indices of arrays in the paths of structures.
+const AnyIndex = "*"
+
+// Structure maps the paths of a document to the names of their types,
+// like "/items/*/price" to "number". Indices are replaced by AnyIndex,
+// so all elements of an array share their paths. Different types at
+// the same path are sorted and joined with "|".
+type Structure map[Path]string
+
+// Paths returns the sorted paths of the structure.
+func (s Structure) Paths() []Path {
+	paths := make([]Path, 0, len(s))
+	for path := range s {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// String implements fmt.Stringer. It returns the canonical signature
+// of the structure with one "path: type" line per path.
+func (s Structure) String() string {
+	var b strings.Builder
+	for _, path := range s.Paths() {
+		fmt.Fprintf(&b, "%s: %s\n", path, s[path])
+	}
+	return b.String()
+}
+
+// Shape returns the structure of the document ignoring its values.
+func Shape(doc *Document) Structure {
+	types := map[Path]map[string]struct{}{}
+	shapeElement(doc.root, Separator, types)
+	s := Structure{}
+	for path, names := range types {
+		joined := make([]string, 0, len(names))
+		for name := range names {
+			joined = append(joined, name)
+		}
+		sort.Strings(joined)
+		s[path] = strings.Join(joined, "|")
+	}
+	return s
+}
+
+// shapeElement recursively collects the types of the element and its
+// children.
+func shapeElement(element Element, path Path, types map[Path]map[string]struct{}) {
+	if decoded, err := decodeRaw(element); err == nil {
+		element = decoded
+	}
+	if types[path] == nil {
+		types[path] = map[string]struct{}{}
+	}
+	types[path][typeName(element)] = struct{}{}
+	switch typed := element.(type) {
+	case Object:
+		for key, child := range typed {
+			shapeElement(child, appendKey(path, key), types)
+		}
+	case Array:
+		for _, child := range typed {
+			shapeElement(child, appendKey(path, AnyIndex), types)
+		}
+	}
+}
+
+//--------------------
+// SHAPE COMPARISON
+//--------------------
+
+// ShapeDifference is a structural difference between two documents.
+// The type is empty if the path does not exist in the document.
+type ShapeDifference struct {
+	Path   Path
+	First  string
+	Second string
+}
+
+// String implements fmt.Stringer.
+func (d ShapeDifference) String() string {
+	switch {
+	case d.First == "":
+		return fmt.Sprintf("added %s (%s)", d.Path, d.Second)
+	case d.Second == "":
+		return fmt.Sprintf("removed %s (%s)", d.Path, d.First)
+	}
+	return fmt.Sprintf("changed %s (%s to %s)", d.Path, d.First, d.Second)
+}
+
+// CompareShapes compares the structures of the documents ignoring their
+// values, e.g. for detecting breaking changes between versions of API
+// payloads. The differences are sorted by path.
+func CompareShapes(first, second *Document) []ShapeDifference {
+	fs := Shape(first)
+	ss := Shape(second)
+	diffs := []ShapeDifference{}
+	for path, ft := range fs {
+		if st := ss[path]; st != ft {
+			diffs = append(diffs, ShapeDifference{path, ft, st})
+		}
+	}
+	for path, st := range ss {
+		if _, ok := fs[path]; !ok {
+			diffs = append(diffs, ShapeDifference{path, "", st})
+		}
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		return diffs[i].Path < diffs[j].Path
+	})
+	return diffs
+}
+
+// EOF
@@ -0,0 +1,66 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestShape tests the structure of documents.
+func TestShape(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"id":1,"items":[{"price":1.5},{"price":"free"}],"tags":[]}`)
+
+	shape := dynaj.Shape(doc)
+	assert.Equal(shape["/items/*/price"], "number|string")
+	assert.Equal(shape.String(), `/: object
+/id: number
+/items: array
+/items/*: object
+/items/*/price: number|string
+/tags: array
+`)
+
+	// Values do not matter.
+	other := mustUnmarshal(assert, `{"tags":[],"id":2,"items":[{"price":"x"},{"price":2}]}`)
+	assert.Equal(dynaj.Shape(other).String(), shape.String())
+	assert.Length(dynaj.CompareShapes(doc, other), 0)
+}
+
+// TestCompareShapes tests listing structural differences.
+func TestCompareShapes(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	v1 := mustUnmarshal(assert, `{"id":1,"name":"a","items":[{"price":1}]}`)
+	v2 := mustUnmarshal(assert, `{"id":"1","items":[{"price":1,"currency":"EUR"}],"total":2}`)
+
+	diffs := dynaj.CompareShapes(v1, v2)
+	msgs := make([]string, len(diffs))
+	for i, diff := range diffs {
+		msgs[i] = diff.String()
+	}
+	assert.Equal(msgs, []string{
+		"changed /id (number to string)",
+		"added /items/*/currency (string)",
+		"removed /name (string)",
+		"added /total (number)",
+	})
+}
+
+// EOF
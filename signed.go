@@ -0,0 +1,115 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+//--------------------
+// SIGNED DOCUMENTS
+//--------------------
+
+// signedEnvelope contains a signed document and its signature.
+type signedEnvelope struct {
+	Document  json.RawMessage `json:"document"`
+	Signature string          `json:"hmac-sha256"`
+}
+
+// MarshalSigned marshals the document into an envelope together with
+// an HMAC-SHA256 over its canonical form, e.g. for configurations
+// distributed to nodes where tampering must be detected:
+//
+//	{"document":{...},"hmac-sha256":"…"}
+//
+// The canonical form has sorted keys and no whitespace, so the signature
+// survives reformatting of the envelope.
+func (d *Document) MarshalSigned(key []byte) ([]byte, error) {
+	root, err := normalizeValue(d.root, Separator, d.nonFinite)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal signed document: %v", err)
+	}
+	canonical, err := canonicalJSON(root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal signed document: %v", err)
+	}
+	data, err := json.Marshal(signedEnvelope{
+		Document:  canonical,
+		Signature: sign(key, canonical),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal signed document: %v", err)
+	}
+	return data, nil
+}
+
+// UnmarshalVerified parses an envelope created by MarshalSigned and
+// verifies the signature of the contained document with the key. A
+// missing or not matching signature returns an error wrapping
+// ErrSignature. The options are applied to the contained document.
+func UnmarshalVerified(data, key []byte, opts ...Option) (*Document, error) {
+	o := newOptions(opts)
+	if o.limits.MaxBytes > 0 && len(data) > o.limits.MaxBytes {
+		return nil, fmt.Errorf("cannot unmarshal verified document: data length %d exceeds limit of %d bytes", len(data), o.limits.MaxBytes)
+	}
+	data, err := toUTF8(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal verified document: %v", err)
+	}
+	var envelope signedEnvelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("cannot unmarshal verified document: %v", err)
+	}
+	if envelope.Document == nil || envelope.Signature == "" {
+		return nil, fmt.Errorf("cannot unmarshal verified document: %w: missing document or signature", ErrSignature)
+	}
+	canonical, err := canonicalJSON(envelope.Document)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal verified document: %v", err)
+	}
+	if !hmac.Equal([]byte(sign(key, canonical)), []byte(envelope.Signature)) {
+		return nil, fmt.Errorf("cannot unmarshal verified document: %w", ErrSignature)
+	}
+	root, err := o.decode(envelope.Document)
+	if err != nil {
+		return nil, fmt.Errorf("cannot unmarshal verified document: %v", err)
+	}
+	return o.newDocument(root), nil
+}
+
+// canonicalJSON marshals the element with sorted keys and without
+// whitespace. Raw fragments and attachments are canonicalized by
+// decoding and marshalling them again.
+func canonicalJSON(element Element) ([]byte, error) {
+	data, err := json.Marshal(element)
+	if err != nil {
+		return nil, err
+	}
+	var decoded Element
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		return nil, err
+	}
+	return json.Marshal(decoded)
+}
+
+// sign returns the hex encoded HMAC-SHA256 of the data.
+func sign(key, data []byte) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(data)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// EOF
@@ -0,0 +1,62 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestSigned tests marshalling signed and unmarshalling verified
+// documents.
+func TestSigned(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	key := []byte("secret")
+	doc := mustUnmarshal(assert, `{"b":[1,2],"a":{"host":"edge-1","port":8080}}`)
+
+	data, err := doc.MarshalSigned(key)
+	assert.NoError(err)
+	assert.Substring(`{"document":{"a":{"host":"edge-1","port":8080},"b":[1,2]},"hmac-sha256":"`, string(data))
+
+	verified, err := dynaj.UnmarshalVerified(data, key)
+	assert.NoError(err)
+	assert.Equal(verified.String(), doc.String())
+
+	// Reformatting keeps the signature valid.
+	var buf bytes.Buffer
+	assert.NoError(json.Indent(&buf, data, "", "  "))
+	_, err = dynaj.UnmarshalVerified(buf.Bytes(), key)
+	assert.NoError(err)
+
+	// Tampering and wrong keys are detected.
+	tampered := bytes.Replace(data, []byte("8080"), []byte("8081"), 1)
+	_, err = dynaj.UnmarshalVerified(tampered, key)
+	assert.True(errors.Is(err, dynaj.ErrSignature))
+	_, err = dynaj.UnmarshalVerified(data, []byte("guess"))
+	assert.True(errors.Is(err, dynaj.ErrSignature))
+	_, err = dynaj.UnmarshalVerified([]byte(`{"document":{"a":1}}`), key)
+	assert.True(errors.Is(err, dynaj.ErrSignature))
+	_, err = dynaj.UnmarshalVerified([]byte(`{"a":1`), key)
+	assert.ErrorMatch(err, "cannot unmarshal verified document: .*")
+}
+
+// EOF
@@ -0,0 +1,80 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestSnapshot tests consistent views while changing the document.
+func TestSnapshot(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	in := `{"a":{"b":[1,2,3]},"c":{},"d":[]}`
+	doc := mustUnmarshal(assert, in)
+
+	snapshot := doc.Snapshot()
+	assert.True(snapshot.IsFrozen())
+	assert.True(errors.Is(snapshot.SetValueAt("/x", 1), dynaj.ErrFrozen))
+
+	assert.NoError(doc.SetValueAt("/a/b/0", 10))
+	assert.NoError(doc.DeleteElementAt("/a/b/2"))
+	assert.NoError(doc.SetValueAt("/c/e", true))
+	assert.NoError(doc.AddToArray("/d", 1))
+	assert.NoError(doc.AppendArrayAt("/a/b", mustUnmarshal(assert, `[4]`)))
+	_, err := doc.DeduplicateSubtrees()
+	assert.NoError(err)
+	assert.Equal(doc.String(), `{"a":{"b":[10,2,4]},"c":{"e":true},"d":[1]}`)
+	assert.Equal(snapshot.String(), in)
+
+	// Snapshots of snapshots are the same.
+	second := doc.Snapshot()
+	doc.Clear()
+	assert.Equal(second.String(), `{"a":{"b":[10,2,4]},"c":{"e":true},"d":[1]}`)
+	assert.True(second.Snapshot() == second)
+}
+
+// TestSnapshotConcurrent tests reading snapshots while writing.
+func TestSnapshotConcurrent(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"counters":[0,0,0]}`)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		snapshot := doc.Snapshot()
+		wg.Add(1)
+		go func(expected int) {
+			defer wg.Done()
+			for j := 0; j < 3; j++ {
+				if snapshot.NodeAt("/counters/"+strconv.Itoa(j)).AsInt(-1) != expected {
+					t.Errorf("snapshot changed")
+				}
+			}
+		}(i)
+		for j := 0; j < 3; j++ {
+			assert.NoError(doc.SetValueAt("/counters/"+strconv.Itoa(j), i+1))
+		}
+	}
+	wg.Wait()
+}
+
+// EOF
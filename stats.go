@@ -0,0 +1,98 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"sync"
+)
+
+//--------------------
+// ACCESS STATISTICS
+//--------------------
+
+// AccessCount contains the number of reads with NodeAt and writes with
+// SetValueAt of a path.
+type AccessCount struct {
+	Reads  int
+	Writes int
+}
+
+// accessStats counts the accesses per path. It is shared by the
+// document and its frozen copies and snapshots.
+type accessStats struct {
+	mu     sync.Mutex
+	counts map[Path]*AccessCount
+}
+
+// SetAccessStats enables or disables counting the reads and writes per
+// path. Frozen copies and snapshots taken afterwards count into the
+// statistics of the document too. This way e.g. the configuration
+// values never read can be found. Disabling drops the statistics.
+// Frozen documents keep their setting.
+func (d *Document) SetAccessStats(enabled bool) {
+	if d.frozen {
+		return
+	}
+	switch {
+	case !enabled:
+		d.stats = nil
+	case d.stats == nil:
+		d.stats = &accessStats{
+			counts: map[Path]*AccessCount{},
+		}
+	}
+}
+
+// AccessStats returns a copy of the counted accesses per path. It is
+// nil if counting is not enabled.
+func (d *Document) AccessStats() map[Path]AccessCount {
+	if d.stats == nil {
+		return nil
+	}
+	d.stats.mu.Lock()
+	defer d.stats.mu.Unlock()
+	counts := make(map[Path]AccessCount, len(d.stats.counts))
+	for path, count := range d.stats.counts {
+		counts[path] = *count
+	}
+	return counts
+}
+
+// countRead counts a read of the path if enabled.
+func (d *Document) countRead(path Path) {
+	if d.stats != nil {
+		d.stats.count(path, 1, 0)
+	}
+}
+
+// countWrite counts a write of the path if enabled.
+func (d *Document) countWrite(path Path) {
+	if d.stats != nil {
+		d.stats.count(path, 0, 1)
+	}
+}
+
+// count adds the reads and writes to the counts of the normalized path.
+func (s *accessStats) count(path Path, reads, writes int) {
+	path = pathify(splitPath(path))
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	count, ok := s.counts[path]
+	if !ok {
+		count = &AccessCount{}
+		s.counts[path] = count
+	}
+	count.Reads += reads
+	count.Writes += writes
+}
+
+// EOF
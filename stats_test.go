@@ -0,0 +1,60 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestAccessStats tests counting the accesses per path.
+func TestAccessStats(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc, err := dynaj.Unmarshal([]byte(`{"server": {"port": 80, "host": "a"}, "debug": false}`), dynaj.WithAccessStats())
+	assert.NoError(err)
+
+	assert.Equal(doc.NodeAt("/server/port").AsInt(0), 80)
+	assert.Equal(doc.NodeAt("server/port/").AsInt(0), 80)
+	assert.True(doc.NodeAt("/missing").IsError())
+	assert.NoError(doc.SetValueAt("/server/host", "b"))
+	assert.NoError(doc.NodeAt("/debug").SetValue(true))
+
+	// Frozen copies count into the statistics of the document.
+	frozen := doc.Freeze()
+	assert.Equal(frozen.NodeAt("/server/host").AsString(""), "b")
+
+	assert.Equal(doc.AccessStats(), map[dynaj.Path]dynaj.AccessCount{
+		"/server/port": {Reads: 2},
+		"/server/host": {Reads: 1, Writes: 1},
+		"/missing":     {Reads: 1},
+		"/debug":       {Reads: 1, Writes: 1},
+	})
+	assert.Equal(frozen.AccessStats(), doc.AccessStats())
+
+	// Disabling drops the statistics.
+	doc.SetAccessStats(false)
+	assert.Nil(doc.AccessStats())
+	doc.NodeAt("/debug")
+	assert.Nil(doc.AccessStats())
+	doc.SetAccessStats(true)
+	doc.NodeAt("/debug")
+	assert.Equal(doc.AccessStats(), map[dynaj.Path]dynaj.AccessCount{"/debug": {Reads: 1}})
+}
+
+// EOF
@@ -0,0 +1,83 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// STRICT MODE
+//--------------------
+
+// SetStrict enables or disables the strict mode of the document. In
+// strict mode setting values does not create missing intermediate
+// objects or arrays but returns an error wrapping ErrPathNotFound.
+// This way typos writing to wrong locations are detected. Containers
+// have to be created explicitly with MkdirAll.
+func (d *Document) SetStrict(strict bool) {
+	if d.frozen {
+		return
+	}
+	d.strict = strict
+}
+
+// IsStrict returns true if the document is in strict mode.
+func (d *Document) IsStrict() bool {
+	return d.strict
+}
+
+// MkdirAll creates all missing objects and arrays along the path,
+// including the last one, like os.MkdirAll does for directories.
+// Containers are arrays if the following key is an index, otherwise
+// objects. The last container is an object. Existing containers are
+// kept, existing values along the path return an error. Containers are
+// set like by SetValueAt, so access rules, locked types, and hooks apply.
+func (d *Document) MkdirAll(path Path) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	keys := splitPath(path)
+	for i := 1; i <= len(keys); i++ {
+		element, err := elementAt(d.root, keys[:i])
+		if err == nil && isObjectOrArray(element) {
+			continue
+		}
+		if err == nil && element != nil {
+			return fmt.Errorf("cannot create containers at %q: %q is a value", path, pathify(keys[:i]))
+		}
+		var container Element = Object{}
+		if i < len(keys) {
+			if _, ok := asIndex(keys[i]); ok {
+				container = Array{}
+			}
+		}
+		if err := d.setValueAt(pathify(keys[:i]), container, false); err != nil {
+			return fmt.Errorf("cannot create containers at %q: %w", path, err)
+		}
+	}
+	return nil
+}
+
+// checkStrict checks in strict mode if the parent of the keys exists.
+func (d *Document) checkStrict(keys Keys) error {
+	if !d.strict || len(keys) < 2 {
+		return nil
+	}
+	if _, err := elementAt(d.root, keys[:len(keys)-1]); err != nil {
+		return fmt.Errorf("%w: cannot insert value at %q: parent %q does not exist",
+			ErrPathNotFound, pathify(keys), pathify(keys[:len(keys)-1]))
+	}
+	return nil
+}
+
+// EOF
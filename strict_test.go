@@ -0,0 +1,76 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestStrict tests the strict mode for setting values.
+func TestStrict(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"server":{"port":8080},"hosts":["a"]}`)
+	assert.False(doc.IsStrict())
+	doc.SetStrict(true)
+	assert.True(doc.IsStrict())
+
+	// Existing parents.
+	assert.NoError(doc.SetValueAt("/server/port", 9090))
+	assert.NoError(doc.SetValueAt("/server/host", "localhost"))
+	assert.NoError(doc.SetValueAt("/hosts/1", "b"))
+	assert.NoError(doc.SetValueAt("/timeout", 10))
+
+	// Missing parents.
+	err := doc.SetValueAt("/sever/port", 1)
+	assert.True(errors.Is(err, dynaj.ErrPathNotFound))
+	assert.ErrorContains(err, `path not found: cannot insert value at "/sever/port": parent "/sever" does not exist`)
+	err = doc.SetRawAt("/a/b/c", json.RawMessage(`1`))
+	assert.True(errors.Is(err, dynaj.ErrPathNotFound))
+	assert.Equal(doc.String(), `{"hosts":["a","b"],"server":{"host":"localhost","port":9090},"timeout":10}`)
+
+	// Explicit creation.
+	assert.NoError(doc.MkdirAll("/db/replicas/0/tags"))
+	assert.NoError(doc.MkdirAll("/db/replicas"))
+	assert.NoError(doc.SetValueAt("/db/replicas/0/host", "db1"))
+	assert.NoError(doc.SetValueAt("/db/replicas/0/tags/env", "prod"))
+	assert.Equal(doc.NodeAt("/db").String(), "map[replicas:[map[host:db1 tags:map[env:prod]]]]")
+	assert.ErrorContains(doc.MkdirAll("/timeout/x"), `cannot create containers at "/timeout/x": "/timeout" is a value`)
+
+	// Empty documents allow top-level values.
+	doc = dynaj.NewDocument()
+	doc.SetStrict(true)
+	assert.NoError(doc.SetValueAt("/a", 1))
+	assert.True(errors.Is(doc.SetValueAt("/b/c", 1), dynaj.ErrPathNotFound))
+	doc.SetStrict(false)
+	assert.NoError(doc.SetValueAt("/b/c", 1))
+
+	// Creation is recorded.
+	doc = dynaj.NewDocument()
+	doc.RecordOperations()
+	assert.NoError(doc.MkdirAll("/x/0"))
+	replayed, err := dynaj.Replay(doc.Operations())
+	assert.NoError(err)
+	assert.Equal(replayed.String(), `{"x":[{}]}`)
+	assert.Equal(doc.Freeze().MkdirAll("/y"), dynaj.ErrFrozen)
+}
+
+// EOF
@@ -0,0 +1,140 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// STRING ACCESSORS
+//--------------------
+
+// SetEmptyAsUndefined lets the accessors of the nodes of the document
+// treat empty strings like undefined values, so they return the
+// default values instead. Frozen documents keep their setting.
+func (d *Document) SetEmptyAsUndefined(enabled bool) {
+	if d.frozen {
+		return
+	}
+	d.emptyUndefined = enabled
+}
+
+// AsTrimmedString returns the value as string without leading and
+// trailing white space. If the trimmed string is empty and empty
+// strings are treated as undefined the default value is returned.
+func (node *Node) AsTrimmedString(dv string) string {
+	if node.isUndefinedValue() {
+		return dv
+	}
+	s := strings.TrimSpace(node.AsString(dv))
+	if s == "" && node.emptyUndefined() {
+		return dv
+	}
+	return s
+}
+
+// AsLowerString returns the value as trimmed string in lower case.
+func (node *Node) AsLowerString(dv string) string {
+	if node.isUndefinedValue() {
+		return dv
+	}
+	return strings.ToLower(node.AsTrimmedString(dv))
+}
+
+// AsOneOf returns the value as string if it is one of the allowed
+// ones, otherwise the default value. This way values like log levels
+// can be checked.
+func (node *Node) AsOneOf(dv string, allowed ...string) string {
+	s, err := node.AsOneOfStrict(allowed...)
+	if err != nil {
+		return dv
+	}
+	return s
+}
+
+// AsOneOfStrict returns the value as string if it is one of the allowed
+// ones, otherwise an error listing the valid options.
+func (node *Node) AsOneOfStrict(allowed ...string) (string, error) {
+	if node.IsError() {
+		return "", node.err
+	}
+	if !node.isUndefinedValue() && node.IsValue() {
+		s := node.AsString("")
+		for _, a := range allowed {
+			if s == a {
+				return s, nil
+			}
+		}
+	}
+	return "", fmt.Errorf("invalid value %v at %q: valid are %s", node, node.path, strings.Join(quoteAll(allowed), ", "))
+}
+
+// AsIntOneOf returns the value as int if it is one of the allowed
+// ones, otherwise the default value.
+func (node *Node) AsIntOneOf(dv int, allowed ...int) int {
+	i, err := node.AsIntOneOfStrict(allowed...)
+	if err != nil {
+		return dv
+	}
+	return i
+}
+
+// AsIntOneOfStrict returns the value as int if it is one of the allowed
+// ones, otherwise an error listing the valid options.
+func (node *Node) AsIntOneOfStrict(allowed ...int) (int, error) {
+	if node.IsError() {
+		return 0, node.err
+	}
+	i, err := node.AsInt64Strict()
+	if err == nil {
+		for _, a := range allowed {
+			if i == int64(a) {
+				return a, nil
+			}
+		}
+	}
+	valid := make([]string, len(allowed))
+	for idx, a := range allowed {
+		valid[idx] = strconv.Itoa(a)
+	}
+	return 0, fmt.Errorf("invalid value %v at %q: valid are %s", node, node.path, strings.Join(valid, ", "))
+}
+
+// quoteAll quotes all strings.
+func quoteAll(ss []string) []string {
+	quoted := make([]string, len(ss))
+	for i, s := range ss {
+		quoted[i] = strconv.Quote(s)
+	}
+	return quoted
+}
+
+// isUndefinedValue returns true if the node is undefined for the
+// accessors.
+func (node *Node) isUndefinedValue() bool {
+	if node.IsUndefined() {
+		return true
+	}
+	s, ok := node.element.(string)
+	return ok && s == "" && node.emptyUndefined()
+}
+
+// emptyUndefined returns true if the document the node has been
+// retrieved from treats empty strings as undefined.
+func (node *Node) emptyUndefined() bool {
+	return node.doc != nil && node.doc.emptyUndefined
+}
+
+// EOF
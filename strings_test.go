@@ -0,0 +1,86 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestStringAccessors tests the trimming and normalizing accessors.
+func TestStringAccessors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"name":"  Foo Bar ","blank":"   ","empty":"","number":12}`)
+
+	assert.Equal(doc.NodeAt("/name").AsTrimmedString("-"), "Foo Bar")
+	assert.Equal(doc.NodeAt("/name").AsLowerString("-"), "foo bar")
+	assert.Equal(doc.NodeAt("/blank").AsTrimmedString("-"), "")
+	assert.Equal(doc.NodeAt("/number").AsLowerString("-"), "12")
+	assert.Equal(doc.NodeAt("/missing").AsTrimmedString("-"), "-")
+	assert.Equal(doc.NodeAt("/empty").AsString("-"), "")
+}
+
+// TestEmptyAsUndefined tests treating empty strings as undefined.
+func TestEmptyAsUndefined(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"blank":"   ","empty":"","name":" X "}`)
+	doc.SetEmptyAsUndefined(true)
+
+	assert.Equal(doc.NodeAt("/empty").AsString("-"), "-")
+	assert.Equal(doc.NodeAt("/empty").AsInt(5), 5)
+	assert.Equal(doc.NodeAt("/empty").AsFloat64(5), 5.0)
+	assert.Equal(doc.NodeAt("/empty").AsBool(true), true)
+	assert.Equal(doc.NodeAt("/blank").AsString("-"), "   ")
+	assert.Equal(doc.NodeAt("/blank").AsTrimmedString("-"), "-")
+	assert.Equal(doc.NodeAt("/blank").AsLowerString("-"), "-")
+	assert.Equal(doc.NodeAt("/name").AsLowerString("-"), "x")
+	_, err := doc.NodeAt("/empty").AsInt32Strict()
+	assert.ErrorContains(err, "undefined")
+
+	// Frozen documents keep the setting.
+	frozen := doc.Freeze()
+	frozen.SetEmptyAsUndefined(false)
+	assert.Equal(frozen.NodeAt("/empty").AsString("-"), "-")
+	doc.SetEmptyAsUndefined(false)
+	assert.Equal(doc.NodeAt("/empty").AsString("-"), "")
+}
+
+// TestAsOneOf tests the enum validating accessors.
+func TestAsOneOf(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"level":"info","bad":"verbose","code":2,"fraction":2.5,"obj":{}}`)
+	levels := []string{"debug", "info", "error"}
+
+	assert.Equal(doc.NodeAt("/level").AsOneOf("error", levels...), "info")
+	assert.Equal(doc.NodeAt("/bad").AsOneOf("error", levels...), "error")
+	assert.Equal(doc.NodeAt("/missing").AsOneOf("error", levels...), "error")
+	assert.Equal(doc.NodeAt("/obj").AsOneOf("error", levels...), "error")
+	assert.Equal(doc.NodeAt("/code").AsOneOf("x", "1", "2"), "2")
+
+	_, err := doc.NodeAt("/bad").AsOneOfStrict(levels...)
+	assert.ErrorContains(err, `invalid value verbose at "/bad": valid are "debug", "info", "error"`)
+	_, err = doc.NodeAt("/missing").AsOneOfStrict(levels...)
+	assert.ErrorContains(err, "invalid path")
+
+	assert.Equal(doc.NodeAt("/code").AsIntOneOf(0, 1, 2, 3), 2)
+	assert.Equal(doc.NodeAt("/code").AsIntOneOf(0, 1, 3), 0)
+	assert.Equal(doc.NodeAt("/fraction").AsIntOneOf(0, 2), 0)
+	_, err = doc.NodeAt("/code").AsIntOneOfStrict(1, 3)
+	assert.ErrorContains(err, `invalid value 2 at "/code": valid are 1, 3`)
+}
+
+// EOF
@@ -0,0 +1,147 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// PATH ERROR
+//--------------------
+
+// PathError is the error of nodes with paths not found in documents
+// with enabled path suggestions. It names the nearest existing ancestor
+// and paths with keys close to the missing one.
+type PathError struct {
+	Path        Path
+	Ancestor    Path
+	Missing     Key
+	Suggestions []Path
+}
+
+// Error implements error.
+func (e *PathError) Error() string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "invalid path %q: key %q not found in %q", e.Path, e.Missing, e.Ancestor)
+	if len(e.Suggestions) > 0 {
+		sb.WriteString(", did you mean ")
+		for i, suggestion := range e.Suggestions {
+			if i > 0 {
+				sb.WriteString(" or ")
+			}
+			sb.WriteString(strconv.Quote(suggestion))
+		}
+		sb.WriteString("?")
+	}
+	return sb.String()
+}
+
+// Unwrap returns ErrPathNotFound.
+func (e *PathError) Unwrap() error {
+	return ErrPathNotFound
+}
+
+// SetPathSuggestions enables or disables path suggestions. If enabled
+// nodes of paths not found contain a PathError. Frozen documents keep
+// their setting.
+func (d *Document) SetPathSuggestions(enabled bool) {
+	if d.frozen {
+		return
+	}
+	d.suggestions = enabled
+}
+
+// maxSuggestions is the maximum number of suggested paths.
+const maxSuggestions = 3
+
+// newPathError looks for the nearest existing ancestor of the path and
+// the keys of it close to the missing key.
+func newPathError(root Element, path Path) *PathError {
+	keys := splitPath(path)
+	element := root
+	for i, key := range keys {
+		element, _ = decodeRaw(element)
+		child, ok := childElement(element, key)
+		if ok {
+			element = child
+			continue
+		}
+		e := &PathError{
+			Path:     path,
+			Ancestor: pathify(keys[:i]),
+			Missing:  key,
+		}
+		type candidate struct {
+			key      Key
+			distance int
+		}
+		candidates := []candidate{}
+		limit := 1
+		if len(key) > 3 {
+			limit = 2
+		}
+		for _, k := range childKeys(element) {
+			distance := editDistance(strings.ToLower(key), strings.ToLower(k))
+			if distance <= limit {
+				candidates = append(candidates, candidate{k, distance})
+			}
+		}
+		sort.SliceStable(candidates, func(i, j int) bool {
+			return candidates[i].distance < candidates[j].distance
+		})
+		for j, c := range candidates {
+			if j == maxSuggestions {
+				break
+			}
+			suggestion := append(append(Keys{}, keys[:i]...), c.key)
+			e.Suggestions = append(e.Suggestions, pathify(append(suggestion, keys[i+1:]...)))
+		}
+		return e
+	}
+	return nil
+}
+
+// editDistance returns the Levenshtein distance of the strings.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	curr := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		curr[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			curr[j] = minInt(prev[j]+1, minInt(curr[j-1]+1, prev[j-1]+cost))
+		}
+		prev, curr = curr, prev
+	}
+	return prev[len(rb)]
+}
+
+// minInt returns the smaller int.
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// EOF
@@ -0,0 +1,65 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestPathSuggestions tests the suggestions for paths not found.
+func TestPathSuggestions(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	data := []byte(`{"server": {"port": 80, "host": "localhost"}, "servers": [], "client": {"Timeout": 5}}`)
+
+	doc, err := dynaj.Unmarshal(data, dynaj.WithPathSuggestions())
+	assert.NoError(err)
+
+	node := doc.NodeAt("/serverr/port")
+	assert.True(node.IsError())
+	assert.True(errors.Is(node.Err(), dynaj.ErrPathNotFound))
+	var perr *dynaj.PathError
+	assert.True(errors.As(node.Err(), &perr))
+	assert.Equal(perr.Ancestor, "/")
+	assert.Equal(perr.Missing, "serverr")
+	assert.Equal(perr.Suggestions, []dynaj.Path{"/server/port", "/servers/port"})
+	assert.ErrorContains(node.Err(), `invalid path "/serverr/port": key "serverr" not found in "/", did you mean "/server/port" or "/servers/port"?`)
+
+	node = doc.NodeAt("/client/timeot")
+	assert.ErrorContains(node.Err(), `key "timeot" not found in "/client", did you mean "/client/Timeout"?`)
+
+	node = doc.NodeAt("/server/xyz")
+	assert.ErrorContains(node.Err(), `invalid path "/server/xyz": key "xyz" not found in "/server"`)
+	assert.True(errors.As(node.Err(), &perr))
+	assert.Length(perr.Suggestions, 0)
+
+	node = doc.NodeAt("/server/port/x")
+	assert.ErrorContains(node.Err(), `key "x" not found in "/server/port"`)
+
+	node = doc.Freeze().NodeAt("/sever")
+	assert.ErrorContains(node.Err(), `did you mean "/server" or "/servers"?`)
+
+	doc.SetPathSuggestions(false)
+	node = doc.NodeAt("/serverr/port")
+	assert.False(errors.As(node.Err(), &perr))
+	assert.ErrorContains(node.Err(), `invalid path "/serverr/port"`)
+}
+
+// EOF
@@ -0,0 +1,179 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+//--------------------
+// TEMPLATE
+//--------------------
+
+// placeholder describes a marker of a template.
+type placeholder struct {
+	required bool
+	kind     string
+}
+
+// placeholderKinds contains the valid kinds of markers.
+var placeholderKinds = map[string]struct{}{
+	"":       {},
+	"string": {},
+	"int":    {},
+	"number": {},
+	"bool":   {},
+	"object": {},
+	"array":  {},
+}
+
+// Template defines the shape of documents. String values like
+// "${required:int}" or "${optional}" are markers for values passed
+// when instantiating the template. Markers are "required" or "optional",
+// optionally followed by one of the kinds "string", "int", "number",
+// "bool", "object", or "array". All other values are copied as they are.
+type Template struct {
+	doc          *Document
+	placeholders map[Path]placeholder
+}
+
+// NewTemplate creates a template out of the document.
+func NewTemplate(doc *Document) (*Template, error) {
+	t := &Template{
+		doc:          doc.Freeze(),
+		placeholders: map[Path]placeholder{},
+	}
+	var merr error
+	err := t.doc.Root().Process(func(node *Node) error {
+		s, ok := node.element.(string)
+		if !ok || !strings.HasPrefix(s, "${") || !strings.HasSuffix(s, "}") {
+			return nil
+		}
+		marker := s[2 : len(s)-1]
+		mode, kind := marker, ""
+		if idx := strings.Index(marker, ":"); idx >= 0 {
+			mode, kind = marker[:idx], marker[idx+1:]
+		}
+		if mode != "required" && mode != "optional" {
+			merr = fmt.Errorf("invalid marker %q at %q", s, node.path)
+			return merr
+		}
+		if _, ok := placeholderKinds[kind]; !ok {
+			merr = fmt.Errorf("invalid kind of marker %q at %q", s, node.path)
+			return merr
+		}
+		t.placeholders[node.path] = placeholder{mode == "required", kind}
+		return nil
+	})
+	if merr != nil {
+		err = merr
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cannot create template: %v", err)
+	}
+	return t, nil
+}
+
+// Placeholders returns the sorted paths of the markers.
+func (t *Template) Placeholders() []Path {
+	paths := make([]Path, 0, len(t.placeholders))
+	for path := range t.placeholders {
+		paths = append(paths, path)
+	}
+	sortPaths(paths)
+	return paths
+}
+
+// Instantiate creates a new document out of the template by replacing
+// the markers with the values. Values have to match the kinds of their
+// markers, missing values of required markers and values for paths
+// without marker are errors. Optional markers without value are removed.
+// All errors are returned together.
+func (t *Template) Instantiate(values map[Path]Value) (*Document, error) {
+	errs := []string{}
+	for path := range values {
+		if _, ok := t.placeholders[pathify(splitPath(path))]; !ok {
+			errs = append(errs, fmt.Sprintf("no marker at %q", path))
+		}
+	}
+	normalized := map[Path]Value{}
+	for path, value := range values {
+		normalized[pathify(splitPath(path))] = value
+	}
+	doc := t.doc.derive(copyElement(t.doc.root))
+	removals := []Path{}
+	for _, path := range t.Placeholders() {
+		ph := t.placeholders[path]
+		value, ok := normalized[path]
+		if !ok {
+			if ph.required {
+				errs = append(errs, fmt.Sprintf("missing value at %q", path))
+			} else {
+				removals = append(removals, path)
+			}
+			continue
+		}
+		if err := doc.setPlaceholder(path, ph.kind, value); err != nil {
+			errs = append(errs, err.Error())
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return nil, fmt.Errorf("cannot instantiate template: %s", strings.Join(errs, "; "))
+	}
+	// Remove from the end to keep the array indices valid.
+	for i := len(removals) - 1; i >= 0; i-- {
+		if err := doc.DeleteElementAt(removals[i]); err != nil {
+			return nil, fmt.Errorf("cannot instantiate template: %v", err)
+		}
+	}
+	return doc, nil
+}
+
+// setPlaceholder checks the kind of the value and sets it.
+func (d *Document) setPlaceholder(path Path, kind string, value Value) error {
+	element, err := normalizeValue(value, path, d.nonFinite)
+	if err != nil {
+		return err
+	}
+	if kind != "" {
+		actual := typeName(element)
+		if kind == "int" {
+			f, ok := asNumber(element)
+			if ok && f == math.Trunc(f) {
+				actual = "int"
+			}
+		}
+		if actual != kind {
+			return fmt.Errorf("invalid value at %q: %s instead of %s", path, actual, kind)
+		}
+	}
+	keys := splitPath(path)
+	root, err := replaceElement(d.root, keys, copyElement(element))
+	if err != nil {
+		return err
+	}
+	d.root = root
+	return nil
+}
+
+// sortPaths sorts the paths with indices compared numerically.
+func sortPaths(paths []Path) {
+	sort.Slice(paths, func(i, j int) bool {
+		return compareKeys(splitPath(paths[i]), splitPath(paths[j])) < 0
+	})
+}
+
+// EOF
@@ -0,0 +1,79 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestTemplate tests instantiating documents out of templates.
+func TestTemplate(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	tmpl, err := dynaj.NewTemplate(mustUnmarshal(assert, `{
+		"kind": "user",
+		"name": "${required:string}",
+		"age": "${required:int}",
+		"admin": "${optional:bool}",
+		"tags": ["${optional}", "fixed", "${optional:string}"],
+		"meta": {"source": "${optional:object}"}
+	}`))
+	assert.NoError(err)
+	assert.Equal(tmpl.Placeholders(), []dynaj.Path{"/admin", "/age", "/meta/source", "/name", "/tags/0", "/tags/2"})
+
+	doc, err := tmpl.Instantiate(map[dynaj.Path]dynaj.Value{
+		"/name":        "alice",
+		"/age":         42,
+		"/tags/2":      "last",
+		"/meta/source": map[string]string{"system": "test"},
+	})
+	assert.NoError(err)
+	assert.Equal(doc.String(), `{"age":42,"kind":"user","meta":{"source":{"system":"test"}},"name":"alice","tags":["fixed","last"]}`)
+
+	doc, err = tmpl.Instantiate(map[dynaj.Path]dynaj.Value{
+		"/name":    "bob",
+		"/age":     17.0,
+		"/admin":   true,
+		"tags/0/":  []int{1, 2},
+		"/tags/2/": "last",
+	})
+	assert.NoError(err)
+	assert.Equal(doc.String(), `{"admin":true,"age":17,"kind":"user","meta":{},"name":"bob","tags":[[1,2],"fixed","last"]}`)
+}
+
+// TestTemplateErrors tests invalid templates and values.
+func TestTemplateErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	_, err := dynaj.NewTemplate(mustUnmarshal(assert, `{"a": "${wanted}"}`))
+	assert.ErrorContains(err, `cannot create template: invalid marker "${wanted}" at "/a"`)
+	_, err = dynaj.NewTemplate(mustUnmarshal(assert, `{"a": "${required:date}"}`))
+	assert.ErrorContains(err, `invalid kind of marker "${required:date}"`)
+
+	tmpl, err := dynaj.NewTemplate(mustUnmarshal(assert, `{"a": "${required:int}", "b": "${required}", "c": "${optional:array}"}`))
+	assert.NoError(err)
+	_, err = tmpl.Instantiate(map[dynaj.Path]dynaj.Value{
+		"/a": 1.5,
+		"/c": "x",
+		"/d": 1,
+	})
+	assert.ErrorContains(err, `cannot instantiate template: invalid value at "/a": number instead of int; `+
+		`invalid value at "/c": string instead of array; missing value at "/b"; no marker at "/d"`)
+}
+
+// EOF
@@ -0,0 +1,113 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+//--------------------
+// TIMES
+//--------------------
+
+// EpochMillis can be used as input or output layout of NormalizeTimes
+// for timestamps as milliseconds since the Unix epoch.
+const EpochMillis = "epochmillis"
+
+// NormalizeTimes converts the time strings at all paths matching the
+// patterns from one of the input layouts into the output layout. The
+// input layouts are tried in their order. With EpochMillis as input
+// layout numbers are accepted too, as output layout the times are
+// stored as numbers. Containers are ignored. If any value cannot be
+// parsed an error naming its path is returned and the document is
+// unchanged. Also failing checks or hooks while setting the values
+// roll back the already set ones, only called hooks are not undone.
+func (d *Document) NormalizeTimes(patterns []string, layoutIn []string, layoutOut string) error {
+	if d.frozen {
+		return ErrFrozen
+	}
+	type change struct {
+		path  Path
+		value Value
+	}
+	changes := []change{}
+	seen := map[Path]bool{}
+	for _, pattern := range patterns {
+		paths, err := d.ExpandPattern(pattern)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			if seen[path] {
+				continue
+			}
+			seen[path] = true
+			element, err := elementAt(d.root, splitPath(path))
+			if err != nil {
+				return fmt.Errorf("invalid path %q: %v", path, err)
+			}
+			element, err = decodeRaw(element)
+			if err != nil {
+				return fmt.Errorf("cannot normalize time at %q: %v", path, err)
+			}
+			if element == nil || isObjectOrArray(element) {
+				continue
+			}
+			t, err := parseTime(element, layoutIn)
+			if err != nil {
+				return fmt.Errorf("cannot normalize time at %q: %v", path, err)
+			}
+			if layoutOut == EpochMillis {
+				changes = append(changes, change{path, int(t.UnixMilli())})
+			} else {
+				changes = append(changes, change{path, t.Format(layoutOut)})
+			}
+		}
+	}
+	return d.atomically(func() error {
+		for _, c := range changes {
+			if err := d.SetValueAt(c.path, c.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// parseTime parses a string or number with the first matching layout.
+func parseTime(element Element, layouts []string) (time.Time, error) {
+	for _, layout := range layouts {
+		if layout == EpochMillis {
+			switch tv := element.(type) {
+			case int:
+				return time.UnixMilli(int64(tv)).UTC(), nil
+			case float64:
+				if tv == math.Trunc(tv) {
+					return time.UnixMilli(int64(tv)).UTC(), nil
+				}
+			}
+			continue
+		}
+		s, ok := element.(string)
+		if !ok {
+			continue
+		}
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("value %v matches no layout", element)
+}
+
+// EOF
@@ -0,0 +1,91 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestNormalizeTimes tests converting times between layouts.
+func TestNormalizeTimes(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"events": [
+		{"at": "2023-04-01T12:30:00Z"},
+		{"at": "01.04.2023 13:45"},
+		{"at": null}
+	], "created": "2023-04-01"}`)
+
+	err := doc.NormalizeTimes(
+		[]string{"/events/*/at", "/created"},
+		[]string{time.RFC3339, "02.01.2006 15:04", "2006-01-02"},
+		"2006-01-02 15:04",
+	)
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/events/0/at").AsString(""), "2023-04-01 12:30")
+	assert.Equal(doc.NodeAt("/events/1/at").AsString(""), "2023-04-01 13:45")
+	assert.True(doc.NodeAt("/events/2/at").IsUndefined())
+	assert.Equal(doc.NodeAt("/created").AsString(""), "2023-04-01 00:00")
+}
+
+// TestNormalizeTimesEpochMillis tests converting from and into epoch
+// milliseconds.
+func TestNormalizeTimesEpochMillis(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": "2023-04-01T00:00:00Z", "b": 1680307200000}`)
+
+	err := doc.NormalizeTimes([]string{"/a"}, []string{time.RFC3339}, dynaj.EpochMillis)
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/a").AsInt(0), 1680307200000)
+
+	err = doc.NormalizeTimes([]string{"/*"}, []string{dynaj.EpochMillis}, time.RFC3339)
+	assert.NoError(err)
+	assert.Equal(doc.NodeAt("/a").AsString(""), "2023-04-01T00:00:00Z")
+	assert.Equal(doc.NodeAt("/b").AsString(""), "2023-04-01T00:00:00Z")
+}
+
+// TestNormalizeTimesErrors tests that invalid times leave the document
+// unchanged.
+func TestNormalizeTimesErrors(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": "2023-04-01", "b": "yesterday"}`)
+
+	err := doc.NormalizeTimes([]string{"/a", "/b"}, []string{"2006-01-02"}, time.RFC3339)
+	assert.ErrorContains(err, `cannot normalize time at "/b": value yesterday matches no layout`)
+	assert.Equal(doc.NodeAt("/a").AsString(""), "2023-04-01")
+
+	// Rejected values roll back the already converted ones.
+	doc = mustUnmarshal(assert, `{"a": "2023-04-01", "b": "2023-04-02"}`)
+	doc.AddHook(dynaj.BeforeSet, func(path dynaj.Path, old, value dynaj.Value) error {
+		if path == "/b" {
+			return errors.New("b is fixed")
+		}
+		return nil
+	})
+	err = doc.NormalizeTimes([]string{"/a", "/b"}, []string{"2006-01-02"}, time.RFC3339)
+	assert.ErrorContains(err, "b is fixed")
+	assert.Equal(doc.String(), `{"a":"2023-04-01","b":"2023-04-02"}`)
+
+	err = doc.Freeze().NormalizeTimes([]string{"/a"}, []string{"2006-01-02"}, time.RFC3339)
+	assert.ErrorMatch(err, ".*frozen.*")
+}
+
+// EOF
@@ -12,6 +12,7 @@ package dynaj // import "tideland.dev/go/dynaj"
 //--------------------
 
 import (
+	"encoding/json"
 	"fmt"
 )
 
@@ -32,6 +33,12 @@ func insertValue(element Element, keys Keys, value Value) (Element, error) {
 		return insertValueInObject(tnode, keys, value)
 	case Array:
 		return insertValueInArray(tnode, keys, value)
+	case json.RawMessage, *records:
+		decoded, err := decodeRaw(tnode)
+		if err != nil {
+			return nil, err
+		}
+		return insertValue(decoded, keys, value)
 	default:
 		return nil, fmt.Errorf("document is not a valid JSON structure")
 	}
@@ -82,7 +89,10 @@ func insertValueInObject(obj Object, keys Keys, value Value) (Element, error) {
 		return obj, nil
 	}
 	// Insert value in element.
-	element := obj[h]
+	element, err := decodeRaw(obj[h])
+	if err != nil {
+		return nil, err
+	}
 	if isValue(element) {
 		return nil, fmt.Errorf("cannot insert value at %v: would corrupt document", keys)
 	}
@@ -119,7 +129,10 @@ func insertValueInArray(arr Array, keys Keys, value Value) (Element, error) {
 		return arr, nil
 	}
 	// Insert value in element.
-	element := arr[index]
+	element, err := decodeRaw(arr[index])
+	if err != nil {
+		return nil, err
+	}
 	if isValue(element) {
 		return nil, fmt.Errorf("cannot insert value at %v: would corrupt document", keys)
 	}
@@ -145,6 +158,12 @@ func deleteElement(element Element, keys Keys, deep bool) (Element, error) {
 		return deleteElementInObject(tnode, keys, deep)
 	case Array:
 		return deleteElementInArray(tnode, keys, deep)
+	case json.RawMessage, *records:
+		decoded, err := decodeRaw(tnode)
+		if err != nil {
+			return nil, err
+		}
+		return deleteElement(decoded, keys, deep)
 	default:
 		return nil, fmt.Errorf("cannot delete value at %v: path too long", keys)
 	}
@@ -196,6 +215,7 @@ func deleteElementInArray(arr Array, keys Keys, deep bool) (Element, error) {
 			// Delete all.
 			copy(arr[index:], arr[index+1:])
 			arr = arr[:len(arr)-1]
+			return arr, nil
 		}
 		// Not deep, so delete only if value.
 		if isObjectOrArray(arr[index]) {
@@ -215,4 +235,63 @@ func deleteElementInArray(arr Array, keys Keys, deep bool) (Element, error) {
 	return arr, nil
 }
 
+// replaceElement replaces the existing element at the end of the keys
+// list, regardless if it is a value or a container element.
+func replaceElement(element Element, keys Keys, replacement Element) (Element, error) {
+	if len(keys) == 0 {
+		return replacement, nil
+	}
+	element, err := decodeRaw(element)
+	if err != nil {
+		return nil, err
+	}
+	h, t := headTail(keys)
+	switch tnode := element.(type) {
+	case Object:
+		child, ok := tnode[h]
+		if !ok {
+			return nil, fmt.Errorf("cannot replace element at %v: invalid path", keys)
+		}
+		newElement, err := replaceElement(child, t, replacement)
+		if err != nil {
+			return nil, err
+		}
+		tnode[h] = newElement
+		return tnode, nil
+	case Array:
+		index, ok := asIndex(h)
+		if !ok || index < 0 || index >= len(tnode) {
+			return nil, fmt.Errorf("cannot replace element at %v: invalid index %q", keys, h)
+		}
+		newElement, err := replaceElement(tnode[index], t, replacement)
+		if err != nil {
+			return nil, err
+		}
+		tnode[index] = newElement
+		return tnode, nil
+	default:
+		return nil, fmt.Errorf("cannot replace element at %v: path too long", keys)
+	}
+}
+
+// copyElement recursively copies an element.
+func copyElement(element Element) Element {
+	switch typed := element.(type) {
+	case Object:
+		obj := make(Object, len(typed))
+		for key, child := range typed {
+			obj[key] = copyElement(child)
+		}
+		return obj
+	case Array:
+		arr := make(Array, len(typed))
+		for idx, child := range typed {
+			arr[idx] = copyElement(child)
+		}
+		return arr
+	default:
+		return typed
+	}
+}
+
 // EOF
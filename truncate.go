@@ -0,0 +1,83 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// TRUNCATION
+//--------------------
+
+// TruncateOptions define the limits of a truncated document. A
+// value of zero means no limit.
+type TruncateOptions struct {
+	// MaxStringLength is the maximum number of runes of strings.
+	MaxStringLength int
+
+	// MaxArrayLength is the maximum number of array elements.
+	MaxArrayLength int
+
+	// MaxDepth is the maximum depth of nested objects and arrays.
+	// The root has depth zero.
+	MaxDepth int
+}
+
+// Truncate returns a copy of the document where strings, arrays, and
+// the depth are cut to the given limits. Cut content is replaced by
+// markers like "…(+945 items)", so the result is a small preview for
+// displaying or logging.
+func (d *Document) Truncate(limits TruncateOptions) *Document {
+	return d.derive(truncateElement(d.root, limits, 0))
+}
+
+// truncateElement recursively copies and truncates an element.
+func truncateElement(element Element, limits TruncateOptions, depth int) Element {
+	switch typed := decodedElement(element).(type) {
+	case string:
+		runes := []rune(typed)
+		if limits.MaxStringLength > 0 && len(runes) > limits.MaxStringLength {
+			return fmt.Sprintf("%s…(+%d chars)", string(runes[:limits.MaxStringLength]), len(runes)-limits.MaxStringLength)
+		}
+		return typed
+	case Object:
+		if limits.MaxDepth > 0 && depth >= limits.MaxDepth {
+			return fmt.Sprintf("…(object with %d keys)", len(typed))
+		}
+		obj := make(Object, len(typed))
+		for key, child := range typed {
+			obj[key] = truncateElement(child, limits, depth+1)
+		}
+		return obj
+	case Array:
+		if limits.MaxDepth > 0 && depth >= limits.MaxDepth {
+			return fmt.Sprintf("…(array with %d items)", len(typed))
+		}
+		length := len(typed)
+		if limits.MaxArrayLength > 0 && length > limits.MaxArrayLength {
+			length = limits.MaxArrayLength
+		}
+		arr := make(Array, length, length+1)
+		for idx := range arr {
+			arr[idx] = truncateElement(typed[idx], limits, depth+1)
+		}
+		if length < len(typed) {
+			arr = append(arr, fmt.Sprintf("…(+%d items)", len(typed)-length))
+		}
+		return arr
+	default:
+		return typed
+	}
+}
+
+// EOF
@@ -0,0 +1,59 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestTruncate tests the truncation of documents.
+func TestTruncate(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	in := []byte(`{"s":"abcdefghij","a":[1,2,3,4,5],"o":{"p":{"q":[1]},"r":"xyz"}}`)
+	doc, err := dynaj.Unmarshal(in)
+	assert.NoError(err)
+
+	// No limits.
+	tdoc := doc.Truncate(dynaj.TruncateOptions{})
+	assert.Equal(tdoc.String(), doc.String())
+
+	// Strings and arrays.
+	tdoc = doc.Truncate(dynaj.TruncateOptions{
+		MaxStringLength: 4,
+		MaxArrayLength:  2,
+	})
+	assert.Equal(tdoc.NodeAt("/s").AsString(""), "abcd…(+6 chars)")
+	assert.Equal(tdoc.Length("/a"), 3)
+	assert.Equal(tdoc.NodeAt("/a/2").AsString(""), "…(+3 items)")
+	assert.Equal(tdoc.NodeAt("/o/r").AsString(""), "xyz")
+
+	// Depth.
+	tdoc = doc.Truncate(dynaj.TruncateOptions{
+		MaxDepth: 2,
+	})
+	assert.Equal(tdoc.String(), `{"a":[1,2,3,4,5],"o":{"p":"…(object with 1 keys)","r":"xyz"},"s":"abcdefghij"}`)
+
+	// Original is unchanged and copy is independent.
+	assert.Equal(doc.String(), `{"a":[1,2,3,4,5],"o":{"p":{"q":[1]},"r":"xyz"},"s":"abcdefghij"}`)
+	assert.NoError(tdoc.SetValueAt("/o/r", "changed"))
+	assert.Equal(doc.NodeAt("/o/r").AsString(""), "xyz")
+}
+
+// EOF
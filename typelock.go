@@ -0,0 +1,80 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// TYPE LOCK
+//--------------------
+
+// LockTypes locks the JSON types of the existing values. Afterwards
+// setting a value of another type, e.g. a string instead of a number,
+// returns an error wrapping ErrTypeChange. Null counts as own type.
+// New values can still be set. This protects long-lived documents
+// against accidental type drift. ForceValueAt ignores the lock.
+func (d *Document) LockTypes() {
+	if d.frozen {
+		return
+	}
+	d.typesLocked = true
+}
+
+// UnlockTypes removes the lock of the types.
+func (d *Document) UnlockTypes() {
+	if d.frozen {
+		return
+	}
+	d.typesLocked = false
+}
+
+// TypesLocked returns true if the types of the values are locked.
+func (d *Document) TypesLocked() bool {
+	return d.typesLocked
+}
+
+// ForceValueAt sets the value at the given path like SetValueAt but
+// ignores locked types.
+func (d *Document) ForceValueAt(path Path, value Value) error {
+	return d.setValueAt(path, value, true)
+}
+
+// checkTypeLock checks if the element replaces an existing value of
+// another type while the types are locked.
+func (d *Document) checkTypeLock(keys Keys, element Element) error {
+	if !d.typesLocked {
+		return nil
+	}
+	current, err := elementAt(d.root, keys)
+	if err != nil {
+		// New value.
+		return nil
+	}
+	current, err = decodeRaw(current)
+	if err != nil {
+		return err
+	}
+	if element, err = decodeRaw(element); err != nil {
+		return err
+	}
+	if isObjectOrArray(current) {
+		return nil
+	}
+	if ct, et := typeName(current), typeName(element); ct != et {
+		return fmt.Errorf("%w: cannot set %s at %q: value is %s", ErrTypeChange, et, pathify(keys), ct)
+	}
+	return nil
+}
+
+// EOF
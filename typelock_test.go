@@ -0,0 +1,63 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestLockTypes tests refusing type changes of existing values.
+func TestLockTypes(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"count": 1, "name": "x", "flag": true, "none": null, "list": [1, 2]}`)
+	assert.NoError(doc.SetValueAt("/count", "2"))
+	assert.NoError(doc.SetValueAt("/count", 1))
+
+	doc.LockTypes()
+	assert.True(doc.TypesLocked())
+
+	err := doc.SetValueAt("/count", "2")
+	assert.True(errors.Is(err, dynaj.ErrTypeChange))
+	assert.ErrorContains(err, `type change: cannot set string at "/count": value is number`)
+	assert.ErrorContains(doc.SetValueAt("/flag", 1), "cannot set number at \"/flag\": value is bool")
+	assert.ErrorContains(doc.SetValueAt("/none", 1), "value is null")
+	assert.ErrorContains(doc.SetValueAt("/list/0", "a"), "value is number")
+	assert.ErrorContains(doc.SetRawAt("/name", []byte(`5`)), "value is string")
+	assert.ErrorContains(doc.NodeAt("/name").SetValue(false), "value is string")
+	assert.Equal(doc.NodeAt("/count").AsInt(0), 1)
+
+	// Same types and new values are fine.
+	assert.NoError(doc.SetValueAt("/count", 2.5))
+	assert.NoError(doc.SetValueAt("/name", "y"))
+	assert.NoError(doc.SetRawAt("/name", []byte(`"z"`)))
+	assert.NoError(doc.SetValueAt("/list/2", "new"))
+	assert.NoError(doc.SetValueAt("/other", []int{1}))
+
+	// Forced changes.
+	assert.NoError(doc.ForceValueAt("/count", "many"))
+	assert.Equal(doc.NodeAt("/count").AsString(""), "many")
+
+	doc.UnlockTypes()
+	assert.False(doc.TypesLocked())
+	assert.NoError(doc.SetValueAt("/flag", 1))
+}
+
+// EOF
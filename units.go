@@ -0,0 +1,202 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+//--------------------
+// UNIT TABLES
+//--------------------
+
+// Quantities with predefined unit tables.
+const (
+	// Bytes is the quantity of data sizes with the base unit byte.
+	Bytes = "bytes"
+
+	// Duration is the quantity of durations with the base unit
+	// nanosecond.
+	Duration = "duration"
+)
+
+// UnitTable maps unit suffixes to the factors converting values into
+// the base unit of their quantity. Suffixes are looked up first as they
+// are and then case-insensitive.
+type UnitTable map[string]float64
+
+// ByteUnits contains the decimal and binary units of data sizes.
+var ByteUnits = UnitTable{
+	"B":   1,
+	"KB":  1e3,
+	"MB":  1e6,
+	"GB":  1e9,
+	"TB":  1e12,
+	"PB":  1e15,
+	"KiB": 1 << 10,
+	"MiB": 1 << 20,
+	"GiB": 1 << 30,
+	"TiB": 1 << 40,
+	"PiB": 1 << 50,
+}
+
+// DurationUnits contains the units of durations including days and
+// weeks.
+var DurationUnits = UnitTable{
+	"ns": float64(time.Nanosecond),
+	"us": float64(time.Microsecond),
+	"µs": float64(time.Microsecond),
+	"ms": float64(time.Millisecond),
+	"s":  float64(time.Second),
+	"m":  float64(time.Minute),
+	"h":  float64(time.Hour),
+	"d":  float64(24 * time.Hour),
+	"w":  float64(7 * 24 * time.Hour),
+}
+
+// SetUnits sets the unit table of a quantity used when reading strings
+// with units from nodes of the document. This way units for lengths,
+// weights, or currencies can be added and the predefined tables of
+// Bytes and Duration can be replaced. A nil table removes the quantity
+// or restores the predefined one. Frozen documents keep their tables.
+func (d *Document) SetUnits(quantity string, table UnitTable) {
+	if d.frozen {
+		return
+	}
+	// Copy the tables, derived documents share them.
+	units := make(map[string]UnitTable, len(d.units)+1)
+	for q, t := range d.units {
+		units[q] = t
+	}
+	if table == nil {
+		delete(units, quantity)
+	} else {
+		units[quantity] = UnitTable{}
+		for unit, factor := range table {
+			units[quantity][strings.TrimSpace(unit)] = factor
+		}
+	}
+	d.units = units
+}
+
+// unitTable returns the unit table of the quantity.
+func (node *Node) unitTable(quantity string) UnitTable {
+	if node.doc != nil {
+		if table, ok := node.doc.units[quantity]; ok {
+			return table
+		}
+	}
+	switch quantity {
+	case Bytes:
+		return ByteUnits
+	case Duration:
+		return DurationUnits
+	}
+	return nil
+}
+
+// factor returns the factor of the unit.
+func (t UnitTable) factor(unit string) (float64, bool) {
+	if unit == "" {
+		return 1, true
+	}
+	if f, ok := t[unit]; ok {
+		return f, true
+	}
+	for u, f := range t {
+		if strings.EqualFold(u, unit) {
+			return f, true
+		}
+	}
+	return 0, false
+}
+
+//--------------------
+// UNIT ACCESSORS
+//--------------------
+
+// AsUnit returns the value converted into the base unit of the quantity.
+// Strings like "3km" are split into the number and the unit, which is
+// looked up in the unit table of the quantity. Numbers and strings
+// without unit are taken as base unit.
+func (node *Node) AsUnit(quantity string, dv float64) float64 {
+	f, err := node.asUnit(quantity)
+	if err != nil {
+		return dv
+	}
+	return f
+}
+
+// AsBytesSize returns the value in bytes, e.g. 5000000 for "5MB" or
+// 5242880 for "5MiB". Fractions of bytes are truncated.
+func (node *Node) AsBytesSize(dv int64) int64 {
+	f, err := node.asUnit(Bytes)
+	if err != nil || f < 0 || f >= math.MaxInt64 {
+		return dv
+	}
+	return int64(f)
+}
+
+// AsDurationString returns the value as duration. Strings are parsed
+// like by time.ParseDuration, also understanding the units of the
+// Duration table like "2d". Numbers are taken as nanoseconds.
+func (node *Node) AsDurationString(dv time.Duration) time.Duration {
+	if s, ok := node.element.(string); ok {
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			return d
+		}
+	}
+	f, err := node.asUnit(Duration)
+	if err != nil || math.Abs(f) >= math.MaxInt64 {
+		return dv
+	}
+	return time.Duration(f)
+}
+
+// asUnit converts the value into the base unit of the quantity.
+func (node *Node) asUnit(quantity string) (float64, error) {
+	if node.isUndefinedValue() {
+		return 0, fmt.Errorf("undefined value at %q", node.path)
+	}
+	switch tv := node.element.(type) {
+	case int:
+		return float64(tv), nil
+	case float64:
+		return tv, nil
+	case string:
+		table := node.unitTable(quantity)
+		if table == nil {
+			return 0, fmt.Errorf("unknown quantity %q", quantity)
+		}
+		s := strings.TrimSpace(tv)
+		end := strings.LastIndexAny(s, "0123456789")
+		if end < 0 {
+			return 0, fmt.Errorf("invalid value %q at %q", tv, node.path)
+		}
+		number, unit := s[:end+1], strings.TrimSpace(s[end+1:])
+		factor, ok := table.factor(unit)
+		if !ok {
+			return 0, fmt.Errorf("unknown unit %q at %q", unit, node.path)
+		}
+		f, err := node.numberFormat().parseFloat(number)
+		if err != nil {
+			return 0, fmt.Errorf("invalid value %q at %q", tv, node.path)
+		}
+		return f * factor, nil
+	}
+	return 0, fmt.Errorf("invalid value at %q", node.path)
+}
+
+// EOF
@@ -0,0 +1,83 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+	"time"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestAsBytesSize tests reading data sizes with units.
+func TestAsBytesSize(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"a": "5MB", "b": "5 MiB", "c": "1.5kb", "d": 512, "e": "100",
+		"f": "5XB", "g": "-1KB", "h": "MB", "i": null
+	}`)
+
+	assert.Equal(doc.NodeAt("/a").AsBytesSize(-1), int64(5000000))
+	assert.Equal(doc.NodeAt("/b").AsBytesSize(-1), int64(5242880))
+	assert.Equal(doc.NodeAt("/c").AsBytesSize(-1), int64(1500))
+	assert.Equal(doc.NodeAt("/d").AsBytesSize(-1), int64(512))
+	assert.Equal(doc.NodeAt("/e").AsBytesSize(-1), int64(100))
+	assert.Equal(doc.NodeAt("/f").AsBytesSize(-1), int64(-1))
+	assert.Equal(doc.NodeAt("/g").AsBytesSize(-1), int64(-1))
+	assert.Equal(doc.NodeAt("/h").AsBytesSize(-1), int64(-1))
+	assert.Equal(doc.NodeAt("/i").AsBytesSize(-1), int64(-1))
+	assert.Equal(doc.NodeAt("/x").AsBytesSize(-1), int64(-1))
+}
+
+// TestAsDurationString tests reading durations with units.
+func TestAsDurationString(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a": "10s", "b": "1h30m", "c": "2d", "d": "1.5 w", "e": 1000, "f": "soon"}`)
+
+	assert.Equal(doc.NodeAt("/a").AsDurationString(0), 10*time.Second)
+	assert.Equal(doc.NodeAt("/b").AsDurationString(0), 90*time.Minute)
+	assert.Equal(doc.NodeAt("/c").AsDurationString(0), 48*time.Hour)
+	assert.Equal(doc.NodeAt("/d").AsDurationString(0), 252*time.Hour)
+	assert.Equal(doc.NodeAt("/e").AsDurationString(0), time.Microsecond)
+	assert.Equal(doc.NodeAt("/f").AsDurationString(time.Minute), time.Minute)
+}
+
+// TestUnitTables tests registering own quantities.
+func TestUnitTables(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"distance": "3km", "short": "250 m", "size": "2K"}`)
+
+	assert.Equal(doc.NodeAt("/distance").AsUnit("length", -1), -1.0)
+	doc.SetUnits("length", dynaj.UnitTable{"m": 1, "km": 1000})
+	assert.Equal(doc.NodeAt("/distance").AsUnit("length", -1), 3000.0)
+	assert.Equal(doc.NodeAt("/short").AsUnit("length", -1), 250.0)
+
+	frozen := doc.Freeze()
+	doc.SetUnits(dynaj.Bytes, dynaj.UnitTable{"K": 1024})
+	assert.Equal(doc.NodeAt("/size").AsBytesSize(-1), int64(2048))
+	assert.Equal(frozen.NodeAt("/size").AsBytesSize(-1), int64(-1))
+	assert.Equal(frozen.NodeAt("/distance").AsUnit("length", -1), 3000.0)
+
+	doc.SetUnits(dynaj.Bytes, nil)
+	assert.Equal(doc.NodeAt("/size").AsBytesSize(-1), int64(-1))
+	doc.SetUnits("length", nil)
+	assert.Equal(doc.NodeAt("/distance").AsUnit("length", -1), -1.0)
+	assert.Equal(frozen.NodeAt("/distance").AsUnit("length", -1), 3000.0)
+}
+
+// EOF
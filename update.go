@@ -0,0 +1,100 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+)
+
+//--------------------
+// CONDITIONAL UPDATES
+//--------------------
+
+// UpdateWhere finds the values with paths matching the pattern, filters
+// them with the predicate, and sets the values returned by the update
+// function. A nil predicate accepts all nodes. Empty objects and arrays
+// are replaced in place. All new values are computed before the document
+// is changed, failing checks or hooks while setting them roll back the
+// already set ones. So in case of an error the document stays unchanged,
+// only hooks already called are not undone. The number of changed
+// values is returned, nodes getting the same value are not counted.
+func (d *Document) UpdateWhere(pattern string, pred func(*Node) bool, fn func(*Node) (Value, error)) (int, error) {
+	if d.frozen {
+		return 0, ErrFrozen
+	}
+	nodes, err := d.Root().Query(pattern)
+	if err != nil {
+		return 0, err
+	}
+	type update struct {
+		node  *Node
+		value Value
+	}
+	updates := []update{}
+	for _, node := range nodes {
+		if pred != nil && !pred(node) {
+			continue
+		}
+		value, err := fn(node)
+		if err != nil {
+			return 0, fmt.Errorf("cannot update value at %q: %v", node.path, err)
+		}
+		value, err = normalizeValue(value, node.path, d.nonFinite)
+		if err != nil {
+			return 0, fmt.Errorf("cannot update value at %q: %v", node.path, err)
+		}
+		if equalElements(node.element, value) {
+			continue
+		}
+		updates = append(updates, update{node, value})
+	}
+	if len(updates) == 0 {
+		return 0, nil
+	}
+	err = d.atomically(func() error {
+		for _, u := range updates {
+			if err := d.replaceValueAt(u.node.path, u.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(updates), nil
+}
+
+// atomically performs the changes. If they fail the root and the log
+// of the recorded operations are restored. Called hooks are not undone.
+func (d *Document) atomically(changes func() error) error {
+	root := copyElement(d.root)
+	d.mu.Lock()
+	recorded := len(d.operations)
+	d.mu.Unlock()
+	err := changes()
+	if err == nil {
+		return nil
+	}
+	d.mu.Lock()
+	if len(d.operations) > recorded {
+		d.operations = d.operations[:recorded]
+	}
+	d.mu.Unlock()
+	d.root = root
+	d.shared = false
+	d.owned = nil
+	d.changed(nil)
+	return err
+}
+
+// EOF
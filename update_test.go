@@ -0,0 +1,111 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestUpdateWhere tests the conditional updating of values.
+func TestUpdateWhere(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{
+		"items": [
+			{"name": "a", "price": 10, "tags": []},
+			{"name": "b", "price": 25, "tags": ["x"]},
+			{"name": "c", "price": 40, "tags": []}
+		]
+	}`)
+
+	// Increase the prices above 20.
+	n, err := doc.UpdateWhere("/items/*/price", func(node *dynaj.Node) bool {
+		return node.AsInt(0) > 20
+	}, func(node *dynaj.Node) (dynaj.Value, error) {
+		return node.AsInt(0) + 5, nil
+	})
+	assert.NoError(err)
+	assert.Equal(n, 2)
+	assert.Equal(doc.NodeAt("/items/0/price").AsInt(0), 10)
+	assert.Equal(doc.NodeAt("/items/1/price").AsInt(0), 30)
+	assert.Equal(doc.NodeAt("/items/2/price").AsInt(0), 45)
+
+	// Unchanged values are not counted.
+	n, err = doc.UpdateWhere("/items/*/name", nil, func(node *dynaj.Node) (dynaj.Value, error) {
+		if node.AsString("") == "b" {
+			return "B", nil
+		}
+		return node.AsString(""), nil
+	})
+	assert.NoError(err)
+	assert.Equal(n, 1)
+	assert.Equal(doc.NodeAt("/items/1/name").AsString(""), "B")
+
+	// Empty containers are replaced.
+	n, err = doc.UpdateWhere("/items/*/tags", nil, func(node *dynaj.Node) (dynaj.Value, error) {
+		return "none", nil
+	})
+	assert.NoError(err)
+	assert.Equal(n, 2)
+	assert.Equal(doc.NodeAt("/items/0/tags").AsString(""), "none")
+	assert.Equal(doc.NodeAt("/items/1/tags/0").AsString(""), "x")
+
+	// Errors leave the document unchanged.
+	before := doc.String()
+	_, err = doc.UpdateWhere("/items/*/price", nil, func(node *dynaj.Node) (dynaj.Value, error) {
+		if node.AsInt(0) > 40 {
+			return nil, errors.New("too expensive")
+		}
+		return 0, nil
+	})
+	assert.ErrorContains(err, `cannot update value at "/items/2/price": too expensive`)
+	assert.Equal(doc.String(), before)
+
+	// Empty containers in arrays are replaced in place.
+	doc = mustUnmarshal(assert, `{"arr":[[],1,{}]}`)
+	n, err = doc.UpdateWhere("/arr/*", nil, func(node *dynaj.Node) (dynaj.Value, error) {
+		return "x", nil
+	})
+	assert.NoError(err)
+	assert.Equal(n, 3)
+	assert.Equal(doc.String(), `{"arr":["x","x","x"]}`)
+
+	// Failing hooks roll back the already set values.
+	doc = mustUnmarshal(assert, `{"a":1,"b":2,"c":3}`)
+	doc.RecordOperations()
+	doc.AddHook(dynaj.BeforeSet, func(path dynaj.Path, old, value dynaj.Value) error {
+		if path == "/c" {
+			return errors.New("c is fixed")
+		}
+		return nil
+	})
+	before = doc.String()
+	_, err = doc.UpdateWhere("/*", nil, func(node *dynaj.Node) (dynaj.Value, error) {
+		return node.AsInt(0) * 10, nil
+	})
+	assert.ErrorContains(err, "c is fixed")
+	assert.Equal(doc.String(), before)
+	assert.Length(doc.Operations(), 1)
+
+	_, err = doc.Freeze().UpdateWhere("*", nil, nil)
+	assert.Equal(err, dynaj.ErrFrozen)
+}
+
+// EOF
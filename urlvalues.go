@@ -0,0 +1,162 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//--------------------
+// ENCODING STYLE
+//--------------------
+
+// EncodingStyle defines how paths are encoded as keys of URL values.
+type EncodingStyle int
+
+// Styles for encoding paths as keys.
+const (
+	// BracketStyle encodes paths like "a[b][0]".
+	BracketStyle EncodingStyle = iota
+
+	// DotStyle encodes paths like "a.b.0".
+	DotStyle
+)
+
+//--------------------
+// URL VALUES
+//--------------------
+
+// ToURLValues converts the document into URL values, e.g. for query
+// strings or form payloads. Each value gets a key built from its path
+// in the given style, null becomes an empty string. Empty objects and
+// arrays are skipped. The root has to be an object or an array.
+func (d *Document) ToURLValues(style EncodingStyle) (url.Values, error) {
+	root, err := decodeRaw(d.root)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert to URL values: %v", err)
+	}
+	if !isObjectOrArray(root) {
+		return nil, fmt.Errorf("cannot convert to URL values: root is no object or array")
+	}
+	values := url.Values{}
+	err = d.Root().Process(func(node *Node) error {
+		if !node.IsValue() {
+			return nil
+		}
+		values.Add(encodeKeys(node.SplitPath(), style), node.AsString(""))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert to URL values: %v", err)
+	}
+	return values, nil
+}
+
+// FromURLValues creates a document out of URL values. Keys may use the
+// bracket style like "a[b][0]" or the dot style like "a.b.0". Numeric
+// keys create arrays, empty brackets like "a[]" append to them. Multiple
+// values of other keys are collected in arrays. All values are strings.
+func FromURLValues(values url.Values) (*Document, error) {
+	doc := NewDocument()
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		keys, err := decodeKey(name)
+		if err != nil {
+			return nil, err
+		}
+		vs := values[name]
+		appending := len(keys) > 0 && keys[len(keys)-1] == ""
+		if appending {
+			keys = keys[:len(keys)-1]
+		}
+		if !appending && len(vs) == 1 {
+			if err := doc.SetValueAt(pathify(keys), vs[0]); err != nil {
+				return nil, fmt.Errorf("cannot set value of key %q: %v", name, err)
+			}
+			continue
+		}
+		// Append all values to the array.
+		path := pathify(keys)
+		start := 0
+		if node := doc.NodeAt(path); node.IsArray() {
+			start = doc.Length(path)
+		}
+		for i, v := range vs {
+			if err := doc.SetValueAt(appendKey(path, strconv.Itoa(start+i)), v); err != nil {
+				return nil, fmt.Errorf("cannot set value of key %q: %v", name, err)
+			}
+		}
+	}
+	return doc, nil
+}
+
+// encodeKeys builds the key of the path in the given style.
+func encodeKeys(keys Keys, style EncodingStyle) string {
+	if style == DotStyle {
+		return strings.Join(keys, ".")
+	}
+	var b strings.Builder
+	for i, key := range keys {
+		if i == 0 {
+			b.WriteString(key)
+			continue
+		}
+		b.WriteString("[" + key + "]")
+	}
+	return b.String()
+}
+
+// decodeKey splits a key in bracket or dot style into the keys of a
+// path. Empty brackets at the end are returned as empty key.
+func decodeKey(name string) (Keys, error) {
+	if name == "" || strings.Contains(name, Separator) {
+		return nil, fmt.Errorf("invalid key %q", name)
+	}
+	open := strings.IndexByte(name, '[')
+	if open < 0 {
+		keys := strings.Split(name, ".")
+		for _, key := range keys {
+			if key == "" {
+				return nil, fmt.Errorf("invalid key %q", name)
+			}
+		}
+		return keys, nil
+	}
+	if open == 0 {
+		return nil, fmt.Errorf("invalid key %q", name)
+	}
+	keys := Keys{name[:open]}
+	rest := name[open:]
+	for rest != "" {
+		end := strings.IndexByte(rest, ']')
+		if rest[0] != '[' || end < 0 {
+			return nil, fmt.Errorf("invalid key %q", name)
+		}
+		key := rest[1:end]
+		rest = rest[end+1:]
+		if key == "" && rest != "" {
+			return nil, fmt.Errorf("invalid key %q: empty brackets have to be last", name)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// EOF
@@ -0,0 +1,71 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"net/url"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestToURLValues tests converting documents into URL values.
+func TestToURLValues(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a":{"b":[1,"x"],"c":true},"d":null,"e":{},"f":1.5}`)
+
+	values, err := doc.ToURLValues(dynaj.BracketStyle)
+	assert.NoError(err)
+	assert.Equal(values.Encode(), "a%5Bb%5D%5B0%5D=1&a%5Bb%5D%5B1%5D=x&a%5Bc%5D=true&d=&f=1.5")
+
+	values, err = doc.ToURLValues(dynaj.DotStyle)
+	assert.NoError(err)
+	assert.Equal(values.Encode(), "a.b.0=1&a.b.1=x&a.c=true&d=&f=1.5")
+
+	_, err = mustUnmarshal(assert, `"foo"`).ToURLValues(dynaj.DotStyle)
+	assert.ErrorContains(err, "root is no object or array")
+}
+
+// TestFromURLValues tests creating documents out of URL values.
+func TestFromURLValues(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+
+	values, err := url.ParseQuery("a[b][0]=x&a[b][1]=y&a[c]=z&tags[]=red&tags[]=blue&n.m=1&multi=1&multi=2")
+	assert.NoError(err)
+	doc, err := dynaj.FromURLValues(values)
+	assert.NoError(err)
+	assert.Equal(doc.String(), `{"a":{"b":["x","y"],"c":"z"},"multi":["1","2"],"n":{"m":"1"},"tags":["red","blue"]}`)
+
+	// Round trip.
+	for _, style := range []dynaj.EncodingStyle{dynaj.BracketStyle, dynaj.DotStyle} {
+		values, err := doc.ToURLValues(style)
+		assert.NoError(err)
+		again, err := dynaj.FromURLValues(values)
+		assert.NoError(err)
+		assert.Equal(again.String(), doc.String())
+	}
+
+	for _, query := range []string{"a[b=1", "a[]x=1", "[a]=1", "a..b=1", "a/b=1", "a=1&a[b]=2"} {
+		values, err := url.ParseQuery(query)
+		assert.NoError(err)
+		_, err = dynaj.FromURLValues(values)
+		assert.ErrorMatch(err, ".*(invalid key|cannot set value).*", query)
+	}
+}
+
+// EOF
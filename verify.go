@@ -0,0 +1,104 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+)
+
+//--------------------
+// VERIFICATION
+//--------------------
+
+// Verify checks the internal invariants of the document. Only objects,
+// arrays, and simple values are allowed, floats must not be NaN or
+// infinite, and objects or arrays must not contain themselves. The
+// returned error names the path of the first violation.
+func Verify(doc *Document) error {
+	return verifyElement(doc.root, Separator, map[uintptr]struct{}{})
+}
+
+// verifyElement recursively verifies an element. The visited containers
+// of the current branch are used to detect cycles.
+func verifyElement(element Element, path Path, visited map[uintptr]struct{}) error {
+	switch typed := element.(type) {
+	case nil, bool, string:
+		return nil
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		return nil
+	case float32:
+		return verifyFloat(float64(typed), path)
+	case float64:
+		return verifyFloat(typed, path)
+	case *Attachment:
+		return nil
+	case *records:
+		return verifyElement(typed.expand(), path, visited)
+	case json.RawMessage:
+		if !json.Valid(typed) {
+			return fmt.Errorf("invalid element at %q: invalid raw JSON", path)
+		}
+		return nil
+	case Object:
+		ptr := reflect.ValueOf(typed).Pointer()
+		if _, ok := visited[ptr]; ok {
+			return fmt.Errorf("invalid element at %q: object contains itself", path)
+		}
+		visited[ptr] = struct{}{}
+		defer delete(visited, ptr)
+		for key, child := range typed {
+			if err := verifyElement(child, appendKey(path, key), visited); err != nil {
+				return err
+			}
+		}
+		return nil
+	case Array:
+		if len(typed) == 0 {
+			return nil
+		}
+		ptr := reflect.ValueOf(typed).Pointer()
+		if _, ok := visited[ptr]; ok {
+			return fmt.Errorf("invalid element at %q: array contains itself", path)
+		}
+		visited[ptr] = struct{}{}
+		defer delete(visited, ptr)
+		for idx, child := range typed {
+			if err := verifyElement(child, appendKey(path, strconv.Itoa(idx)), visited); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	// Any other Go type.
+	rv := reflect.ValueOf(element)
+	if rv.Kind() == reflect.Map && rv.Type().Key().Kind() != reflect.String {
+		return fmt.Errorf("invalid element at %q: map with non-string keys %T", path, element)
+	}
+	return fmt.Errorf("invalid element at %q: unsupported type %T", path, element)
+}
+
+// verifyFloat checks that a float is neither NaN nor infinite.
+func verifyFloat(f float64, path Path) error {
+	switch {
+	case math.IsNaN(f):
+		return fmt.Errorf("invalid element at %q: NaN float", path)
+	case math.IsInf(f, 0):
+		return fmt.Errorf("invalid element at %q: infinite float", path)
+	}
+	return nil
+}
+
+// EOF
@@ -0,0 +1,68 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"math"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestVerify tests the verification of document invariants.
+func TestVerify(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	bs, _ := createDocument(assert)
+
+	// Valid documents.
+	doc, err := dynaj.Unmarshal(bs)
+	assert.NoError(err)
+	assert.NoError(dynaj.Verify(doc))
+	assert.NoError(dynaj.Verify(dynaj.NewDocument()))
+
+	doc = dynaj.NewDocument()
+	shared := dynaj.Object{"x": 1}
+	assert.NoError(doc.SetValueAt("/a", shared))
+	assert.NoError(doc.SetValueAt("/b", shared))
+	assert.NoError(doc.SetValueAt("/c/0", 1))
+	assert.NoError(dynaj.Verify(doc))
+
+	// Invalid floats, types, and cycles can only be introduced by
+	// changing inserted objects and arrays afterwards.
+	doc = dynaj.NewDocument()
+	obj := dynaj.Object{}
+	assert.NoError(doc.SetValueAt("/a", obj))
+	obj["b"] = math.NaN()
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/b": NaN float`)
+	obj["b"] = math.Inf(-1)
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/b": infinite float`)
+	obj["b"] = map[int]string{1: "one"}
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/b": map with non-string keys`)
+	obj["b"] = struct{ A int }{1}
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/b": unsupported type`)
+	obj["b"] = obj
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/b": object contains itself`)
+
+	doc = dynaj.NewDocument()
+	arr := make(dynaj.Array, 1)
+	assert.NoError(doc.SetValueAt("/a", arr))
+	arr[0] = arr
+	assert.ErrorContains(dynaj.Verify(doc), `invalid element at "/a/0": array contains itself`)
+}
+
+// EOF
@@ -0,0 +1,62 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+//--------------------
+// VERSIONING
+//--------------------
+
+// Version returns a stable hash of the document content usable as ETag.
+// Documents with the same content have the same version regardless of
+// the key order. The version is cached and invalidated by the mutation
+// methods of the document. Changes of values set as objects or arrays
+// after inserting them are not detected. If the document cannot be
+// marshaled the version is empty.
+func (d *Document) Version() string {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.version != "" {
+		return d.version
+	}
+	// Marshalling sorts the keys of objects, so it is canonical.
+	root, err := normalizeValue(d.root, Separator, d.nonFinite)
+	if err != nil {
+		return ""
+	}
+	data, err := json.Marshal(root)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	d.version = hex.EncodeToString(sum[:16])
+	return d.version
+}
+
+// SetValueAtIf sets the value at the given path only if the document
+// still has the expected version. Otherwise an error wrapping
+// ErrConflict is returned. This way optimistic locking can be
+// implemented for stored documents.
+func (d *Document) SetValueAtIf(path Path, value Value, version string) error {
+	if current := d.Version(); current != version {
+		return fmt.Errorf("%w: cannot insert value at %q: version is %q, expected %q", ErrConflict, path, current, version)
+	}
+	return d.SetValueAt(path, value)
+}
+
+// EOF
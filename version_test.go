@@ -0,0 +1,78 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"errors"
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestVersion tests the versioning of documents.
+func TestVersion(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"a":1,"b":{"c":[1,2]}}`)
+	same := mustUnmarshal(assert, `{"b":{"c":[1,2]},"a":1}`)
+
+	version := doc.Version()
+	assert.Length(version, 32)
+	assert.Equal(doc.Version(), version)
+	assert.Equal(same.Version(), version)
+
+	// Built documents with ints have the same version.
+	built := dynaj.NewDocument()
+	assert.NoError(built.SetValueAt("/a", 1))
+	assert.NoError(built.SetValueAt("/b/c/0", 1))
+	assert.NoError(built.SetValueAt("/b/c/1", 2))
+	assert.Equal(built.Version(), version)
+
+	// Mutations invalidate the version.
+	assert.NoError(doc.SetValueAt("/a", 2))
+	assert.Different(doc.Version(), version)
+	assert.NoError(doc.SetValueAt("/a", 1))
+	assert.Equal(doc.Version(), version)
+	assert.NoError(doc.DeleteValueAt("/b/c/1"))
+	assert.Different(doc.Version(), version)
+	assert.NoError(doc.DeleteElementAt("/b"))
+	assert.Different(doc.Version(), version)
+	doc.Clear()
+	assert.Equal(doc.Version(), dynaj.NewDocument().Version())
+}
+
+// TestSetValueAtIf tests conditional updates.
+func TestSetValueAtIf(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"counter":1}`)
+
+	version := doc.Version()
+	assert.NoError(doc.SetValueAtIf("/counter", 2, version))
+	assert.Equal(doc.NodeAt("/counter").AsInt(0), 2)
+
+	// Second writer with the old version fails.
+	err := doc.SetValueAtIf("/counter", 3, version)
+	assert.True(errors.Is(err, dynaj.ErrConflict))
+	assert.ErrorContains(err, `cannot insert value at "/counter"`)
+	assert.Equal(doc.NodeAt("/counter").AsInt(0), 2)
+
+	// Retry with the current version.
+	assert.NoError(doc.SetValueAtIf("/counter", 3, doc.Version()))
+	assert.Equal(doc.NodeAt("/counter").AsInt(0), 3)
+}
+
+// EOF
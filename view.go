@@ -0,0 +1,117 @@
+// Tideland Go Dynamic JSON
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj // import "tideland.dev/go/dynaj"
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+//--------------------
+// VIEW
+//--------------------
+
+// View is a window onto a subtree of a document. All paths are relative
+// to the subtree while the storage is shared with the document, so
+// changes are visible in both directions without copying.
+type View struct {
+	doc  *Document
+	base Path
+}
+
+// ViewAt returns a view onto the subtree at the given path.
+func (d *Document) ViewAt(path Path) (*View, error) {
+	keys := splitPath(path)
+	if _, err := elementAt(d.root, keys); err != nil {
+		return nil, fmt.Errorf("invalid path %q: %v", path, err)
+	}
+	return &View{
+		doc:  d,
+		base: pathify(keys),
+	}, nil
+}
+
+// Base returns the path of the view inside the document.
+func (v *View) Base() Path {
+	return v.base
+}
+
+// Length returns the number of elements for the given path.
+func (v *View) Length(path Path) int {
+	return v.doc.Length(v.absolute(path))
+}
+
+// SetValueAt sets the value at the given path.
+func (v *View) SetValueAt(path Path, value Value) error {
+	return v.doc.SetValueAt(v.absolute(path), value)
+}
+
+// DeleteValueAt deletes the value at the given path.
+func (v *View) DeleteValueAt(path Path) error {
+	return v.doc.DeleteValueAt(v.absolute(path))
+}
+
+// DeleteElementAt deletes the element at the given path.
+func (v *View) DeleteElementAt(path Path) error {
+	return v.doc.DeleteElementAt(v.absolute(path))
+}
+
+// NodeAt returns the addressed value. Its path is relative to the view.
+func (v *View) NodeAt(path Path) *Node {
+	keys := splitPath(path)
+	node := &Node{
+		path: pathify(keys),
+		doc:  v.doc,
+		base: v.base,
+	}
+	element, err := elementAt(v.doc.root, append(splitPath(v.base), keys...))
+	if err != nil {
+		node.err = fmt.Errorf("invalid path %q: %v", path, err)
+	} else {
+		node.element = decodedElement(element)
+	}
+	return node
+}
+
+// Root returns the root node of the view.
+func (v *View) Root() *Node {
+	return v.NodeAt(Separator)
+}
+
+// MarshalJSON implements json.Marshaler.
+func (v *View) MarshalJSON() ([]byte, error) {
+	node := v.Root()
+	if node.IsError() {
+		return nil, node.Err()
+	}
+	root, err := normalizeValue(node.element, v.base, v.doc.nonFinite)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal view: %v", err)
+	}
+	return json.Marshal(root)
+}
+
+// String implements fmt.Stringer.
+func (v *View) String() string {
+	data, err := v.MarshalJSON()
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// absolute returns the absolute path inside the document.
+func (v *View) absolute(path Path) Path {
+	return joinPaths(v.base, path)
+}
+
+// EOF
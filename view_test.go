@@ -0,0 +1,74 @@
+// Tideland Go Dynamic JSON - Unit Tests
+//
+// Copyright (C) 2019-2023 Frank Mueller / Tideland / Oldenburg / Germany
+//
+// All rights reserved. Use of this source code is governed
+// by the new BSD license.
+
+package dynaj_test
+
+//--------------------
+// IMPORTS
+//--------------------
+
+import (
+	"testing"
+
+	"tideland.dev/go/audit/asserts"
+
+	"tideland.dev/go/dynaj"
+)
+
+//--------------------
+// TESTS
+//--------------------
+
+// TestView tests views onto subtrees.
+func TestView(t *testing.T) {
+	assert := asserts.NewTesting(t, asserts.FailStop)
+	doc := mustUnmarshal(assert, `{"meta":{"name":"app"},"spec":{"replicas":2,"ports":[80,443]}}`)
+
+	view, err := doc.ViewAt("spec")
+	assert.NoError(err)
+	assert.Equal(view.Base(), "/spec")
+	assert.Equal(view.String(), `{"ports":[80,443],"replicas":2}`)
+	assert.Equal(view.Length("/ports"), 2)
+	assert.Equal(view.Length("/"), 2)
+
+	// Relative reading.
+	node := view.NodeAt("/replicas")
+	assert.Equal(node.AsInt(0), 2)
+	assert.Equal(node.Path(), "/replicas")
+	nodes, err := view.Root().Query("/ports/*")
+	assert.NoError(err)
+	assert.Length(nodes, 2)
+	node = view.NodeAt("/meta")
+	assert.ErrorContains(node.Err(), "invalid path")
+
+	// Shared writing.
+	assert.NoError(view.SetValueAt("/replicas", 3))
+	assert.NoError(view.SetValueAt("/ports/2", 8080))
+	assert.NoError(view.DeleteValueAt("/ports/0"))
+	assert.Equal(doc.NodeAt("/spec/replicas").AsInt(0), 3)
+	assert.Equal(doc.String(), `{"meta":{"name":"app"},"spec":{"ports":[443,8080],"replicas":3}}`)
+	assert.NoError(doc.SetValueAt("/spec/paused", true))
+	assert.True(view.NodeAt("paused").AsBool(false))
+	assert.NoError(view.DeleteElementAt("/ports"))
+	assert.Equal(doc.Length("/spec"), 2)
+
+	// Frozen documents lead to frozen views.
+	view, err = doc.Freeze().ViewAt("/spec")
+	assert.NoError(err)
+	assert.Equal(view.SetValueAt("/replicas", 1), dynaj.ErrFrozen)
+
+	// Invalid views.
+	_, err = doc.ViewAt("/status")
+	assert.ErrorContains(err, `invalid path "/status"`)
+	view, err = doc.ViewAt("/meta")
+	assert.NoError(err)
+	assert.NoError(doc.DeleteElementAt("/meta"))
+	_, err = view.MarshalJSON()
+	assert.ErrorContains(err, "invalid path")
+}
+
+// EOF